@@ -0,0 +1,24 @@
+// Command fake-claude stands in for the Claude CLI in end-to-end tests,
+// playing the sessions scripted in the scenario file named by
+// FAKE_CLAUDE_SCENARIO. See package bmad-automate/internal/fakeclaude.
+//
+// Usage:
+//
+//	FAKE_CLAUDE_SCENARIO=scenario.yaml BMAD_CLAUDE_PATH=fake-claude bmad-automate run 1-1
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"bmad-automate/internal/fakeclaude"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := fakeclaude.Run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
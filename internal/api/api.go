@@ -0,0 +1,406 @@
+// Package api provides a [claude.Executor] that talks to an Anthropic-compatible
+// Messages API endpoint directly, for machines where the Claude CLI cannot be
+// installed.
+//
+// The [Executor] runs its own tool loop: the model is offered Bash, Read,
+// Write, Edit, Glob, and Grep tools that operate inside the project
+// directory, and every model turn and tool result is reported as the same
+// [claude.Event] stream the Claude CLI produces. [workflow.Runner] and the
+// output printer therefore work unchanged.
+//
+// Key types:
+//   - [Executor]: Messages API implementation of [claude.Executor]
+//   - [Config]: Endpoint, model, and tool settings
+//   - [APIError]: An error status returned by the endpoint
+//
+// Sessions are kept in memory, so [claude.RunOptions.ResumeSessionID] can
+// continue a session started earlier by the same Executor, such as when a
+// failed step is retried.
+//
+// [workflow.Runner]: bmad-automate/internal/workflow.Runner
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"bmad-automate/internal/claude"
+)
+
+// Defaults applied by [NewExecutor].
+const (
+	DefaultBaseURL     = "https://api.anthropic.com"
+	DefaultModel       = "claude-sonnet-4-5"
+	DefaultMaxTokens   = 8192
+	DefaultMaxTurns    = 100
+	DefaultBashTimeout = 2 * time.Minute
+)
+
+// systemPrompt is sent with every session; %s is the project directory.
+const systemPrompt = `You are an autonomous coding agent working in the project directory %s.
+Use the tools to inspect and change the project. Relative paths are resolved against the project directory, and files outside it cannot be accessed.
+Work without asking questions; when a choice is needed, use your best judgment and continue.`
+
+// Config contains configuration for creating an [Executor].
+//
+// All fields except APIKey are optional and have defaults; see [NewExecutor].
+type Config struct {
+	// BaseURL is the endpoint root; requests go to BaseURL + "/v1/messages".
+	BaseURL string
+
+	// APIKey is sent in the x-api-key header. It may be empty for endpoints
+	// that need no key.
+	APIKey string
+
+	// Model is the model name sent with every request.
+	Model string
+
+	// MaxTokens is the max_tokens limit of each model turn.
+	MaxTokens int
+
+	// MaxTurns limits the model turns in one session. A session that reaches
+	// it ends with an error result.
+	MaxTurns int
+
+	// Dir is the project directory the tools operate in.
+	// Defaults to the current working directory.
+	Dir string
+
+	// BashTimeout limits each Bash tool call.
+	BashTimeout time.Duration
+
+	// HTTPClient sends the requests. Defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// Executor implements [claude.Executor] on top of the Messages API.
+//
+// Create instances using [NewExecutor] rather than constructing directly.
+type Executor struct {
+	config Config
+	tools  *toolbox
+
+	mu       sync.Mutex
+	sessions map[string][]message
+}
+
+// NewExecutor creates a new [Executor] with the given configuration.
+//
+// Default values are applied for any unset fields: [DefaultBaseURL],
+// [DefaultModel], [DefaultMaxTokens], [DefaultMaxTurns], [DefaultBashTimeout],
+// the current directory, and [http.DefaultClient].
+func NewExecutor(config Config) *Executor {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultBaseURL
+	}
+	if config.Model == "" {
+		config.Model = DefaultModel
+	}
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = DefaultMaxTokens
+	}
+	if config.MaxTurns <= 0 {
+		config.MaxTurns = DefaultMaxTurns
+	}
+	if config.BashTimeout <= 0 {
+		config.BashTimeout = DefaultBashTimeout
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.Dir == "" {
+		config.Dir = "."
+	}
+	if abs, err := filepath.Abs(config.Dir); err == nil {
+		config.Dir = abs
+	}
+
+	return &Executor{
+		config:   config,
+		tools:    &toolbox{dir: config.Dir, bashTimeout: config.BashTimeout},
+		sessions: make(map[string][]message),
+	}
+}
+
+// Start runs a session in the background and returns its handle; see
+// [claude.Executor.Start].
+func (e *Executor) Start(ctx context.Context, prompt string) (*claude.Session, error) {
+	return claude.StartSession(ctx, func(ctx context.Context, handler claude.EventHandler) (int, error) {
+		return e.ExecuteWithResult(ctx, prompt, handler)
+	}), nil
+}
+
+// ExecuteWithResult runs a session with the given prompt and waits for it to end.
+//
+// The model is called in a loop; after each turn that requests tools, the
+// tools run and their results are sent back, until the model stops asking
+// for tools. Each turn and tool result is passed to handler as the events
+// the Claude CLI would emit, framed by a system init event and a result
+// event carrying the session's token usage. Cost is not known and is zero.
+//
+// The [claude.RunOptions] on ctx are honored: ResumeSessionID continues a
+// session of this Executor with prompt as the next user message, Timeout
+// limits the whole session, IdleTimeout limits each API call, and
+// Permissions limit the tools offered: the "plan" mode offers only the
+// read-only tools, and DisallowedTools removes whole tools ("Bash") or
+// rejects Bash commands by rule ("Bash(git push:*)").
+//
+// Exit code semantics match [claude.DefaultExecutor.ExecuteWithResult]:
+// 0 on success, [claude.ExitCodeTimeout] with a [claude.TimeoutError] for a
+// timeout, and 1 otherwise. A failed API call returns its error, usually an
+// [APIError]; reaching [Config.MaxTurns] ends with an error result event, as
+// does a response cut off at [Config.MaxTokens]. Once ctx is done no further
+// tools run: the tool calls left in the turn are answered as not run, so the
+// session can still be resumed.
+func (e *Executor) ExecuteWithResult(ctx context.Context, prompt string, handler claude.EventHandler) (int, error) {
+	if handler == nil {
+		handler = func(claude.Event) {}
+	}
+	opts := claude.RunOptionsFromContext(ctx)
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, opts.Timeout, &claude.TimeoutError{After: opts.Timeout})
+		defer cancel()
+	}
+
+	sessionID, messages, err := e.startSession(opts.ResumeSessionID, prompt)
+	if err != nil {
+		return 1, err
+	}
+	s := &session{id: sessionID, handler: handler, start: time.Now()}
+	s.emit(&claude.StreamEvent{Type: string(claude.EventTypeSystem), Subtype: claude.SubtypeInit, SessionID: sessionID})
+
+	perms := claude.Permissions{}
+	if opts.Permissions != nil {
+		perms = *opts.Permissions
+	}
+	req := request{
+		Model:     e.config.Model,
+		MaxTokens: e.config.MaxTokens,
+		System:    fmt.Sprintf(systemPrompt, e.config.Dir),
+		Tools:     e.tools.specs(perms),
+	}
+
+	for turn := 1; ; turn++ {
+		if turn > e.config.MaxTurns {
+			s.finish("error_max_turns", true, fmt.Sprintf("reached the limit of %d turns", e.config.MaxTurns))
+			return 1, nil
+		}
+
+		req.Messages = messages
+		resp, err := e.call(ctx, req, opts.IdleTimeout, s)
+		if err != nil {
+			var timeoutErr *claude.TimeoutError
+			if errors.As(err, &timeoutErr) {
+				return claude.ExitCodeTimeout, err
+			}
+			if ctx.Err() == nil {
+				// Like the CLI, report API errors in the result event
+				s.finish("error_during_execution", true, err.Error())
+			}
+			return 1, err
+		}
+
+		s.stats.NumTurns++
+		s.stats.Usage.InputTokens += resp.Usage.InputTokens
+		s.stats.Usage.OutputTokens += resp.Usage.OutputTokens
+		s.stats.Usage.CacheCreationInputTokens += resp.Usage.CacheCreationInputTokens
+		s.stats.Usage.CacheReadInputTokens += resp.Usage.CacheReadInputTokens
+
+		if resp.StopReason != stopToolUse {
+			// Tool calls are only run, and answered, when the model stops to
+			// ask for them; a response cut short may hold a half-formed one.
+			resp.Content = withoutToolUses(resp.Content)
+		}
+		if len(resp.Content) > 0 {
+			messages = append(messages, message{Role: "assistant", Content: resp.Content})
+			e.saveSession(sessionID, messages)
+		}
+		s.emitAssistant(resp)
+
+		switch resp.StopReason {
+		case stopToolUse:
+		case stopMaxTokens:
+			s.finish("error_during_execution", true,
+				fmt.Sprintf("the response was cut off at the limit of %d output tokens", e.config.MaxTokens))
+			return 1, nil
+		default:
+			s.finish("success", false, lastText(resp.Content))
+			return 0, nil
+		}
+
+		var results []block
+		for _, use := range resp.Content {
+			if use.Type != "tool_use" {
+				continue
+			}
+			// Once the session is stopped, the remaining calls are answered
+			// without running them, so none changes files after the stop.
+			out := toolOutput{stderr: "not run: the session was stopped", isError: true, interrupted: true}
+			if ctx.Err() == nil {
+				out = e.tools.run(ctx, perms, use.Name, use.Input)
+			}
+			result := block{Type: "tool_result", ToolUseID: use.ID, Content: out.forModel(), IsError: out.isError}
+			results = append(results, result)
+			s.emit(&claude.StreamEvent{
+				Type:      string(claude.EventTypeUser),
+				SessionID: sessionID,
+				Message: &claude.MessageContent{Content: []claude.ContentBlock{
+					{Type: "tool_result", ToolUseID: use.ID, IsError: out.isError},
+				}},
+				ToolUseResult: &claude.ToolResult{Stdout: out.stdout, Stderr: out.stderr, Interrupted: out.interrupted},
+			})
+		}
+		messages = append(messages, message{Role: "user", Content: results})
+		e.saveSession(sessionID, messages)
+		if ctx.Err() != nil {
+			return stopped(ctx)
+		}
+	}
+}
+
+// stopped returns the exit code and error of a session whose context ended
+// between API calls: [claude.ExitCodeTimeout] with a [claude.TimeoutError]
+// if it timed out, and 1 with the context's error otherwise.
+func stopped(ctx context.Context) (int, error) {
+	var timeoutErr *claude.TimeoutError
+	if errors.As(context.Cause(ctx), &timeoutErr) {
+		return claude.ExitCodeTimeout, timeoutErr
+	}
+	return 1, ctx.Err()
+}
+
+// call makes one API call, limited to idleTimeout when set, and records the
+// time spent waiting on the API.
+func (e *Executor) call(ctx context.Context, req request, idleTimeout time.Duration, s *session) (*response, error) {
+	callCtx := ctx
+	if idleTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeoutCause(ctx, idleTimeout, &claude.TimeoutError{Idle: true, After: idleTimeout})
+		defer cancel()
+	}
+
+	start := time.Now()
+	resp, err := e.send(callCtx, req)
+	s.stats.DurationAPIMS += time.Since(start).Milliseconds()
+	if err != nil && callCtx.Err() != nil {
+		var timeoutErr *claude.TimeoutError
+		if errors.As(context.Cause(callCtx), &timeoutErr) {
+			return nil, timeoutErr
+		}
+		return nil, callCtx.Err()
+	}
+	return resp, err
+}
+
+// startSession returns the ID and messages of the session to run: a new one
+// with prompt as its first message, or the resumed one with prompt appended.
+func (e *Executor) startSession(resumeID, prompt string) (string, []message, error) {
+	text := block{Type: "text", Text: prompt}
+	if resumeID == "" {
+		return newSessionID(), []message{{Role: "user", Content: []block{text}}}, nil
+	}
+
+	e.mu.Lock()
+	saved, ok := e.sessions[resumeID]
+	e.mu.Unlock()
+	if !ok {
+		return "", nil, fmt.Errorf("unknown session %s: API sessions can only be resumed by the process that started them", resumeID)
+	}
+
+	messages := make([]message, len(saved), len(saved)+1)
+	copy(messages, saved)
+	if last := &messages[len(messages)-1]; last.Role == "user" {
+		// The session stopped before the model answered; add to that turn
+		last.Content = append(append([]block(nil), last.Content...), text)
+	} else {
+		messages = append(messages, message{Role: "user", Content: []block{text}})
+	}
+	return resumeID, messages, nil
+}
+
+// saveSession records a session's messages for later resumption.
+func (e *Executor) saveSession(id string, messages []message) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sessions[id] = messages
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) //nolint:errcheck // crypto/rand.Read never fails
+	return hex.EncodeToString(b[:])
+}
+
+// withoutToolUses returns content without its tool_use blocks.
+func withoutToolUses(content []block) []block {
+	return slices.DeleteFunc(slices.Clone(content), func(b block) bool {
+		return b.Type == "tool_use"
+	})
+}
+
+// lastText returns the last text block of a response, used as the result text.
+func lastText(content []block) string {
+	for i := len(content) - 1; i >= 0; i-- {
+		if content[i].Type == "text" {
+			return content[i].Text
+		}
+	}
+	return ""
+}
+
+// session emits the events of one running session.
+type session struct {
+	id      string
+	handler claude.EventHandler
+	start   time.Time
+	stats   claude.ResultStats
+}
+
+// emit passes the events of a stream event to the handler.
+func (s *session) emit(raw *claude.StreamEvent) {
+	for _, event := range claude.NewEventsFromStream(raw) {
+		s.handler(event)
+	}
+}
+
+// emitAssistant emits a model turn as an assistant event.
+func (s *session) emitAssistant(resp *response) {
+	content := make([]claude.ContentBlock, 0, len(resp.Content))
+	for _, b := range resp.Content {
+		cb := claude.ContentBlock{Type: b.Type, Text: b.Text, Thinking: b.Thinking, ID: b.ID, Name: b.Name}
+		if b.Type == "tool_use" {
+			cb.Input = b.Input
+		}
+		content = append(content, cb)
+	}
+	usage := resp.Usage
+	s.emit(&claude.StreamEvent{
+		Type:      string(claude.EventTypeAssistant),
+		SessionID: s.id,
+		Message:   &claude.MessageContent{ID: resp.ID, Content: content, Usage: &usage},
+	})
+}
+
+// finish emits the result event that ends the session.
+func (s *session) finish(subtype string, isError bool, result string) {
+	stats := s.stats
+	stats.DurationMS = time.Since(s.start).Milliseconds()
+	stats.IsError = isError
+	stats.Result = result
+	s.emit(&claude.StreamEvent{
+		Type:        string(claude.EventTypeResult),
+		Subtype:     subtype,
+		SessionID:   s.id,
+		ResultStats: stats,
+	})
+}
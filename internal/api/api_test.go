@@ -0,0 +1,464 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/claude"
+)
+
+// fakeAPI is a Messages API endpoint that answers each call with the next
+// scripted reply and records the requests it received.
+type fakeAPI struct {
+	t       *testing.T
+	mu      sync.Mutex
+	replies []func(w http.ResponseWriter)
+	reqs    []request
+	headers []http.Header
+}
+
+func newFakeAPI(t *testing.T, replies ...func(w http.ResponseWriter)) (*fakeAPI, *httptest.Server) {
+	f := &fakeAPI{t: t, replies: replies}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(f.t, "/v1/messages", r.URL.Path)
+
+	var req request
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+
+	f.mu.Lock()
+	n := len(f.reqs)
+	f.reqs = append(f.reqs, req)
+	f.headers = append(f.headers, r.Header.Clone())
+	f.mu.Unlock()
+
+	if !assert.Less(f.t, n, len(f.replies), "unexpected API call") {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	f.replies[n](w)
+}
+
+func (f *fakeAPI) requests() []request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reqs
+}
+
+// reply answers with a response.
+func reply(stopReason string, content ...block) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("content-type", "application/json")
+		_ = json.NewEncoder(w).Encode(response{
+			ID:         "msg_" + stopReason,
+			Content:    content,
+			StopReason: stopReason,
+			Usage:      claude.Usage{InputTokens: 100, OutputTokens: 10},
+		})
+	}
+}
+
+// replyError answers with an error status.
+func replyError(status int, errType, message string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"` + errType + `","message":"` + message + `"}}`))
+	}
+}
+
+func text(s string) block {
+	return block{Type: "text", Text: s}
+}
+
+func toolUse(id, name, input string) block {
+	return block{Type: "tool_use", ID: id, Name: name, Input: json.RawMessage(input)}
+}
+
+func newTestExecutor(t *testing.T, server *httptest.Server) *Executor {
+	return NewExecutor(Config{BaseURL: server.URL, APIKey: "test-key", Dir: t.TempDir()})
+}
+
+func collect(t *testing.T, e *Executor, ctx context.Context, prompt string) ([]claude.Event, int, error) {
+	t.Helper()
+	var events []claude.Event
+	code, err := e.ExecuteWithResult(ctx, prompt, func(event claude.Event) {
+		events = append(events, event)
+	})
+	return events, code, err
+}
+
+func TestNewExecutor_Defaults(t *testing.T) {
+	e := NewExecutor(Config{})
+
+	assert.Equal(t, DefaultBaseURL, e.config.BaseURL)
+	assert.Equal(t, DefaultModel, e.config.Model)
+	assert.Equal(t, DefaultMaxTokens, e.config.MaxTokens)
+	assert.Equal(t, DefaultMaxTurns, e.config.MaxTurns)
+	assert.Equal(t, DefaultBashTimeout, e.config.BashTimeout)
+	assert.Equal(t, http.DefaultClient, e.config.HTTPClient)
+	assert.True(t, filepath.IsAbs(e.config.Dir))
+}
+
+func TestExecutor_ExecuteWithResult_TextOnly(t *testing.T) {
+	fake, server := newFakeAPI(t, reply("end_turn", text("All done")))
+	e := newTestExecutor(t, server)
+
+	events, code, err := collect(t, e, context.Background(), "hello")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	require.Len(t, events, 3)
+
+	assert.True(t, events[0].SessionStarted)
+	sessionID := events[0].SessionID
+	assert.NotEmpty(t, sessionID)
+
+	assert.Equal(t, "All done", events[1].Text)
+	assert.Equal(t, "msg_end_turn", events[1].MessageID)
+	require.NotNil(t, events[1].Usage)
+	assert.Equal(t, int64(100), events[1].Usage.InputTokens)
+
+	result := events[2]
+	assert.True(t, result.SessionComplete)
+	assert.Equal(t, sessionID, result.SessionID)
+	require.NotNil(t, result.Stats)
+	assert.False(t, result.Stats.IsError)
+	assert.Equal(t, "All done", result.Stats.Result)
+	assert.Equal(t, 1, result.Stats.NumTurns)
+
+	reqs := fake.requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, DefaultModel, reqs[0].Model)
+	assert.Equal(t, DefaultMaxTokens, reqs[0].MaxTokens)
+	assert.Contains(t, reqs[0].System, e.config.Dir)
+	require.Len(t, reqs[0].Messages, 1)
+	assert.Equal(t, "user", reqs[0].Messages[0].Role)
+	assert.Equal(t, "hello", reqs[0].Messages[0].Content[0].Text)
+	assert.Len(t, reqs[0].Tools, 6)
+
+	assert.Equal(t, "test-key", fake.headers[0].Get("x-api-key"))
+	assert.Equal(t, apiVersion, fake.headers[0].Get("anthropic-version"))
+}
+
+func TestExecutor_ExecuteWithResult_ToolLoop(t *testing.T) {
+	fake, server := newFakeAPI(t,
+		reply("tool_use",
+			text("Let me write the file"),
+			toolUse("tu_1", "Write", `{"file_path":"notes.txt","content":"hi\n"}`),
+			toolUse("tu_2", "Bash", `{"command":"cat notes.txt","description":"Show notes"}`),
+		),
+		reply("end_turn", text("Wrote notes.txt")),
+	)
+	e := newTestExecutor(t, server)
+
+	events, code, err := collect(t, e, context.Background(), "write notes")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	var toolUses, toolResults []claude.Event
+	for _, event := range events {
+		if event.IsToolUse() {
+			toolUses = append(toolUses, event)
+		}
+		if event.Type == claude.EventTypeUser {
+			toolResults = append(toolResults, event)
+		}
+	}
+	require.Len(t, toolUses, 2)
+	assert.Equal(t, "Write", toolUses[0].ToolName)
+	assert.Equal(t, "notes.txt", toolUses[0].ToolFilePath)
+	assert.Equal(t, "Bash", toolUses[1].ToolName)
+	assert.Equal(t, "cat notes.txt", toolUses[1].ToolCommand)
+	assert.Equal(t, "Show notes", toolUses[1].ToolDescription)
+
+	require.Len(t, toolResults, 2)
+	assert.Equal(t, "tu_1", toolResults[0].ToolUseID)
+	assert.Equal(t, "tu_2", toolResults[1].ToolUseID)
+	assert.Equal(t, "hi\n", toolResults[1].ToolStdout)
+	assert.False(t, toolResults[1].ToolIsError)
+
+	data, err := os.ReadFile(filepath.Join(e.config.Dir, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", string(data))
+
+	// The second call carries the tool results back to the model
+	reqs := fake.requests()
+	require.Len(t, reqs, 2)
+	require.Len(t, reqs[1].Messages, 3)
+	assert.Equal(t, "assistant", reqs[1].Messages[1].Role)
+	results := reqs[1].Messages[2]
+	assert.Equal(t, "user", results.Role)
+	require.Len(t, results.Content, 2)
+	assert.Equal(t, "tool_result", results.Content[0].Type)
+	assert.Equal(t, "tu_1", results.Content[0].ToolUseID)
+	assert.Equal(t, "tu_2", results.Content[1].ToolUseID)
+	assert.Equal(t, "hi\n", results.Content[1].Content)
+
+	result := events[len(events)-1]
+	require.NotNil(t, result.Stats)
+	assert.Equal(t, 2, result.Stats.NumTurns)
+	assert.Equal(t, int64(200), result.Stats.Usage.InputTokens)
+	assert.Equal(t, "Wrote notes.txt", result.Stats.Result)
+}
+
+func TestExecutor_ExecuteWithResult_ToolError(t *testing.T) {
+	fake, server := newFakeAPI(t,
+		reply("tool_use", toolUse("tu_1", "Bash", `{"command":"exit 3"}`)),
+		reply("end_turn", text("The command failed")),
+	)
+	e := newTestExecutor(t, server)
+
+	events, code, err := collect(t, e, context.Background(), "run it")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code, "a failed tool does not fail the session")
+
+	var result claude.Event
+	for _, event := range events {
+		if event.IsToolResult() {
+			result = event
+		}
+	}
+	assert.True(t, result.ToolIsError)
+
+	sent := fake.requests()[1].Messages[2].Content[0]
+	assert.True(t, sent.IsError)
+	assert.Contains(t, sent.Content, "exit code 3")
+}
+
+func TestExecutor_ExecuteWithResult_APIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		errType   string
+		wantClass claude.FailureClass
+	}{
+		{"overloaded", 529, "overloaded_error", claude.FailureOverloaded},
+		{"rate limit", 429, "rate_limit_error", claude.FailureRateLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newFakeAPI(t, replyError(tt.status, tt.errType, "try again later"))
+			e := newTestExecutor(t, server)
+
+			events, code, err := collect(t, e, context.Background(), "hello")
+
+			assert.Equal(t, 1, code)
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.errType, apiErr.Type)
+			assert.Equal(t, "try again later", apiErr.Message)
+			assert.Equal(t, tt.wantClass, claude.Classify(err))
+
+			result := events[len(events)-1]
+			require.True(t, result.SessionComplete)
+			assert.True(t, result.Stats.IsError)
+			assert.Equal(t, tt.wantClass, claude.Classify(errors.New(result.Stats.Result)))
+		})
+	}
+}
+
+func TestAPIError_Error(t *testing.T) {
+	err := &APIError{StatusCode: 529, Type: "overloaded_error", Message: "Overloaded"}
+	assert.Equal(t, "API Error: 529 overloaded_error: Overloaded", err.Error())
+
+	err = &APIError{StatusCode: 502}
+	assert.Equal(t, "API Error: 502", err.Error())
+}
+
+func TestExecutor_ExecuteWithResult_MaxTurns(t *testing.T) {
+	loop := reply("tool_use", toolUse("tu_1", "Glob", `{"pattern":"*"}`))
+	fake, server := newFakeAPI(t, loop, loop)
+	e := NewExecutor(Config{BaseURL: server.URL, Dir: t.TempDir(), MaxTurns: 2})
+
+	events, code, err := collect(t, e, context.Background(), "loop")
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, code)
+	assert.Len(t, fake.requests(), 2)
+
+	result := events[len(events)-1]
+	require.True(t, result.SessionComplete)
+	assert.Equal(t, "error_max_turns", result.Subtype)
+	assert.True(t, result.Stats.IsError)
+}
+
+func TestExecutor_ExecuteWithResult_MaxTokens(t *testing.T) {
+	fake, server := newFakeAPI(t,
+		reply("max_tokens", text("Writing the file"), toolUse("tu_1", "Write", `{"file_path":"notes.txt"}`)),
+		reply("end_turn", text("done")),
+	)
+	e := newTestExecutor(t, server)
+
+	events, code, err := collect(t, e, context.Background(), "write notes")
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, code, "a cut-off response fails the session")
+	for _, event := range events {
+		assert.False(t, event.IsToolUse(), "the half-formed tool call is dropped")
+	}
+	assert.NoFileExists(t, filepath.Join(e.config.Dir, "notes.txt"))
+	result := events[len(events)-1]
+	require.True(t, result.SessionComplete)
+	assert.True(t, result.Stats.IsError)
+	assert.Contains(t, result.Stats.Result, "limit of 8192 output tokens")
+
+	// The saved session holds no unanswered tool call, so it can be resumed
+	ctx := claude.WithRunOptions(context.Background(), claude.RunOptions{ResumeSessionID: events[0].SessionID})
+	_, code, err = collect(t, e, ctx, "continue")
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	msgs := fake.requests()[1].Messages
+	require.Len(t, msgs, 3)
+	assert.Equal(t, []block{text("Writing the file")}, msgs[1].Content)
+}
+
+func TestExecutor_ExecuteWithResult_StopSkipsRemainingTools(t *testing.T) {
+	fake, server := newFakeAPI(t, reply("tool_use",
+		toolUse("tu_1", "Write", `{"file_path":"a.txt","content":"a"}`),
+		toolUse("tu_2", "Write", `{"file_path":"b.txt","content":"b"}`),
+	))
+	e := newTestExecutor(t, server)
+
+	// Stop the session, as a second Ctrl-C would, once the first tool has run
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var results []claude.Event
+	code, err := e.ExecuteWithResult(ctx, "write", func(event claude.Event) {
+		if event.IsToolResult() {
+			results = append(results, event)
+			cancel()
+		}
+	})
+
+	assert.Equal(t, 1, code)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, fake.requests(), 1, "no further API calls")
+	assert.FileExists(t, filepath.Join(e.config.Dir, "a.txt"))
+	assert.NoFileExists(t, filepath.Join(e.config.Dir, "b.txt"))
+	require.Len(t, results, 2)
+	assert.True(t, results[1].ToolIsError)
+	assert.True(t, results[1].ToolInterrupted)
+
+	for _, msgs := range e.sessions {
+		last := msgs[len(msgs)-1]
+		assert.Equal(t, "user", last.Role)
+		require.Len(t, last.Content, 2, "every tool call is answered")
+		assert.Equal(t, "tu_2", last.Content[1].ToolUseID)
+		assert.Contains(t, last.Content[1].Content, "not run")
+	}
+}
+
+func TestExecutor_ExecuteWithResult_Resume(t *testing.T) {
+	fake, server := newFakeAPI(t,
+		reply("end_turn", text("first")),
+		reply("end_turn", text("second")),
+	)
+	e := newTestExecutor(t, server)
+
+	events, _, err := collect(t, e, context.Background(), "start")
+	require.NoError(t, err)
+	sessionID := events[0].SessionID
+
+	ctx := claude.WithRunOptions(context.Background(), claude.RunOptions{ResumeSessionID: sessionID})
+	events, code, err := collect(t, e, ctx, "continue")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, sessionID, events[0].SessionID)
+
+	msgs := fake.requests()[1].Messages
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "start", msgs[0].Content[0].Text)
+	assert.Equal(t, "first", msgs[1].Content[0].Text)
+	assert.Equal(t, "continue", msgs[2].Content[0].Text)
+}
+
+func TestExecutor_ExecuteWithResult_ResumeUnknownSession(t *testing.T) {
+	_, server := newFakeAPI(t)
+	e := newTestExecutor(t, server)
+
+	ctx := claude.WithRunOptions(context.Background(), claude.RunOptions{ResumeSessionID: "nope"})
+	_, code, err := collect(t, e, ctx, "continue")
+
+	assert.Equal(t, 1, code)
+	assert.ErrorContains(t, err, "unknown session nope")
+}
+
+func TestExecutor_ExecuteWithResult_IdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	_, server := newFakeAPI(t, func(w http.ResponseWriter) {
+		<-release
+	})
+	defer close(release)
+	e := newTestExecutor(t, server)
+
+	ctx := claude.WithRunOptions(context.Background(), claude.RunOptions{IdleTimeout: 50 * time.Millisecond})
+	events, code, err := collect(t, e, ctx, "hello")
+
+	assert.Equal(t, claude.ExitCodeTimeout, code)
+	var timeoutErr *claude.TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.True(t, timeoutErr.Idle)
+	assert.Equal(t, claude.FailureTimeout, claude.Classify(err))
+	for _, event := range events {
+		assert.False(t, event.SessionComplete, "a timed-out session has no result event")
+	}
+}
+
+func TestExecutor_ExecuteWithResult_PlanModeOffersReadOnlyTools(t *testing.T) {
+	fake, server := newFakeAPI(t, reply("end_turn", text("planned")))
+	e := newTestExecutor(t, server)
+
+	ctx := claude.WithRunOptions(context.Background(), claude.RunOptions{
+		Permissions: &claude.Permissions{Mode: "plan"},
+	})
+	_, _, err := collect(t, e, ctx, "plan")
+	require.NoError(t, err)
+
+	var names []string
+	for _, tool := range fake.requests()[0].Tools {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"Read", "Glob", "Grep"}, names)
+}
+
+func TestExecutor_Start(t *testing.T) {
+	_, server := newFakeAPI(t, reply("end_turn", text("hi")))
+	e := newTestExecutor(t, server)
+
+	session, err := e.Start(context.Background(), "hello")
+	require.NoError(t, err)
+
+	var got []claude.Event
+	for event := range session.Events() {
+		got = append(got, event)
+	}
+	require.Len(t, got, 3)
+	assert.Equal(t, "hi", got[1].Text)
+	assert.True(t, got[2].SessionComplete)
+
+	result, err := session.Wait()
+	require.NoError(t, err)
+	assert.Zero(t, result.ExitCode)
+	require.NotNil(t, result.Stats)
+	assert.Equal(t, "hi", result.Stats.Result)
+}
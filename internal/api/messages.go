@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"bmad-automate/internal/claude"
+)
+
+// apiVersion is the Messages API version sent in the anthropic-version header.
+const apiVersion = "2023-06-01"
+
+// request is the body of a Messages API call.
+type request struct {
+	Model     string     `json:"model"`
+	MaxTokens int        `json:"max_tokens"`
+	System    string     `json:"system,omitempty"`
+	Messages  []message  `json:"messages"`
+	Tools     []toolSpec `json:"tools,omitempty"`
+}
+
+// message is one turn of the conversation.
+type message struct {
+	Role    string  `json:"role"`
+	Content []block `json:"content"`
+}
+
+// block is a content block of a message, in the Messages API wire format.
+//
+// Tool inputs are kept as raw JSON, since each tool has its own schema.
+type block struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// toolSpec describes a tool offered to the model.
+type toolSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// response is the body of a successful Messages API call.
+type response struct {
+	ID         string       `json:"id"`
+	Content    []block      `json:"content"`
+	StopReason string       `json:"stop_reason"`
+	Usage      claude.Usage `json:"usage"`
+}
+
+// Stop reasons of interest in a [response].
+const (
+	stopToolUse   = "tool_use"
+	stopMaxTokens = "max_tokens"
+)
+
+// APIError reports a Messages API call answered with an error status.
+//
+// Its message quotes the HTTP status and error type, such as
+// "API Error: 529 overloaded_error: Overloaded", so [claude.Classify] files
+// it under the same failure class as the Claude CLI reporting the same error.
+type APIError struct {
+	// StatusCode is the HTTP status code.
+	StatusCode int
+
+	// Type is the error type from the response body, e.g. "overloaded_error".
+	Type string
+
+	// Message is the error message from the response body.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API Error: %d", e.StatusCode)
+	if e.Type != "" {
+		msg += " " + e.Type
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// send makes one Messages API call.
+func (e *Executor) send(ctx context.Context, req request) (*response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	url := strings.TrimSuffix(e.config.BaseURL, "/") + "/v1/messages"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	httpReq.Header.Set("anthropic-version", apiVersion)
+	if e.config.APIKey != "" {
+		httpReq.Header.Set("x-api-key", e.config.APIKey)
+	}
+
+	httpResp, err := e.config.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: httpResp.StatusCode}
+		var errBody struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &errBody) == nil {
+			apiErr.Type = errBody.Error.Type
+			apiErr.Message = errBody.Error.Message
+		}
+		if apiErr.Type == "" && apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, apiErr
+	}
+
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &resp, nil
+}
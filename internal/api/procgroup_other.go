@@ -0,0 +1,9 @@
+//go:build !unix
+
+package api
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups are unavailable; context
+// cancellation kills only the shell itself.
+func setProcessGroup(cmd *exec.Cmd) {}
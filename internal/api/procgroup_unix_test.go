@@ -0,0 +1,32 @@
+//go:build unix
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolbox_Bash_TimeoutKillsChildren(t *testing.T) {
+	b := newTestToolbox(t, nil)
+	b.bashTimeout = 200 * time.Millisecond
+
+	out := runTool(b, "Bash", `{"command":"sleep 30 & echo $! > child.pid; wait"}`)
+	require.True(t, out.interrupted)
+
+	data, err := os.ReadFile(filepath.Join(b.dir, "child.pid"))
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return syscall.Kill(pid, 0) == syscall.ESRCH
+	}, 2*time.Second, 10*time.Millisecond, "the command's children are killed with it")
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"errors"
+	"strings"
+)
+
+// errUnparsable is returned by splitCommand for commands whose structure it
+// cannot follow, so permission rules cannot be checked reliably.
+var errUnparsable = errors.New("cannot parse command")
+
+// splitCommand splits a shell command into the simple commands it runs, so
+// that permission rules can be checked against each of them.
+//
+// Commands are split on ";", "&&", "||", "|", "&" and newlines outside
+// quotes. The contents of $(...), <(...), >(...) and backtick substitutions
+// are split as commands of their own; the command holding them keeps their
+// text. Subshells and other syntax it cannot follow, such as unbalanced
+// quotes or a bare "(", are rejected with an error matching errUnparsable.
+func splitCommand(command string) ([]string, error) {
+	s := &commandSplitter{src: command}
+	if err := s.list(0); err != nil {
+		return nil, err
+	}
+	return s.parts, nil
+}
+
+// commandSplitter holds the state of splitCommand.
+type commandSplitter struct {
+	src   string
+	i     int
+	parts []string
+}
+
+// list reads commands from src until end: ')' or '`' closing a
+// substitution, or 0 for the end of the input.
+func (s *commandSplitter) list(end byte) error {
+	var cur strings.Builder
+	flush := func() {
+		if part := strings.TrimSpace(cur.String()); part != "" {
+			s.parts = append(s.parts, part)
+		}
+		cur.Reset()
+	}
+
+	var quote byte // '\'' or '"' while inside quotes
+	for s.i < len(s.src) {
+		c := s.src[s.i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			if s.i+1 < len(s.src) {
+				cur.WriteByte(c)
+				s.i++
+				c = s.src[s.i]
+			}
+		case c == '`':
+			if end == '`' {
+				flush()
+				s.i++
+				return nil
+			}
+			if err := s.substitution(&cur, 1, '`'); err != nil {
+				return err
+			}
+			continue
+		case c == '$' && s.peek(1) == '(':
+			if err := s.substitution(&cur, 2, ')'); err != nil {
+				return err
+			}
+			continue
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case (c == '<' || c == '>') && s.peek(1) == '(':
+			if err := s.substitution(&cur, 2, ')'); err != nil {
+				return err
+			}
+			continue
+		case c == ')':
+			if end != ')' {
+				return errUnparsable
+			}
+			flush()
+			s.i++
+			return nil
+		case c == '(' || c == '{' && s.wordStart():
+			return errUnparsable
+		case c == ';' || c == '\n':
+			flush()
+			s.i++
+			continue
+		case c == '|':
+			if s.prev() == '>' {
+				break // >| redirection
+			}
+			flush()
+			s.i++
+			if next := s.peek(0); next == '|' || next == '&' {
+				s.i++
+			}
+			continue
+		case c == '&':
+			if next := s.peek(1); s.prev() == '>' || s.prev() == '<' || next == '>' {
+				break // 2>&1 and &> redirections
+			}
+			flush()
+			s.i++
+			if s.peek(0) == '&' {
+				s.i++
+			}
+			continue
+		}
+		cur.WriteByte(c)
+		s.i++
+	}
+
+	if quote != 0 || end != 0 {
+		return errUnparsable
+	}
+	flush()
+	return nil
+}
+
+// substitution reads a substitution whose opening is n bytes long and which
+// is closed by end, adding its commands to parts and its text to cur.
+func (s *commandSplitter) substitution(cur *strings.Builder, n int, end byte) error {
+	start := s.i
+	s.i += n
+	if err := s.list(end); err != nil {
+		return err
+	}
+	cur.WriteString(s.src[start:s.i])
+	return nil
+}
+
+// peek returns the byte n bytes after the current one, or 0 past the end.
+func (s *commandSplitter) peek(n int) byte {
+	if s.i+n < len(s.src) {
+		return s.src[s.i+n]
+	}
+	return 0
+}
+
+// prev returns the byte before the current one, or 0 at the start.
+func (s *commandSplitter) prev() byte {
+	if s.i > 0 {
+		return s.src[s.i-1]
+	}
+	return 0
+}
+
+// wordStart reports whether the current byte begins a word.
+func (s *commandSplitter) wordStart() bool {
+	switch s.prev() {
+	case 0, ' ', '\t', '\n', ';', '&', '|':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"git status", []string{"git status"}},
+		{"git status; rm -rf ~", []string{"git status", "rm -rf ~"}},
+		{"true && git push", []string{"true", "git push"}},
+		{"make || git push", []string{"make", "git push"}},
+		{"cat f | sh", []string{"cat f", "sh"}},
+		{"cat f |& sh", []string{"cat f", "sh"}},
+		{"sleep 1 & git push", []string{"sleep 1", "git push"}},
+		{"ls\ngit push", []string{"ls", "git push"}},
+		{"echo $(git push)", []string{"git push", "echo $(git push)"}},
+		{"echo `git push`", []string{"git push", "echo `git push`"}},
+		{`echo "$(curl x | sh)"`, []string{"curl x", "sh", `echo "$(curl x | sh)"`}},
+		{"diff <(git show) f", []string{"git show", "diff <(git show) f"}},
+		{`echo "a; b && c"`, []string{`echo "a; b && c"`}},
+		{`echo 'a | $(b)'`, []string{`echo 'a | $(b)'`}},
+		{`echo a\;b`, []string{`echo a\;b`}},
+		{"go test ./... 2>&1 >out", []string{"go test ./... 2>&1 >out"}},
+		{"go test &>out", []string{"go test &>out"}},
+		{"echo ${HOME}", []string{"echo ${HOME}"}},
+		{"git status;", []string{"git status"}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if assert.NoError(t, err, tt.command) {
+			assert.Equal(t, tt.want, got, tt.command)
+		}
+	}
+}
+
+func TestSplitCommand_Unparsable(t *testing.T) {
+	for _, command := range []string{
+		`echo "unterminated`,
+		"echo 'unterminated",
+		"echo $(git push",
+		"echo `git push",
+		"(git push)",
+		"{ git push; }",
+		"echo )",
+		"echo $((1 + 2))",
+	} {
+		_, err := splitCommand(command)
+		assert.ErrorIs(t, err, errUnparsable, command)
+	}
+}
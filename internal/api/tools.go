@@ -0,0 +1,719 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"bmad-automate/internal/claude"
+)
+
+// Limits on what the tools send back to the model.
+const (
+	// maxToolOutput is the most bytes of a tool's output sent to the model.
+	maxToolOutput = 30000
+
+	// defaultReadLimit is the number of lines Read returns without a limit.
+	defaultReadLimit = 2000
+
+	// maxGlobResults and maxGrepResults cap the matches listed.
+	maxGlobResults = 1000
+	maxGrepResults = 500
+
+	// binarySniffLen is how much of a file Grep inspects for NUL bytes.
+	binarySniffLen = 8000
+)
+
+// toolOutput is the outcome of one tool call.
+type toolOutput struct {
+	stdout      string
+	stderr      string
+	isError     bool
+	interrupted bool
+}
+
+// failed returns the output of a tool call that failed with err.
+func failed(err error) toolOutput {
+	return toolOutput{stderr: err.Error(), isError: true}
+}
+
+// forModel returns the tool result text sent back to the model.
+func (o toolOutput) forModel() string {
+	text := o.stdout
+	if o.stderr != "" {
+		if text != "" {
+			text += "\n"
+		}
+		text += o.stderr
+	}
+	return truncate(text, maxToolOutput)
+}
+
+// truncate cuts s to at most n bytes, noting how much was dropped.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + fmt.Sprintf("\n[... %d bytes truncated]", len(s)-n)
+}
+
+// tool is one tool offered to the model.
+type tool struct {
+	spec     toolSpec
+	readOnly bool
+	run      func(b *toolbox, ctx context.Context, input json.RawMessage) toolOutput
+}
+
+// tools lists the tools in the order they are offered. Names match the
+// Claude CLI's tools, so permission rules and output look the same.
+var tools = []tool{
+	{
+		spec: toolSpec{
+			Name:        "Bash",
+			Description: "Run a shell command in the project directory and return its output.",
+			InputSchema: schema(map[string]any{
+				"command":     prop("string", "The command to run"),
+				"description": prop("string", "A short description of what the command does"),
+			}, "command"),
+		},
+		run: (*toolbox).bash,
+	},
+	{
+		spec: toolSpec{
+			Name:        "Read",
+			Description: "Read a file, returning numbered lines.",
+			InputSchema: schema(map[string]any{
+				"file_path": prop("string", "Path of the file to read"),
+				"offset":    prop("integer", "Line number to start from (1-based)"),
+				"limit":     prop("integer", "Maximum number of lines to read"),
+			}, "file_path"),
+		},
+		readOnly: true,
+		run:      (*toolbox).read,
+	},
+	{
+		spec: toolSpec{
+			Name:        "Write",
+			Description: "Create or overwrite a file with the given content.",
+			InputSchema: schema(map[string]any{
+				"file_path": prop("string", "Path of the file to write"),
+				"content":   prop("string", "The full content of the file"),
+			}, "file_path", "content"),
+		},
+		run: (*toolbox).write,
+	},
+	{
+		spec: toolSpec{
+			Name:        "Edit",
+			Description: "Replace old_string with new_string in a file. old_string must match exactly once unless replace_all is set.",
+			InputSchema: schema(map[string]any{
+				"file_path":   prop("string", "Path of the file to edit"),
+				"old_string":  prop("string", "The exact text to replace"),
+				"new_string":  prop("string", "The replacement text"),
+				"replace_all": prop("boolean", "Replace every occurrence"),
+			}, "file_path", "old_string", "new_string"),
+		},
+		run: (*toolbox).edit,
+	},
+	{
+		spec: toolSpec{
+			Name:        "Glob",
+			Description: "List files matching a glob pattern such as \"**/*.go\", relative to the project directory or path.",
+			InputSchema: schema(map[string]any{
+				"pattern": prop("string", "The glob pattern; ** matches any number of directories"),
+				"path":    prop("string", "Directory to search in"),
+			}, "pattern"),
+		},
+		readOnly: true,
+		run:      (*toolbox).glob,
+	},
+	{
+		spec: toolSpec{
+			Name:        "Grep",
+			Description: "Search file contents with a regular expression, returning path:line:text matches.",
+			InputSchema: schema(map[string]any{
+				"pattern": prop("string", "The regular expression (Go syntax)"),
+				"path":    prop("string", "File or directory to search in"),
+				"glob":    prop("string", "Only search files matching this glob, e.g. \"*.go\""),
+			}, "pattern"),
+		},
+		readOnly: true,
+		run:      (*toolbox).grep,
+	},
+}
+
+// schema returns a JSON schema for an object with the given properties.
+func schema(properties map[string]any, required ...string) map[string]any {
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// prop returns a JSON schema property.
+func prop(typ, description string) map[string]any {
+	return map[string]any{"type": typ, "description": description}
+}
+
+// toolbox runs tools inside the project directory.
+type toolbox struct {
+	dir         string
+	bashTimeout time.Duration
+}
+
+// specs returns the tools offered under perms: only read-only tools in plan
+// mode, only tools that an AllowedTools rule names when there are any, and
+// none that a DisallowedTools rule names without arguments.
+func (b *toolbox) specs(perms claude.Permissions) []toolSpec {
+	var specs []toolSpec
+	for _, t := range tools {
+		if b.allowed(perms, t) {
+			specs = append(specs, t.spec)
+		}
+	}
+	return specs
+}
+
+// allowed reports whether t may be used at all under perms.
+func (b *toolbox) allowed(perms claude.Permissions, t tool) bool {
+	if perms.Mode == "plan" && !t.readOnly {
+		return false
+	}
+	if len(perms.AllowedTools) > 0 && !slices.ContainsFunc(perms.AllowedTools, func(rule string) bool {
+		name, _ := parseRule(rule)
+		return name == t.spec.Name
+	}) {
+		return false
+	}
+	return !slices.Contains(perms.DisallowedTools, t.spec.Name)
+}
+
+// run runs the named tool with its raw input.
+func (b *toolbox) run(ctx context.Context, perms claude.Permissions, name string, input json.RawMessage) toolOutput {
+	i := slices.IndexFunc(tools, func(t tool) bool { return t.spec.Name == name })
+	if i < 0 || !b.allowed(perms, tools[i]) {
+		return failed(fmt.Errorf("tool %s is not available", name))
+	}
+	if name == "Bash" {
+		var in struct {
+			Command string `json:"command"`
+		}
+		_ = json.Unmarshal(input, &in) //nolint:errcheck // bash reports bad input
+		if err := checkCommand(perms, in.Command); err != nil {
+			return failed(err)
+		}
+	}
+	return tools[i].run(b, ctx, input)
+}
+
+// checkCommand applies the Bash(...) permission rules to command.
+//
+// As in the Claude CLI, a compound command is split into the commands it
+// runs (see splitCommand) and every one of them must pass the rules, so
+// "git status && git push" is denied by a Bash(git push:*) rule and not
+// allowed by a Bash(git status:*) rule alone. When rules apply, commands
+// that cannot be split reliably are refused.
+func checkCommand(perms claude.Permissions, command string) error {
+	if !restrictsCommands(perms) {
+		return nil
+	}
+	parts, err := splitCommand(command)
+	if err != nil {
+		return fmt.Errorf("command not allowed: %w: %q", err, command)
+	}
+	for _, part := range parts {
+		if rule := matchCommand(perms.DisallowedTools, part); rule != "" {
+			return fmt.Errorf("command denied by rule %s", rule)
+		}
+		if !commandAllowed(perms.AllowedTools, part) {
+			return fmt.Errorf("command not allowed: no AllowedTools rule matches %q", part)
+		}
+	}
+	return nil
+}
+
+// restrictsCommands reports whether any Bash(...) rule limits the commands
+// Bash may run under perms.
+func restrictsCommands(perms claude.Permissions) bool {
+	hasCommandRule := func(rule string) bool {
+		name, pattern := parseRule(rule)
+		return name == "Bash" && pattern != ""
+	}
+	allowsAll := len(perms.AllowedTools) == 0 || slices.Contains(perms.AllowedTools, "Bash")
+	return !allowsAll || slices.ContainsFunc(perms.DisallowedTools, hasCommandRule)
+}
+
+// parseRule splits a permission rule into its tool name and argument
+// pattern: "Bash(git push:*)" gives "Bash" and "git push:*", and "Read"
+// gives "Read" with no pattern.
+func parseRule(rule string) (name, pattern string) {
+	name, pattern, ok := strings.Cut(rule, "(")
+	if !ok || !strings.HasSuffix(pattern, ")") {
+		return rule, ""
+	}
+	return name, strings.TrimSuffix(pattern, ")")
+}
+
+// commandAllowed reports whether the AllowedTools rules let Bash run
+// command. A bare "Bash" rule, or no rules at all, allows every command;
+// otherwise the command must match one of the Bash(...) rules.
+func commandAllowed(rules []string, command string) bool {
+	if len(rules) == 0 || slices.Contains(rules, "Bash") {
+		return true
+	}
+	return matchCommand(rules, command) != ""
+}
+
+// matchCommand returns the first Bash(...) rule that matches command, or "".
+//
+// Rules use Claude's syntax: "Bash(git push:*)" matches commands starting
+// with "git push", and "Bash(make deploy)" matches that exact command.
+func matchCommand(rules []string, command string) string {
+	command = strings.TrimSpace(command)
+	for _, rule := range rules {
+		name, pattern := parseRule(rule)
+		if name != "Bash" || pattern == "" {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(pattern, ":*"); ok {
+			if command == prefix || strings.HasPrefix(command, prefix+" ") {
+				return rule
+			}
+		} else if command == pattern {
+			return rule
+		}
+	}
+	return ""
+}
+
+// decode unmarshals a tool's input.
+func decode(input json.RawMessage, v any) error {
+	if len(input) == 0 {
+		input = []byte("{}")
+	}
+	if err := json.Unmarshal(input, v); err != nil {
+		return fmt.Errorf("invalid input: %w", err)
+	}
+	return nil
+}
+
+// path resolves a tool's path argument, which must stay inside the project
+// directory. Relative paths are taken from the project directory. Symlinks
+// are followed before the check, so a link inside the project cannot lead
+// outside it.
+func (b *toolbox) path(p string) (string, error) {
+	if p == "" {
+		p = "."
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(b.dir, p)
+	}
+	p = filepath.Clean(p)
+	dir, err := resolve(b.dir)
+	if err != nil {
+		return "", err
+	}
+	target, err := resolve(p)
+	if err != nil {
+		return "", err
+	}
+	if !within(dir, target) {
+		return "", fmt.Errorf("%s is outside the project directory", p)
+	}
+	return p, nil
+}
+
+// resolve follows the symlinks in p. A path that does not exist yet, such as
+// a file about to be written, is resolved through its nearest existing parent;
+// a dangling symlink is resolved to where it points.
+func resolve(p string) (string, error) {
+	var missing string
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if target, err := os.Readlink(p); err == nil {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			p = filepath.Clean(target)
+			continue
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", err
+		}
+		missing = filepath.Join(filepath.Base(p), missing)
+		p = parent
+	}
+}
+
+// within reports whether path is dir or lies under it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// rel returns p relative to the project directory, for display.
+func (b *toolbox) rel(p string) string {
+	if rel, err := filepath.Rel(b.dir, p); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return p
+}
+
+// bash runs a shell command.
+func (b *toolbox) bash(ctx context.Context, input json.RawMessage) toolOutput {
+	var in struct {
+		Command string `json:"command"`
+	}
+	if err := decode(input, &in); err != nil {
+		return failed(err)
+	}
+	if strings.TrimSpace(in.Command) == "" {
+		return failed(errors.New("command is required"))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.bashTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", in.Command)
+	cmd.Dir = b.dir
+	setProcessGroup(cmd)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+
+	out := toolOutput{stdout: stdout.String(), stderr: stderr.String()}
+	switch {
+	case ctx.Err() != nil:
+		out.isError, out.interrupted = true, true
+		out.stderr += fmt.Sprintf("\ncommand interrupted: %v", context.Cause(ctx))
+	case err != nil:
+		out.isError = true
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			out.stderr += fmt.Sprintf("\nexit code %d", exitErr.ExitCode())
+		} else {
+			out.stderr += "\n" + err.Error()
+		}
+	}
+	out.stderr = strings.TrimPrefix(out.stderr, "\n")
+	return out
+}
+
+// read returns numbered lines of a file.
+func (b *toolbox) read(_ context.Context, input json.RawMessage) toolOutput {
+	var in struct {
+		FilePath string `json:"file_path"`
+		Offset   int    `json:"offset"`
+		Limit    int    `json:"limit"`
+	}
+	if err := decode(input, &in); err != nil {
+		return failed(err)
+	}
+	path, err := b.path(in.FilePath)
+	if err != nil {
+		return failed(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return failed(err)
+	}
+
+	offset := max(in.Offset, 1)
+	limit := in.Limit
+	if limit <= 0 {
+		limit = defaultReadLimit
+	}
+
+	var sb strings.Builder
+	lines := strings.SplitAfter(string(data), "\n")
+	for n := offset; n < offset+limit && n <= len(lines); n++ {
+		line := strings.TrimRight(lines[n-1], "\r\n")
+		if n == len(lines) && line == "" {
+			break // the file ends with a newline
+		}
+		fmt.Fprintf(&sb, "%6d\t%s\n", n, line)
+	}
+	if sb.Len() == 0 {
+		return toolOutput{stdout: fmt.Sprintf("(no lines at offset %d; the file has %d)", offset, len(lines))}
+	}
+	return toolOutput{stdout: sb.String()}
+}
+
+// write creates or overwrites a file.
+func (b *toolbox) write(_ context.Context, input json.RawMessage) toolOutput {
+	var in struct {
+		FilePath string `json:"file_path"`
+		Content  string `json:"content"`
+	}
+	if err := decode(input, &in); err != nil {
+		return failed(err)
+	}
+	path, err := b.path(in.FilePath)
+	if err != nil {
+		return failed(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return failed(err)
+	}
+	if err := os.WriteFile(path, []byte(in.Content), 0o644); err != nil {
+		return failed(err)
+	}
+	return toolOutput{stdout: fmt.Sprintf("Wrote %d bytes to %s", len(in.Content), b.rel(path))}
+}
+
+// edit replaces text in a file.
+func (b *toolbox) edit(_ context.Context, input json.RawMessage) toolOutput {
+	var in struct {
+		FilePath   string `json:"file_path"`
+		OldString  string `json:"old_string"`
+		NewString  string `json:"new_string"`
+		ReplaceAll bool   `json:"replace_all"`
+	}
+	if err := decode(input, &in); err != nil {
+		return failed(err)
+	}
+	path, err := b.path(in.FilePath)
+	if err != nil {
+		return failed(err)
+	}
+	if in.OldString == "" {
+		return failed(errors.New("old_string is required"))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return failed(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return failed(err)
+	}
+
+	content := string(data)
+	count := strings.Count(content, in.OldString)
+	switch {
+	case count == 0:
+		return failed(fmt.Errorf("old_string not found in %s", b.rel(path)))
+	case count > 1 && !in.ReplaceAll:
+		return failed(fmt.Errorf("old_string matches %d times in %s; include more context or set replace_all", count, b.rel(path)))
+	}
+	if in.ReplaceAll {
+		content = strings.ReplaceAll(content, in.OldString, in.NewString)
+	} else {
+		content = strings.Replace(content, in.OldString, in.NewString, 1)
+	}
+	if err := os.WriteFile(path, []byte(content), info.Mode().Perm()); err != nil {
+		return failed(err)
+	}
+	return toolOutput{stdout: fmt.Sprintf("Replaced %d occurrence(s) in %s", count, b.rel(path))}
+}
+
+// glob lists files matching a pattern.
+func (b *toolbox) glob(_ context.Context, input json.RawMessage) toolOutput {
+	var in struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+	}
+	if err := decode(input, &in); err != nil {
+		return failed(err)
+	}
+	root, err := b.path(in.Path)
+	if err != nil {
+		return failed(err)
+	}
+	re, err := globRegexp(in.Pattern)
+	if err != nil {
+		return failed(err)
+	}
+
+	var matches []string
+	err = walkFiles(root, func(path string) bool {
+		rel, _ := filepath.Rel(root, path)
+		if re.MatchString(filepath.ToSlash(rel)) {
+			matches = append(matches, b.rel(path))
+		}
+		return len(matches) < maxGlobResults
+	})
+	if err != nil {
+		return failed(err)
+	}
+	if len(matches) == 0 {
+		return toolOutput{stdout: "No files found"}
+	}
+	out := strings.Join(matches, "\n")
+	if len(matches) == maxGlobResults {
+		out += fmt.Sprintf("\n(results limited to %d files)", maxGlobResults)
+	}
+	return toolOutput{stdout: out}
+}
+
+// grep searches file contents.
+func (b *toolbox) grep(_ context.Context, input json.RawMessage) toolOutput {
+	var in struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+		Glob    string `json:"glob"`
+	}
+	if err := decode(input, &in); err != nil {
+		return failed(err)
+	}
+	root, err := b.path(in.Path)
+	if err != nil {
+		return failed(err)
+	}
+	re, err := regexp.Compile(in.Pattern)
+	if err != nil {
+		return failed(fmt.Errorf("invalid pattern: %w", err))
+	}
+	var filter *regexp.Regexp
+	if in.Glob != "" {
+		if filter, err = globRegexp(in.Glob); err != nil {
+			return failed(err)
+		}
+	}
+
+	var matches []string
+	err = walkFiles(root, func(path string) bool {
+		if filter != nil && !filter.MatchString(filepath.Base(path)) && !filter.MatchString(b.rel(path)) {
+			return true
+		}
+		matches = grepFile(path, b.rel(path), re, matches)
+		return len(matches) < maxGrepResults
+	})
+	if err != nil {
+		return failed(err)
+	}
+	if len(matches) == 0 {
+		return toolOutput{stdout: "No matches found"}
+	}
+	out := strings.Join(matches, "\n")
+	if len(matches) >= maxGrepResults {
+		out += fmt.Sprintf("\n(results limited to %d matches)", maxGrepResults)
+	}
+	return toolOutput{stdout: out}
+}
+
+// grepFile appends the matching lines of a text file to matches.
+func grepFile(path, display string, re *regexp.Regexp, matches []string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return matches
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if head, _ := r.Peek(binarySniffLen); bytes.IndexByte(head, 0) >= 0 {
+		return matches // binary file
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxToolOutput)
+	for n := 1; scanner.Scan() && len(matches) < maxGrepResults; n++ {
+		if line := scanner.Text(); re.MatchString(line) {
+			matches = append(matches, fmt.Sprintf("%s:%d:%s", display, n, line))
+		}
+	}
+	return matches
+}
+
+// walkFiles calls visit for each regular file under root in lexical order,
+// skipping .git directories, until visit returns false. A root that is a
+// file is visited by itself.
+func walkFiles(root string, visit func(path string) bool) error {
+	done := errors.New("done")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // skip unreadable entries
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if !visit(path) {
+			return done
+		}
+		return nil
+	})
+	if errors.Is(err, done) {
+		return nil
+	}
+	return err
+}
+
+// globRegexp converts a glob pattern to a regular expression over
+// slash-separated relative paths. "*" and "?" do not cross directories, "**"
+// does, and "{a,b}" matches either alternative.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("pattern is required")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				sb.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '{':
+			depth++
+			sb.WriteString("(?:")
+		case '}':
+			if depth == 0 {
+				sb.WriteString(`\}`)
+				continue
+			}
+			depth--
+			sb.WriteString(")")
+		case ',':
+			if depth > 0 {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(",")
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/claude"
+)
+
+// newTestToolbox returns a toolbox over a temporary project directory
+// holding the given files.
+func newTestToolbox(t *testing.T, files map[string]string) *toolbox {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return &toolbox{dir: dir, bashTimeout: 5 * time.Second}
+}
+
+func runTool(b *toolbox, name, input string) toolOutput {
+	return b.run(context.Background(), claude.Permissions{}, name, json.RawMessage(input))
+}
+
+func TestToolbox_Bash(t *testing.T) {
+	b := newTestToolbox(t, map[string]string{"a.txt": "x"})
+
+	out := runTool(b, "Bash", `{"command":"ls; echo oops >&2"}`)
+	assert.False(t, out.isError)
+	assert.Equal(t, "a.txt\n", out.stdout)
+	assert.Equal(t, "oops\n", out.stderr)
+
+	out = runTool(b, "Bash", `{"command":"exit 2"}`)
+	assert.True(t, out.isError)
+	assert.Contains(t, out.stderr, "exit code 2")
+
+	out = runTool(b, "Bash", `{}`)
+	assert.True(t, out.isError)
+}
+
+func TestToolbox_Bash_Timeout(t *testing.T) {
+	b := newTestToolbox(t, nil)
+	b.bashTimeout = 50 * time.Millisecond
+
+	out := runTool(b, "Bash", `{"command":"sleep 5"}`)
+	assert.True(t, out.isError)
+	assert.True(t, out.interrupted)
+}
+
+func TestToolbox_Read(t *testing.T) {
+	b := newTestToolbox(t, map[string]string{"f.txt": "one\ntwo\nthree\n"})
+
+	out := runTool(b, "Read", `{"file_path":"f.txt"}`)
+	assert.False(t, out.isError)
+	assert.Equal(t, "     1\tone\n     2\ttwo\n     3\tthree\n", out.stdout)
+
+	out = runTool(b, "Read", `{"file_path":"f.txt","offset":2,"limit":1}`)
+	assert.Equal(t, "     2\ttwo\n", out.stdout)
+
+	out = runTool(b, "Read", `{"file_path":"missing.txt"}`)
+	assert.True(t, out.isError)
+}
+
+func TestToolbox_Write(t *testing.T) {
+	b := newTestToolbox(t, nil)
+
+	out := runTool(b, "Write", `{"file_path":"sub/dir/new.txt","content":"hello"}`)
+	require.False(t, out.isError, out.stderr)
+	assert.Contains(t, out.stdout, "sub/dir/new.txt")
+
+	data, err := os.ReadFile(filepath.Join(b.dir, "sub", "dir", "new.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestToolbox_Edit(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{"single match", `{"file_path":"f.go","old_string":"foo()","new_string":"baz()"}`, "foo := 1\nbaz()\nbar()\n", ""},
+		{"replace all", `{"file_path":"f.go","old_string":"foo","new_string":"qux","replace_all":true}`, "qux := 1\nqux()\nbar()\n", ""},
+		{"ambiguous", `{"file_path":"f.go","old_string":"foo","new_string":"qux"}`, "", "matches 2 times"},
+		{"not found", `{"file_path":"f.go","old_string":"nope","new_string":"x"}`, "", "old_string not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestToolbox(t, map[string]string{"f.go": "foo := 1\nfoo()\nbar()\n"})
+
+			out := runTool(b, "Edit", tt.input)
+
+			data, err := os.ReadFile(filepath.Join(b.dir, "f.go"))
+			require.NoError(t, err)
+			if tt.wantErr != "" {
+				assert.True(t, out.isError)
+				assert.Contains(t, out.stderr, tt.wantErr)
+				assert.Equal(t, "foo := 1\nfoo()\nbar()\n", string(data), "a failed edit leaves the file alone")
+				return
+			}
+			require.False(t, out.isError, out.stderr)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestToolbox_Glob(t *testing.T) {
+	b := newTestToolbox(t, map[string]string{
+		"main.go":           "",
+		"internal/a/a.go":   "",
+		"internal/a/a.txt":  "",
+		"internal/b/b.go":   "",
+		".git/config":       "",
+		"docs/README.md":    "",
+		"docs/guide/use.md": "",
+	})
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    string
+	}{
+		{"**/*.go", "", "internal/a/a.go\ninternal/b/b.go\nmain.go"},
+		{"*.go", "", "main.go"},
+		{"*.go", "internal/a", "internal/a/a.go"},
+		{"docs/**", "", "docs/README.md\ndocs/guide/use.md"},
+		{"**/*.{md,txt}", "", "docs/README.md\ndocs/guide/use.md\ninternal/a/a.txt"},
+		{"internal/?/b.go", "", "internal/b/b.go"},
+		{"*.rs", "", "No files found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" in "+tt.path, func(t *testing.T) {
+			input, _ := json.Marshal(map[string]string{"pattern": tt.pattern, "path": tt.path})
+			out := runTool(b, "Glob", string(input))
+			require.False(t, out.isError, out.stderr)
+			assert.Equal(t, tt.want, out.stdout)
+		})
+	}
+}
+
+func TestToolbox_Grep(t *testing.T) {
+	b := newTestToolbox(t, map[string]string{
+		"a.go":        "package a\nfunc Foo() {}\n",
+		"sub/b.go":    "package b\n// Foo calls\nvar x = Foo\n",
+		"notes.txt":   "Foo in text\n",
+		"bin.dat":     "Foo\x00\x01",
+		".git/HEAD":   "Foo",
+		"sub/c.go.md": "Foo",
+	})
+
+	out := runTool(b, "Grep", `{"pattern":"Foo","glob":"*.go"}`)
+	require.False(t, out.isError, out.stderr)
+	assert.Equal(t, "a.go:2:func Foo() {}\nsub/b.go:2:// Foo calls\nsub/b.go:3:var x = Foo", out.stdout)
+
+	out = runTool(b, "Grep", `{"pattern":"^Foo","path":"notes.txt"}`)
+	assert.Equal(t, "notes.txt:1:Foo in text", out.stdout)
+
+	out = runTool(b, "Grep", `{"pattern":"Foo"}`)
+	assert.NotContains(t, out.stdout, "bin.dat", "binary files are skipped")
+	assert.NotContains(t, out.stdout, ".git", ".git is skipped")
+
+	out = runTool(b, "Grep", `{"pattern":"nothing here"}`)
+	assert.Equal(t, "No matches found", out.stdout)
+
+	out = runTool(b, "Grep", `{"pattern":"("}`)
+	assert.True(t, out.isError)
+}
+
+func TestToolbox_PathOutsideProject(t *testing.T) {
+	b := newTestToolbox(t, nil)
+	outside := filepath.Join(filepath.Dir(b.dir), "outside.txt")
+
+	for _, input := range []string{
+		`{"file_path":"../outside.txt","content":"x"}`,
+		`{"file_path":"` + outside + `","content":"x"}`,
+	} {
+		out := runTool(b, "Write", input)
+		assert.True(t, out.isError)
+		assert.Contains(t, out.stderr, "outside the project directory")
+	}
+	assert.NoFileExists(t, outside)
+
+	out := runTool(b, "Glob", `{"pattern":"*","path":".."}`)
+	assert.True(t, out.isError)
+}
+
+func TestToolbox_SymlinkOutsideProject(t *testing.T) {
+	b := newTestToolbox(t, map[string]string{"inside.txt": "ok"})
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(b.dir, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(b.dir, "dangling")))
+	require.NoError(t, os.Symlink("inside.txt", filepath.Join(b.dir, "alias.txt")))
+
+	for _, call := range []struct{ tool, input string }{
+		{"Read", `{"file_path":"escape/secret.txt"}`},
+		{"Write", `{"file_path":"escape/new.txt","content":"x"}`},
+		{"Write", `{"file_path":"escape/sub/new.txt","content":"x"}`},
+		{"Write", `{"file_path":"dangling","content":"x"}`},
+		{"Grep", `{"pattern":"secret","path":"escape"}`},
+	} {
+		out := runTool(b, call.tool, call.input)
+		assert.True(t, out.isError, "%s %s", call.tool, call.input)
+		assert.Contains(t, out.stderr, "outside the project directory")
+	}
+	assert.NoFileExists(t, filepath.Join(outside, "new.txt"))
+	assert.NoDirExists(t, filepath.Join(outside, "sub"))
+
+	out := runTool(b, "Read", `{"file_path":"alias.txt"}`)
+	assert.False(t, out.isError, "links within the project are fine")
+	assert.Contains(t, out.stdout, "ok")
+}
+
+func TestToolbox_Permissions(t *testing.T) {
+	b := newTestToolbox(t, map[string]string{"f.txt": "x"})
+	ctx := context.Background()
+
+	t.Run("plan mode allows only read-only tools", func(t *testing.T) {
+		perms := claude.Permissions{Mode: "plan"}
+		out := b.run(ctx, perms, "Write", json.RawMessage(`{"file_path":"g.txt","content":"y"}`))
+		assert.True(t, out.isError)
+		assert.Contains(t, out.stderr, "not available")
+		assert.False(t, b.run(ctx, perms, "Read", json.RawMessage(`{"file_path":"f.txt"}`)).isError)
+	})
+
+	t.Run("disallowed tool", func(t *testing.T) {
+		perms := claude.Permissions{DisallowedTools: []string{"Bash"}}
+		assert.True(t, b.run(ctx, perms, "Bash", json.RawMessage(`{"command":"true"}`)).isError)
+		assert.Len(t, b.specs(perms), 5)
+	})
+
+	t.Run("disallowed command rules", func(t *testing.T) {
+		perms := claude.Permissions{DisallowedTools: []string{"Bash(git push:*)", "Bash(make deploy)"}}
+		for command, denied := range map[string]bool{
+			"git push origin main": true,
+			"git push":             true,
+			"git pushy":            false,
+			"git status":           false,
+			"make deploy":          true,
+			"make deploy-docs":     false,
+			"true && git push":     true,
+			"cd . && git push":     true,
+			"git status; git push": true,
+			"echo $(git push)":     true,
+			"echo `make deploy`":   true,
+			"git status && ls":     false,
+		} {
+			input, _ := json.Marshal(map[string]string{"command": command})
+			out := b.run(ctx, perms, "Bash", input)
+			if denied {
+				assert.Contains(t, out.stderr, "denied by rule", command)
+			} else {
+				assert.NotContains(t, out.stderr, "denied by rule", command)
+			}
+		}
+		out := b.run(ctx, perms, "Bash", json.RawMessage(`{"command":"(git push)"}`))
+		assert.Contains(t, out.stderr, "cannot parse command", "subshells are refused")
+		assert.Len(t, b.specs(perms), 6, "command rules do not remove the tool")
+	})
+
+	t.Run("allowed tools narrow the tool set", func(t *testing.T) {
+		perms := claude.Permissions{AllowedTools: []string{"Read", "Grep"}}
+		assert.Len(t, b.specs(perms), 2)
+		assert.False(t, b.run(ctx, perms, "Read", json.RawMessage(`{"file_path":"f.txt"}`)).isError)
+		out := b.run(ctx, perms, "Write", json.RawMessage(`{"file_path":"g.txt","content":"y"}`))
+		assert.Contains(t, out.stderr, "not available")
+	})
+
+	t.Run("allowed command rules", func(t *testing.T) {
+		perms := claude.Permissions{AllowedTools: []string{"Read", "Bash(echo:*)"}}
+		assert.Len(t, b.specs(perms), 2, "a command rule offers Bash")
+		assert.False(t, b.run(ctx, perms, "Bash", json.RawMessage(`{"command":"echo hi"}`)).isError)
+		out := b.run(ctx, perms, "Bash", json.RawMessage(`{"command":"rm f.txt"}`))
+		assert.Contains(t, out.stderr, "command not allowed")
+		assert.FileExists(t, filepath.Join(b.dir, "f.txt"))
+
+		for _, command := range []string{
+			"echo hi; rm f.txt",
+			"echo hi && rm f.txt",
+			"echo hi || rm f.txt",
+			"echo hi | rm f.txt",
+			"echo hi\nrm f.txt",
+			"echo $(rm f.txt)",
+			"echo `rm f.txt`",
+			"echo \"$(rm f.txt)\"",
+			"echo \"unterminated",
+		} {
+			input, _ := json.Marshal(map[string]string{"command": command})
+			out := b.run(ctx, perms, "Bash", input)
+			assert.Contains(t, out.stderr, "command not allowed", command)
+		}
+		assert.FileExists(t, filepath.Join(b.dir, "f.txt"))
+		assert.False(t, b.run(ctx, perms, "Bash", json.RawMessage(`{"command":"echo a && echo b | echo c"}`)).isError,
+			"compound commands run when every part is allowed")
+
+		perms.AllowedTools = append(perms.AllowedTools, "Bash")
+		assert.False(t, b.run(ctx, perms, "Bash", json.RawMessage(`{"command":"true"}`)).isError, "a bare rule allows every command")
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		out := b.run(ctx, claude.Permissions{}, "WebFetch", nil)
+		assert.True(t, out.isError)
+	})
+}
+
+func TestToolOutput_ForModel(t *testing.T) {
+	assert.Equal(t, "out\nerr", toolOutput{stdout: "out", stderr: "err"}.forModel())
+	assert.Equal(t, "err", toolOutput{stderr: "err"}.forModel())
+
+	long := toolOutput{stdout: strings.Repeat("x", maxToolOutput+10)}.forModel()
+	assert.True(t, strings.HasPrefix(long, strings.Repeat("x", maxToolOutput)))
+	assert.Contains(t, long, "[... 10 bytes truncated]")
+}
@@ -0,0 +1,399 @@
+// Package archive keeps an on-disk record of every Claude session run by the
+// workflow runner, so a run can be reviewed, searched, or attached to a bug
+// report after the terminal output is gone.
+//
+// Each run is a directory <dir>/<story>/<timestamp>-<workflow>/ holding:
+//   - [EventsFile] and its info file events.json: the session's stream-json
+//     events and their timing, a transcript readable by [claude.ReadTranscript]
+//   - [StderrFile]: every line the session wrote to stderr
+//   - [PromptFile]: the rendered prompt
+//   - [InfoFile]: the [Info] summary of the run (story, workflow, outcome,
+//     duration, cost)
+//
+// Key types:
+//   - [Archive] creates runs and applies the retention limits
+//   - [Recorder] writes one run while its session streams
+//   - [Run] is an archived run read back by [Load] or [Archive.List]
+//   - [Filter] selects runs by story, epic, status, or start time
+//   - [ToolCall] is a tool call of a run with its result, searched by [Grep]
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bmad-automate/internal/claude"
+)
+
+// Files of a run directory.
+const (
+	// InfoFile holds the run's [Info] as JSON.
+	InfoFile = "run.json"
+
+	// EventsFile holds the session's stream-json events.
+	EventsFile = "events" + claude.TranscriptExt
+
+	// StderrFile holds the session's stderr.
+	StderrFile = "stderr.log"
+
+	// PromptFile holds the rendered prompt.
+	PromptFile = "prompt.txt"
+)
+
+// RawStory is the story directory of runs of raw prompts, which have no story.
+const RawStory = "raw"
+
+// timestampLayout formats the start time in run directory names.
+const timestampLayout = "20060102-150405"
+
+// Info summarizes an archived run.
+type Info struct {
+	// StoryKey is the story the run worked on, empty for raw prompts.
+	StoryKey string `json:"story_key,omitempty"`
+
+	// Workflow is the workflow that ran, or "raw" for a raw prompt.
+	Workflow string `json:"workflow"`
+
+	// SessionID is the Claude session ID, if the session reported one.
+	SessionID string `json:"session_id,omitempty"`
+
+	// ResumeSessionID is the session the run resumed, if any.
+	ResumeSessionID string `json:"resume_session_id,omitempty"`
+
+	// Agent is the agent the session ran on, empty for Claude.
+	Agent string `json:"agent,omitempty"`
+
+	// StartedAt is when the run started.
+	StartedAt time.Time `json:"started_at"`
+
+	// Finished is false while the run is in progress, and stays false for
+	// a run whose process died before it ended.
+	Finished bool `json:"finished"`
+
+	// DurationMS is the wall-clock duration of the run in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+
+	// ExitCode is the run's exit code.
+	ExitCode int `json:"exit_code"`
+
+	// Success reports whether the run succeeded.
+	Success bool `json:"success"`
+
+	// Error explains why the run failed, when known.
+	Error string `json:"error,omitempty"`
+
+	// Stats holds the usage, cost, and turn statistics of the session's
+	// result event.
+	Stats claude.ResultStats `json:"stats"`
+}
+
+// Duration returns the run's wall-clock duration.
+func (i Info) Duration() time.Duration {
+	return time.Duration(i.DurationMS) * time.Millisecond
+}
+
+// Run is an archived run.
+type Run struct {
+	Info
+
+	// Dir is the run directory.
+	Dir string
+}
+
+// Path returns the path of the named file in the run directory.
+func (r *Run) Path(name string) string {
+	return filepath.Join(r.Dir, name)
+}
+
+// Load reads the run in dir.
+func Load(dir string) (*Run, error) {
+	data, err := os.ReadFile(filepath.Join(dir, InfoFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading run: %w", err)
+	}
+	run := &Run{Dir: dir}
+	if err := json.Unmarshal(data, &run.Info); err != nil {
+		return nil, fmt.Errorf("reading run %s: %w", dir, err)
+	}
+	return run, nil
+}
+
+// Config configures an [Archive].
+type Config struct {
+	// Dir is the archive directory.
+	Dir string
+
+	// MaxRuns is the number of most recent runs [Archive.Prune] keeps.
+	// Zero means no limit.
+	MaxRuns int
+
+	// MaxAge is how long [Archive.Prune] keeps runs. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// Archive is a directory of archived runs.
+type Archive struct {
+	config Config
+
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+// New creates an archive with the given configuration. The directory is
+// created when the first run starts.
+func New(cfg Config) *Archive {
+	return &Archive{config: cfg, now: time.Now}
+}
+
+// Dir returns the archive directory.
+func (a *Archive) Dir() string {
+	return a.config.Dir
+}
+
+// Start creates the directory of a new run and starts recording it.
+//
+// The storyKey is empty for raw prompts. The resumed session and agent are
+// taken from opts.
+func (a *Archive) Start(storyKey, workflow, prompt string, opts claude.RunOptions) (*Recorder, error) {
+	start := a.now()
+	dir, err := a.makeRunDir(storyKey, workflow, start)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, PromptFile), []byte(prompt), 0o644); err != nil {
+		return nil, fmt.Errorf("writing prompt: %w", err)
+	}
+
+	r := &Recorder{
+		dir:   dir,
+		start: time.Now(),
+		info: Info{
+			StoryKey:        storyKey,
+			Workflow:        workflow,
+			ResumeSessionID: opts.ResumeSessionID,
+			Agent:           opts.Agent,
+			StartedAt:       start,
+		},
+	}
+	if err := r.writeInfo(); err != nil {
+		return nil, err
+	}
+
+	if r.stderr, err = os.Create(filepath.Join(dir, StderrFile)); err != nil {
+		return nil, fmt.Errorf("creating stderr log: %w", err)
+	}
+	if r.transcript, err = claude.NewTranscriptWriter(filepath.Join(dir, EventsFile), prompt, opts); err != nil {
+		r.stderr.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// makeRunDir creates a uniquely named directory for a run.
+func (a *Archive) makeRunDir(storyKey, workflow string, start time.Time) (string, error) {
+	if storyKey == "" {
+		storyKey = RawStory
+	}
+	parent := filepath.Join(a.config.Dir, pathSegment(storyKey))
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", fmt.Errorf("creating archive directory: %w", err)
+	}
+
+	name := start.Format(timestampLayout) + "-" + pathSegment(workflow)
+	for n := 1; ; n++ {
+		dir := filepath.Join(parent, name)
+		if n > 1 {
+			dir += fmt.Sprintf("-%d", n)
+		}
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return dir, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("creating run directory: %w", err)
+		}
+	}
+}
+
+// pathSegment makes s safe to use as a single path element.
+func pathSegment(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+	if s == "" || strings.Trim(s, ".") == "" {
+		return "_"
+	}
+	return s
+}
+
+// List returns the archived runs, oldest first. Directories without a
+// readable [InfoFile] are skipped.
+func (a *Archive) List() ([]*Run, error) {
+	dirs, err := filepath.Glob(filepath.Join(a.config.Dir, "*", "*", InfoFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*Run
+	for _, path := range dirs {
+		run, err := Load(filepath.Dir(path))
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	slices.SortStableFunc(runs, func(a, b *Run) int {
+		if c := a.StartedAt.Compare(b.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Dir, b.Dir)
+	})
+	return runs, nil
+}
+
+// Prune deletes the runs beyond [Config.MaxRuns] and those older than
+// [Config.MaxAge], and returns how many it deleted.
+func (a *Archive) Prune() (int, error) {
+	if a.config.MaxRuns == 0 && a.config.MaxAge == 0 {
+		return 0, nil
+	}
+	runs, err := a.List()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := a.now().Add(-a.config.MaxAge)
+	var errs []error
+	removed := 0
+	for i, run := range runs {
+		tooMany := a.config.MaxRuns > 0 && len(runs)-i > a.config.MaxRuns
+		tooOld := a.config.MaxAge > 0 && run.StartedAt.Before(cutoff)
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.RemoveAll(run.Dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+		// Drop the story directory once its last run is gone
+		_ = os.Remove(filepath.Dir(run.Dir)) //nolint:errcheck // Fails while other runs remain
+	}
+	return removed, errors.Join(errs...)
+}
+
+// Recorder records one run. It is created by [Archive.Start].
+//
+// Pass each event of the session to [Recorder.Event] and each stderr line to
+// [Recorder.Stderr], then call [Recorder.Finish] with the outcome.
+type Recorder struct {
+	dir        string
+	start      time.Time // for the duration, measured on the monotonic clock
+	info       Info
+	transcript *claude.TranscriptWriter
+
+	// mu guards stderr, which is written from the executor's stderr goroutine.
+	mu     sync.Mutex
+	stderr *os.File
+	err    error
+}
+
+// Dir returns the run directory.
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// Event records an event of the session.
+func (r *Recorder) Event(event claude.Event) {
+	r.transcript.Write(event)
+}
+
+// Stderr records a line the session wrote to stderr. Lines arriving after
+// [Recorder.Finish] are dropped.
+func (r *Recorder) Stderr(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stderr == nil || r.err != nil {
+		return
+	}
+	if _, err := fmt.Fprintln(r.stderr, line); err != nil {
+		r.err = fmt.Errorf("writing stderr log: %w", err)
+	}
+}
+
+// Outcome is how a run ended, passed to [Recorder.Finish].
+type Outcome struct {
+	// ExitCode is the run's exit code.
+	ExitCode int
+
+	// SessionErr is the error the executor returned, recorded in the
+	// transcript so a replay ends the same way.
+	SessionErr error
+
+	// Err is the reason the run failed, if known; it may differ from
+	// SessionErr, e.g. for an error result or an exceeded budget.
+	Err error
+
+	// SessionID is the Claude session ID, if reported.
+	SessionID string
+
+	// Stats holds the statistics of the session's result event.
+	Stats claude.ResultStats
+}
+
+// Finish records the outcome of the run and closes its files. It returns
+// the first error met while recording the run.
+func (r *Recorder) Finish(outcome Outcome) error {
+	r.mu.Lock()
+	err := r.err
+	if closeErr := r.stderr.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("writing stderr log: %w", closeErr)
+	}
+	r.stderr = nil
+	r.mu.Unlock()
+
+	if transcriptErr := r.transcript.Close(outcome.ExitCode, outcome.SessionErr); err == nil {
+		err = transcriptErr
+	}
+
+	r.info.Finished = true
+	r.info.DurationMS = time.Since(r.start).Milliseconds()
+	r.info.ExitCode = outcome.ExitCode
+	r.info.Success = outcome.ExitCode == 0
+	r.info.SessionID = outcome.SessionID
+	r.info.Stats = outcome.Stats
+	if runErr := outcome.Err; runErr != nil {
+		r.info.Error = runErr.Error()
+	} else if outcome.SessionErr != nil {
+		r.info.Error = outcome.SessionErr.Error()
+	}
+	if infoErr := r.writeInfo(); err == nil {
+		err = infoErr
+	}
+	return err
+}
+
+// writeInfo writes the run's [InfoFile].
+func (r *Recorder) writeInfo() error {
+	data, err := json.MarshalIndent(r.info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding run info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, InfoFile), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing run info: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/claude"
+)
+
+// sessionLines is a short stream-json session with a tool call.
+var sessionLines = []string{
+	`{"type":"system","subtype":"init","session_id":"sess-1"}`,
+	`{"type":"assistant","session_id":"sess-1","message":{"id":"msg_1","content":[{"type":"tool_use","id":"tu_1","name":"Bash","input":{"command":"go mod tidy"}}]}}`,
+	`{"type":"result","subtype":"success","session_id":"sess-1","total_cost_usd":0.5,"usage":{"input_tokens":10,"output_tokens":5},"result":"done"}`,
+}
+
+// sessionEvents returns the events of sessionLines.
+func sessionEvents(t *testing.T) []claude.Event {
+	t.Helper()
+	var events []claude.Event
+	for _, line := range sessionLines {
+		var raw claude.StreamEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &raw))
+		events = append(events, claude.NewEventsFromStream(&raw)...)
+	}
+	return events
+}
+
+// fixedClock returns an archive clock that starts at start and advances by
+// step on every call.
+func fixedClock(start time.Time, step time.Duration) func() time.Time {
+	now := start
+	return func() time.Time {
+		t := now
+		now = now.Add(step)
+		return t
+	}
+}
+
+func TestArchive_RecordsRun(t *testing.T) {
+	dir := t.TempDir()
+	a := New(Config{Dir: dir})
+	start := time.Date(2026, 3, 14, 9, 26, 53, 0, time.Local)
+	a.now = fixedClock(start, time.Second)
+
+	rec, err := a.Start("1-2-login", "dev-story", "/bmad-bmm-dev-story 1-2-login", claude.RunOptions{ResumeSessionID: "sess-0"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "1-2-login", "20260314-092653-dev-story"), rec.Dir())
+
+	running, err := Load(rec.Dir())
+	require.NoError(t, err)
+	assert.False(t, running.Finished, "a run is listed while in progress")
+
+	events := sessionEvents(t)
+	for _, event := range events {
+		rec.Event(event)
+	}
+	rec.Stderr("warning: slow")
+	exitErr := &claude.ExitError{Code: 1, Stderr: []string{"warning: slow"}}
+	require.NoError(t, rec.Finish(Outcome{
+		ExitCode:   1,
+		SessionErr: exitErr,
+		SessionID:  "sess-1",
+		Stats:      *events[len(events)-1].Stats,
+	}))
+	rec.Stderr("late line")
+
+	run, err := Load(rec.Dir())
+	require.NoError(t, err)
+	assert.Equal(t, "1-2-login", run.StoryKey)
+	assert.Equal(t, "dev-story", run.Workflow)
+	assert.Equal(t, "sess-1", run.SessionID)
+	assert.Equal(t, "sess-0", run.ResumeSessionID)
+	assert.True(t, run.StartedAt.Equal(start))
+	assert.True(t, run.Finished)
+	assert.Equal(t, 1, run.ExitCode)
+	assert.False(t, run.Success)
+	assert.Equal(t, exitErr.Error(), run.Error)
+	assert.InDelta(t, 0.5, run.Stats.TotalCostUSD, 1e-9)
+
+	prompt, err := os.ReadFile(run.Path(PromptFile))
+	require.NoError(t, err)
+	assert.Equal(t, "/bmad-bmm-dev-story 1-2-login", string(prompt))
+
+	stderr, err := os.ReadFile(run.Path(StderrFile))
+	require.NoError(t, err)
+	assert.Equal(t, "warning: slow\n", string(stderr), "lines after Finish are dropped")
+
+	transcript, err := claude.ReadTranscript(run.Path(EventsFile))
+	require.NoError(t, err)
+	assert.Len(t, transcript.Lines, len(sessionLines))
+	assert.Equal(t, "/bmad-bmm-dev-story 1-2-login", transcript.Prompt)
+	assert.Equal(t, exitErr, transcript.Err(), "the transcript replays with the session's error")
+}
+
+func TestArchive_RunErrorOverridesSessionError(t *testing.T) {
+	a := New(Config{Dir: t.TempDir()})
+	rec, err := a.Start("", "raw", "hello", claude.RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, RawStory, filepath.Base(filepath.Dir(rec.Dir())))
+
+	require.NoError(t, rec.Finish(Outcome{ExitCode: 1, Err: assert.AnError}))
+
+	run, err := Load(rec.Dir())
+	require.NoError(t, err)
+	assert.Equal(t, assert.AnError.Error(), run.Error)
+	assert.Empty(t, run.StoryKey)
+}
+
+func TestArchive_UniqueRunDirs(t *testing.T) {
+	a := New(Config{Dir: t.TempDir()})
+	a.now = fixedClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local), 0)
+
+	var dirs []string
+	for range 3 {
+		rec, err := a.Start("../1-1", "code/review", "p", claude.RunOptions{})
+		require.NoError(t, err)
+		require.NoError(t, rec.Finish(Outcome{}))
+		dirs = append(dirs, rec.Dir())
+	}
+
+	parent := filepath.Join(a.Dir(), ".._1-1")
+	assert.Equal(t, []string{
+		filepath.Join(parent, "20260102-030405-code_review"),
+		filepath.Join(parent, "20260102-030405-code_review-2"),
+		filepath.Join(parent, "20260102-030405-code_review-3"),
+	}, dirs)
+}
+
+func TestPathSegment(t *testing.T) {
+	assert.Equal(t, "1-2-login", pathSegment("1-2-login"))
+	assert.Equal(t, "a_b", pathSegment("a/b"))
+	assert.Equal(t, "_", pathSegment(".."))
+	assert.Equal(t, "_", pathSegment(""))
+}
+
+// startRuns records finished runs at the given times.
+func startRuns(t *testing.T, a *Archive, story string, times ...time.Time) []string {
+	t.Helper()
+	var dirs []string
+	for _, at := range times {
+		a.now = func() time.Time { return at }
+		rec, err := a.Start(story, "dev-story", "p", claude.RunOptions{})
+		require.NoError(t, err)
+		require.NoError(t, rec.Finish(Outcome{}))
+		dirs = append(dirs, rec.Dir())
+	}
+	return dirs
+}
+
+func TestArchive_List(t *testing.T) {
+	a := New(Config{Dir: t.TempDir()})
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	late := startRuns(t, a, "1-2", base.Add(2*time.Hour))
+	early := startRuns(t, a, "1-1", base, base.Add(time.Hour))
+	require.NoError(t, os.MkdirAll(filepath.Join(a.Dir(), "1-3", "broken"), 0o755))
+
+	runs, err := a.List()
+
+	require.NoError(t, err)
+	require.Len(t, runs, 3, "directories without run info are skipped")
+	assert.Equal(t, []string{early[0], early[1], late[0]}, []string{runs[0].Dir, runs[1].Dir, runs[2].Dir})
+}
+
+func TestArchive_List_Missing(t *testing.T) {
+	runs, err := New(Config{Dir: filepath.Join(t.TempDir(), "none")}).List()
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestArchive_Prune(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.Local)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantAge []int // days ago of the runs kept
+	}{
+		{"no limits", Config{}, []int{9, 5, 2, 1, 0}},
+		{"max runs", Config{MaxRuns: 2}, []int{1, 0}},
+		{"max age", Config{MaxAge: 3 * day}, []int{2, 1, 0}},
+		{"both", Config{MaxRuns: 4, MaxAge: 6 * day}, []int{5, 2, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Dir = t.TempDir()
+			a := New(tt.config)
+			startRuns(t, a, "1-1", now.Add(-9*day), now.Add(-5*day))
+			startRuns(t, a, "1-2", now.Add(-2*day), now.Add(-1*day), now)
+			a.now = func() time.Time { return now }
+
+			removed, err := a.Prune()
+			require.NoError(t, err)
+			assert.Equal(t, 5-len(tt.wantAge), removed)
+
+			runs, err := a.List()
+			require.NoError(t, err)
+			var ages []int
+			for _, run := range runs {
+				ages = append(ages, int(now.Sub(run.StartedAt)/day))
+			}
+			assert.Equal(t, tt.wantAge, ages)
+		})
+	}
+
+	t.Run("removes empty story directories", func(t *testing.T) {
+		a := New(Config{Dir: t.TempDir(), MaxRuns: 1})
+		startRuns(t, a, "1-1", now.Add(-day))
+		startRuns(t, a, "1-2", now)
+
+		_, err := a.Prune()
+		require.NoError(t, err)
+		assert.NoDirExists(t, filepath.Join(a.Dir(), "1-1"))
+		assert.DirExists(t, filepath.Join(a.Dir(), "1-2"))
+	})
+}
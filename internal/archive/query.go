@@ -0,0 +1,221 @@
+package archive
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"bmad-automate/internal/claude"
+)
+
+// Statuses of a run, as reported by [Info.Status].
+const (
+	StatusSuccess    = "success"
+	StatusFailed     = "failed"
+	StatusUnfinished = "unfinished"
+)
+
+// Statuses lists the valid statuses of a run, for filtering.
+var Statuses = []string{StatusSuccess, StatusFailed, StatusUnfinished}
+
+// Status returns the outcome of the run: [StatusSuccess], [StatusFailed], or
+// [StatusUnfinished] for a run that is in progress or whose process died.
+func (i Info) Status() string {
+	switch {
+	case !i.Finished:
+		return StatusUnfinished
+	case i.Success:
+		return StatusSuccess
+	default:
+		return StatusFailed
+	}
+}
+
+// ID returns the run's ID: its directory relative to the archive directory,
+// as <story>/<timestamp>-<workflow>.
+func (a *Archive) ID(run *Run) string {
+	if rel, err := filepath.Rel(a.config.Dir, run.Dir); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return run.Dir
+}
+
+// Find returns the run with the given ID, or in the given directory.
+func (a *Archive) Find(id string) (*Run, error) {
+	if run, err := Load(filepath.Join(a.config.Dir, filepath.FromSlash(id))); err == nil {
+		return run, nil
+	}
+	run, err := Load(id)
+	if err != nil {
+		return nil, fmt.Errorf("no run %s in %s", id, a.config.Dir)
+	}
+	return run, nil
+}
+
+// Filter selects archived runs. Zero fields match every run.
+type Filter struct {
+	// StoryKey matches runs of this story.
+	StoryKey string
+
+	// Epic matches runs of the stories of this epic.
+	Epic string
+
+	// Status matches runs with this [Info.Status].
+	Status string
+
+	// Since matches runs started at or after this time.
+	Since time.Time
+
+	// Until matches runs started before this time.
+	Until time.Time
+}
+
+// Match reports whether the filter selects run.
+func (f Filter) Match(run *Run) bool {
+	switch {
+	case f.StoryKey != "" && run.StoryKey != f.StoryKey:
+		return false
+	case f.Epic != "" && !strings.HasPrefix(run.StoryKey, f.Epic+"-"):
+		return false
+	case f.Status != "" && run.Status() != f.Status:
+		return false
+	case !f.Since.IsZero() && run.StartedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !run.StartedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Select returns the runs the filter selects, in order.
+func (f Filter) Select(runs []*Run) []*Run {
+	var selected []*Run
+	for _, run := range runs {
+		if f.Match(run) {
+			selected = append(selected, run)
+		}
+	}
+	return selected
+}
+
+// Events returns the parsed events of the run's session. Lines that are not
+// valid stream-json are skipped.
+func (r *Run) Events() ([]claude.Event, error) {
+	transcript, err := claude.ReadTranscript(r.Path(EventsFile))
+	if err != nil {
+		return nil, err
+	}
+	var events []claude.Event
+	for _, line := range transcript.Lines {
+		lineEvents, err := claude.ParseLine(string(line))
+		if err != nil {
+			continue
+		}
+		events = append(events, lineEvents...)
+	}
+	return events, nil
+}
+
+// ToolCall is a tool call of a run with its result.
+type ToolCall struct {
+	// Call is the tool_use event.
+	Call claude.Event
+
+	// Result is the tool result event, or nil if the session ended before
+	// the tool returned.
+	Result *claude.Event
+}
+
+// Failed reports whether the tool call returned an error.
+func (c ToolCall) Failed() bool {
+	return c.Result != nil && c.Result.ToolIsError
+}
+
+// ToolCalls returns the run's tool calls in order, each paired with its
+// result. The Nth call is the one replay --from-tool N starts at.
+func (r *Run) ToolCalls() ([]ToolCall, error) {
+	events, err := r.Events()
+	if err != nil {
+		return nil, err
+	}
+	return toolCalls(events), nil
+}
+
+// toolCalls pairs the tool_use events with their results.
+func toolCalls(events []claude.Event) []ToolCall {
+	var calls []ToolCall
+	index := make(map[string]int)
+	for _, event := range events {
+		switch {
+		case event.IsToolUse():
+			index[event.ToolUseID] = len(calls)
+			calls = append(calls, ToolCall{Call: event})
+		case event.IsToolResult():
+			if i, ok := index[event.ToolUseID]; ok && calls[i].Result == nil {
+				calls[i].Result = &event
+			}
+		}
+	}
+	return calls
+}
+
+// Fields of a tool call searched by [Grep].
+const (
+	FieldCommand = "command"
+	FieldFile    = "file"
+	FieldStdout  = "stdout"
+	FieldStderr  = "stderr"
+)
+
+// Match is a line of a tool call found by [Grep].
+type Match struct {
+	// Run is the run that made the tool call.
+	Run *Run
+
+	// Call is the position of the tool call in the run, starting at 1.
+	Call int
+
+	// Tool is the name of the tool.
+	Tool string
+
+	// Field is the part of the tool call that matched: [FieldCommand],
+	// [FieldFile], [FieldStdout], or [FieldStderr].
+	Field string
+
+	// Line is the matching line.
+	Line string
+}
+
+// Grep searches the commands, file paths, and outputs of the tool calls of
+// runs for lines matching re. Runs whose events cannot be read are skipped.
+func Grep(runs []*Run, re *regexp.Regexp) []Match {
+	type field struct{ name, text string }
+
+	var matches []Match
+	for _, run := range runs {
+		calls, err := run.ToolCalls()
+		if err != nil {
+			continue
+		}
+		for i, call := range calls {
+			fields := []field{
+				{FieldCommand, call.Call.ToolCommand},
+				{FieldFile, call.Call.ToolFilePath},
+			}
+			if call.Result != nil {
+				fields = append(fields, field{FieldStdout, call.Result.ToolStdout}, field{FieldStderr, call.Result.ToolStderr})
+			}
+			for _, f := range fields {
+				for line := range strings.Lines(f.text) {
+					line = strings.TrimRight(line, "\r\n")
+					if re.MatchString(line) {
+						matches = append(matches, Match{Run: run, Call: i + 1, Tool: call.Call.ToolName, Field: f.name, Line: line})
+					}
+				}
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/claude"
+)
+
+// toolSessionLines is a session with a successful and a failed tool call.
+var toolSessionLines = []string{
+	`{"type":"system","subtype":"init","session_id":"s"}`,
+	`{"type":"assistant","session_id":"s","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go mod tidy"}}]}}`,
+	`{"type":"user","session_id":"s","message":{"content":[{"type":"tool_result","tool_use_id":"t1"}]},"tool_use_result":{"stdout":"go: added golang.org/x/sync v0.8.0\ngo: removed unused module"}}`,
+	`not json`,
+	`{"type":"assistant","session_id":"s","message":{"id":"m2","content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"go.mod"}}]}}`,
+	`{"type":"user","session_id":"s","message":{"content":[{"type":"tool_result","tool_use_id":"t2","is_error":true}]},"tool_use_result":{"stderr":"go.mod: file changed"}}`,
+	`{"type":"assistant","session_id":"s","message":{"id":"m3","content":[{"type":"tool_use","id":"t3","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+}
+
+// recordRun archives a finished run of story with the given stream-json lines.
+func recordRun(t *testing.T, a *Archive, story string, at time.Time, success bool, lines ...string) *Run {
+	t.Helper()
+	a.now = func() time.Time { return at }
+	rec, err := a.Start(story, "dev-story", "p", claude.RunOptions{})
+	require.NoError(t, err)
+	for _, line := range lines {
+		events, err := claude.ParseLine(line)
+		if err != nil {
+			continue
+		}
+		for _, event := range events {
+			rec.Event(event)
+		}
+	}
+	outcome := Outcome{}
+	if !success {
+		outcome.ExitCode = 1
+	}
+	require.NoError(t, rec.Finish(outcome))
+	run, err := Load(rec.Dir())
+	require.NoError(t, err)
+	return run
+}
+
+func TestInfo_Status(t *testing.T) {
+	assert.Equal(t, StatusUnfinished, Info{}.Status())
+	assert.Equal(t, StatusSuccess, Info{Finished: true, Success: true}.Status())
+	assert.Equal(t, StatusFailed, Info{Finished: true}.Status())
+}
+
+func TestArchive_IDAndFind(t *testing.T) {
+	a := New(Config{Dir: t.TempDir()})
+	run := recordRun(t, a, "1-2-login", time.Date(2026, 3, 14, 9, 26, 53, 0, time.Local), true)
+
+	id := a.ID(run)
+	assert.Equal(t, "1-2-login/20260314-092653-dev-story", id)
+
+	found, err := a.Find(id)
+	require.NoError(t, err)
+	assert.Equal(t, run.Dir, found.Dir)
+
+	found, err = a.Find(run.Dir)
+	require.NoError(t, err, "a run directory is found too")
+	assert.Equal(t, run.Dir, found.Dir)
+
+	_, err = a.Find("1-2-login/none")
+	assert.ErrorContains(t, err, "no run 1-2-login/none")
+}
+
+func TestFilter_Select(t *testing.T) {
+	a := New(Config{Dir: t.TempDir()})
+	day := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	runs := []*Run{
+		recordRun(t, a, "1-1-setup", day, true),
+		recordRun(t, a, "1-2-api", day.Add(24*time.Hour), false),
+		recordRun(t, a, "11-1-other", day.Add(48*time.Hour), true),
+	}
+	unfinished, err := a.Start("2-1-ui", "dev-story", "p", claude.RunOptions{})
+	require.NoError(t, err)
+	running, err := Load(unfinished.Dir())
+	require.NoError(t, err)
+	runs = append(runs, running)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"none", Filter{}, []string{"1-1-setup", "1-2-api", "11-1-other", "2-1-ui"}},
+		{"story", Filter{StoryKey: "1-2-api"}, []string{"1-2-api"}},
+		{"epic", Filter{Epic: "1"}, []string{"1-1-setup", "1-2-api"}},
+		{"failed", Filter{Status: StatusFailed}, []string{"1-2-api"}},
+		{"unfinished", Filter{Status: StatusUnfinished}, []string{"2-1-ui"}},
+		{"since", Filter{Since: day.Add(time.Hour), Until: day.Add(48 * time.Hour)}, []string{"1-2-api"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, run := range tt.filter.Select(runs) {
+				got = append(got, run.StoryKey)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRun_ToolCalls(t *testing.T) {
+	run := recordRun(t, New(Config{Dir: t.TempDir()}), "1-1", time.Now(), true, toolSessionLines...)
+
+	calls, err := run.ToolCalls()
+
+	require.NoError(t, err)
+	require.Len(t, calls, 3)
+	assert.Equal(t, "go mod tidy", calls[0].Call.ToolCommand)
+	require.NotNil(t, calls[0].Result)
+	assert.Contains(t, calls[0].Result.ToolStdout, "golang.org/x/sync")
+	assert.False(t, calls[0].Failed())
+	assert.Equal(t, "go.mod", calls[1].Call.ToolFilePath)
+	assert.True(t, calls[1].Failed())
+	assert.Nil(t, calls[2].Result, "the session ended before the call returned")
+}
+
+func TestGrep(t *testing.T) {
+	a := New(Config{Dir: t.TempDir()})
+	withTools := recordRun(t, a, "1-1", time.Now(), true, toolSessionLines...)
+	other := recordRun(t, a, "1-2", time.Now(), true, sessionLines...)
+	missing := &Run{Dir: filepath.Join(t.TempDir(), "gone")}
+
+	matches := Grep([]*Run{withTools, other, missing}, regexp.MustCompile(`go\.mod|go mod`))
+
+	require.Len(t, matches, 4)
+	assert.Equal(t, Match{Run: withTools, Call: 1, Tool: "Bash", Field: FieldCommand, Line: "go mod tidy"}, matches[0])
+	assert.Equal(t, Match{Run: withTools, Call: 2, Tool: "Edit", Field: FieldFile, Line: "go.mod"}, matches[1])
+	assert.Equal(t, Match{Run: withTools, Call: 2, Tool: "Edit", Field: FieldStderr, Line: "go.mod: file changed"}, matches[2])
+	assert.Equal(t, Match{Run: other, Call: 1, Tool: "Bash", Field: FieldCommand, Line: "go mod tidy"}, matches[3])
+
+	matches = Grep([]*Run{withTools}, regexp.MustCompile(`removed`))
+	require.Len(t, matches, 1)
+	assert.Equal(t, "go: removed unused module", matches[0].Line, "outputs are searched line by line")
+}
@@ -0,0 +1,233 @@
+// Package autoanswer answers the questions Claude asks at the end of a turn.
+//
+// Prompts tell Claude not to ask questions, but a session still sometimes
+// ends its turn waiting for input, and the step then stops short of its goal.
+// A [Policy] detects such a final message, with built-in heuristics and
+// configurable regular expressions, and replies with a configured answer or
+// with the answer of a second "answerer" Claude session. It gives up after a
+// configured number of exchanges per session.
+//
+// Key types:
+//   - [Config] holds the patterns, the answer, and the exchange limit
+//   - [Policy] detects questions and produces replies
+//   - [Question] is a question asked in a session
+//   - [Reply] is the answer to send back, and what it cost
+//
+// Replying past the limit fails with an error matching [ErrGaveUp].
+package autoanswer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"bmad-automate/internal/claude"
+)
+
+// ErrGaveUp is returned by [Policy.Respond] once a session has used up its
+// exchanges.
+var ErrGaveUp = errors.New("gave up answering questions")
+
+// Config configures a [Policy].
+type Config struct {
+	// Patterns are regular expressions (Go syntax) that mark a final
+	// message as a question, in addition to the built-in heuristics.
+	Patterns []string
+
+	// Answer is the reply sent to every question.
+	Answer string
+
+	// AnswererPrompt, if set, is a Go template for the prompt of an
+	// answerer session, whose answer is sent instead of Answer. It is
+	// expanded with [PromptData].
+	AnswererPrompt string
+
+	// MaxExchanges is how many questions are answered per session.
+	MaxExchanges int
+}
+
+// PromptData is the data the answerer prompt template is expanded with.
+type PromptData struct {
+	// Workflow is the name of the asking session's workflow, empty for raw
+	// prompts.
+	Workflow string
+
+	// StoryKey is the story the asking session works on, if any.
+	StoryKey string
+
+	// Question is Claude's final message.
+	Question string
+}
+
+// Question is a question asked at the end of a session turn.
+type Question struct {
+	// Workflow is the name of the session's workflow, empty for raw prompts.
+	Workflow string
+
+	// StoryKey is the story the session works on, empty for raw prompts.
+	StoryKey string
+
+	// Text is Claude's final message.
+	Text string
+
+	// Exchange numbers the questions of the session, starting at 1.
+	Exchange int
+}
+
+// Reply is the answer to a [Question].
+type Reply struct {
+	// Text is the message to send back.
+	Text string
+
+	// Stats are the result statistics of the answerer session, or nil for
+	// the configured answer.
+	Stats *claude.ResultStats
+}
+
+// Policy decides which final messages are questions and how to answer them.
+//
+// Use [New] to create a Policy.
+type Policy struct {
+	patterns     []*regexp.Regexp
+	answer       string
+	prompt       *template.Template
+	maxExchanges int
+	executor     claude.Executor
+}
+
+// New creates a Policy. The executor runs the answerer sessions; it is only
+// used when cfg.AnswererPrompt is set.
+//
+// Returns an error if a pattern or the answerer prompt does not parse, or if
+// there is neither an answer nor an answerer prompt.
+func New(cfg Config, executor claude.Executor) (*Policy, error) {
+	p := &Policy{answer: cfg.Answer, maxExchanges: cfg.MaxExchanges, executor: executor}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	if cfg.AnswererPrompt != "" {
+		prompt, err := template.New("answerer").Option("missingkey=error").Parse(cfg.AnswererPrompt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid answerer prompt: %w", err)
+		}
+		p.prompt = prompt
+	} else if strings.TrimSpace(cfg.Answer) == "" {
+		return nil, errors.New("an answer or an answerer prompt is required")
+	}
+	return p, nil
+}
+
+// askPhrases are phrases that ask for input when they open a sentence of the
+// last paragraph, even without a question mark.
+var askPhrases = regexp.MustCompile(`(?i)(^|[.!:]\s+)(please (confirm|advise|clarify|choose|decide|specify|let me know)\b|(would|do) you (like|want|prefer)\b|should I\b|shall I\b|I need (your|you to|a decision|confirmation)\b|waiting for (your|you)\b)`)
+
+// IsQuestion reports whether a final message asks for input.
+//
+// It does when one of the configured patterns matches anywhere in the text,
+// or when its last paragraph ends with a question mark, has a line ending in
+// one before a list of options, or contains a request such as "please confirm",
+// "should I" or "would you like".
+func (p *Policy) IsQuestion(text string) bool {
+	for _, re := range p.patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+
+	paragraphs := strings.Split(strings.TrimSpace(text), "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if isOptionList(last) && len(paragraphs) > 1 {
+		last = paragraphs[len(paragraphs)-2] + "\n" + last
+	}
+	for _, line := range strings.Split(last, "\n") {
+		if strings.HasSuffix(strings.TrimRight(line, " \t*_`)"), "?") {
+			return true
+		}
+	}
+	return askPhrases.MatchString(strings.Join(strings.Fields(last), " "))
+}
+
+// optionLine matches a list item, such as "1. Keep it", "- Keep it" or "(a) Keep it".
+var optionLine = regexp.MustCompile(`^\s*([-*•]|\d+[.)]|\(?[a-zA-Z][.)])\s+`)
+
+// isOptionList reports whether every line of a paragraph is a list item.
+func isOptionList(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !optionLine.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxExchanges returns how many questions are answered per session.
+func (p *Policy) MaxExchanges() int {
+	return p.maxExchanges
+}
+
+// Respond returns the reply to a question: the answer of an answerer session
+// when an answerer prompt is configured, the configured answer otherwise.
+//
+// Returns an error matching [ErrGaveUp] once q.Exchange is over the limit,
+// or an error if the answerer session fails or gives no answer.
+func (p *Policy) Respond(ctx context.Context, q Question) (Reply, error) {
+	if q.Exchange > p.maxExchanges {
+		return Reply{}, fmt.Errorf("%w: still asking after %d answers", ErrGaveUp, p.maxExchanges)
+	}
+	if p.prompt == nil {
+		return Reply{Text: p.answer}, nil
+	}
+	return p.runAnswerer(ctx, q)
+}
+
+// answererPermissions lets the answerer read the project but change nothing.
+var answererPermissions = claude.Permissions{Mode: "plan"}
+
+// runAnswerer asks an answerer session, in plan mode, to answer q.
+func (p *Policy) runAnswerer(ctx context.Context, q Question) (Reply, error) {
+	var prompt strings.Builder
+	data := PromptData{Workflow: q.Workflow, StoryKey: q.StoryKey, Question: q.Text}
+	if err := p.prompt.Execute(&prompt, data); err != nil {
+		return Reply{}, fmt.Errorf("expanding answerer prompt: %w", err)
+	}
+
+	permissions := answererPermissions
+	ctx = claude.WithRunOptions(ctx, claude.RunOptions{Permissions: &permissions})
+	var lastText string
+	var result *claude.Event
+	_, err := p.executor.ExecuteWithResult(ctx, prompt.String(), func(event claude.Event) {
+		if event.IsText() {
+			lastText = event.Text
+		}
+		if event.Stats != nil {
+			result = &event
+		}
+	})
+	var reply Reply
+	if result != nil {
+		reply.Stats = result.Stats
+		if result.Stats.Result != "" {
+			lastText = result.Stats.Result
+		}
+	}
+	if err != nil {
+		return reply, fmt.Errorf("answerer session: %w", err)
+	}
+	if result != nil {
+		if resultErr := claude.NewResultError(*result); resultErr != nil {
+			return reply, fmt.Errorf("answerer session: %w", resultErr)
+		}
+	}
+	reply.Text = strings.TrimSpace(lastText)
+	if reply.Text == "" {
+		return reply, errors.New("answerer session gave no answer")
+	}
+	return reply, nil
+}
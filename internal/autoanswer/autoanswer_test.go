@@ -0,0 +1,106 @@
+package autoanswer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/claude"
+)
+
+func TestPolicy_IsQuestion(t *testing.T) {
+	policy, err := New(Config{Answer: "Proceed.", Patterns: []string{`(?i)awaiting approval`}}, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"question mark", "I found two helpers.\n\nWhich one should I extend?", true},
+		{"markdown question", "**Should the API be versioned?**", true},
+		{"question before options", "How should I proceed?\n\n1. Keep the old schema\n2. Migrate it", true},
+		{"request phrase", "The tests need a database. Please confirm that I can start one.", true},
+		{"would you like", "All done. Would you like me to also update the docs", true},
+		{"configured pattern", "Migration written, awaiting approval before running it.", true},
+		{"done", "All tests pass and the story is complete.", false},
+		{"earlier question", "Why did it fail? The import was missing.\n\nFixed it; all tests pass.", false},
+		{"list without question", "Changes:\n\n- Added a helper\n- Updated tests", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.IsQuestion(tt.text))
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := New(Config{Answer: "ok", Patterns: []string{"("}}, nil)
+	assert.ErrorContains(t, err, `invalid pattern "("`)
+
+	_, err = New(Config{AnswererPrompt: "{{.Question"}, nil)
+	assert.ErrorContains(t, err, "invalid answerer prompt")
+
+	_, err = New(Config{MaxExchanges: 1}, nil)
+	assert.ErrorContains(t, err, "an answer or an answerer prompt is required")
+}
+
+func TestPolicy_Respond_Answer(t *testing.T) {
+	policy, err := New(Config{Answer: "Proceed.", MaxExchanges: 2}, nil)
+	require.NoError(t, err)
+
+	reply, err := policy.Respond(context.Background(), Question{Text: "Should I?", Exchange: 2})
+	require.NoError(t, err)
+	assert.Equal(t, Reply{Text: "Proceed."}, reply)
+
+	_, err = policy.Respond(context.Background(), Question{Text: "Should I?", Exchange: 3})
+	assert.ErrorIs(t, err, ErrGaveUp)
+	assert.ErrorContains(t, err, "still asking after 2 answers")
+}
+
+func TestPolicy_Respond_Answerer(t *testing.T) {
+	executor := &claude.MockExecutor{
+		Events: []claude.Event{
+			{Type: claude.EventTypeAssistant, Text: "Let me check the story."},
+			{Type: claude.EventTypeResult, SessionComplete: true, Stats: &claude.ResultStats{Result: " Keep the old schema. ", TotalCostUSD: 0.02}},
+		},
+	}
+	policy, err := New(Config{
+		AnswererPrompt: "Story {{.StoryKey}} ({{.Workflow}}) asks: {{.Question}}",
+		MaxExchanges:   1,
+	}, executor)
+	require.NoError(t, err)
+
+	reply, err := policy.Respond(context.Background(), Question{
+		Workflow: "dev-story", StoryKey: "1-2", Text: "Which schema?", Exchange: 1,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Keep the old schema.", reply.Text)
+	require.NotNil(t, reply.Stats)
+	assert.Equal(t, 0.02, reply.Stats.TotalCostUSD)
+	assert.Equal(t, []string{"Story 1-2 (dev-story) asks: Which schema?"}, executor.RecordedPrompts)
+	require.Len(t, executor.RecordedOptions, 1)
+	assert.Equal(t, "plan", executor.RecordedOptions[0].Permissions.Mode, "the answerer cannot change anything")
+}
+
+func TestPolicy_Respond_AnswererFails(t *testing.T) {
+	executor := &claude.MockExecutor{
+		Events: []claude.Event{
+			{Type: claude.EventTypeResult, SessionComplete: true, Stats: &claude.ResultStats{IsError: true, Result: "API Error: 529"}},
+		},
+	}
+	policy, err := New(Config{AnswererPrompt: "{{.Question}}", MaxExchanges: 1}, executor)
+	require.NoError(t, err)
+
+	_, err = policy.Respond(context.Background(), Question{Text: "Which?", Exchange: 1})
+	assert.ErrorContains(t, err, "answerer session")
+
+	executor.Events = nil
+	_, err = policy.Respond(context.Background(), Question{Text: "Which?", Exchange: 1})
+	assert.ErrorContains(t, err, "gave no answer")
+}
@@ -0,0 +1,33 @@
+package autoanswer_test
+
+import (
+	"context"
+	"fmt"
+
+	"bmad-automate/internal/autoanswer"
+)
+
+// This example demonstrates detecting a question and answering it until the
+// session runs out of exchanges.
+func Example_policy() {
+	policy, err := autoanswer.New(autoanswer.Config{
+		Answer:       "Proceed with your best judgment.",
+		MaxExchanges: 1,
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	text := "The tests pass. Should I also refactor the handler?"
+	fmt.Println("question:", policy.IsQuestion(text))
+
+	reply, _ := policy.Respond(context.Background(), autoanswer.Question{Text: text, Exchange: 1})
+	fmt.Println("reply:", reply.Text)
+
+	_, err = policy.Respond(context.Background(), autoanswer.Question{Text: text, Exchange: 2})
+	fmt.Println(err)
+	// Output:
+	// question: true
+	// reply: Proceed with your best judgment.
+	// gave up answering questions: still asking after 1 answers
+}
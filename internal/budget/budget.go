@@ -0,0 +1,184 @@
+// Package budget enforces spending limits on Claude sessions.
+//
+// Limits can be set on three scopes: a single workflow step, all steps of one
+// story, and the whole CLI invocation. A [Tracker] accumulates spend per scope
+// and reports an [*ExceededError] as soon as any limit is crossed, so the
+// caller can cancel the running session.
+//
+// Key types:
+//   - [Limits] caps cost and tokens for one scope (zero means unlimited)
+//   - [Spend] is an amount of tokens and dollars spent
+//   - [Tracker] accumulates spend and checks it against the limits
+//
+// All exceeded errors match [ErrExceeded] via errors.Is.
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrExceeded is the sentinel matched by every [ExceededError].
+var ErrExceeded = errors.New("budget exceeded")
+
+// Scope identifies which budget a limit applies to.
+type Scope string
+
+const (
+	// ScopeStep limits a single workflow step (one Claude session).
+	ScopeStep Scope = "step"
+
+	// ScopeStory limits all steps run for one story.
+	ScopeStory Scope = "story"
+
+	// ScopeInvocation limits everything run by one CLI invocation.
+	ScopeInvocation Scope = "invocation"
+)
+
+// Limits caps the spend allowed within one [Scope].
+//
+// A zero field means that dimension is unlimited.
+type Limits struct {
+	// MaxCostUSD is the maximum cost in US dollars.
+	MaxCostUSD float64
+
+	// MaxTokens is the maximum number of tokens (input, output, and cache).
+	MaxTokens int64
+}
+
+// exceededBy reports whether spend goes over any limit.
+func (l Limits) exceededBy(s Spend) bool {
+	if l.MaxCostUSD > 0 && s.CostUSD > l.MaxCostUSD {
+		return true
+	}
+	if l.MaxTokens > 0 && s.Tokens > l.MaxTokens {
+		return true
+	}
+	return false
+}
+
+// IsZero reports whether no limits are set.
+func (l Limits) IsZero() bool {
+	return l.MaxCostUSD <= 0 && l.MaxTokens <= 0
+}
+
+// Spend is an amount of tokens and money spent.
+type Spend struct {
+	// Tokens is the number of tokens used (input, output, and cache).
+	Tokens int64
+
+	// CostUSD is the cost in US dollars.
+	CostUSD float64
+}
+
+// Add returns the sum of s and other.
+func (s Spend) Add(other Spend) Spend {
+	return Spend{Tokens: s.Tokens + other.Tokens, CostUSD: s.CostUSD + other.CostUSD}
+}
+
+// ExceededError reports that spend within a scope went over its limits.
+type ExceededError struct {
+	// Scope is the budget that was exceeded.
+	Scope Scope
+
+	// Limits are the limits configured for the scope.
+	Limits Limits
+
+	// Spent is the total spend within the scope when the limit was crossed.
+	Spent Spend
+}
+
+// Error implements the error interface.
+func (e *ExceededError) Error() string {
+	var limits []string
+	if e.Limits.MaxCostUSD > 0 {
+		limits = append(limits, fmt.Sprintf("$%.2f", e.Limits.MaxCostUSD))
+	}
+	if e.Limits.MaxTokens > 0 {
+		limits = append(limits, fmt.Sprintf("%d tokens", e.Limits.MaxTokens))
+	}
+	return fmt.Sprintf("budget exceeded: %s limit of %s reached (spent $%.2f, %d tokens)",
+		e.Scope, strings.Join(limits, " / "), e.Spent.CostUSD, e.Spent.Tokens)
+}
+
+// Is reports whether target is [ErrExceeded].
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// Tracker accumulates spend per story and per invocation and checks it against limits.
+//
+// Use [NewTracker] to create a Tracker. A nil *Tracker is valid and enforces
+// nothing. Tracker is not safe for concurrent use.
+type Tracker struct {
+	step       Limits
+	story      Limits
+	invocation Limits
+
+	stories map[string]Spend
+	total   Spend
+}
+
+// NewTracker creates a [Tracker] with the given limits for each scope.
+func NewTracker(step, story, invocation Limits) *Tracker {
+	return &Tracker{
+		step:       step,
+		story:      story,
+		invocation: invocation,
+		stories:    make(map[string]Spend),
+	}
+}
+
+// Begin checks whether a new step may start for storyKey.
+//
+// Returns an [*ExceededError] if the story or invocation budget has already
+// been used up by earlier steps. An empty storyKey skips the story check.
+func (t *Tracker) Begin(storyKey string) error {
+	return t.Check(storyKey, Spend{})
+}
+
+// Check reports whether the running step's spend so far breaks any limit.
+//
+// session is the spend of the current step only; spend already committed via
+// [Tracker.Commit] is added for the story and invocation checks. Scopes are
+// checked from narrowest to widest, and the first exceeded one is returned.
+func (t *Tracker) Check(storyKey string, session Spend) error {
+	if t == nil {
+		return nil
+	}
+
+	if t.step.exceededBy(session) {
+		return &ExceededError{Scope: ScopeStep, Limits: t.step, Spent: session}
+	}
+	if storyKey != "" {
+		storySpend := t.stories[storyKey].Add(session)
+		if t.story.exceededBy(storySpend) {
+			return &ExceededError{Scope: ScopeStory, Limits: t.story, Spent: storySpend}
+		}
+	}
+	totalSpend := t.total.Add(session)
+	if t.invocation.exceededBy(totalSpend) {
+		return &ExceededError{Scope: ScopeInvocation, Limits: t.invocation, Spent: totalSpend}
+	}
+	return nil
+}
+
+// Commit records a finished step's spend against the story and invocation totals.
+func (t *Tracker) Commit(storyKey string, session Spend) {
+	if t == nil {
+		return
+	}
+	if storyKey != "" {
+		t.stories[storyKey] = t.stories[storyKey].Add(session)
+	}
+	t.total = t.total.Add(session)
+}
+
+// Total returns the spend committed across the whole invocation.
+func (t *Tracker) Total() Spend {
+	if t == nil {
+		return Spend{}
+	}
+	return t.total
+}
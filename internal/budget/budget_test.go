@@ -0,0 +1,123 @@
+package budget
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker_Check(t *testing.T) {
+	tests := []struct {
+		name      string
+		step      Limits
+		story     Limits
+		total     Limits
+		committed map[string]Spend
+		storyKey  string
+		session   Spend
+		wantScope Scope
+	}{
+		{
+			name:    "no limits never exceeded",
+			session: Spend{Tokens: 1_000_000, CostUSD: 100},
+		},
+		{
+			name:      "step token limit",
+			step:      Limits{MaxTokens: 1000},
+			session:   Spend{Tokens: 1001},
+			wantScope: ScopeStep,
+		},
+		{
+			name:    "at the limit is allowed",
+			step:    Limits{MaxTokens: 1000, MaxCostUSD: 1},
+			session: Spend{Tokens: 1000, CostUSD: 1},
+		},
+		{
+			name:      "story limit includes committed spend",
+			story:     Limits{MaxCostUSD: 2},
+			committed: map[string]Spend{"s-1": {CostUSD: 1.5}},
+			storyKey:  "s-1",
+			session:   Spend{CostUSD: 0.6},
+			wantScope: ScopeStory,
+		},
+		{
+			name:      "other stories do not count toward story limit",
+			story:     Limits{MaxCostUSD: 2},
+			committed: map[string]Spend{"s-2": {CostUSD: 1.5}},
+			storyKey:  "s-1",
+			session:   Spend{CostUSD: 0.6},
+		},
+		{
+			name:      "invocation limit includes all stories",
+			total:     Limits{MaxTokens: 100},
+			committed: map[string]Spend{"s-1": {Tokens: 60}, "s-2": {Tokens: 30}},
+			storyKey:  "s-3",
+			session:   Spend{Tokens: 20},
+			wantScope: ScopeInvocation,
+		},
+		{
+			name:      "narrowest scope reported first",
+			step:      Limits{MaxTokens: 10},
+			total:     Limits{MaxTokens: 10},
+			session:   Spend{Tokens: 20},
+			wantScope: ScopeStep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(tt.step, tt.story, tt.total)
+			for key, spend := range tt.committed {
+				tracker.Commit(key, spend)
+			}
+
+			err := tracker.Check(tt.storyKey, tt.session)
+
+			if tt.wantScope == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var exceeded *ExceededError
+			require.ErrorAs(t, err, &exceeded)
+			assert.Equal(t, tt.wantScope, exceeded.Scope)
+			assert.True(t, errors.Is(err, ErrExceeded))
+		})
+	}
+}
+
+func TestTracker_Begin(t *testing.T) {
+	tracker := NewTracker(Limits{}, Limits{MaxCostUSD: 1}, Limits{})
+	require.NoError(t, tracker.Begin("s-1"))
+
+	tracker.Commit("s-1", Spend{CostUSD: 1.01})
+
+	assert.ErrorIs(t, tracker.Begin("s-1"), ErrExceeded)
+	assert.NoError(t, tracker.Begin("s-2"))
+	assert.NoError(t, tracker.Begin(""), "empty story key skips story scope")
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+
+	assert.NoError(t, tracker.Check("s-1", Spend{Tokens: 1 << 40}))
+	tracker.Commit("s-1", Spend{Tokens: 1})
+	assert.Equal(t, Spend{}, tracker.Total())
+}
+
+func TestExceededError_Error(t *testing.T) {
+	err := &ExceededError{
+		Scope:  ScopeStory,
+		Limits: Limits{MaxCostUSD: 5, MaxTokens: 1000},
+		Spent:  Spend{CostUSD: 5.25, Tokens: 900},
+	}
+
+	assert.Equal(t, "budget exceeded: story limit of $5.00 / 1000 tokens reached (spent $5.25, 900 tokens)", err.Error())
+}
+
+func TestLimits_IsZero(t *testing.T) {
+	assert.True(t, Limits{}.IsZero())
+	assert.False(t, Limits{MaxTokens: 1}.IsZero())
+	assert.False(t, Limits{MaxCostUSD: 0.5}.IsZero())
+}
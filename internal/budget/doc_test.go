@@ -0,0 +1,29 @@
+package budget_test
+
+import (
+	"errors"
+	"fmt"
+
+	"bmad-automate/internal/budget"
+)
+
+// This example demonstrates checking a running step against story and
+// invocation budgets.
+func Example_tracker() {
+	tracker := budget.NewTracker(
+		budget.Limits{},                // no per-step limit
+		budget.Limits{MaxCostUSD: 2.0}, // $2 per story
+		budget.Limits{MaxTokens: 5_000_000},
+	)
+
+	// First step finished within budget
+	tracker.Commit("7-1-define-schema", budget.Spend{Tokens: 400_000, CostUSD: 1.5})
+
+	// Second step is streaming; check its spend so far
+	err := tracker.Check("7-1-define-schema", budget.Spend{Tokens: 200_000, CostUSD: 0.75})
+	fmt.Println("exceeded:", errors.Is(err, budget.ErrExceeded))
+	fmt.Println(err)
+	// Output:
+	// exceeded: true
+	// budget exceeded: story limit of $2.00 reached (spent $2.25, 600000 tokens)
+}
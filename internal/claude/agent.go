@@ -0,0 +1,190 @@
+package claude
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// Output formats understood by [NewExecutor], set via [ExecutorConfig.OutputFormat].
+const (
+	// FormatStreamJSON is Claude's stream-json format, parsed by [DefaultParser].
+	FormatStreamJSON = "stream-json"
+
+	// FormatText is plain text, parsed by [TextParser]. Use it for coding-agent
+	// CLIs that do not speak stream-json.
+	FormatText = "text"
+)
+
+// ErrUnknownAgent is returned by [AgentExecutor] when [RunOptions.Agent] names
+// an agent it does not know.
+var ErrUnknownAgent = errors.New("unknown agent")
+
+// ArgsData is the data available to [ExecutorConfig.Args] templates.
+type ArgsData struct {
+	// Prompt is the prompt for the session.
+	Prompt string
+
+	// ResumeSessionID is the session to resume, or empty for a new session.
+	ResumeSessionID string
+
+	// PermissionMode is the configured permission mode, e.g. "bypassPermissions".
+	PermissionMode string
+
+	// AllowedTools is the comma-separated list of allowed tool rules.
+	AllowedTools string
+
+	// DisallowedTools is the comma-separated list of disallowed tool rules.
+	DisallowedTools string
+}
+
+// newArgsData returns the [ArgsData] for a session.
+func newArgsData(prompt string, perms Permissions, opts RunOptions) ArgsData {
+	return ArgsData{
+		Prompt:          prompt,
+		ResumeSessionID: opts.ResumeSessionID,
+		PermissionMode:  perms.Mode,
+		AllowedTools:    strings.Join(perms.AllowedTools, ","),
+		DisallowedTools: strings.Join(perms.DisallowedTools, ","),
+	}
+}
+
+// parseArgs parses [ExecutorConfig.Args] templates.
+func parseArgs(args []string) ([]*template.Template, error) {
+	tmpls := make([]*template.Template, len(args))
+	for i, arg := range args {
+		tmpl, err := template.New(fmt.Sprintf("arg %d", i+1)).Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument template %q: %w", arg, err)
+		}
+		tmpls[i] = tmpl
+	}
+	return tmpls, nil
+}
+
+// expandArgs expands parsed argument templates, dropping empty results.
+func expandArgs(tmpls []*template.Template, data ArgsData) ([]string, error) {
+	args := make([]string, 0, len(tmpls))
+	for _, tmpl := range tmpls {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return nil, fmt.Errorf("expanding arguments: %w", err)
+		}
+		if sb.Len() > 0 {
+			args = append(args, sb.String())
+		}
+	}
+	return args, nil
+}
+
+// TextParser implements [Parser] for agents that print plain text.
+//
+// Each non-blank line of output becomes an [EventTypeAssistant] event whose
+// Text is the line. Lines longer than BufferSize are cut off and reported
+// with an [ErrFieldTruncated] parse error event.
+type TextParser struct {
+	// BufferSize is the maximum length in bytes kept for a single line.
+	// Defaults to 10MB (10 * 1024 * 1024) if not set or <= 0.
+	BufferSize int
+}
+
+// Parse reads lines from the reader and emits them as text events.
+func (p *TextParser) Parse(reader io.Reader) <-chan Event {
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		maxLine := p.BufferSize
+		if maxLine <= 0 {
+			maxLine = defaultBufferSize
+		}
+		r := bufio.NewReaderSize(reader, 64*1024)
+
+		for lineNum := 1; ; lineNum++ {
+			var line []byte
+			var dropped int64
+			var err error
+			for {
+				var chunk []byte
+				chunk, err = r.ReadSlice('\n')
+				if room := maxLine - len(line); room < len(chunk) {
+					dropped += int64(len(chunk) - max(room, 0))
+					chunk = chunk[:max(room, 0)]
+				}
+				line = append(line, chunk...)
+				if err != bufio.ErrBufferFull {
+					break
+				}
+			}
+
+			if text := strings.TrimRight(string(line), "\r\n"); strings.TrimSpace(text) != "" {
+				if dropped > 0 {
+					events <- parseErrorEvent(fmt.Errorf("line %d: %w: %d bytes dropped", lineNum, ErrFieldTruncated, dropped))
+				}
+				events <- Event{Type: EventTypeAssistant, Text: text}
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					events <- parseErrorEvent(fmt.Errorf("reading agent output: %w", err))
+				}
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// AgentExecutor implements [Executor] by dispatching each session to the
+// executor of the agent named by [RunOptions.Agent].
+//
+// Sessions that name no agent go to the fallback executor, normally Claude.
+// This lets each workflow pick its own coding agent, for example so that code
+// is reviewed by a different model than the one that wrote it.
+type AgentExecutor struct {
+	fallback Executor
+	agents   map[string]Executor
+}
+
+// NewAgentExecutor creates an [AgentExecutor] over the named agents.
+func NewAgentExecutor(fallback Executor, agents map[string]Executor) *AgentExecutor {
+	return &AgentExecutor{fallback: fallback, agents: agents}
+}
+
+// Start starts the session on the selected agent; see [Executor.Start].
+func (e *AgentExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	executor, err := e.executor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return executor.Start(ctx, prompt)
+}
+
+// ExecuteWithResult runs the session on the selected agent; see
+// [Executor.ExecuteWithResult].
+func (e *AgentExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	executor, err := e.executor(ctx)
+	if err != nil {
+		return 1, err
+	}
+	return executor.ExecuteWithResult(ctx, prompt, handler)
+}
+
+// executor returns the executor for the agent named on ctx.
+func (e *AgentExecutor) executor(ctx context.Context) (Executor, error) {
+	name := RunOptionsFromContext(ctx).Agent
+	if name == "" {
+		return e.fallback, nil
+	}
+	executor, ok := e.agents[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAgent, name)
+	}
+	return executor, nil
+}
@@ -0,0 +1,131 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandArgs(t *testing.T) {
+	tmpls, err := parseArgs([]string{
+		"exec",
+		"{{if .ResumeSessionID}}--resume{{end}}",
+		"{{.ResumeSessionID}}",
+		"--sandbox={{.PermissionMode}}",
+		"{{.Prompt}}",
+	})
+	require.NoError(t, err)
+
+	data := newArgsData("review 1-1", Permissions{Mode: "plan", AllowedTools: []string{"Read", "Grep"}}, RunOptions{})
+	args, err := expandArgs(tmpls, data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec", "--sandbox=plan", "review 1-1"}, args, "empty arguments are dropped")
+	assert.Equal(t, "Read,Grep", data.AllowedTools)
+
+	args, err = expandArgs(tmpls, newArgsData("go on", Permissions{}, RunOptions{ResumeSessionID: "s1"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec", "--resume", "s1", "--sandbox=", "go on"}, args)
+}
+
+func TestParseArgs_Invalid(t *testing.T) {
+	_, err := parseArgs([]string{"{{.Prompt"})
+	assert.ErrorContains(t, err, "invalid argument template")
+
+	tmpls, err := parseArgs([]string{"{{.Model}}"})
+	require.NoError(t, err)
+	_, err = expandArgs(tmpls, ArgsData{})
+	assert.ErrorContains(t, err, "expanding arguments")
+}
+
+func TestNewExecutor_InvalidArgs(t *testing.T) {
+	executor := NewExecutor(ExecutorConfig{BinaryPath: "agent", Args: []string{"{{"}})
+
+	_, err := executor.ExecuteWithResult(context.Background(), "prompt", nil)
+	assert.ErrorContains(t, err, "invalid argument template")
+
+	_, err = executor.Start(context.Background(), "prompt")
+	assert.ErrorContains(t, err, "invalid argument template")
+}
+
+func TestNewExecutor_TextFormatUsesTextParser(t *testing.T) {
+	executor := NewExecutor(ExecutorConfig{OutputFormat: FormatText})
+	assert.IsType(t, &TextParser{}, executor.parser)
+}
+
+func TestTextParser_Parse(t *testing.T) {
+	input := "first line\n\n  \nsecond line\r\nlast line without newline"
+
+	var events []Event
+	for event := range (&TextParser{}).Parse(strings.NewReader(input)) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 3)
+	for _, event := range events {
+		assert.True(t, event.IsText())
+	}
+	assert.Equal(t, "first line", events[0].Text)
+	assert.Equal(t, "second line", events[1].Text)
+	assert.Equal(t, "last line without newline", events[2].Text)
+}
+
+func TestTextParser_TruncatesLongLines(t *testing.T) {
+	input := strings.Repeat("x", 100) + "\nnext\n"
+
+	var events []Event
+	for event := range (&TextParser{BufferSize: 10}).Parse(strings.NewReader(input)) {
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 3)
+	assert.True(t, events[0].IsParseError())
+	assert.ErrorIs(t, events[0].Err, ErrFieldTruncated)
+	assert.Equal(t, strings.Repeat("x", 10), events[1].Text)
+	assert.Equal(t, "next", events[2].Text)
+}
+
+func TestAgentExecutor(t *testing.T) {
+	fallback := &MockExecutor{Events: []Event{{Type: EventTypeAssistant, Text: "claude"}}}
+	codex := &MockExecutor{Events: []Event{{Type: EventTypeAssistant, Text: "codex"}}, ExitCode: 3}
+	executor := NewAgentExecutor(fallback, map[string]Executor{"codex": codex})
+
+	var texts []string
+	handler := func(e Event) { texts = append(texts, e.Text) }
+
+	code, err := executor.ExecuteWithResult(context.Background(), "write it", handler)
+	require.NoError(t, err)
+	assert.Zero(t, code)
+
+	ctx := WithRunOptions(context.Background(), RunOptions{Agent: "codex"})
+	code, err = executor.ExecuteWithResult(ctx, "review it", handler)
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+
+	assert.Equal(t, []string{"claude", "codex"}, texts)
+	assert.Equal(t, []string{"write it"}, fallback.RecordedPrompts)
+	assert.Equal(t, []string{"review it"}, codex.RecordedPrompts)
+
+	session, err := executor.Start(ctx, "stream it")
+	require.NoError(t, err)
+	result, err := session.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, []string{"review it", "stream it"}, codex.RecordedPrompts)
+}
+
+func TestAgentExecutor_UnknownAgent(t *testing.T) {
+	executor := NewAgentExecutor(&MockExecutor{}, nil)
+	ctx := WithRunOptions(context.Background(), RunOptions{Agent: "gemini"})
+
+	code, err := executor.ExecuteWithResult(ctx, "prompt", nil)
+	assert.Equal(t, 1, code)
+	assert.True(t, errors.Is(err, ErrUnknownAgent))
+	assert.ErrorContains(t, err, "gemini")
+
+	_, err = executor.Start(ctx, "prompt")
+	assert.ErrorIs(t, err, ErrUnknownAgent)
+}
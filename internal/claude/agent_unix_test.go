@@ -0,0 +1,30 @@
+//go:build unix
+
+package claude
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultExecutor_AgentArgs(t *testing.T) {
+	script := writeScript(t, `
+for arg in "$@"; do echo "arg: $arg"; done
+`)
+	executor := NewExecutor(ExecutorConfig{
+		BinaryPath:   script,
+		OutputFormat: FormatText,
+		Args:         []string{"exec", "{{if .ResumeSessionID}}--resume{{end}}", "--mode={{.PermissionMode}}", "{{.Prompt}}"},
+	})
+	ctx := WithRunOptions(context.Background(), RunOptions{Permissions: &Permissions{Mode: "plan"}})
+
+	var texts []string
+	exitCode, err := executor.ExecuteWithResult(ctx, "review story 1-1", func(e Event) { texts = append(texts, e.Text) })
+
+	require.NoError(t, err)
+	assert.Zero(t, exitCode)
+	assert.Equal(t, []string{"arg: exec", "arg: --mode=plan", "arg: review story 1-1"}, texts)
+}
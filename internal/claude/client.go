@@ -3,29 +3,37 @@ package claude
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
-// Executor runs Claude CLI and returns streaming events.
+// waitDelay bounds how long to wait for Claude's output pipes to close after
+// the process has been killed.
+const waitDelay = 5 * time.Second
+
+// Executor runs Claude CLI sessions.
 //
-// Executor provides two execution modes:
-//   - [Executor.Execute]: Fire-and-forget mode that returns a channel of [Event] objects.
-//     Use this when you want to process events as they arrive but don't need the exit code.
+// Executor provides two ways to run a session:
+//   - [Executor.Start]: starts the session and returns a [Session] handle, which
+//     any number of observers can follow, and which reports the [Result] and
+//     live [SessionStats]. Use this to watch or cancel a session while it runs.
 //   - [Executor.ExecuteWithResult]: Blocking mode that processes events via an [EventHandler]
 //     callback and returns the exit code. Use this for production workflows where you need
 //     to know if Claude completed successfully.
 //
 // For testing, use [MockExecutor] which implements this interface without spawning processes.
 type Executor interface {
-	// Execute runs Claude with the given prompt and returns a channel of [Event] objects.
-	// The channel is closed when Claude exits or the context is canceled.
-	// Returns an error if Claude fails to start (e.g., binary not found).
-	//
-	// Note: This method is fire-and-forget; the exit status is not available.
-	// Use [Executor.ExecuteWithResult] if you need the exit code.
-	Execute(ctx context.Context, prompt string) (<-chan Event, error)
+	// Start runs Claude with the given prompt and returns the running [Session].
+	// Returns an error if Claude fails to start (e.g., binary not found);
+	// later failures are reported by [Session.Wait].
+	Start(ctx context.Context, prompt string) (*Session, error)
 
 	// ExecuteWithResult runs Claude with the given prompt and waits for completion.
 	// The handler is called for each [Event] received during execution.
@@ -58,19 +66,70 @@ type ExecutorConfig struct {
 	BinaryPath string
 
 	// OutputFormat is the Claude CLI output format flag.
-	// If empty, defaults to "stream-json" which is required for event parsing.
-	// This should not normally be changed.
+	// If empty, defaults to [FormatStreamJSON] which is required for event parsing.
+	// It also selects the default Parser: [FormatText] output is parsed by a
+	// [TextParser].
 	OutputFormat string
 
-	// Parser is the JSON parser used to parse Claude's streaming output.
-	// If nil, a [DefaultParser] is created with default settings.
+	// InputFormat is how the prompt is written to Claude's stdin. With
+	// [FormatText] (the default, used when empty) stdin holds the prompt as
+	// plain text. With [FormatStreamJSON] the prompt is sent as a stream-json
+	// user message (--input-format stream-json), and stdin stays open for
+	// the follow-up messages of a [RunOptions.Input]; OutputFormat must then
+	// be [FormatStreamJSON] too. Ignored when Args is set, since the
+	// argument templates carry the prompt.
+	InputFormat string
+
+	// Parser is the parser used for the process's output.
+	// If nil, a [DefaultParser] is created with default settings, or a
+	// [TextParser] when OutputFormat is [FormatText].
 	// Provide a custom parser only if you need to adjust buffer sizes.
 	Parser Parser
 
+	// Args, when set, replaces the Claude CLI arguments built by
+	// [ExecutorConfig.BuildArgs]. Each entry is a Go template for one
+	// argument, expanded with [ArgsData]; entries that expand to an empty
+	// string are dropped, so a flag can be conditional, e.g.
+	// "{{if .ResumeSessionID}}--resume{{end}}". Together with BinaryPath and
+	// OutputFormat, this runs a coding-agent CLI other than Claude.
+	Args []string
+
 	// StderrHandler is called for each line written to stderr by Claude.
 	// If nil, stderr output is silently discarded.
 	// Set this to capture error messages or debug output from Claude.
 	StderrHandler func(line string)
+
+	// Permissions are the default permission settings passed to Claude.
+	// A per-invocation override may be supplied via [WithRunOptions].
+	// The zero value adds no permission flags.
+	Permissions Permissions
+}
+
+// BuildArgs returns the Claude CLI arguments for a session.
+//
+// The prompt is not among them: it is written to stdin, which keeps it out of
+// the process list and free of argument length limits. Permission flags come
+// from opts.Permissions when set, otherwise from [ExecutorConfig.Permissions].
+// When opts.ResumeSessionID is set, the session is resumed with --resume.
+func (c ExecutorConfig) BuildArgs(opts RunOptions) []string {
+	perms := c.Permissions
+	if opts.Permissions != nil {
+		perms = *opts.Permissions
+	}
+
+	args := perms.Args()
+	if opts.ResumeSessionID != "" {
+		args = append(args, "--resume", opts.ResumeSessionID)
+	}
+	args = append(args,
+		"--verbose",
+		"-p",
+		"--output-format", c.OutputFormat,
+	)
+	if c.InputFormat == FormatStreamJSON {
+		args = append(args, "--input-format", FormatStreamJSON)
+	}
+	return args
 }
 
 // DefaultExecutor implements [Executor] by spawning Claude as a subprocess.
@@ -83,6 +142,11 @@ type ExecutorConfig struct {
 type DefaultExecutor struct {
 	config ExecutorConfig
 	parser Parser
+
+	// args holds the parsed [ExecutorConfig.Args], and argsErr the error
+	// parsing them, which is returned when a session starts.
+	args    []*template.Template
+	argsErr error
 }
 
 // NewExecutor creates a new [DefaultExecutor] with the given configuration.
@@ -90,74 +154,142 @@ type DefaultExecutor struct {
 // Default values are applied for any unset configuration fields:
 //   - BinaryPath defaults to "claude"
 //   - OutputFormat defaults to "stream-json"
-//   - Parser defaults to a new [DefaultParser]
+//   - Parser defaults to a new [DefaultParser], or a [TextParser] for
+//     [FormatText] output
 //
+// Invalid [ExecutorConfig.Args] templates are reported by every execution.
 // Pass an empty [ExecutorConfig] to use all defaults.
 func NewExecutor(config ExecutorConfig) *DefaultExecutor {
 	if config.BinaryPath == "" {
 		config.BinaryPath = "claude"
 	}
 	if config.OutputFormat == "" {
-		config.OutputFormat = "stream-json"
+		config.OutputFormat = FormatStreamJSON
 	}
 
 	parser := config.Parser
 	if parser == nil {
-		parser = NewParser()
+		if config.OutputFormat == FormatText {
+			parser = &TextParser{}
+		} else {
+			parser = NewParser()
+		}
 	}
 
-	return &DefaultExecutor{
+	executor := &DefaultExecutor{
 		config: config,
 		parser: parser,
 	}
+	if len(config.Args) > 0 {
+		executor.args, executor.argsErr = parseArgs(config.Args)
+	}
+	return executor
 }
 
-// Execute runs Claude with the given prompt and returns a channel of [Event] objects.
+// Start starts Claude with the given prompt and returns the running [Session].
 //
-// The returned channel emits events as they are parsed from Claude's streaming output.
-// The channel is closed when:
-//   - Claude exits normally
-//   - The context is canceled
-//   - An unrecoverable error occurs
+// The session's events are parsed from Claude's streaming output. Timeouts
+// from [RunOptions] are enforced, and [Session.Cancel] kills Claude's whole
+// process group. The [Result] carries the exit code, the last lines of
+// stderr (also passed to [ExecutorConfig.StderrHandler]), the final result
+// statistics, and the signal that killed the process, if any.
 //
-// Note: This method does not provide the exit status. The command's exit code is
-// intentionally not propagated. Use [DefaultExecutor.ExecuteWithResult] if you need
-// to check whether Claude completed successfully.
-func (e *DefaultExecutor) Execute(ctx context.Context, prompt string) (<-chan Event, error) {
-	cmd := exec.CommandContext(ctx, e.config.BinaryPath,
-		"--dangerously-skip-permissions",
-		"--verbose",
-		"-p", prompt,
-		"--output-format", e.config.OutputFormat,
-	)
+// Returns an error if Claude cannot be started, e.g. because the binary is
+// not found; failures after that are reported by [Session.Wait].
+func (e *DefaultExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	opts := RunOptionsFromContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	w := newWatchdog(ctx, opts)
+	fail := func(err error) (*Session, error) {
+		w.stop()
+		cancel()
+		if opts.Input != nil {
+			opts.Input.end()
+		}
+		return nil, err
+	}
+
+	cmd, err := e.command(w.ctx, prompt)
+	if err != nil {
+		return fail(err)
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fail(fmt.Errorf("failed to create stdout pipe: %w", err))
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+		return fail(fmt.Errorf("failed to create stderr pipe: %w", err))
+	}
+
+	sendPrompt, err := e.promptInput(cmd, prompt, opts, w)
+	if err != nil {
+		return fail(err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start claude: %w", err)
+		return fail(fmt.Errorf("failed to start claude: %w", err))
 	}
 
-	// Handle stderr in background
-	go e.handleStderr(stderr)
+	// Handle stderr in background, keeping the tail for the result
+	tail := &stderrTail{}
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		e.handleStderr(stderr, tail.add, opts.Stderr)
+	}()
 
-	// Parse stdout and return events channel
-	events := e.parser.Parse(stdout)
+	// A prompt that cannot be sent ends the session; it is reported unless
+	// Claude's exit status explains more
+	promptDone := make(chan error, 1)
+	go func() { promptDone <- sendPrompt() }()
 
-	// Wait for command completion in background.
-	// Note: Exit status is intentionally not propagated; use ExecuteWithResult if needed.
+	s := newSession(cancel)
 	go func() {
-		_ = cmd.Wait() //nolint:errcheck // Exit status intentionally ignored; use ExecuteWithResult if needed
+		// Publish parsed events, feeding the idle timer
+		for event := range e.parser.Parse(stdout) {
+			w.activity()
+			if event.SessionComplete && opts.Input != nil && opts.Input.Ready() {
+				// Claude now waits for a follow-up, which may take an answerer
+				// session longer than the idle limit to write
+				w.pause()
+			}
+			s.publish(event)
+		}
+
+		// Let stderr drain, unless something else still holds it open
+		select {
+		case <-stderrDone:
+		case <-w.ctx.Done():
+		case <-time.After(waitDelay):
+		}
+
+		err := cmd.Wait()
+		if opts.Input != nil {
+			opts.Input.end()
+		}
+		result := Result{Stderr: tail.lines(), Signal: processSignal(cmd.ProcessState)}
+		var exitErr *exec.ExitError
+		switch {
+		case w.err() != nil:
+			result.ExitCode, err = ExitCodeTimeout, w.err()
+		case errors.As(err, &exitErr):
+			result.ExitCode = exitErr.ExitCode()
+			err = &ExitError{Code: result.ExitCode, Stderr: result.Stderr, Signal: result.Signal}
+		case err != nil:
+			result.ExitCode = 1
+		default:
+			if promptErr := <-promptDone; promptErr != nil {
+				result.ExitCode, err = 1, promptErr
+			}
+		}
+		w.stop()
+		s.finish(result, err)
 	}()
 
-	return events, nil
+	return s, nil
 }
 
 // ExecuteWithResult runs Claude with the given prompt and waits for completion.
@@ -167,74 +299,136 @@ func (e *DefaultExecutor) Execute(ctx context.Context, prompt string) (<-chan Ev
 //
 // Exit code semantics:
 //   - 0: Claude completed successfully
-//   - Non-zero: Claude exited with an error (check stderr via [ExecutorConfig.StderrHandler])
+//   - [ExitCodeTimeout]: the session was killed by [RunOptions.Timeout] or
+//     [RunOptions.IdleTimeout]; the error is a [TimeoutError]
+//   - Other non-zero: Claude exited with an error; the error is an [ExitError]
+//     holding the last lines of stderr (also passed to [ExecutorConfig.StderrHandler])
 //
 // The handler may be nil if you only need the exit code without processing events.
 // If the handler is provided, it is called synchronously for each event before
-// this method returns.
+// this method returns. It is equivalent to [DefaultExecutor.Start] followed by
+// [Session.Handle].
 func (e *DefaultExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
-	cmd := exec.CommandContext(ctx, e.config.BinaryPath,
-		"--dangerously-skip-permissions",
-		"--verbose",
-		"-p", prompt,
-		"--output-format", e.config.OutputFormat,
-	)
-
-	stdout, err := cmd.StdoutPipe()
+	s, err := e.Start(ctx, prompt)
 	if err != nil {
-		return 1, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return 1, err
 	}
+	return s.Handle(handler)
+}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return 1, fmt.Errorf("failed to create stderr pipe: %w", err)
+// command builds the Claude subprocess for a prompt, applying any [RunOptions] on ctx.
+//
+// The arguments come from [ExecutorConfig.Args] when set, and from
+// [ExecutorConfig.BuildArgs] otherwise.
+func (e *DefaultExecutor) command(ctx context.Context, prompt string) (*exec.Cmd, error) {
+	opts := RunOptionsFromContext(ctx)
+	if len(e.config.Args) == 0 {
+		return e.newCommand(ctx, e.config.BuildArgs(opts)), nil
 	}
 
-	if err := cmd.Start(); err != nil {
-		return 1, fmt.Errorf("failed to start claude: %w", err)
+	if e.argsErr != nil {
+		return nil, e.argsErr
 	}
-
-	// Handle stderr in background
-	go e.handleStderr(stderr)
-
-	// Process events
-	events := e.parser.Parse(stdout)
-	for event := range events {
-		if handler != nil {
-			handler(event)
-		}
+	perms := e.config.Permissions
+	if opts.Permissions != nil {
+		perms = *opts.Permissions
 	}
+	args, err := expandArgs(e.args, newArgsData(prompt, perms, opts))
+	if err != nil {
+		return nil, err
+	}
+	return e.newCommand(ctx, args), nil
+}
 
-	// Wait for command completion
-	err = cmd.Wait()
+// promptInput connects the prompt to the stdin of cmd before it starts, and
+// returns the function that sends it once cmd has started.
+//
+// Text input is copied to stdin by cmd itself. Stream-json input is written
+// as a user message, after which stdin is handed to opts.Input for follow-up
+// messages, or closed if there is none; each follow-up restarts the idle
+// timer of w. With [ExecutorConfig.Args] the prompt is in the arguments and
+// stdin is left empty.
+func (e *DefaultExecutor) promptInput(cmd *exec.Cmd, prompt string, opts RunOptions, w *watchdog) (func() error, error) {
+	noop := func() error { return nil }
+	if len(e.config.Args) > 0 {
+		return noop, nil
+	}
+	if e.config.InputFormat != FormatStreamJSON {
+		cmd.Stdin = strings.NewReader(prompt)
+		return noop, nil
+	}
 
-	exitCode := 0
+	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			return 1, err
-		}
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
+	return func() error {
+		if err := writeUserMessage(stdin, prompt); err != nil {
+			stdin.Close()
+			return fmt.Errorf("failed to send prompt: %w", err)
+		}
+		if opts.Input == nil {
+			return stdin.Close()
+		}
+		return opts.Input.attach(activityWriter{WriteCloser: stdin, w: w})
+	}, nil
+}
 
-	return exitCode, nil
+// newCommand creates the subprocess with the given arguments. The process runs
+// in its own process group, which is killed as a whole when ctx is canceled.
+func (e *DefaultExecutor) newCommand(ctx context.Context, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, e.config.BinaryPath, args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	return cmd
 }
 
-func (e *DefaultExecutor) handleStderr(stderr io.ReadCloser) {
-	if e.config.StderrHandler == nil {
+// handleStderr passes each stderr line to the configured handler and to each
+// non-nil record function.
+func (e *DefaultExecutor) handleStderr(stderr io.ReadCloser, records ...func(line string)) {
+	handlers := slices.DeleteFunc(append([]func(string){e.config.StderrHandler}, records...),
+		func(h func(string)) bool { return h == nil })
+	if len(handlers) == 0 {
 		_, _ = io.Copy(io.Discard, stderr) //nolint:errcheck // Intentionally discarding stderr
 		return
 	}
 
 	scanner := bufio.NewScanner(stderr)
 	for scanner.Scan() {
-		e.config.StderrHandler(scanner.Text())
+		line := scanner.Text()
+		for _, handle := range handlers {
+			handle(line)
+		}
 	}
 }
 
+// stderrTail keeps the last [stderrTailLines] lines written to stderr.
+type stderrTail struct {
+	mu   sync.Mutex
+	tail []string
+}
+
+func (t *stderrTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	t.tail = append(t.tail, line)
+	if len(t.tail) > stderrTailLines {
+		t.tail = t.tail[len(t.tail)-stderrTailLines:]
+	}
+}
+
+func (t *stderrTail) lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.tail)
+}
+
 // MockExecutor implements [Executor] for testing without spawning real processes.
 //
-// Configure the mock by setting its fields before calling Execute or ExecuteWithResult:
+// Configure the mock by setting its fields before calling Start or ExecuteWithResult:
 //
 //	mock := &MockExecutor{
 //	    Events: []Event{{Type: EventTypeAssistant, Text: "Hello"}},
@@ -253,10 +447,10 @@ func (e *DefaultExecutor) handleStderr(stderr io.ReadCloser) {
 //	mock := &MockExecutor{Error: errors.New("connection failed")}
 type MockExecutor struct {
 	// Events is the list of [Event] objects to emit during execution.
-	// These are sent in order to the events channel or handler.
+	// These are sent in order to the session or handler.
 	Events []Event
 
-	// Error is returned from Execute/ExecuteWithResult if non-nil.
+	// Error is returned from Start/ExecuteWithResult if non-nil.
 	// When set, no events are emitted.
 	Error error
 
@@ -264,37 +458,39 @@ type MockExecutor struct {
 	// Ignored if Error is set.
 	ExitCode int
 
-	// RecordedPrompts accumulates all prompts passed to Execute/ExecuteWithResult.
+	// RecordedPrompts accumulates all prompts passed to Start/ExecuteWithResult.
 	// Use this in tests to verify the correct prompts were sent.
 	RecordedPrompts []string
+
+	// RecordedOptions accumulates the [RunOptions] found on each call's context,
+	// in the same order as RecordedPrompts.
+	RecordedOptions []RunOptions
 }
 
-// Execute returns the pre-configured [MockExecutor.Events] via a channel.
+// Start returns a [Session] that emits the pre-configured [MockExecutor.Events]
+// and ends with [MockExecutor.ExitCode].
 //
 // The prompt is recorded in [MockExecutor.RecordedPrompts] for later verification.
 // If [MockExecutor.Error] is set, it returns nil and the error immediately.
-// Otherwise, events are sent to the channel asynchronously and the channel is
-// closed when all events have been sent or the context is canceled.
-func (m *MockExecutor) Execute(ctx context.Context, prompt string) (<-chan Event, error) {
+// Canceling ctx or the session stops the events early.
+func (m *MockExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
 	m.RecordedPrompts = append(m.RecordedPrompts, prompt)
+	m.RecordedOptions = append(m.RecordedOptions, RunOptionsFromContext(ctx))
 
 	if m.Error != nil {
 		return nil, m.Error
 	}
 
-	events := make(chan Event)
-	go func() {
-		defer close(events)
-		for _, event := range m.Events {
-			select {
-			case <-ctx.Done():
-				return
-			case events <- event:
+	events, exitCode := slices.Clone(m.Events), m.ExitCode
+	return StartSession(ctx, func(ctx context.Context, handler EventHandler) (int, error) {
+		for _, event := range events {
+			if ctx.Err() != nil {
+				return 1, context.Cause(ctx)
 			}
+			handler(event)
 		}
-	}()
-
-	return events, nil
+		return exitCode, nil
+	}), nil
 }
 
 // ExecuteWithResult returns the pre-configured [MockExecutor.ExitCode].
@@ -305,6 +501,7 @@ func (m *MockExecutor) Execute(ctx context.Context, prompt string) (<-chan Event
 // then the configured exit code is returned.
 func (m *MockExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
 	m.RecordedPrompts = append(m.RecordedPrompts, prompt)
+	m.RecordedOptions = append(m.RecordedOptions, RunOptionsFromContext(ctx))
 
 	if m.Error != nil {
 		return 1, m.Error
@@ -9,7 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestMockExecutor_Execute(t *testing.T) {
+func TestMockExecutor_Start(t *testing.T) {
 	events := []Event{
 		{Type: EventTypeSystem, SessionStarted: true},
 		{Type: EventTypeAssistant, Text: "Hello!"},
@@ -19,13 +19,13 @@ func TestMockExecutor_Execute(t *testing.T) {
 	mock := &MockExecutor{Events: events}
 
 	ctx := context.Background()
-	ch, err := mock.Execute(ctx, "test prompt")
+	session, err := mock.Start(ctx, "test prompt")
 
 	require.NoError(t, err)
 
 	// Collect all events
 	var collected []Event
-	for event := range ch {
+	for event := range session.Events() {
 		collected = append(collected, event)
 	}
 
@@ -33,13 +33,13 @@ func TestMockExecutor_Execute(t *testing.T) {
 	assert.Equal(t, []string{"test prompt"}, mock.RecordedPrompts)
 }
 
-func TestMockExecutor_Execute_WithError(t *testing.T) {
+func TestMockExecutor_Start_WithError(t *testing.T) {
 	mock := &MockExecutor{
 		Error: assert.AnError,
 	}
 
 	ctx := context.Background()
-	_, err := mock.Execute(ctx, "test prompt")
+	_, err := mock.Start(ctx, "test prompt")
 
 	assert.Error(t, err)
 }
@@ -81,7 +81,7 @@ func TestMockExecutor_ExecuteWithResult_NonZeroExit(t *testing.T) {
 	assert.Equal(t, 1, exitCode)
 }
 
-func TestMockExecutor_Execute_ContextCancellation(t *testing.T) {
+func TestMockExecutor_Start_ContextCancellation(t *testing.T) {
 	events := []Event{
 		{Type: EventTypeSystem, SessionStarted: true},
 		{Type: EventTypeAssistant, Text: "Hello!"},
@@ -91,20 +91,21 @@ func TestMockExecutor_Execute_ContextCancellation(t *testing.T) {
 	mock := &MockExecutor{Events: events}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	ch, err := mock.Execute(ctx, "test prompt")
+	session, err := mock.Start(ctx, "test prompt")
 
 	require.NoError(t, err)
 
-	// Read first event
-	<-ch
-
 	// Cancel context
 	cancel()
 
-	// Should eventually close (may receive more events before closing)
-	for range ch {
+	// Should eventually end (events may arrive before it does)
+	for range session.Events() {
 		// Drain channel
 	}
+	_, err = session.Wait()
+	if err != nil {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
 }
 
 func TestNewExecutor(t *testing.T) {
@@ -192,8 +193,8 @@ func TestMockExecutor_MultiplePrompts(t *testing.T) {
 	ctx := context.Background()
 
 	// Execute multiple prompts
-	_, _ = mock.Execute(ctx, "prompt 1")
-	_, _ = mock.Execute(ctx, "prompt 2")
+	_, _ = mock.Start(ctx, "prompt 1")
+	_, _ = mock.Start(ctx, "prompt 2")
 	_, _ = mock.ExecuteWithResult(ctx, "prompt 3", nil)
 
 	assert.Equal(t, []string{"prompt 1", "prompt 2", "prompt 3"}, mock.RecordedPrompts)
@@ -227,3 +228,68 @@ func TestNewExecutor_UsesProvidedParser(t *testing.T) {
 
 	assert.Equal(t, customParser, exec.parser)
 }
+
+func TestExecutorConfig_BuildArgs(t *testing.T) {
+	cfg := ExecutorConfig{
+		OutputFormat: "stream-json",
+		Permissions:  Permissions{Mode: "bypassPermissions"},
+	}
+
+	t.Run("uses configured permissions by default", func(t *testing.T) {
+		args := cfg.BuildArgs(RunOptions{})
+		assert.Equal(t, []string{
+			"--permission-mode", "bypassPermissions",
+			"--verbose",
+			"-p",
+			"--output-format", "stream-json",
+		}, args)
+	})
+
+	t.Run("run options override configured permissions", func(t *testing.T) {
+		override := Permissions{Mode: "acceptEdits", DisallowedTools: []string{"Bash"}}
+		args := cfg.BuildArgs(RunOptions{Permissions: &override})
+		assert.Equal(t, []string{
+			"--permission-mode", "acceptEdits",
+			"--disallowedTools", "Bash",
+			"--verbose",
+			"-p",
+			"--output-format", "stream-json",
+		}, args)
+	})
+
+	t.Run("resume session adds --resume", func(t *testing.T) {
+		args := cfg.BuildArgs(RunOptions{ResumeSessionID: "abc-123"})
+		assert.Equal(t, []string{
+			"--permission-mode", "bypassPermissions",
+			"--resume", "abc-123",
+			"--verbose",
+			"-p",
+			"--output-format", "stream-json",
+		}, args)
+	})
+
+	t.Run("stream-json input adds --input-format", func(t *testing.T) {
+		streaming := cfg
+		streaming.InputFormat = FormatStreamJSON
+		args := streaming.BuildArgs(RunOptions{})
+		assert.Equal(t, []string{"--input-format", "stream-json"}, args[len(args)-2:])
+	})
+
+	t.Run("never passes dangerously-skip-permissions", func(t *testing.T) {
+		args := cfg.BuildArgs(RunOptions{})
+		assert.NotContains(t, args, "--dangerously-skip-permissions")
+	})
+}
+
+func TestMockExecutor_RecordsRunOptions(t *testing.T) {
+	mock := &MockExecutor{}
+	perms := Permissions{Mode: "plan"}
+	ctx := WithRunOptions(context.Background(), RunOptions{Permissions: &perms})
+
+	_, _ = mock.ExecuteWithResult(ctx, "prompt", nil)
+	_, _ = mock.ExecuteWithResult(context.Background(), "prompt", nil)
+
+	require.Len(t, mock.RecordedOptions, 2)
+	assert.Equal(t, &perms, mock.RecordedOptions[0].Permissions)
+	assert.Nil(t, mock.RecordedOptions[1].Permissions)
+}
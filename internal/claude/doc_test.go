@@ -42,6 +42,37 @@ func Example_mockExecutor() {
 	// exit code: 0
 }
 
+// Example_session demonstrates following a session started in the background
+// and waiting for its result.
+func Example_session() {
+	mock := &claude.MockExecutor{
+		Events: []claude.Event{
+			{Type: claude.EventTypeSystem, SessionStarted: true, SessionID: "sess-1"},
+			{Type: claude.EventTypeAssistant, Text: "Done."},
+			{Type: claude.EventTypeResult, SessionComplete: true},
+		},
+	}
+
+	session, err := mock.Start(context.Background(), "Analyze this code")
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	for event := range session.Events() {
+		if event.IsText() {
+			fmt.Println(event.Text)
+		}
+	}
+
+	result, err := session.Wait()
+	fmt.Println("exit code:", result.ExitCode, "error:", err)
+	fmt.Println("session:", result.SessionID, "events:", session.Stats().Events)
+	// Output:
+	// Done.
+	// exit code: 0 error: <nil>
+	// session: sess-1 events: 3
+}
+
 // Example_parseSingle demonstrates parsing a single JSON line from Claude's
 // streaming output into an Event.
 func Example_parseSingle() {
@@ -125,3 +156,27 @@ func Example_parser() {
 	// text: Starting analysis...
 	// session complete
 }
+
+// Example_chain demonstrates wrapping an executor in middleware that stops a
+// session when Claude runs a denied command.
+func Example_chain() {
+	mock := &claude.MockExecutor{
+		Events: []claude.Event{
+			{Type: claude.EventTypeAssistant, ToolName: "Bash", ToolCommand: "git push --force"},
+			{Type: claude.EventTypeResult, SessionComplete: true},
+		},
+	}
+	policy, err := claude.NewToolPolicy(nil, []string{`git push .*--force`}, nil)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	executor := claude.Chain(mock, claude.WithPolicy(policy))
+	exitCode, err := executor.ExecuteWithResult(context.Background(), "Ship it", nil)
+	fmt.Println("exit code:", exitCode)
+	fmt.Println(err)
+	// Output:
+	// exit code: 1
+	// session stopped by policy: Bash: command "git push --force" matches "git push .*--force"
+}
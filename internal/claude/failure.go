@@ -0,0 +1,161 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// stderrTailLines is the number of trailing stderr lines kept for an [ExitError].
+const stderrTailLines = 20
+
+// ExitError reports a Claude process that exited with a non-zero code.
+//
+// It is returned by [DefaultExecutor.ExecuteWithResult] together with the
+// exit code, so callers can see what Claude printed before it exited.
+type ExitError struct {
+	// Code is the process exit code.
+	Code int
+
+	// Stderr holds the last lines Claude wrote to stderr, oldest first.
+	Stderr []string
+
+	// Signal is the signal that killed Claude, or nil if it exited.
+	Signal os.Signal
+}
+
+// Error implements the error interface, quoting the last stderr line.
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("claude exited with code %d", e.Code)
+	if e.Signal != nil {
+		msg = fmt.Sprintf("claude was killed by signal %v", e.Signal)
+	}
+	if n := len(e.Stderr); n > 0 {
+		msg += ": " + e.Stderr[n-1]
+	}
+	return msg
+}
+
+// ResultError reports a session whose result event was marked is_error.
+//
+// Claude sends such a result when the session ends because of an API error,
+// such as an overloaded or rate-limited API, rather than finishing its task.
+type ResultError struct {
+	// Subtype is the result event subtype, e.g. "error_during_execution".
+	Subtype string
+
+	// Message is the result text Claude reported.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ResultError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Subtype
+	}
+	if msg == "" {
+		return "claude session ended with an error"
+	}
+	return "claude session ended with an error: " + msg
+}
+
+// NewResultError returns the [ResultError] for a result event, or nil if the
+// event is not a result or did not end in an error.
+func NewResultError(event Event) *ResultError {
+	if event.Stats == nil || !event.Stats.IsError {
+		return nil
+	}
+	return &ResultError{Subtype: event.Subtype, Message: event.Stats.Result}
+}
+
+// FailureClass groups Claude failures by cause, so callers can decide which
+// ones are worth retrying. See [Classify].
+type FailureClass string
+
+const (
+	// FailureUnknown is any failure not matched by another class, including
+	// Claude exiting with an error of its own.
+	FailureUnknown FailureClass = "unknown"
+
+	// FailureOverloaded is the API reporting it is overloaded (HTTP 529) or
+	// otherwise unavailable (HTTP 500 and 503).
+	FailureOverloaded FailureClass = "overloaded"
+
+	// FailureRateLimit is the API rejecting requests for exceeding a rate
+	// limit (HTTP 429).
+	FailureRateLimit FailureClass = "rate_limit"
+
+	// FailureNetwork is a connection problem between Claude and the API,
+	// such as a reset connection, DNS failure, or gateway error.
+	FailureNetwork FailureClass = "network"
+
+	// FailureTimeout is a session killed by a configured timeout or idle
+	// timeout (see [ErrTimeout]).
+	FailureTimeout FailureClass = "timeout"
+
+	// FailureUsageLimit is the Claude subscription reaching its usage limit
+	// (see [UsageLimitError]). It clears only when the limit resets.
+	FailureUsageLimit FailureClass = "usage_limit"
+)
+
+// FailureClasses lists every [FailureClass] that [Classify] can return.
+var FailureClasses = []FailureClass{
+	FailureUnknown,
+	FailureOverloaded,
+	FailureRateLimit,
+	FailureNetwork,
+	FailureTimeout,
+	FailureUsageLimit,
+}
+
+// failurePatterns maps message patterns to the class they indicate. They are
+// checked in order, so more specific classes come first. HTTP statuses and
+// error types only count in the API's error format ("API Error: 529 ...",
+// "overloaded_error"), since the output of the task itself, such as a test
+// log mentioning "500 lines" or an "internal server error", can contain them.
+var failurePatterns = []struct {
+	class   FailureClass
+	pattern *regexp.Regexp
+}{
+	{FailureRateLimit, regexp.MustCompile(`(?i)rate_limit_error|API Error: 429\b`)},
+	{FailureOverloaded, regexp.MustCompile(`(?i)overloaded_error|"type":\s*"api_error"|API Error: (Overloaded|(500|503|529)\b)`)},
+	{FailureNetwork, regexp.MustCompile(`(?i)econnreset|econnrefused|etimedout|enotfound|eai_again|epipe|` +
+		`socket hang up|connection (error|reset|refused)|network error|fetch failed|` +
+		`API Error: (502|504)\b`)},
+}
+
+// Classify returns the [FailureClass] of a failed Claude session.
+//
+// Timeouts are recognized by [ErrTimeout], and sessions stopped by a
+// [Policy] are always [FailureUnknown]. Other classes are recognized from
+// the text of err, including the stderr lines of an [ExitError] and the
+// message of a [ResultError] anywhere in its chain. Usage limits are checked
+// first, since their messages also mention limits. A nil err, or one that
+// matches nothing, is [FailureUnknown].
+func Classify(err error) FailureClass {
+	if err == nil {
+		return FailureUnknown
+	}
+	if errors.Is(err, ErrTimeout) {
+		return FailureTimeout
+	}
+	if errors.Is(err, ErrPolicyViolation) {
+		// The rejected command may mention anything, such as "429"
+		return FailureUnknown
+	}
+
+	haystack := failureText(err)
+	var usageErr *UsageLimitError
+	if errors.As(err, &usageErr) || usageLimitPattern.MatchString(haystack) {
+		return FailureUsageLimit
+	}
+
+	for _, fp := range failurePatterns {
+		if fp.pattern.MatchString(haystack) {
+			return fp.class
+		}
+	}
+	return FailureUnknown
+}
@@ -0,0 +1,74 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want FailureClass
+	}{
+		{"nil", nil, FailureUnknown},
+		{"timeout", &TimeoutError{After: time.Minute}, FailureTimeout},
+		{"overloaded stderr", &ExitError{Code: 1, Stderr: []string{`API Error: 529 {"type":"overloaded_error"}`}}, FailureOverloaded},
+		{"service unavailable", &ExitError{Code: 1, Stderr: []string{"API Error: 503 Service Unavailable"}}, FailureOverloaded},
+		{"api error type", &ResultError{Message: `API Error: {"type":"error","error":{"type":"api_error","message":"Internal server error"}}`}, FailureOverloaded},
+		{"rate limit result", &ResultError{Message: "API Error: 429 rate_limit_error"}, FailureRateLimit},
+		{"rate limit status", errors.New("API Error: 429 Too Many Requests"), FailureRateLimit},
+		{"connection reset", &ExitError{Code: 1, Stderr: []string{"Error: read ECONNRESET"}}, FailureNetwork},
+		{"connection error result", &ResultError{Message: "API Error: Connection error."}, FailureNetwork},
+		{"bad gateway", &ExitError{Code: 1, Stderr: []string{"API Error: 502 Bad Gateway"}}, FailureNetwork},
+		{"wrapped", fmt.Errorf("step: %w", &ResultError{Message: "API Error: 529 Overloaded"}), FailureOverloaded},
+		{"task failure", &ExitError{Code: 1, Stderr: []string{"FAIL: 3 tests failed"}}, FailureUnknown},
+		{"digits inside other numbers", &ExitError{Code: 1, Stderr: []string{"story 5029-1 cost 14290"}}, FailureUnknown},
+		{"line count", &ExitError{Code: 1, Stderr: []string{"refusing to edit: file has 500 lines"}}, FailureUnknown},
+		{"app under test status", &ResultError{Message: "The e2e suite failed: HTTP 503 from the app under test"}, FailureUnknown},
+		{"app server error log", &ExitError{Code: 1, Stderr: []string{"handler_test.go:42: got 500 internal server error"}}, FailureUnknown},
+		{"app rate limit", &ExitError{Code: 1, Stderr: []string{"expected 429 Too Many Requests, got 200"}}, FailureUnknown},
+		{"app gateway", &ResultError{Message: "proxy returned 502 Bad Gateway and 504"}, FailureUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func TestExitError_Error(t *testing.T) {
+	assert.Equal(t, "claude exited with code 2", (&ExitError{Code: 2}).Error())
+	assert.Equal(t, "claude exited with code 1: last", (&ExitError{Code: 1, Stderr: []string{"first", "last"}}).Error())
+}
+
+func TestNewResultError(t *testing.T) {
+	assert.Nil(t, NewResultError(Event{Type: EventTypeResult, Stats: &ResultStats{}}))
+	assert.Nil(t, NewResultError(Event{Type: EventTypeAssistant}))
+
+	err := NewResultError(Event{
+		Type:    EventTypeResult,
+		Subtype: "error_during_execution",
+		Stats:   &ResultStats{IsError: true, Result: "API Error: Overloaded"},
+	})
+	assert.Equal(t, "claude session ended with an error: API Error: Overloaded", err.Error())
+	assert.Equal(t, "claude session ended with an error: error_during_execution", (&ResultError{Subtype: "error_during_execution"}).Error())
+}
+
+func TestStderrTail(t *testing.T) {
+	tail := &stderrTail{}
+	for i := range stderrTailLines + 5 {
+		tail.add(fmt.Sprintf("line %d", i))
+	}
+	tail.add("   ")
+
+	lines := tail.lines()
+	assert.Len(t, lines, stderrTailLines)
+	assert.Equal(t, "line 5", lines[0])
+	assert.Equal(t, fmt.Sprintf("line %d", stderrTailLines+4), lines[len(lines)-1])
+}
@@ -0,0 +1,157 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrInputUnsupported is returned by [Input.Send] when the session does not
+// accept follow-up messages: it has not started, or it does not use the
+// stream-json input format.
+var ErrInputUnsupported = errors.New("session does not accept follow-up messages")
+
+// ErrInputClosed is returned by [Input.Send] after the input was closed or
+// the session ended.
+var ErrInputClosed = errors.New("session input is closed")
+
+// Input sends follow-up user messages into a live session, for example to
+// answer a question Claude asked.
+//
+// Attach an Input with [RunOptions.Input]. A [DefaultExecutor] with
+// [ExecutorConfig.InputFormat] [FormatStreamJSON] writes the prompt as the
+// first user message and then keeps the session's stdin open for the Input:
+// each [Input.Send] starts another turn, and Claude answers it with more
+// events and another result event. The session ends once the Input is closed
+// and the last message is answered, so the owner must call [Input.Close],
+// typically when a result event leaves nothing more to say. Other executors
+// never attach the Input, and Send returns [ErrInputUnsupported].
+//
+// An Input belongs to one session. Its methods are safe for concurrent use.
+type Input struct {
+	mu     sync.Mutex
+	w      io.WriteCloser // the session's stdin, once attached
+	sent   int
+	closed bool
+}
+
+// NewInput creates an Input to attach to a session.
+func NewInput() *Input {
+	return &Input{}
+}
+
+// Send writes a user message into the session.
+//
+// Returns [ErrInputUnsupported] if the session does not accept input, or
+// [ErrInputClosed] once the input is closed or the session has ended.
+func (in *Input) Send(text string) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	switch {
+	case in.closed:
+		return ErrInputClosed
+	case in.w == nil:
+		return ErrInputUnsupported
+	}
+	if err := writeUserMessage(in.w, text); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+	in.sent++
+	return nil
+}
+
+// Ready reports whether the input accepts messages: it is attached to a
+// session that has not ended, and it is not closed.
+func (in *Input) Ready() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.w != nil && !in.closed
+}
+
+// Sent returns the number of follow-up messages sent.
+func (in *Input) Sent() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.sent
+}
+
+// Close ends the input. Claude finishes answering the messages already sent,
+// then the session ends. Closing an Input more than once has no effect.
+func (in *Input) Close() error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.closed {
+		return nil
+	}
+	in.closed = true
+	if in.w == nil {
+		return nil
+	}
+	return in.w.Close()
+}
+
+// attach connects the input to the session's stdin. If the input is already
+// closed, stdin is closed at once.
+func (in *Input) attach(w io.WriteCloser) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.w = w
+	if in.closed {
+		return w.Close()
+	}
+	return nil
+}
+
+// end marks the input closed when its session has ended. An input that was
+// never attached keeps reporting [ErrInputUnsupported], since observers of a
+// [Session] may see its events after the session has ended.
+func (in *Input) end() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.w != nil {
+		in.closed = true
+	}
+}
+
+// userMessage is a user message of the stream-json input format.
+type userMessage struct {
+	Type    string `json:"type"`
+	Message struct {
+		Role    string         `json:"role"`
+		Content []ContentBlock `json:"content"`
+	} `json:"message"`
+}
+
+// writeUserMessage writes text as a stream-json user message line.
+func writeUserMessage(w io.Writer, text string) error {
+	msg := userMessage{Type: string(EventTypeUser)}
+	msg.Message.Role = "user"
+	msg.Message.Content = []ContentBlock{{Type: "text", Text: text}}
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// ReadUserMessage decodes a stream-json user message line and returns its
+// text. It is the inverse of the encoding used by [Input.Send].
+func ReadUserMessage(line []byte) (string, error) {
+	var msg userMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return "", fmt.Errorf("invalid stream-json input: %w", err)
+	}
+	if msg.Type != string(EventTypeUser) {
+		return "", fmt.Errorf("invalid stream-json input: type %q, want %q", msg.Type, EventTypeUser)
+	}
+	var text string
+	for _, block := range msg.Message.Content {
+		if block.Type == "text" {
+			text += block.Text
+		}
+	}
+	return text, nil
+}
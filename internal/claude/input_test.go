@@ -0,0 +1,64 @@
+package claude
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nopWriteCloser records writes and whether it was closed.
+type nopWriteCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (w *nopWriteCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+var _ io.WriteCloser = (*nopWriteCloser)(nil)
+
+func TestInput_SendAndClose(t *testing.T) {
+	input := NewInput()
+	assert.ErrorIs(t, input.Send("early"), ErrInputUnsupported, "nothing is attached yet")
+
+	assert.False(t, input.Ready())
+
+	w := &nopWriteCloser{}
+	require.NoError(t, input.attach(w))
+	assert.True(t, input.Ready())
+	require.NoError(t, input.Send("Use the existing helper."))
+	assert.Equal(t, 1, input.Sent())
+
+	text, err := ReadUserMessage(bytes.TrimSpace(w.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "Use the existing helper.", text)
+	assert.Contains(t, w.String(), `"role":"user"`)
+
+	require.NoError(t, input.Close())
+	require.NoError(t, input.Close(), "closing twice is harmless")
+	assert.True(t, w.closed)
+	assert.False(t, input.Ready())
+	assert.ErrorIs(t, input.Send("late"), ErrInputClosed)
+}
+
+func TestInput_ClosedBeforeAttach(t *testing.T) {
+	input := NewInput()
+	require.NoError(t, input.Close())
+
+	w := &nopWriteCloser{}
+	require.NoError(t, input.attach(w))
+	assert.True(t, w.closed, "stdin is closed as soon as it is attached")
+}
+
+func TestReadUserMessage_Invalid(t *testing.T) {
+	_, err := ReadUserMessage([]byte(`not json`))
+	assert.ErrorContains(t, err, "invalid stream-json input")
+
+	_, err = ReadUserMessage([]byte(`{"type":"assistant","message":{"content":[]}}`))
+	assert.ErrorContains(t, err, `type "assistant"`)
+}
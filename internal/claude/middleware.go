@@ -0,0 +1,125 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware wraps an [Executor] to add behavior to every session it runs,
+// such as recording, retries or safety policies.
+type Middleware func(next Executor) Executor
+
+// Chain wraps base in the given middleware. The first middleware is the
+// outermost: Chain(base, a, b) is a(b(base)), so a sees each session first
+// and b sees it as base runs it. With no middleware, Chain returns base.
+func Chain(base Executor, middleware ...Middleware) Executor {
+	executor := base
+	for i := len(middleware) - 1; i >= 0; i-- {
+		executor = middleware[i](executor)
+	}
+	return executor
+}
+
+// WithRecording records every session as a transcript in dir, passing
+// failures to record one to onError; see [RecordingExecutor].
+func WithRecording(dir string, onError func(err error)) Middleware {
+	return func(next Executor) Executor {
+		return NewRecordingExecutor(next, dir, onError)
+	}
+}
+
+// WithLogging writes a line to w when each session starts and when it ends,
+// with its exit code, duration, tool calls and tokens; see [LoggingExecutor].
+func WithLogging(w io.Writer) Middleware {
+	return func(next Executor) Executor {
+		return NewLoggingExecutor(next, w)
+	}
+}
+
+// LoggingExecutor is an [Executor] decorator that logs the start and end of
+// each session, for an audit trail of what ran. A session's log lines are
+// written by the time it has ended. Its methods are safe for concurrent use.
+//
+// Use [NewLoggingExecutor] or [WithLogging] to create a LoggingExecutor.
+type LoggingExecutor struct {
+	inner Executor
+	now   func() time.Time
+
+	mu sync.Mutex // serializes writes to w
+	w  io.Writer
+}
+
+// NewLoggingExecutor creates a [LoggingExecutor] that runs sessions on inner
+// and logs them to w.
+func NewLoggingExecutor(inner Executor, w io.Writer) *LoggingExecutor {
+	return &LoggingExecutor{inner: inner, now: time.Now, w: w}
+}
+
+// Start starts the session on the inner executor and logs it; see
+// [Executor.Start].
+func (e *LoggingExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	opts := RunOptionsFromContext(ctx)
+	started := e.now()
+	agent := opts.Agent
+	if agent == "" {
+		agent = "claude"
+	}
+	session, err := e.inner.Start(ctx, prompt)
+	if err != nil {
+		e.log(started, "session failed to start on %s: %v", agent, err)
+		return nil, err
+	}
+	e.log(started, "session started on %s: %s", agent, summarize(prompt))
+
+	s := newSession(session.Cancel)
+	go func() {
+		for event := range session.Events() {
+			s.publish(event)
+		}
+		result, err := session.Wait()
+		stats := session.Stats()
+		outcome := "ok"
+		if err != nil {
+			outcome = err.Error()
+		}
+		ended := e.now()
+		e.log(ended, "session ended with exit code %d after %s (%d tool calls, %d tokens, id %q): %s",
+			result.ExitCode, ended.Sub(started).Round(time.Millisecond), stats.ToolCalls, stats.Tokens, result.SessionID, outcome)
+		s.finish(result, err)
+	}()
+	return s, nil
+}
+
+// ExecuteWithResult runs the session with [LoggingExecutor.Start] and waits
+// for it; see [Executor.ExecuteWithResult].
+func (e *LoggingExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	session, err := e.Start(ctx, prompt)
+	if err != nil {
+		return 1, err
+	}
+	return session.Handle(handler)
+}
+
+// log writes one timestamped line.
+func (e *LoggingExecutor) log(at time.Time, format string, args ...any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.w, "%s %s\n", at.Format(time.RFC3339), fmt.Sprintf(format, args...)) //nolint:errcheck // Logging is best effort
+}
+
+// summarize returns the first line of a prompt, shortened for a log line.
+func summarize(prompt string) string {
+	const limit = 80
+	line, rest, _ := strings.Cut(prompt, "\n")
+	if runes := []rune(line); len(runes) > limit {
+		return string(runes[:limit]) + "…"
+	}
+	if rest != "" {
+		return line + " …"
+	}
+	return line
+}
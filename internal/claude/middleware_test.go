@@ -0,0 +1,85 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagExecutor appends its tag to the shared log when a session starts.
+type tagExecutor struct {
+	inner Executor
+	tag   string
+	log   *[]string
+}
+
+func (e *tagExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	*e.log = append(*e.log, e.tag)
+	return e.inner.Start(ctx, prompt)
+}
+
+func (e *tagExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	*e.log = append(*e.log, e.tag)
+	return e.inner.ExecuteWithResult(ctx, prompt, handler)
+}
+
+func tag(name string, log *[]string) Middleware {
+	return func(next Executor) Executor {
+		return &tagExecutor{inner: next, tag: name, log: log}
+	}
+}
+
+func TestChain_Order(t *testing.T) {
+	var log []string
+	base := &MockExecutor{}
+	executor := Chain(base, tag("outer", &log), tag("middle", &log), tag("inner", &log))
+
+	_, err := executor.ExecuteWithResult(context.Background(), "prompt", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer", "middle", "inner"}, log, "the first middleware sees the session first")
+	assert.Equal(t, []string{"prompt"}, base.RecordedPrompts)
+}
+
+func TestChain_NoMiddleware(t *testing.T) {
+	base := &MockExecutor{}
+	assert.Same(t, base, Chain(base))
+}
+
+func TestLoggingExecutor(t *testing.T) {
+	var buf bytes.Buffer
+	events := parseSession(t, sessionLines)
+	executor := NewLoggingExecutor(&outcomeExecutor{events: events, code: 1, err: &ExitError{Code: 1}}, &buf)
+	executor.now = func() time.Time { return time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC) }
+
+	ctx := WithRunOptions(context.Background(), RunOptions{Agent: "codex"})
+	session, err := executor.Start(ctx, "implement story 1-2\nwith context")
+	require.NoError(t, err)
+	_, err = session.Wait()
+	require.Error(t, err)
+
+	assert.Equal(t, `2026-03-14T09:26:53Z session started on codex: implement story 1-2 …
+2026-03-14T09:26:53Z session ended with exit code 1 after 0s (1 tool calls, 15 tokens, id "sess-1"): claude exited with code 1
+`, buf.String())
+}
+
+func TestLoggingExecutor_StartError(t *testing.T) {
+	var buf bytes.Buffer
+	executor := NewLoggingExecutor(&MockExecutor{Error: assert.AnError}, &buf)
+
+	_, err := executor.Start(context.Background(), "prompt")
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Contains(t, buf.String(), "session failed to start on claude: "+assert.AnError.Error())
+}
+
+func TestSummarize(t *testing.T) {
+	assert.Equal(t, "short", summarize("short"))
+	assert.Equal(t, "first …", summarize("first\nsecond"))
+	assert.Equal(t, strings.Repeat("é", 80)+"…", summarize(strings.Repeat("é", 100)))
+}
@@ -0,0 +1,94 @@
+package claude
+
+import (
+	"context"
+	"time"
+)
+
+// Permissions describes the Claude CLI permission settings for a session.
+//
+// The fields map directly onto Claude CLI flags:
+//   - Mode: --permission-mode (e.g., "default", "acceptEdits", "bypassPermissions", "plan")
+//   - AllowedTools: --allowedTools, one flag per rule (e.g., "Bash(git push:*)")
+//   - DisallowedTools: --disallowedTools, one flag per rule
+//
+// The zero value adds no permission flags, leaving Claude in its default mode.
+type Permissions struct {
+	// Mode is the Claude permission mode. Empty means Claude's default mode.
+	Mode string
+
+	// AllowedTools lists tool rules Claude may use without prompting.
+	AllowedTools []string
+
+	// DisallowedTools lists tool rules Claude must never use.
+	DisallowedTools []string
+}
+
+// Args returns the Claude CLI arguments for these permissions.
+//
+// Returns an empty slice for the zero value.
+func (p Permissions) Args() []string {
+	args := make([]string, 0, 2+2*(len(p.AllowedTools)+len(p.DisallowedTools)))
+	if p.Mode != "" {
+		args = append(args, "--permission-mode", p.Mode)
+	}
+	for _, tool := range p.AllowedTools {
+		args = append(args, "--allowedTools", tool)
+	}
+	for _, tool := range p.DisallowedTools {
+		args = append(args, "--disallowedTools", tool)
+	}
+	return args
+}
+
+// RunOptions holds per-invocation settings for a single Claude session.
+//
+// The [Executor] interface takes only a prompt, so settings that vary between
+// workflows travel on the context instead. Attach them with [WithRunOptions];
+// executors read them back with [RunOptionsFromContext]. Unset fields fall back
+// to the executor's own configuration.
+type RunOptions struct {
+	// Permissions overrides [ExecutorConfig.Permissions] when non-nil.
+	Permissions *Permissions
+
+	// ResumeSessionID resumes an earlier Claude session (--resume) instead
+	// of starting a new one. The prompt is sent as the next user message.
+	ResumeSessionID string
+
+	// Timeout limits the total run time of the session. Zero means no limit.
+	Timeout time.Duration
+
+	// IdleTimeout limits how long the session may go without emitting a
+	// stream event. Zero means no limit.
+	IdleTimeout time.Duration
+
+	// Agent names the coding agent that runs the session (see
+	// [AgentExecutor]). Empty means the default, Claude.
+	Agent string
+
+	// Stderr, if set, is called with each line the session writes to
+	// stderr, in addition to [ExecutorConfig.StderrHandler]. It is called
+	// from a separate goroutine.
+	Stderr func(line string)
+
+	// Input, if set, sends follow-up user messages into the live session.
+	// Only sessions using the stream-json input format accept them; see
+	// [Input] and [ExecutorConfig.InputFormat].
+	Input *Input
+}
+
+// runOptionsKey is the context key for [RunOptions].
+type runOptionsKey struct{}
+
+// WithRunOptions returns a copy of ctx carrying the given [RunOptions].
+func WithRunOptions(ctx context.Context, opts RunOptions) context.Context {
+	return context.WithValue(ctx, runOptionsKey{}, opts)
+}
+
+// RunOptionsFromContext returns the [RunOptions] attached to ctx.
+//
+// Returns the zero value if ctx carries no options.
+func RunOptionsFromContext(ctx context.Context) RunOptions {
+	opts, _ := ctx.Value(runOptionsKey{}).(RunOptions)
+	return opts
+}
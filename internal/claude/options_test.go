@@ -0,0 +1,59 @@
+package claude
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissions_Args(t *testing.T) {
+	tests := []struct {
+		name  string
+		perms Permissions
+		want  []string
+	}{
+		{
+			name:  "zero value adds no flags",
+			perms: Permissions{},
+			want:  []string{},
+		},
+		{
+			name:  "mode only",
+			perms: Permissions{Mode: "acceptEdits"},
+			want:  []string{"--permission-mode", "acceptEdits"},
+		},
+		{
+			name: "one flag per tool rule",
+			perms: Permissions{
+				Mode:            "default",
+				AllowedTools:    []string{"Read", "Bash(git push:*)"},
+				DisallowedTools: []string{"WebFetch"},
+			},
+			want: []string{
+				"--permission-mode", "default",
+				"--allowedTools", "Read",
+				"--allowedTools", "Bash(git push:*)",
+				"--disallowedTools", "WebFetch",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.perms.Args())
+		})
+	}
+}
+
+func TestRunOptionsFromContext(t *testing.T) {
+	assert.Equal(t, RunOptions{}, RunOptionsFromContext(context.Background()))
+
+	perms := Permissions{Mode: "plan"}
+	ctx := WithRunOptions(context.Background(), RunOptions{Permissions: &perms})
+
+	opts := RunOptionsFromContext(ctx)
+	if assert.NotNil(t, opts.Permissions) {
+		assert.Equal(t, "plan", opts.Permissions.Mode)
+	}
+}
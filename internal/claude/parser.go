@@ -2,7 +2,10 @@ package claude
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 )
 
@@ -10,103 +13,256 @@ import (
 //
 // The parser expects Claude's stream-json format, where each line of output is a
 // complete JSON object representing a [StreamEvent]. The parser reads lines,
-// deserializes them, and converts them to [Event] objects.
+// deserializes them, and converts them to [Event] objects. A single line may
+// yield several events, one per content block (see [NewEventsFromStream]).
 //
 // The channel returned by Parse is closed when:
 //   - EOF is reached (normal completion)
 //   - The underlying reader is closed
 //   - An unrecoverable read error occurs
 //
-// Malformed JSON lines are silently skipped to provide resilience against
-// partial or corrupted output.
+// Problems are never silent: malformed lines, truncated fields, and read
+// errors are reported as [EventTypeParseError] events, and parsing continues
+// with the next line wherever possible.
 type Parser interface {
 	// Parse reads streaming JSON from the given reader and returns a channel of [Event] objects.
 	// The channel is closed when the reader is exhausted or an error occurs.
-	// Empty lines and unparseable JSON lines are skipped.
+	// Empty lines are skipped; unparseable lines produce an [EventTypeParseError] event.
 	Parse(reader io.Reader) <-chan Event
 }
 
+// ErrInvalidLine is wrapped by the [Event.Err] of parse error events for lines
+// that are not valid stream-json.
+var ErrInvalidLine = errors.New("invalid stream-json line")
+
+// ErrFieldTruncated is wrapped by the [Event.Err] of parse error events for
+// lines whose oversized string fields were truncated. The line's own events
+// follow the parse error event.
+var ErrFieldTruncated = errors.New("oversized field truncated")
+
+// defaultBufferSize is the default [DefaultParser.BufferSize].
+const defaultBufferSize = 10 * 1024 * 1024 // 10MB
+
+// invalidLineSnippet is how much of a malformed line is quoted in its error.
+const invalidLineSnippet = 80
+
 // DefaultParser implements [Parser] for Claude's stream-json format.
 //
-// DefaultParser uses a buffered scanner to read JSON lines from Claude's stdout.
-// The BufferSize field controls the maximum allowed line length, which is important
-// because Claude may output large JSON objects (e.g., file contents in tool results).
+// Lines of any length are accepted. Claude may output very large JSON objects
+// (e.g., a whole log file in a tool result), so any single JSON string value
+// longer than BufferSize is truncated while the line is being read. This keeps
+// memory bounded and lets the rest of the line, and the rest of the stream,
+// parse normally.
 //
 // Create instances using [NewParser] rather than constructing directly to ensure
 // proper default values.
 type DefaultParser struct {
-	// BufferSize is the maximum size in bytes for a single JSON line.
-	// Lines exceeding this size will cause a scanner error and stop parsing.
+	// BufferSize is the maximum size in bytes kept for a single JSON string
+	// value. Longer values are cut off with a "[... N bytes truncated]" marker
+	// and an [ErrFieldTruncated] parse error event is emitted.
 	// Defaults to 10MB (10 * 1024 * 1024) if not set or <= 0.
 	BufferSize int
 }
 
 // NewParser creates a new [DefaultParser] with default settings.
 //
-// The default buffer size is 10MB, which should accommodate most Claude output
+// The default buffer size is 10MB, which keeps most Claude output intact,
 // including large file contents in tool results.
 func NewParser() *DefaultParser {
 	return &DefaultParser{
-		BufferSize: 10 * 1024 * 1024, // 10MB
+		BufferSize: defaultBufferSize,
 	}
 }
 
 // Parse reads streaming JSON from the reader and emits parsed [Event] objects.
 //
 // Parse spawns a goroutine that reads lines from the reader, parses each line as
-// a [StreamEvent], converts it to an [Event], and sends it to the returned channel.
+// a [StreamEvent], converts it to events, and sends them to the returned channel.
 //
 // Error handling behavior:
 //   - Empty lines are silently skipped
-//   - Lines that fail JSON parsing are silently skipped (resilience against partial output)
-//   - Scanner errors (e.g., line too long) terminate parsing and close the channel
+//   - Lines that fail JSON parsing produce an [EventTypeParseError] event
+//     wrapping [ErrInvalidLine], and parsing continues
+//   - Every event of a line, and the parse error event of a malformed line,
+//     carries the line's bytes in [Event.Line]
+//   - Lines with string values over [DefaultParser.BufferSize] are truncated;
+//     an [EventTypeParseError] event wrapping [ErrFieldTruncated] precedes
+//     the line's events
+//   - Read errors other than EOF produce a final [EventTypeParseError] event
+//     and close the channel
 //   - EOF closes the channel normally
-//
-// The scanner buffer is configured based on [DefaultParser.BufferSize] to handle
-// large JSON objects that may appear in Claude's output.
 func (p *DefaultParser) Parse(reader io.Reader) <-chan Event {
 	events := make(chan Event)
 
 	go func() {
 		defer close(events)
 
-		scanner := bufio.NewScanner(reader)
+		maxString := p.BufferSize
+		if maxString <= 0 {
+			maxString = defaultBufferSize
+		}
+		lines := newLineReader(reader, maxString)
 
-		// Set up buffer for large JSON lines
-		bufSize := p.BufferSize
-		if bufSize <= 0 {
-			bufSize = 10 * 1024 * 1024
+		for lineNum := 1; ; lineNum++ {
+			line, truncated, err := lines.next()
+
+			if len(bytes.TrimSpace(line)) > 0 {
+				if truncated > 0 {
+					events <- parseErrorEvent(fmt.Errorf("line %d: %w: %d bytes dropped", lineNum, ErrFieldTruncated, truncated))
+				}
+
+				var streamEvent StreamEvent
+				if jsonErr := json.Unmarshal(line, &streamEvent); jsonErr != nil {
+					event := parseErrorEvent(fmt.Errorf("line %d: %w: %v (%s)", lineNum, ErrInvalidLine, jsonErr, snippet(line)))
+					event.Line = line
+					events <- event
+				} else {
+					for _, event := range NewEventsFromStream(&streamEvent) {
+						event.Line = line
+						events <- event
+					}
+				}
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					events <- parseErrorEvent(fmt.Errorf("reading claude output: %w", err))
+				}
+				return
+			}
 		}
-		buf := make([]byte, 0, 1024*1024)
-		scanner.Buffer(buf, bufSize)
+	}()
+
+	return events
+}
+
+// parseErrorEvent returns an [EventTypeParseError] event carrying err.
+func parseErrorEvent(err error) Event {
+	return Event{Type: EventTypeParseError, Err: err}
+}
 
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
+// snippet returns the start of a malformed line for use in error messages.
+func snippet(line []byte) string {
+	line = bytes.TrimSpace(line)
+	if len(line) <= invalidLineSnippet {
+		return string(line)
+	}
+	return string(line[:invalidLineSnippet]) + "..."
+}
+
+// lineReader splits a stream into lines, truncating JSON string values longer
+// than maxString as it goes so that no line needs unbounded memory.
+//
+// Only values are truncated, never object keys. Truncation happens only at
+// points where the output stays valid JSON: never inside an escape sequence,
+// and always followed by the original closing quote.
+type lineReader struct {
+	r         *bufio.Reader
+	maxString int
+}
+
+// newLineReader creates a [lineReader] over r.
+func newLineReader(r io.Reader, maxString int) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, 64*1024), maxString: maxString}
+}
+
+// next returns the next line without its trailing newline, along with the
+// number of bytes dropped by truncation. At the end of the stream it returns
+// the final (possibly empty) line together with the read error, which is
+// [io.EOF] on normal completion.
+func (l *lineReader) next() (line []byte, truncated int64, err error) {
+	var (
+		inString  bool
+		isKey     bool // the current string is an object key, never truncated
+		escape    int  // 0: none; -1: after backslash; n > 0: hex digits left in \uXXXX
+		strLen    int
+		dropped   int64 // bytes dropped from the current string
+		dropping  bool
+		last      byte   // last structural byte outside strings
+		container []byte // stack of open '{' and '['
+	)
+
+	for {
+		chunk, readErr := l.r.ReadSlice('\n')
+		for _, b := range chunk {
+			if b == '\n' {
+				break
+			}
+
+			if !inString {
+				switch b {
+				case '"':
+					inString = true
+					strLen = 0
+					isKey = last == '{' || (last == ',' && len(container) > 0 && container[len(container)-1] == '{')
+				case '{', '[':
+					container = append(container, b)
+				case '}', ']':
+					if len(container) > 0 {
+						container = container[:len(container)-1]
+					}
+				}
+				if b != ' ' && b != '\t' && b != '\r' {
+					last = b
+				}
+				line = append(line, b)
 				continue
 			}
 
-			var streamEvent StreamEvent
-			if err := json.Unmarshal([]byte(line), &streamEvent); err != nil {
-				// Skip unparseable lines
+			// Track escapes so truncation never splits one and the closing
+			// quote is recognised even while dropping.
+			switch {
+			case escape > 0:
+				escape--
+			case escape == -1:
+				escape = 0
+				if b == 'u' {
+					escape = 4
+				}
+			case b == '"':
+				if dropping {
+					line = append(line, fmt.Sprintf("[... %d bytes truncated]", dropped)...)
+					dropping = false
+					dropped = 0
+				}
+				inString = false
+				line = append(line, b)
 				continue
+			default:
+				if !dropping && !isKey && strLen >= l.maxString {
+					dropping = true
+				}
+				if b == '\\' {
+					escape = -1
+				}
 			}
 
-			event := NewEventFromStream(&streamEvent)
-			events <- event
+			if dropping {
+				dropped++
+				truncated++
+				continue
+			}
+			line = append(line, b)
+			strLen++
 		}
 
-		// Note: scanner.Err() is intentionally not checked here
-		// as we want to gracefully handle EOF and pipe closure
-	}()
-
-	return events
+		switch readErr {
+		case nil:
+			return line, truncated, nil
+		case bufio.ErrBufferFull:
+			continue
+		default:
+			return line, truncated, readErr
+		}
+	}
 }
 
 // ParseSingle parses a single JSON line into an [Event].
 //
 // This is a utility function useful for testing and debugging. It parses a single
 // line of Claude's stream-json output without requiring a reader or channel.
+// If the line carries several content blocks, only the first event is returned;
+// use [ParseLine] to get them all.
 //
 // Returns an error if the JSON is malformed or cannot be unmarshaled into a
 // [StreamEvent]. Unlike [Parser.Parse], this function does not silently skip
@@ -124,5 +280,17 @@ func ParseSingle(line string) (Event, error) {
 	if err := json.Unmarshal([]byte(line), &streamEvent); err != nil {
 		return Event{}, err
 	}
-	return NewEventFromStream(&streamEvent), nil
+	return NewEventsFromStream(&streamEvent)[0], nil
+}
+
+// ParseLine parses a single JSON line into all of its [Event] values.
+//
+// Like [ParseSingle], but returns one event per content block (see
+// [NewEventsFromStream]). Returns an error if the JSON is malformed.
+func ParseLine(line string) ([]Event, error) {
+	var streamEvent StreamEvent
+	if err := json.Unmarshal([]byte(line), &streamEvent); err != nil {
+		return nil, err
+	}
+	return NewEventsFromStream(&streamEvent), nil
 }
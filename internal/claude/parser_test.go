@@ -1,6 +1,9 @@
 package claude
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"strings"
 	"testing"
 
@@ -39,7 +42,7 @@ func TestDefaultParser_Parse(t *testing.T) {
 	assert.True(t, collected[2].SessionComplete)
 }
 
-func TestDefaultParser_Parse_SkipsInvalidJSON(t *testing.T) {
+func TestDefaultParser_Parse_ReportsInvalidJSON(t *testing.T) {
 	input := `{"type":"system","subtype":"init"}
 not valid json
 {"type":"result"}`
@@ -55,10 +58,14 @@ not valid json
 		collected = append(collected, event)
 	}
 
-	// Should have 2 events, skipping the invalid line
-	require.Len(t, collected, 2)
+	// The invalid line becomes a parse error and parsing continues
+	require.Len(t, collected, 3)
 	assert.Equal(t, EventTypeSystem, collected[0].Type)
-	assert.Equal(t, EventTypeResult, collected[1].Type)
+	assert.True(t, collected[1].IsParseError())
+	assert.ErrorIs(t, collected[1].Err, ErrInvalidLine)
+	assert.Contains(t, collected[1].Err.Error(), "line 2")
+	assert.Contains(t, collected[1].Err.Error(), "not valid json")
+	assert.Equal(t, EventTypeResult, collected[2].Type)
 }
 
 func TestDefaultParser_Parse_EmptyLines(t *testing.T) {
@@ -152,3 +159,145 @@ func TestParseSingle(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultParser_Parse_MultipleBlocksPerLine(t *testing.T) {
+	input := `{"type":"assistant","message":{"content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}},{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"pwd"}}]}}
+{"type":"result"}`
+
+	var collected []Event
+	for event := range NewParser().Parse(strings.NewReader(input)) {
+		collected = append(collected, event)
+	}
+
+	require.Len(t, collected, 4)
+	assert.Equal(t, "Checking.", collected[0].Text)
+	assert.Equal(t, "ls", collected[1].ToolCommand)
+	assert.Equal(t, "pwd", collected[2].ToolCommand)
+	assert.True(t, collected[3].SessionComplete)
+}
+
+func TestDefaultParser_Parse_TruncatesOversizedField(t *testing.T) {
+	big := strings.Repeat("x", 100)
+	input := `{"type":"user","tool_use_result":{"stdout":"` + big + `","stderr":"short"}}
+{"type":"result"}`
+
+	parser := &DefaultParser{BufferSize: 10}
+
+	var collected []Event
+	for event := range parser.Parse(strings.NewReader(input)) {
+		collected = append(collected, event)
+	}
+
+	require.Len(t, collected, 3)
+	assert.True(t, collected[0].IsParseError())
+	assert.ErrorIs(t, collected[0].Err, ErrFieldTruncated)
+	assert.Contains(t, collected[0].Err.Error(), "90 bytes dropped")
+
+	assert.True(t, collected[1].IsToolResult())
+	assert.Equal(t, strings.Repeat("x", 10)+"[... 90 bytes truncated]", collected[1].ToolStdout)
+	assert.Equal(t, "short", collected[1].ToolStderr)
+
+	assert.True(t, collected[2].SessionComplete)
+}
+
+func TestDefaultParser_Parse_LineLargerThanBuffer(t *testing.T) {
+	// Well beyond both the parser's field limit and bufio's internal buffer.
+	big := strings.Repeat("y", 300*1024)
+	input := `{"type":"assistant","message":{"content":[{"type":"text","text":"` + big + `"}]}}
+{"type":"result"}`
+
+	parser := &DefaultParser{BufferSize: 1024}
+
+	var collected []Event
+	for event := range parser.Parse(strings.NewReader(input)) {
+		collected = append(collected, event)
+	}
+
+	require.Len(t, collected, 3)
+	assert.ErrorIs(t, collected[0].Err, ErrFieldTruncated)
+	assert.True(t, strings.HasPrefix(collected[1].Text, strings.Repeat("y", 1024)+"[..."))
+	assert.True(t, collected[2].SessionComplete, "events after the oversized line must still arrive")
+}
+
+func TestLineReader_TruncationKeepsEscapesIntact(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "unicode escape at limit", value: "abcd\u00e9\u00e9\u00e9"},
+		{name: "simple escape at limit", value: "abcd\\\"\\n\\t"},
+		{name: "escaped quote after limit", value: "abcdefgh\\\"ij"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `{"text":"` + tt.value + `","other":"ok"}`
+
+			line, truncated, err := newLineReader(strings.NewReader(input), 5).next()
+
+			assert.ErrorIs(t, err, io.EOF)
+			assert.Positive(t, truncated)
+
+			var decoded map[string]string
+			require.NoError(t, json.Unmarshal(line, &decoded), "truncated line must stay valid JSON: %s", line)
+			assert.Contains(t, decoded["text"], "bytes truncated]")
+			assert.Equal(t, "ok", decoded["other"])
+		})
+	}
+}
+
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestDefaultParser_Parse_ReportsReadError(t *testing.T) {
+	readErr := errors.New("pipe broken")
+	reader := &failingReader{data: "{\"type\":\"system\",\"subtype\":\"init\"}\n", err: readErr}
+
+	var collected []Event
+	for event := range NewParser().Parse(reader) {
+		collected = append(collected, event)
+	}
+
+	require.Len(t, collected, 2)
+	assert.True(t, collected[0].SessionStarted)
+	assert.True(t, collected[1].IsParseError())
+	assert.ErrorIs(t, collected[1].Err, readErr)
+}
+
+func TestDefaultParser_Parse_StringShapedFields(t *testing.T) {
+	input := `{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","is_error":true}]},"tool_use_result":"Error: File does not exist."}
+{"type":"user","message":{"role":"user","content":"Continue."}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2"}]},"tool_use_result":[{"type":"text","text":"mcp"}]}`
+
+	var collected []Event
+	for event := range NewParser().Parse(strings.NewReader(input)) {
+		collected = append(collected, event)
+	}
+
+	require.Len(t, collected, 3)
+	for _, event := range collected {
+		assert.False(t, event.IsParseError(), "unexpected parse error: %v", event.Err)
+	}
+
+	assert.Equal(t, "t1", collected[0].ToolUseID)
+	assert.True(t, collected[0].ToolIsError)
+	assert.Equal(t, "Error: File does not exist.", collected[0].ToolStderr)
+
+	require.NotNil(t, collected[1].Raw.Message)
+	require.Len(t, collected[1].Raw.Message.Content, 1)
+	assert.Equal(t, "Continue.", collected[1].Raw.Message.Content[0].Text)
+
+	assert.Equal(t, "t2", collected[2].ToolUseID)
+	assert.Empty(t, collected[2].ToolStdout)
+}
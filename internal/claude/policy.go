@@ -0,0 +1,170 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// ErrPolicyViolation is matched by the error of a session stopped by a
+// [Policy]; the error itself is a [*PolicyError].
+var ErrPolicyViolation = errors.New("session stopped by policy")
+
+// PolicyError reports the event a [Policy] rejected.
+type PolicyError struct {
+	// Tool is the name of the tool whose call was rejected, if any.
+	Tool string
+
+	// Reason explains why the event was rejected.
+	Reason string
+}
+
+// Error returns the rejected tool and the reason.
+func (e *PolicyError) Error() string {
+	if e.Tool == "" {
+		return fmt.Sprintf("%v: %s", ErrPolicyViolation, e.Reason)
+	}
+	return fmt.Sprintf("%v: %s: %s", ErrPolicyViolation, e.Tool, e.Reason)
+}
+
+// Is reports whether target is [ErrPolicyViolation].
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// Policy vets the events of a session. An event for which Check returns a
+// [PolicyError] stops the session; see [PolicyExecutor].
+type Policy interface {
+	Check(event Event) *PolicyError
+}
+
+// ToolPolicy is a [Policy] that rejects tool calls by tool name, by Bash
+// command, or by file path.
+//
+// Use [NewToolPolicy] to create a ToolPolicy.
+type ToolPolicy struct {
+	denyTools    []string
+	denyCommands []*regexp.Regexp
+	denyPaths    []*regexp.Regexp
+}
+
+// NewToolPolicy creates a [ToolPolicy] rejecting calls to the tools named in
+// denyTools, Bash commands matching one of denyCommands, and file operations
+// on paths matching one of denyPaths. Patterns are regular expressions (Go
+// syntax) matched anywhere in the command or path.
+//
+// Returns an error if a pattern does not parse.
+func NewToolPolicy(denyTools, denyCommands, denyPaths []string) (*ToolPolicy, error) {
+	p := &ToolPolicy{denyTools: slices.Clone(denyTools)}
+	var err error
+	if p.denyCommands, err = compilePatterns(denyCommands); err != nil {
+		return nil, err
+	}
+	if p.denyPaths, err = compilePatterns(denyPaths); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compilePatterns compiles regular expressions, naming the one that fails.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Check rejects a tool call the policy denies.
+func (p *ToolPolicy) Check(event Event) *PolicyError {
+	if !event.IsToolUse() {
+		return nil
+	}
+	if slices.Contains(p.denyTools, event.ToolName) {
+		return &PolicyError{Tool: event.ToolName, Reason: "tool is denied"}
+	}
+	for _, re := range p.denyCommands {
+		if event.ToolCommand != "" && re.MatchString(event.ToolCommand) {
+			return &PolicyError{Tool: event.ToolName, Reason: fmt.Sprintf("command %q matches %q", event.ToolCommand, re)}
+		}
+	}
+	for _, re := range p.denyPaths {
+		if event.ToolFilePath != "" && re.MatchString(event.ToolFilePath) {
+			return &PolicyError{Tool: event.ToolName, Reason: fmt.Sprintf("path %q matches %q", event.ToolFilePath, re)}
+		}
+	}
+	return nil
+}
+
+// WithPolicy stops sessions whose events the policy rejects; see
+// [PolicyExecutor].
+func WithPolicy(policy Policy) Middleware {
+	return func(next Executor) Executor {
+		return NewPolicyExecutor(next, policy)
+	}
+}
+
+// PolicyExecutor is an [Executor] decorator that checks every event of a
+// session against a [Policy] and cancels the session at the first event the
+// policy rejects. The session then ends with the [*PolicyError].
+//
+// A policy acts on the event stream, so a rejected tool call may already have
+// run by the time the session is killed; use [Permissions] to keep Claude
+// from calling a tool at all. The rejected event itself is still delivered.
+//
+// Use [NewPolicyExecutor] or [WithPolicy] to create a PolicyExecutor.
+type PolicyExecutor struct {
+	inner  Executor
+	policy Policy
+}
+
+// NewPolicyExecutor creates a [PolicyExecutor] that runs sessions on inner.
+func NewPolicyExecutor(inner Executor, policy Policy) *PolicyExecutor {
+	return &PolicyExecutor{inner: inner, policy: policy}
+}
+
+// Start starts the session on the inner executor; see [Executor.Start].
+func (e *PolicyExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	inner, err := e.inner.Start(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSession(inner.Cancel)
+	go func() {
+		var violation *PolicyError
+		for event := range inner.Events() {
+			s.publish(event)
+			if violation == nil {
+				if violation = e.policy.Check(event); violation != nil {
+					inner.Cancel()
+				}
+			}
+		}
+		result, err := inner.Wait()
+		if violation != nil {
+			err = violation
+			if result.ExitCode == 0 {
+				result.ExitCode = 1
+			}
+		}
+		s.finish(result, err)
+	}()
+	return s, nil
+}
+
+// ExecuteWithResult runs the session with [PolicyExecutor.Start] and waits
+// for it; see [Executor.ExecuteWithResult].
+func (e *PolicyExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	session, err := e.Start(ctx, prompt)
+	if err != nil {
+		return 1, err
+	}
+	return session.Handle(handler)
+}
@@ -0,0 +1,77 @@
+package claude
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolPolicy_Check(t *testing.T) {
+	policy, err := NewToolPolicy([]string{"WebFetch"}, []string{`git push .*--force`}, []string{`(^|/)\.env$`})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		event  Event
+		reason string
+	}{
+		{"text", Event{Type: EventTypeAssistant, Text: "git push --force"}, ""},
+		{"allowed tool", Event{Type: EventTypeAssistant, ToolName: "Read", ToolFilePath: "main.go"}, ""},
+		{"denied tool", Event{Type: EventTypeAssistant, ToolName: "WebFetch"}, "tool is denied"},
+		{"allowed command", Event{Type: EventTypeAssistant, ToolName: "Bash", ToolCommand: "git push origin main"}, ""},
+		{"denied command", Event{Type: EventTypeAssistant, ToolName: "Bash", ToolCommand: "git push origin main --force"}, `command "git push origin main --force" matches "git push .*--force"`},
+		{"denied path", Event{Type: EventTypeAssistant, ToolName: "Write", ToolFilePath: "config/.env"}, `path "config/.env" matches "(^|/)\\.env$"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := policy.Check(tt.event)
+			if tt.reason == "" {
+				assert.Nil(t, violation)
+				return
+			}
+			require.NotNil(t, violation)
+			assert.Equal(t, tt.event.ToolName, violation.Tool)
+			assert.Equal(t, tt.reason, violation.Reason)
+		})
+	}
+}
+
+func TestNewToolPolicy_InvalidPattern(t *testing.T) {
+	_, err := NewToolPolicy(nil, []string{"("}, nil)
+	assert.ErrorContains(t, err, `invalid pattern "("`)
+}
+
+func TestPolicyExecutor_StopsSession(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	policy, err := NewToolPolicy(nil, []string{`go test`}, nil)
+	require.NoError(t, err)
+	executor := NewPolicyExecutor(&outcomeExecutor{events: events}, policy)
+
+	var seen []Event
+	code, err := executor.ExecuteWithResult(context.Background(), "prompt", func(e Event) { seen = append(seen, e) })
+
+	assert.Equal(t, 1, code)
+	assert.ErrorIs(t, err, ErrPolicyViolation)
+	var violation *PolicyError
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, "Bash", violation.Tool)
+	assert.EqualError(t, err, `session stopped by policy: Bash: command "go test ./..." matches "go test"`)
+	assert.Contains(t, seen, events[2], "the rejected tool call is delivered")
+	assert.Equal(t, FailureUnknown, Classify(err))
+}
+
+func TestPolicyExecutor_AllowsSession(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	policy, err := NewToolPolicy([]string{"WebFetch"}, nil, nil)
+	require.NoError(t, err)
+	executor := NewPolicyExecutor(&outcomeExecutor{events: events}, policy)
+
+	var seen []Event
+	code, err := executor.ExecuteWithResult(context.Background(), "prompt", func(e Event) { seen = append(seen, e) })
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, events, seen)
+}
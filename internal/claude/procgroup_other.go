@@ -0,0 +1,15 @@
+//go:build !unix
+
+package claude
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op where process groups are unavailable; context
+// cancellation kills only the Claude process itself.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// processSignal returns nil: signals are not reported on this platform.
+func processSignal(state *os.ProcessState) os.Signal { return nil }
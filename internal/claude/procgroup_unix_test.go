@@ -0,0 +1,258 @@
+//go:build unix
+
+package claude
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes an executable shell script standing in for the Claude binary.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755))
+	return path
+}
+
+func TestDefaultExecutor_IdleTimeoutKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	script := writeScript(t, `
+echo '{"type":"system","subtype":"init","session_id":"s1"}'
+sleep 60 &
+echo $! > `+pidFile+`
+wait
+`)
+
+	executor := NewExecutor(ExecutorConfig{BinaryPath: script})
+	ctx := WithRunOptions(context.Background(), RunOptions{IdleTimeout: 200 * time.Millisecond})
+
+	var events []Event
+	start := time.Now()
+	exitCode, err := executor.ExecuteWithResult(ctx, "prompt", func(e Event) { events = append(events, e) })
+
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, ExitCodeTimeout, exitCode)
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.True(t, timeoutErr.Idle)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "s1", events[0].SessionID)
+
+	// The grandchild must have died with the group.
+	raw, readErr := os.ReadFile(pidFile)
+	require.NoError(t, readErr)
+	pid, convErr := strconv.Atoi(strings.TrimSpace(string(raw)))
+	require.NoError(t, convErr)
+	assert.Eventually(t, func() bool {
+		return syscall.Kill(pid, 0) != nil
+	}, 5*time.Second, 20*time.Millisecond, "child process %d still running", pid)
+}
+
+func TestDefaultExecutor_Timeout(t *testing.T) {
+	script := writeScript(t, `
+while true; do
+  echo '{"type":"assistant","message":{"content":[{"type":"text","text":"tick"}]}}'
+  sleep 0.05
+done
+`)
+
+	executor := NewExecutor(ExecutorConfig{BinaryPath: script})
+	ctx := WithRunOptions(context.Background(), RunOptions{
+		Timeout:     300 * time.Millisecond,
+		IdleTimeout: time.Second,
+	})
+
+	exitCode, err := executor.ExecuteWithResult(ctx, "prompt", nil)
+
+	assert.Equal(t, ExitCodeTimeout, exitCode)
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.False(t, timeoutErr.Idle, "steady output must not trip the idle timeout")
+}
+
+func TestDefaultExecutor_NonZeroExitIsNotTimeout(t *testing.T) {
+	script := writeScript(t, `exit 3`)
+
+	executor := NewExecutor(ExecutorConfig{BinaryPath: script})
+	ctx := WithRunOptions(context.Background(), RunOptions{Timeout: time.Minute, IdleTimeout: time.Minute})
+
+	exitCode, err := executor.ExecuteWithResult(ctx, "prompt", nil)
+
+	assert.Equal(t, 3, exitCode)
+	assert.NotErrorIs(t, err, ErrTimeout)
+}
+
+func TestDefaultExecutor_NonZeroExitKeepsStderrTail(t *testing.T) {
+	script := writeScript(t, `echo 'starting' >&2
+echo 'API Error: 529 {"type":"error","error":{"type":"overloaded_error"}}' >&2
+exit 1`)
+
+	var relayed []string
+	executor := NewExecutor(ExecutorConfig{
+		BinaryPath:    script,
+		StderrHandler: func(line string) { relayed = append(relayed, line) },
+	})
+
+	exitCode, err := executor.ExecuteWithResult(context.Background(), "prompt", nil)
+
+	assert.Equal(t, 1, exitCode)
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.Code)
+	assert.Len(t, exitErr.Stderr, 2)
+	assert.Equal(t, exitErr.Stderr, relayed, "stderr still reaches the handler")
+	assert.Equal(t, FailureOverloaded, Classify(err))
+}
+
+func TestDefaultExecutor_RunOptionsStderr(t *testing.T) {
+	script := writeScript(t, `echo 'one' >&2
+echo 'two' >&2`)
+
+	var relayed, session []string
+	executor := NewExecutor(ExecutorConfig{
+		BinaryPath:    script,
+		StderrHandler: func(line string) { relayed = append(relayed, line) },
+	})
+	ctx := WithRunOptions(context.Background(), RunOptions{
+		Stderr: func(line string) { session = append(session, line) },
+	})
+
+	exitCode, err := executor.ExecuteWithResult(ctx, "prompt", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"one", "two"}, session)
+	assert.Equal(t, session, relayed, "the configured handler still gets every line")
+}
+
+func TestDefaultExecutor_PromptOnStdin(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, `printf '%s\n' "$@" > `+dir+`/args
+cat > `+dir+`/prompt`)
+
+	executor := NewExecutor(ExecutorConfig{BinaryPath: script})
+	exitCode, err := executor.ExecuteWithResult(context.Background(), "implement story 1-2\nwith context", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	prompt, err := os.ReadFile(filepath.Join(dir, "prompt"))
+	require.NoError(t, err)
+	assert.Equal(t, "implement story 1-2\nwith context", string(prompt))
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(args), "implement story", "the prompt is kept out of argv")
+}
+
+// turnScript answers each stream-json user message on stdin with a result
+// event, logging the messages to dir/input, until stdin is closed.
+func turnScript(t *testing.T, dir string) string {
+	return writeScript(t, `n=0
+while IFS= read -r line; do
+  n=$((n+1))
+  printf '%s\n' "$line" >> `+dir+`/input
+  echo '{"type":"result","subtype":"success","session_id":"s","result":"turn '$n'"}'
+done`)
+}
+
+func TestDefaultExecutor_StreamJSONInput(t *testing.T) {
+	dir := t.TempDir()
+	executor := NewExecutor(ExecutorConfig{BinaryPath: turnScript(t, dir), InputFormat: FormatStreamJSON})
+	input := NewInput()
+	ctx := WithRunOptions(context.Background(), RunOptions{Input: input, IdleTimeout: 10 * time.Second})
+
+	var results []string
+	exitCode, err := executor.ExecuteWithResult(ctx, "Implement 1-2", func(e Event) {
+		if !e.SessionComplete {
+			return
+		}
+		results = append(results, e.Stats.Result)
+		if len(results) == 1 {
+			assert.NoError(t, input.Send("Yes, use option A."))
+		} else {
+			assert.NoError(t, input.Close())
+		}
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, []string{"turn 1", "turn 2"}, results)
+	assert.Equal(t, 1, input.Sent())
+	assert.ErrorIs(t, input.Send("too late"), ErrInputClosed)
+
+	raw, err := os.ReadFile(filepath.Join(dir, "input"))
+	require.NoError(t, err)
+	var messages []string
+	for line := range strings.Lines(string(raw)) {
+		text, err := ReadUserMessage([]byte(line))
+		require.NoError(t, err)
+		messages = append(messages, text)
+	}
+	assert.Equal(t, []string{"Implement 1-2", "Yes, use option A."}, messages)
+}
+
+func TestDefaultExecutor_StreamJSONInputWithoutFollowUps(t *testing.T) {
+	dir := t.TempDir()
+	executor := NewExecutor(ExecutorConfig{BinaryPath: turnScript(t, dir), InputFormat: FormatStreamJSON})
+
+	var events []Event
+	exitCode, err := executor.ExecuteWithResult(context.Background(), "Implement 1-2", func(e Event) { events = append(events, e) })
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	require.Len(t, events, 1, "stdin is closed after the prompt")
+}
+
+func TestDefaultExecutor_TextInputRejectsFollowUps(t *testing.T) {
+	executor := NewExecutor(ExecutorConfig{BinaryPath: writeScript(t, `cat > /dev/null
+echo '{"type":"result","subtype":"success","session_id":"s"}'`)})
+	input := NewInput()
+	ctx := WithRunOptions(context.Background(), RunOptions{Input: input})
+
+	var sendErr error
+	_, err := executor.ExecuteWithResult(ctx, "prompt", func(e Event) { sendErr = input.Send("more") })
+
+	require.NoError(t, err)
+	assert.ErrorIs(t, sendErr, ErrInputUnsupported)
+}
+
+func TestDefaultExecutor_StartCancel(t *testing.T) {
+	script := writeScript(t, `
+echo '{"type":"system","subtype":"init","session_id":"s1"}'
+echo '{"type":"assistant","session_id":"s1","message":{"content":[{"type":"tool_use","id":"tu_1","name":"Bash","input":{"command":"sleep 60"}}]}}'
+sleep 60
+`)
+
+	executor := NewExecutor(ExecutorConfig{BinaryPath: script})
+	session, err := executor.Start(context.Background(), "prompt")
+	require.NoError(t, err)
+
+	events := session.Events()
+	first := <-events
+	assert.Equal(t, "s1", first.SessionID)
+	assert.Eventually(t, func() bool { return session.Stats().ToolCalls == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	session.Cancel()
+	for range events {
+	}
+	result, err := session.Wait()
+
+	assert.Equal(t, syscall.SIGKILL, result.Signal)
+	assert.Equal(t, -1, result.ExitCode)
+	assert.Equal(t, "s1", result.SessionID)
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Contains(t, exitErr.Error(), "killed by signal")
+}
@@ -0,0 +1,284 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNoTranscript is returned by [ReplayExecutor] when a session starts after
+// every transcript has been replayed.
+var ErrNoTranscript = errors.New("no transcript left to replay")
+
+// RecordingExecutor implements [Executor] by running sessions on another
+// executor and recording each one as a transcript in a directory.
+//
+// Transcripts are named session-0001.jsonl, session-0002.jsonl, and so on,
+// continuing after any already in the directory, and can be played back with
+// a [ReplayExecutor]. Record real sessions once, then replay them in tests
+// and demos without spending tokens.
+//
+// Recording never fails a session: a transcript that cannot be created or
+// written is reported to the error callback, and the session runs and ends
+// as it would unrecorded.
+type RecordingExecutor struct {
+	inner   Executor
+	dir     string
+	onError func(err error)
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecordingExecutor creates a [RecordingExecutor] that runs sessions on
+// inner and records them in dir, which is created if needed. Failures to
+// record a session are passed to onError, if set.
+func NewRecordingExecutor(inner Executor, dir string, onError func(err error)) *RecordingExecutor {
+	return &RecordingExecutor{inner: inner, dir: dir, onError: onError}
+}
+
+// Start starts the session on the inner executor and records it; see
+// [Executor.Start]. The transcript is complete once the session has ended.
+func (e *RecordingExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	w, err := e.newWriter(ctx, prompt)
+	if err != nil {
+		e.report(err)
+		return e.inner.Start(ctx, prompt)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	inner, err := e.inner.Start(ctx, prompt)
+	if err != nil {
+		cancel()
+		e.close(w, 1, err)
+		return nil, err
+	}
+
+	s := newSession(cancel)
+	go func() {
+		for event := range inner.Events() {
+			w.Write(event)
+			s.publish(event)
+		}
+		result, err := inner.Wait()
+		e.close(w, result.ExitCode, err)
+		s.finish(result, err)
+	}()
+	return s, nil
+}
+
+// ExecuteWithResult runs the session on the inner executor and records it;
+// see [Executor.ExecuteWithResult].
+func (e *RecordingExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	w, err := e.newWriter(ctx, prompt)
+	if err != nil {
+		e.report(err)
+		return e.inner.ExecuteWithResult(ctx, prompt, handler)
+	}
+
+	code, err := e.inner.ExecuteWithResult(ctx, prompt, func(event Event) {
+		w.Write(event)
+		if handler != nil {
+			handler(event)
+		}
+	})
+	e.close(w, code, err)
+	return code, err
+}
+
+// close records the session's outcome and reports a failure to record it.
+func (e *RecordingExecutor) close(w *TranscriptWriter, exitCode int, sessionErr error) {
+	if err := w.Close(exitCode, sessionErr); err != nil {
+		e.report(fmt.Errorf("recording %s: %w", w.Path(), err))
+	}
+}
+
+// report passes a failure to record a session to the error callback.
+func (e *RecordingExecutor) report(err error) {
+	if e.onError != nil {
+		e.onError(err)
+	}
+}
+
+// newWriter starts the transcript of the next session.
+func (e *RecordingExecutor) newWriter(ctx context.Context, prompt string) (*TranscriptWriter, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for {
+		e.seq++
+		path := filepath.Join(e.dir, fmt.Sprintf("session-%04d%s", e.seq, TranscriptExt))
+		_, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return NewTranscriptWriter(path, prompt, RunOptionsFromContext(ctx))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("naming transcript: %w", err)
+		}
+	}
+}
+
+// ReplayConfig contains configuration for creating a [ReplayExecutor].
+type ReplayConfig struct {
+	// Transcripts are the event files of the transcripts to replay, one per
+	// session, in order. See [FindTranscripts].
+	Transcripts []string
+
+	// Speed scales the original timing of the events: 1 replays them at the
+	// pace they were recorded, 2 twice as fast. Zero replays them without
+	// delay.
+	Speed float64
+
+	// JumpTo is the index of the line of each transcript where timed replay
+	// begins: that line and all lines before it are replayed without delay,
+	// to skip ahead to a point of interest.
+	JumpTo int
+
+	// Parser parses the recorded stream-json.
+	// If nil, a [DefaultParser] is created with default settings.
+	Parser Parser
+}
+
+// ReplayExecutor implements [Executor] by playing back recorded transcripts.
+//
+// Each session replays the next transcript: its events are read by the
+// configured [Parser] exactly as live Claude output would be, and the
+// session ends with the recorded exit code and error. The prompt is not
+// checked against the recording; compare [ReplayExecutor.RecordedPrompts]
+// with the transcripts in tests that care. [RunOptions] timeouts are not
+// enforced, since the recorded outcome is replayed.
+//
+// Create instances using [NewReplayExecutor] rather than constructing directly.
+type ReplayExecutor struct {
+	config ReplayConfig
+	parser Parser
+
+	mu   sync.Mutex
+	next int
+
+	// RecordedPrompts accumulates all prompts passed to Execute/ExecuteWithResult.
+	RecordedPrompts []string
+
+	// RecordedOptions accumulates the [RunOptions] found on each call's context,
+	// in the same order as RecordedPrompts.
+	RecordedOptions []RunOptions
+}
+
+// NewReplayExecutor creates a new [ReplayExecutor] with the given configuration.
+func NewReplayExecutor(config ReplayConfig) *ReplayExecutor {
+	parser := config.Parser
+	if parser == nil {
+		parser = NewParser()
+	}
+	return &ReplayExecutor{config: config, parser: parser}
+}
+
+// Remaining returns the number of transcripts not yet replayed.
+func (e *ReplayExecutor) Remaining() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.config.Transcripts) - e.next
+}
+
+// Start replays the next transcript in the background; see [Executor.Start].
+// The session ends with the recorded exit code and error.
+func (e *ReplayExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	t, err := e.start(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return StartSession(ctx, func(ctx context.Context, handler EventHandler) (int, error) {
+		return e.replay(ctx, t, handler)
+	}), nil
+}
+
+// ExecuteWithResult replays the next transcript, passing its events to
+// handler, and returns the recorded exit code and error.
+//
+// A canceled ctx stops the replay early with exit code 1 and the cause of
+// the cancellation.
+func (e *ReplayExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	t, err := e.start(ctx, prompt)
+	if err != nil {
+		return 1, err
+	}
+	return e.replay(ctx, t, handler)
+}
+
+// replay passes the events of t to handler and returns its recorded outcome.
+func (e *ReplayExecutor) replay(ctx context.Context, t *Transcript, handler EventHandler) (int, error) {
+	for event := range e.parser.Parse(e.feed(ctx, t)) {
+		if handler != nil {
+			handler(event)
+		}
+	}
+	if ctx.Err() != nil {
+		return 1, context.Cause(ctx)
+	}
+	return t.ExitCode, t.Err()
+}
+
+// start records the call and reads the next transcript.
+func (e *ReplayExecutor) start(ctx context.Context, prompt string) (*Transcript, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.RecordedPrompts = append(e.RecordedPrompts, prompt)
+	e.RecordedOptions = append(e.RecordedOptions, RunOptionsFromContext(ctx))
+
+	if e.next >= len(e.config.Transcripts) {
+		return nil, ErrNoTranscript
+	}
+	path := e.config.Transcripts[e.next]
+	e.next++
+	return ReadTranscript(path)
+}
+
+// feed returns a reader that yields the transcript's lines, paced by the
+// recorded offsets when [ReplayConfig.Speed] is set. It ends early when ctx
+// is canceled.
+func (e *ReplayExecutor) feed(ctx context.Context, t *Transcript) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+
+		var prev int64
+		for i, line := range t.Lines {
+			if e.config.Speed > 0 && i < len(t.OffsetsMS) {
+				delay := time.Duration(float64(t.OffsetsMS[i]-prev) / e.config.Speed * float64(time.Millisecond))
+				prev = t.OffsetsMS[i]
+				jumping := e.config.JumpTo > 0 && i <= e.config.JumpTo
+				if !jumping && !sleepContext(ctx, delay) {
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(pw, "%s\n", line); err != nil {
+				return
+			}
+		}
+	}()
+	return pr
+}
+
+// sleepContext waits for d, returning false early if ctx is canceled.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
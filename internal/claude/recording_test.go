@@ -0,0 +1,346 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionLines is a short stream-json session with a tool call.
+var sessionLines = []string{
+	`{"type":"system","subtype":"init","session_id":"sess-1"}`,
+	`{"type":"assistant","session_id":"sess-1","message":{"id":"msg_1","content":[{"type":"text","text":"Running tests"},{"type":"tool_use","id":"tu_1","name":"Bash","input":{"command":"go test ./...","description":"Run tests"}}],"usage":{"input_tokens":10,"output_tokens":5}}}`,
+	`{"type":"user","session_id":"sess-1","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1"}]},"tool_use_result":{"stdout":"ok","stderr":""}}`,
+	`{"type":"result","subtype":"success","session_id":"sess-1","total_cost_usd":0.01,"usage":{"input_tokens":10,"output_tokens":5},"num_turns":1,"result":"Tests pass"}`,
+}
+
+// parseSession returns the events of the given stream-json lines, as
+// [DefaultParser] would.
+func parseSession(t *testing.T, lines []string) []Event {
+	t.Helper()
+	var events []Event
+	for _, line := range lines {
+		var raw StreamEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &raw))
+		for _, event := range NewEventsFromStream(&raw) {
+			event.Line = []byte(line)
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// outcomeExecutor emits its events, then returns its exit code and error.
+type outcomeExecutor struct {
+	events []Event
+	code   int
+	err    error
+}
+
+func (e *outcomeExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	return StartSession(ctx, func(ctx context.Context, handler EventHandler) (int, error) {
+		return e.ExecuteWithResult(ctx, prompt, handler)
+	}), nil
+}
+
+func (e *outcomeExecutor) ExecuteWithResult(_ context.Context, _ string, handler EventHandler) (int, error) {
+	for _, event := range e.events {
+		handler(event)
+	}
+	return e.code, e.err
+}
+
+func TestRecordingExecutor_RecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	events := parseSession(t, sessionLines)
+	recorder := NewRecordingExecutor(&MockExecutor{Events: events}, dir, nil)
+
+	ctx := WithRunOptions(context.Background(), RunOptions{Agent: "codex"})
+	var seen []Event
+	code, err := recorder.ExecuteWithResult(ctx, "run the tests", func(e Event) { seen = append(seen, e) })
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, events, seen, "events pass through unchanged")
+
+	paths, err := FindTranscripts(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "session-0001.jsonl")}, paths)
+
+	transcript, err := ReadTranscript(paths[0])
+	require.NoError(t, err)
+	assert.Len(t, transcript.Lines, 4, "content blocks of one message are written once")
+	assert.Len(t, transcript.OffsetsMS, 4)
+	assert.Equal(t, "run the tests", transcript.Prompt)
+	assert.Equal(t, "codex", transcript.Agent)
+	assert.False(t, transcript.StartedAt.IsZero())
+	assert.Empty(t, transcript.Error)
+
+	replay := NewReplayExecutor(ReplayConfig{Transcripts: paths})
+	var replayed []Event
+	code, err = replay.ExecuteWithResult(context.Background(), "run the tests", func(e Event) { replayed = append(replayed, e) })
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, events, replayed, "replayed events match the recorded ones")
+	assert.Equal(t, []string{"run the tests"}, replay.RecordedPrompts)
+	assert.Equal(t, 0, replay.Remaining())
+}
+
+func TestRecordingExecutor_RecordsLinesAsRead(t *testing.T) {
+	lines := []string{
+		`{"type":"system","subtype":"init","session_id":"s","model":"m","tools":["Bash"]}`,
+		`not json at all`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_1","content":[{"type":"text","text":"ok"}]}]}}`,
+		`{"type":"result", "subtype":"success",  "result":"done"}`,
+	}
+	stdout := strings.Join(lines, "\n") + "\n"
+	var events []Event
+	for event := range NewParser().Parse(strings.NewReader(stdout)) {
+		events = append(events, event)
+	}
+	require.True(t, events[1].IsParseError())
+
+	dir := t.TempDir()
+	recorder := NewRecordingExecutor(&MockExecutor{Events: events}, dir, nil)
+	_, err := recorder.ExecuteWithResult(context.Background(), "p", nil)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "session-0001.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, stdout, string(data), "unknown fields, malformed lines and formatting are kept")
+}
+
+func TestRecordingExecutor_RecordsOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		err  error
+	}{
+		{"exit error", 1, &ExitError{Code: 1, Stderr: []string{"API Error: 529 overloaded_error"}}},
+		{"timeout", ExitCodeTimeout, &TimeoutError{Idle: true, After: 5 * time.Minute}},
+		{"exit error without stderr", 2, &ExitError{Code: 2}},
+		{"plain error", 1, errors.New("failed to start claude: not found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			inner := &outcomeExecutor{events: parseSession(t, sessionLines[:2]), code: tt.code, err: tt.err}
+			recorder := NewRecordingExecutor(inner, dir, nil)
+
+			code, err := recorder.ExecuteWithResult(context.Background(), "p", nil)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.err, err)
+
+			paths, err := FindTranscripts(dir)
+			require.NoError(t, err)
+			replay := NewReplayExecutor(ReplayConfig{Transcripts: paths})
+			code, err = replay.ExecuteWithResult(context.Background(), "p", nil)
+
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.err, err, "the error is rebuilt with its original type")
+			assert.Equal(t, Classify(tt.err), Classify(err))
+		})
+	}
+}
+
+func TestRecordingExecutor_ContinuesNumbering(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "session-0001.jsonl"), nil, 0o644))
+
+	recorder := NewRecordingExecutor(&MockExecutor{}, dir, nil)
+	_, err := recorder.ExecuteWithResult(context.Background(), "one", nil)
+	require.NoError(t, err)
+	_, err = recorder.ExecuteWithResult(context.Background(), "two", nil)
+	require.NoError(t, err)
+
+	paths, err := FindTranscripts(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "session-0001.jsonl"),
+		filepath.Join(dir, "session-0002.jsonl"),
+		filepath.Join(dir, "session-0003.jsonl"),
+	}, paths)
+}
+
+func TestRecordingExecutor_Start(t *testing.T) {
+	dir := t.TempDir()
+	events := parseSession(t, sessionLines)
+	recorder := NewRecordingExecutor(&outcomeExecutor{events: events, code: 2, err: assert.AnError}, dir, nil)
+
+	session, err := recorder.Start(context.Background(), "prompt")
+	require.NoError(t, err)
+	var seen []Event
+	for event := range session.Events() {
+		seen = append(seen, event)
+	}
+	assert.Equal(t, events, seen)
+	result, err := session.Wait()
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 2, result.ExitCode)
+
+	transcript, err := ReadTranscript(filepath.Join(dir, "session-0001.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, transcript.Lines, 4)
+	assert.Equal(t, 2, transcript.ExitCode, "the exit code is recorded")
+}
+
+func TestRecordingExecutor_StartError(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewRecordingExecutor(&MockExecutor{Error: assert.AnError}, dir, nil)
+
+	_, err := recorder.Start(context.Background(), "prompt")
+	assert.ErrorIs(t, err, assert.AnError)
+
+	transcript, err := ReadTranscript(filepath.Join(dir, "session-0001.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, assert.AnError.Error(), transcript.Error)
+}
+
+func TestRecordingExecutor_RecordErrorsDoNotFailSessions(t *testing.T) {
+	events := parseSession(t, sessionLines)
+
+	t.Run("transcript not created", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o644))
+		var errs []error
+		recorder := NewRecordingExecutor(&MockExecutor{Events: events}, filepath.Join(file, "dir"), func(err error) { errs = append(errs, err) })
+
+		var seen []Event
+		code, err := recorder.ExecuteWithResult(context.Background(), "p", func(e Event) { seen = append(seen, e) })
+		assert.NoError(t, err)
+		assert.Equal(t, 0, code)
+		assert.Equal(t, events, seen)
+
+		session, err := recorder.Start(context.Background(), "p")
+		require.NoError(t, err)
+		_, err = session.Wait()
+		assert.NoError(t, err)
+		assert.Len(t, errs, 2)
+	})
+
+	t.Run("transcript info not written", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "session-0001.json"), 0o755))
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "session-0002.json"), 0o755))
+		var errs []error
+		recorder := NewRecordingExecutor(&MockExecutor{Events: events}, dir, func(err error) { errs = append(errs, err) })
+
+		_, err := recorder.ExecuteWithResult(context.Background(), "p", nil)
+		assert.NoError(t, err)
+
+		session, err := recorder.Start(context.Background(), "p")
+		require.NoError(t, err)
+		_, err = session.Wait()
+		assert.NoError(t, err)
+
+		require.Len(t, errs, 2)
+		assert.ErrorContains(t, errs[0], "session-0001.jsonl")
+	})
+}
+
+func TestReplayExecutor_NoTranscriptLeft(t *testing.T) {
+	replay := NewReplayExecutor(ReplayConfig{})
+
+	code, err := replay.ExecuteWithResult(context.Background(), "prompt", nil)
+	assert.Equal(t, 1, code)
+	assert.ErrorIs(t, err, ErrNoTranscript)
+
+	_, err = replay.Start(context.Background(), "prompt")
+	assert.ErrorIs(t, err, ErrNoTranscript)
+}
+
+// writeTranscript writes a transcript with the given lines and offsets.
+func writeTranscript(t *testing.T, lines []string, offsetsMS []int64) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	var data []byte
+	for _, line := range lines {
+		data = append(data, line+"\n"...)
+	}
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	info, err := json.Marshal(TranscriptInfo{OffsetsMS: offsetsMS})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "session.json"), info, 0o644))
+	return path
+}
+
+func TestReplayExecutor_Speed(t *testing.T) {
+	path := writeTranscript(t, sessionLines, []int64{0, 1000, 2000, 3000})
+
+	start := time.Now()
+	_, err := NewReplayExecutor(ReplayConfig{Transcripts: []string{path}, Speed: 100}).
+		ExecuteWithResult(context.Background(), "p", nil)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond, "3s of events at 100x speed")
+
+	start = time.Now()
+	_, err = NewReplayExecutor(ReplayConfig{Transcripts: []string{path}}).
+		ExecuteWithResult(context.Background(), "p", nil)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "no delays by default")
+}
+
+func TestReplayExecutor_Canceled(t *testing.T) {
+	path := writeTranscript(t, sessionLines, []int64{0, 10_000, 20_000, 30_000})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var events []Event
+	code, err := NewReplayExecutor(ReplayConfig{Transcripts: []string{path}, Speed: 1}).
+		ExecuteWithResult(ctx, "p", func(e Event) {
+			events = append(events, e)
+			cancel()
+		})
+
+	assert.Equal(t, 1, code)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, events, 1, "the replay stops at the cancellation")
+}
+
+func TestReadTranscript_WithoutInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captured.jsonl")
+	data := sessionLines[0] + "\n\n" + sessionLines[3] + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	transcript, err := ReadTranscript(path)
+	require.NoError(t, err)
+	assert.Len(t, transcript.Lines, 2, "blank lines are skipped")
+	assert.Equal(t, 0, transcript.ExitCode)
+	assert.NoError(t, transcript.Err())
+
+	replay := NewReplayExecutor(ReplayConfig{Transcripts: []string{path}})
+	var events []Event
+	code, err := replay.ExecuteWithResult(context.Background(), "p", func(e Event) { events = append(events, e) })
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	require.Len(t, events, 2)
+	assert.True(t, events[1].SessionComplete)
+}
+
+func TestReadTranscript_Missing(t *testing.T) {
+	_, err := ReadTranscript(filepath.Join(t.TempDir(), "missing.jsonl"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReplayExecutor_JumpTo(t *testing.T) {
+	path := writeTranscript(t, sessionLines, []int64{0, 10_000, 20_000, 20_030})
+
+	start := time.Now()
+	var events []Event
+	_, err := NewReplayExecutor(ReplayConfig{Transcripts: []string{path}, Speed: 1, JumpTo: 2}).
+		ExecuteWithResult(context.Background(), "p", func(e Event) { events = append(events, e) })
+
+	require.NoError(t, err)
+	assert.Len(t, events, 5, "skipped-ahead lines are still replayed")
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 30*time.Millisecond, "timing resumes after the jump")
+	assert.Less(t, elapsed, 5*time.Second, "lines up to the jump are not delayed")
+}
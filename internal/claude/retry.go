@@ -0,0 +1,169 @@
+package claude
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// RetryConfig configures a [RetryExecutor].
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts per session, including
+	// the first. Values below 2 disable retries.
+	MaxAttempts int
+
+	// Backoff is the wait before each further attempt.
+	Backoff time.Duration
+
+	// RetryOn lists the failure classes worth retrying (see [Classify]).
+	RetryOn []FailureClass
+
+	// OnRetry, if set, is called before the wait for each further attempt,
+	// with the number of the attempt that failed and its error.
+	OnRetry func(attempt int, err error)
+}
+
+// WithRetry retries sessions that fail before Claude did any work; see
+// [RetryExecutor].
+func WithRetry(cfg RetryConfig) Middleware {
+	return func(next Executor) Executor {
+		return NewRetryExecutor(next, cfg)
+	}
+}
+
+// RetryExecutor is an [Executor] decorator that starts a session again when
+// it fails for a transient reason, such as an overloaded API, before Claude
+// did any work.
+//
+// An attempt's events are held back until Claude sends an assistant message:
+// from then on the session has made progress and is never retried, since
+// running the prompt again could repeat its changes. A failed attempt that
+// made no progress and whose failure is in [RetryConfig.RetryOn] is dropped,
+// events and all, and the prompt runs again after [RetryConfig.Backoff].
+// Step-level retries, which resume the session instead, are the lifecycle's
+// business.
+//
+// Sessions with a [RunOptions.Input] are not retried, since an [Input]
+// belongs to one session.
+//
+// Use [NewRetryExecutor] or [WithRetry] to create a RetryExecutor.
+type RetryExecutor struct {
+	inner Executor
+	cfg   RetryConfig
+	sleep func(ctx context.Context, d time.Duration) bool
+}
+
+// NewRetryExecutor creates a [RetryExecutor] that runs sessions on inner.
+func NewRetryExecutor(inner Executor, cfg RetryConfig) *RetryExecutor {
+	return &RetryExecutor{inner: inner, cfg: cfg, sleep: sleepContext}
+}
+
+// Start starts the session on the inner executor; see [Executor.Start].
+// Failures to start are retried like failed attempts.
+func (e *RetryExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	if e.cfg.MaxAttempts < 2 || RunOptionsFromContext(ctx).Input != nil {
+		return e.inner.Start(ctx, prompt)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	first, err := e.startAttempt(ctx, prompt, 1)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s := newSession(cancel)
+	go func() {
+		current := first
+		for {
+			held, progressed := relay(s, current.session)
+			result, err := current.session.Wait()
+			if !progressed && err != nil && ctx.Err() == nil && e.retryable(current.attempt, err) {
+				next, retryErr := e.retry(ctx, prompt, current.attempt, err)
+				if retryErr == nil {
+					current = next
+					continue
+				}
+				if ctx.Err() == nil {
+					// The next attempt failed to start
+					held, result, err = nil, Result{ExitCode: 1}, retryErr
+				}
+			}
+			for _, event := range held {
+				s.publish(event)
+			}
+			s.finish(result, err)
+			return
+		}
+	}()
+	return s, nil
+}
+
+// ExecuteWithResult runs the session with [RetryExecutor.Start] and waits
+// for it; see [Executor.ExecuteWithResult].
+func (e *RetryExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	session, err := e.Start(ctx, prompt)
+	if err != nil {
+		return 1, err
+	}
+	return session.Handle(handler)
+}
+
+// started is an attempt's running session.
+type started struct {
+	attempt int
+	session *Session
+}
+
+// startAttempt starts the given attempt, retrying failures to start.
+func (e *RetryExecutor) startAttempt(ctx context.Context, prompt string, attempt int) (started, error) {
+	session, err := e.inner.Start(ctx, prompt)
+	if err == nil {
+		return started{attempt, session}, nil
+	}
+	if !e.retryable(attempt, err) || ctx.Err() != nil {
+		return started{}, err
+	}
+	return e.retry(ctx, prompt, attempt, err)
+}
+
+// retry waits for the backoff after a failed attempt and starts the next one.
+// It returns err if the wait was canceled, or the error that stopped the
+// next attempt from starting.
+func (e *RetryExecutor) retry(ctx context.Context, prompt string, attempt int, err error) (started, error) {
+	if e.cfg.OnRetry != nil {
+		e.cfg.OnRetry(attempt, err)
+	}
+	if !e.sleep(ctx, e.cfg.Backoff) {
+		return started{}, err
+	}
+	return e.startAttempt(ctx, prompt, attempt+1)
+}
+
+// relay publishes an attempt's events to s once the attempt makes progress,
+// that is once Claude sends an assistant message. It returns the events held
+// back because the attempt made none, and whether it made progress.
+func relay(s *Session, attempt *Session) ([]Event, bool) {
+	var held []Event
+	progressed := false
+	for event := range attempt.Events() {
+		if !progressed && event.Type == EventTypeAssistant {
+			progressed = true
+			for _, h := range held {
+				s.publish(h)
+			}
+			held = nil
+		}
+		if progressed {
+			s.publish(event)
+		} else {
+			held = append(held, event)
+		}
+	}
+	return held, progressed
+}
+
+// retryable reports whether the given failed attempt may be followed by another.
+func (e *RetryExecutor) retryable(attempt int, err error) bool {
+	return attempt < e.cfg.MaxAttempts && slices.Contains(e.cfg.RetryOn, Classify(err))
+}
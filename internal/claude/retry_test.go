@@ -0,0 +1,166 @@
+package claude
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overloaded is the failure of an attempt the API rejected.
+var overloaded = &ExitError{Code: 1, Stderr: []string{"API Error: 529 overloaded_error"}}
+
+// attemptsExecutor plays one outcome per session, in order.
+type attemptsExecutor struct {
+	attempts []*outcomeExecutor
+	startErr []error
+	started  int
+}
+
+func (e *attemptsExecutor) Start(ctx context.Context, prompt string) (*Session, error) {
+	i := e.started
+	e.started++
+	if i < len(e.startErr) && e.startErr[i] != nil {
+		return nil, e.startErr[i]
+	}
+	return e.attempts[i].Start(ctx, prompt)
+}
+
+func (e *attemptsExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler EventHandler) (int, error) {
+	session, err := e.Start(ctx, prompt)
+	if err != nil {
+		return 1, err
+	}
+	return session.Handle(handler)
+}
+
+// newTestRetryExecutor retries overloaded sessions up to three attempts
+// without waiting, recording the failed attempts.
+func newTestRetryExecutor(inner Executor, failed *[]int) *RetryExecutor {
+	e := NewRetryExecutor(inner, RetryConfig{
+		MaxAttempts: 3,
+		Backoff:     time.Minute,
+		RetryOn:     []FailureClass{FailureOverloaded},
+		OnRetry:     func(attempt int, err error) { *failed = append(*failed, attempt) },
+	})
+	e.sleep = func(ctx context.Context, _ time.Duration) bool { return ctx.Err() == nil }
+	return e
+}
+
+func TestRetryExecutor_RetriesBeforeProgress(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	inner := &attemptsExecutor{attempts: []*outcomeExecutor{
+		{events: events[:1], code: 1, err: overloaded},
+		{events: events},
+	}}
+	var failed []int
+	executor := newTestRetryExecutor(inner, &failed)
+
+	var seen []Event
+	code, err := executor.ExecuteWithResult(context.Background(), "prompt", func(e Event) { seen = append(seen, e) })
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, events, seen, "the failed attempt's events are dropped")
+	assert.Equal(t, []int{1}, failed)
+	assert.Equal(t, 2, inner.started)
+}
+
+func TestRetryExecutor_NoRetryAfterProgress(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	inner := &attemptsExecutor{attempts: []*outcomeExecutor{
+		{events: events[:2], code: 1, err: overloaded},
+		{events: events},
+	}}
+	var failed []int
+	executor := newTestRetryExecutor(inner, &failed)
+
+	session, err := executor.Start(context.Background(), "prompt")
+	require.NoError(t, err)
+	var seen []Event
+	for event := range session.Events() {
+		seen = append(seen, event)
+	}
+	result, err := session.Wait()
+
+	assert.ErrorIs(t, err, overloaded)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, events[:2], seen)
+	assert.Empty(t, failed)
+	assert.Equal(t, 1, inner.started)
+}
+
+func TestRetryExecutor_GivesUp(t *testing.T) {
+	inner := &attemptsExecutor{attempts: []*outcomeExecutor{
+		{code: 1, err: overloaded},
+		{code: 1, err: overloaded},
+		{code: 2, err: overloaded},
+	}}
+	var failed []int
+	executor := newTestRetryExecutor(inner, &failed)
+
+	code, err := executor.ExecuteWithResult(context.Background(), "prompt", nil)
+
+	assert.ErrorIs(t, err, overloaded)
+	assert.Equal(t, 2, code, "the last attempt's result is kept")
+	assert.Equal(t, []int{1, 2}, failed)
+	assert.Equal(t, 3, inner.started)
+}
+
+func TestRetryExecutor_OtherFailuresNotRetried(t *testing.T) {
+	inner := &attemptsExecutor{attempts: []*outcomeExecutor{
+		{code: 3, err: &ExitError{Code: 3}},
+		{},
+	}}
+	var failed []int
+	executor := newTestRetryExecutor(inner, &failed)
+
+	code, err := executor.ExecuteWithResult(context.Background(), "prompt", nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, 1, inner.started)
+}
+
+func TestRetryExecutor_RetriesStartErrors(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	inner := &attemptsExecutor{
+		attempts: []*outcomeExecutor{nil, {events: events}},
+		startErr: []error{overloaded},
+	}
+	var failed []int
+	executor := newTestRetryExecutor(inner, &failed)
+
+	code, err := executor.ExecuteWithResult(context.Background(), "prompt", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, []int{1}, failed)
+}
+
+func TestRetryExecutor_CanceledBackoff(t *testing.T) {
+	inner := &attemptsExecutor{attempts: []*outcomeExecutor{{code: 1, err: overloaded}, {}}}
+	executor := NewRetryExecutor(inner, RetryConfig{MaxAttempts: 3, Backoff: time.Hour, RetryOn: []FailureClass{FailureOverloaded}})
+
+	session, err := executor.Start(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return inner.started == 1 }, time.Second, time.Millisecond)
+	session.Cancel()
+	_, err = session.Wait()
+
+	assert.ErrorIs(t, err, overloaded, "the failed attempt is reported")
+}
+
+func TestRetryExecutor_InputNotRetried(t *testing.T) {
+	inner := &attemptsExecutor{attempts: []*outcomeExecutor{{code: 1, err: overloaded}, {}}}
+	var failed []int
+	executor := newTestRetryExecutor(inner, &failed)
+
+	ctx := WithRunOptions(context.Background(), RunOptions{Input: NewInput()})
+	_, err := executor.ExecuteWithResult(ctx, "prompt", nil)
+
+	assert.ErrorIs(t, err, overloaded)
+	assert.Equal(t, 1, inner.started)
+}
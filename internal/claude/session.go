@@ -0,0 +1,282 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Result is the outcome of a finished [Session].
+type Result struct {
+	// ExitCode is the process exit code: 0 for success, [ExitCodeTimeout]
+	// for a session killed by a timeout, and -1 for a process killed by a
+	// signal, as after [Session.Cancel].
+	ExitCode int
+
+	// Stderr holds the last lines the session wrote to stderr, oldest first.
+	Stderr []string
+
+	// Stats are the statistics of the last result event, or nil if the
+	// session ended without one.
+	Stats *ResultStats
+
+	// SessionID is the Claude session ID, if the session reported one.
+	SessionID string
+
+	// Signal is the signal that killed the process, or nil if it exited on
+	// its own or the executor runs no process.
+	Signal os.Signal
+}
+
+// SessionStats are the live counters of a [Session].
+type SessionStats struct {
+	// Events is the number of events received.
+	Events int
+
+	// ToolCalls is the number of tool calls Claude made.
+	ToolCalls int
+
+	// ToolErrors is the number of tool results marked as errors.
+	ToolErrors int
+
+	// Tokens is the number of tokens used so far: the sum over the API
+	// messages streamed, replaced by the session total once a result event
+	// arrives.
+	Tokens int64
+
+	// CostUSD is the session cost, known once a result event arrives.
+	CostUSD float64
+}
+
+// RunFunc runs a session, passing each event to handler, and returns its
+// exit code and error with the semantics of [Executor.ExecuteWithResult].
+type RunFunc func(ctx context.Context, handler EventHandler) (int, error)
+
+// maxUnobservedEvents is how many events a [Session] holds while no channel
+// from [Session.Events] is open. Older events are dropped.
+const maxUnobservedEvents = 10_000
+
+// Session is a running Claude session, as returned by [Executor.Start].
+//
+// Any number of observers can follow a session at once: each call to
+// [Session.Events] returns a channel of its own. [Session.Wait] blocks until
+// the session has ended and returns its [Result]; [Session.Stats] reports
+// live counters. The methods of a Session are safe for concurrent use.
+//
+// A session holds each event only until every open channel has delivered it,
+// so memory stays bounded however long the session runs.
+type Session struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	changed   *sync.Cond // signaled when an event arrives or the events end
+	history   []Event    // events not yet delivered to every observer
+	first     int        // number of the event history[0]
+	observers map[*int]struct{}
+	ended     bool
+	stats     SessionStats
+	messages  map[string]Usage
+	final     *ResultStats
+	id        string
+	result    Result
+	err       error
+}
+
+// newSession creates a session whose Cancel calls cancel.
+func newSession(cancel context.CancelFunc) *Session {
+	s := &Session{
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		observers: make(map[*int]struct{}),
+		messages:  make(map[string]Usage),
+	}
+	s.changed = sync.NewCond(&s.mu)
+	return s
+}
+
+// StartSession runs a session in the background and returns its handle.
+// run is called with a context that [Session.Cancel] cancels; its events
+// become the session's events, and its exit code and error its result.
+//
+// Executors that implement [Executor.ExecuteWithResult] directly use it to
+// implement [Executor.Start].
+func StartSession(ctx context.Context, run RunFunc) *Session {
+	ctx, cancel := context.WithCancel(ctx)
+	s := newSession(cancel)
+	go func() {
+		code, err := run(ctx, s.publish)
+		result := Result{ExitCode: code}
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			result.Stderr = exitErr.Stderr
+			result.Signal = exitErr.Signal
+		}
+		s.finish(result, err)
+	}()
+	return s
+}
+
+// Events returns a channel that delivers the events of the session, from
+// the oldest one still held, and is closed after the last. Each call returns
+// a new channel, so several observers can follow the same session. The
+// channel must be read until it is closed.
+//
+// Events are held until every open channel has delivered them, and up to
+// [maxUnobservedEvents] of them while no channel is open. A channel opened
+// right after [Executor.Start], or before any other channel has been read,
+// delivers every event; one opened later may start part way.
+func (s *Session) Events() <-chan Event {
+	events := make(chan Event)
+
+	s.mu.Lock()
+	next := s.first
+	s.observers[&next] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		defer close(events)
+		for {
+			s.mu.Lock()
+			for next >= s.first+len(s.history) && !s.ended {
+				s.changed.Wait()
+			}
+			if next >= s.first+len(s.history) {
+				delete(s.observers, &next)
+				s.trim()
+				s.mu.Unlock()
+				return
+			}
+			event := s.history[next-s.first]
+			next++
+			s.trim()
+			s.mu.Unlock()
+			events <- event
+		}
+	}()
+	return events
+}
+
+// trim drops the events every observer has been given, or the oldest events
+// beyond [maxUnobservedEvents] when there are no observers. Callers must
+// hold s.mu.
+func (s *Session) trim() {
+	keep := s.first + len(s.history) - maxUnobservedEvents
+	if len(s.observers) > 0 {
+		keep = s.first + len(s.history)
+		for next := range s.observers {
+			keep = min(keep, *next)
+		}
+	}
+	if drop := keep - s.first; drop > 0 {
+		clear(s.history[:drop])
+		s.history = s.history[drop:]
+		s.first = keep
+	}
+}
+
+// Wait blocks until the session has ended and returns its result.
+//
+// The error follows [Executor.ExecuteWithResult]: an [*ExitError] for a
+// non-zero exit, a [*TimeoutError] for a timeout, or the error that stopped
+// the session from running.
+func (s *Session) Wait() (Result, error) {
+	<-s.done
+	return s.result, s.err
+}
+
+// Done returns a channel that is closed once the session has ended.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Cancel stops the session, killing its process. It does not wait for the
+// session to end; call [Session.Wait] for that. Canceling a session that has
+// ended has no effect.
+func (s *Session) Cancel() {
+	s.cancel()
+}
+
+// Stats returns the session's counters so far.
+func (s *Session) Stats() SessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Handle passes each event of the session to handler, which may be nil, and
+// returns the exit code and error once the session has ended. It is how
+// [Executor.ExecuteWithResult] is implemented on top of [Executor.Start].
+func (s *Session) Handle(handler EventHandler) (int, error) {
+	for event := range s.Events() {
+		if handler != nil {
+			handler(event)
+		}
+	}
+	result, err := s.Wait()
+	return result.ExitCode, err
+}
+
+// publish records an event and wakes the observers.
+func (s *Session) publish(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, event)
+	if len(s.observers) == 0 {
+		s.trim()
+	}
+	s.stats.Events++
+	switch {
+	case event.IsToolUse():
+		s.stats.ToolCalls++
+	case event.IsToolResult() && event.ToolIsError:
+		s.stats.ToolErrors++
+	}
+	if event.SessionID != "" {
+		s.id = event.SessionID
+	}
+	if event.Usage != nil && event.MessageID != "" {
+		s.messages[event.MessageID] = *event.Usage
+	}
+	if event.Stats != nil {
+		stats := *event.Stats
+		s.final = &stats
+	}
+	s.stats.Tokens = s.tokens()
+	if s.final != nil {
+		s.stats.CostUSD = s.final.TotalCostUSD
+	}
+	s.changed.Broadcast()
+}
+
+// tokens returns the tokens used so far. Callers must hold s.mu.
+func (s *Session) tokens() int64 {
+	if s.final != nil {
+		return s.final.Usage.TotalTokens()
+	}
+	var tokens int64
+	for _, u := range s.messages {
+		tokens += u.TotalTokens()
+	}
+	return tokens
+}
+
+// finish ends the session's events and records its result, completed with
+// what the events reported.
+func (s *Session) finish(result Result, err error) {
+	s.mu.Lock()
+	s.ended = true
+	result.Stats = s.final
+	result.SessionID = s.id
+	result.Stderr = slices.Clone(result.Stderr)
+	s.result = result
+	s.err = err
+	s.changed.Broadcast()
+	s.mu.Unlock()
+
+	s.cancel()
+	close(s.done)
+}
@@ -0,0 +1,188 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_EventsHasSeveralObservers(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	session, err := (&MockExecutor{Events: events}).Start(context.Background(), "prompt")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	observers := []<-chan Event{session.Events(), session.Events()}
+	seen := make([][]Event, len(observers))
+	for i, observer := range observers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range observer {
+				seen[i] = append(seen[i], event)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, events, seen[0])
+	assert.Equal(t, events, seen[1])
+
+	var late []Event
+	for event := range session.Events() {
+		late = append(late, event)
+	}
+	assert.Empty(t, late, "events every observer was given are dropped")
+}
+
+func TestSession_HoldsEventsForSlowObservers(t *testing.T) {
+	session := newSession(func() {})
+	held := func() int {
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		return len(session.history)
+	}
+	fast, slow := session.Events(), session.Events()
+
+	events := parseSession(t, sessionLines)
+	for _, event := range events {
+		session.publish(event)
+	}
+	for range events {
+		<-fast
+	}
+	// The slow observer may already be holding its first event.
+	assert.GreaterOrEqual(t, held(), len(events)-1, "events the slow observer has not read are held")
+
+	assert.Equal(t, events[0], <-slow)
+	assert.Equal(t, events[1], <-slow)
+	assert.LessOrEqual(t, held(), len(events)-2, "events every observer has read are dropped")
+
+	session.finish(Result{}, nil)
+	var rest []Event
+	for event := range slow {
+		rest = append(rest, event)
+	}
+	assert.Equal(t, events[2:], rest)
+	_, open := <-fast
+	assert.False(t, open)
+	assert.Zero(t, held())
+}
+
+func TestSession_BoundsUnobservedEvents(t *testing.T) {
+	session := newSession(func() {})
+	for i := range maxUnobservedEvents + 5 {
+		session.publish(Event{Type: EventTypeAssistant, Text: fmt.Sprint(i)})
+	}
+	session.finish(Result{}, nil)
+
+	var texts []string
+	for event := range session.Events() {
+		texts = append(texts, event.Text)
+	}
+	require.Len(t, texts, maxUnobservedEvents)
+	assert.Equal(t, "5", texts[0], "the oldest events are dropped")
+	assert.Equal(t, maxUnobservedEvents+5, session.Stats().Events, "stats count every event")
+}
+
+func TestSession_Wait(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	session := StartSession(context.Background(), func(ctx context.Context, handler EventHandler) (int, error) {
+		for _, event := range events {
+			handler(event)
+		}
+		return 1, &ExitError{Code: 1, Stderr: []string{"boom"}}
+	})
+
+	result, err := session.Wait()
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, []string{"boom"}, result.Stderr)
+	assert.Equal(t, "sess-1", result.SessionID)
+	require.NotNil(t, result.Stats)
+	assert.Equal(t, "Tests pass", result.Stats.Result)
+	assert.Nil(t, result.Signal)
+
+	select {
+	case <-session.Done():
+	default:
+		t.Fatal("Done is not closed after Wait")
+	}
+}
+
+func TestSession_Stats(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	step, ack := make(chan struct{}), make(chan struct{})
+	session := StartSession(context.Background(), func(ctx context.Context, handler EventHandler) (int, error) {
+		for _, event := range events {
+			handler(event)
+			step <- struct{}{}
+			<-ack
+		}
+		return 0, nil
+	})
+	// next returns the stats after the next event
+	next := func() SessionStats {
+		<-step
+		defer func() { ack <- struct{}{} }()
+		return session.Stats()
+	}
+
+	assert.Equal(t, SessionStats{Events: 1}, next())
+	next() // assistant text
+	stats := next()
+	assert.Equal(t, 1, stats.ToolCalls)
+	assert.Equal(t, int64(15), stats.Tokens, "usage counts once per message")
+	next() // tool result
+	stats = next()
+	assert.Equal(t, int64(15), stats.Tokens)
+	assert.InDelta(t, 0.01, stats.CostUSD, 1e-9)
+	assert.Equal(t, len(events), stats.Events)
+
+	_, err := session.Wait()
+	require.NoError(t, err)
+}
+
+func TestSession_Cancel(t *testing.T) {
+	session := StartSession(context.Background(), func(ctx context.Context, handler EventHandler) (int, error) {
+		<-ctx.Done()
+		return -1, ctx.Err()
+	})
+
+	session.Cancel()
+	result, err := session.Wait()
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, -1, result.ExitCode)
+}
+
+func TestSession_Handle(t *testing.T) {
+	events := parseSession(t, sessionLines)
+	session, err := (&MockExecutor{Events: events, ExitCode: 2}).Start(context.Background(), "prompt")
+	require.NoError(t, err)
+
+	var seen []Event
+	code, err := session.Handle(func(e Event) { seen = append(seen, e) })
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, events, seen)
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = session.Handle(nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle with a nil handler did not return")
+	}
+}
@@ -0,0 +1,134 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is matched (via errors.Is) by every [TimeoutError].
+var ErrTimeout = errors.New("claude session timed out")
+
+// ExitCodeTimeout is the exit code [DefaultExecutor.ExecuteWithResult] returns
+// when a session is killed by [RunOptions.Timeout] or [RunOptions.IdleTimeout].
+// It matches the convention of the coreutils timeout command.
+const ExitCodeTimeout = 124
+
+// TimeoutError reports a Claude session that was killed for running too long
+// or for going quiet.
+//
+// Use errors.Is(err, [ErrTimeout]) to detect any timeout, or errors.As to
+// inspect which limit fired.
+type TimeoutError struct {
+	// Idle is true if the session produced no stream events for After,
+	// and false if it exceeded its overall time limit.
+	Idle bool
+
+	// After is the limit that fired.
+	After time.Duration
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	if e.Idle {
+		return fmt.Sprintf("claude session stalled: no output for %s", e.After)
+	}
+	return fmt.Sprintf("claude session timed out after %s", e.After)
+}
+
+// Is reports whether target is [ErrTimeout].
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// watchdog enforces the timeouts of one session.
+//
+// Its context is canceled with a [TimeoutError] cause when the overall limit
+// passes or when activity stops arriving for the idle limit. Canceling the
+// context kills the Claude process group (see setProcessGroup).
+type watchdog struct {
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	stopTimeout context.CancelFunc
+	idle        time.Duration
+
+	mu        sync.Mutex // serializes restarting and pausing idleTimer
+	idleTimer *time.Timer
+}
+
+// newWatchdog starts a watchdog for the timeouts in opts. Zero limits are not enforced.
+func newWatchdog(parent context.Context, opts RunOptions) *watchdog {
+	ctx, cancel := context.WithCancelCause(parent)
+	w := &watchdog{ctx: ctx, cancel: cancel, stopTimeout: func() {}, idle: opts.IdleTimeout}
+
+	if opts.Timeout > 0 {
+		w.ctx, w.stopTimeout = context.WithTimeoutCause(ctx, opts.Timeout, &TimeoutError{After: opts.Timeout})
+	}
+	if opts.IdleTimeout > 0 {
+		w.idleTimer = time.AfterFunc(opts.IdleTimeout, func() {
+			cancel(&TimeoutError{Idle: true, After: opts.IdleTimeout})
+		})
+	}
+	return w
+}
+
+// activity records a stream event or a message sent to Claude, restarting
+// the idle timer.
+func (w *watchdog) activity() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.idleTimer != nil {
+		w.idleTimer.Reset(w.idle)
+	}
+}
+
+// pause stops the idle timer until the next activity, while Claude is quiet
+// because it waits for a follow-up message rather than because it stalled.
+func (w *watchdog) pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.idleTimer != nil {
+		w.idleTimer.Stop()
+	}
+}
+
+// activityWriter is the stdin of a session that counts every message written,
+// and closing stdin, as activity of its watchdog.
+type activityWriter struct {
+	io.WriteCloser
+	w *watchdog
+}
+
+// Write writes p and records activity.
+func (a activityWriter) Write(p []byte) (int, error) {
+	a.w.activity()
+	return a.WriteCloser.Write(p)
+}
+
+// Close closes stdin and records activity, so a session that does not exit
+// after its input ends is still caught by the idle limit.
+func (a activityWriter) Close() error {
+	a.w.activity()
+	return a.WriteCloser.Close()
+}
+
+// err returns the [TimeoutError] that ended the session, or nil if no limit fired.
+func (w *watchdog) err() error {
+	var timeoutErr *TimeoutError
+	if errors.As(context.Cause(w.ctx), &timeoutErr) {
+		return timeoutErr
+	}
+	return nil
+}
+
+// stop releases the watchdog's timers. Call it once the session has exited.
+func (w *watchdog) stop() {
+	if w.idleTimer != nil {
+		w.idleTimer.Stop()
+	}
+	w.stopTimeout()
+	w.cancel(nil)
+}
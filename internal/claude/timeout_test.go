@@ -0,0 +1,86 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutError(t *testing.T) {
+	overall := &TimeoutError{After: 30 * time.Minute}
+	assert.Equal(t, "claude session timed out after 30m0s", overall.Error())
+	assert.ErrorIs(t, overall, ErrTimeout)
+
+	idle := &TimeoutError{Idle: true, After: 10 * time.Minute}
+	assert.Equal(t, "claude session stalled: no output for 10m0s", idle.Error())
+	assert.ErrorIs(t, idle, ErrTimeout)
+
+	assert.NotErrorIs(t, errors.New("other"), ErrTimeout)
+}
+
+func TestWatchdog_NoLimits(t *testing.T) {
+	w := newWatchdog(context.Background(), RunOptions{})
+	defer w.stop()
+
+	assert.NoError(t, w.ctx.Err())
+	assert.NoError(t, w.err())
+}
+
+func TestWatchdog_Timeout(t *testing.T) {
+	w := newWatchdog(context.Background(), RunOptions{Timeout: 20 * time.Millisecond})
+	defer w.stop()
+
+	<-w.ctx.Done()
+
+	var timeoutErr *TimeoutError
+	assert.ErrorAs(t, w.err(), &timeoutErr)
+	assert.False(t, timeoutErr.Idle)
+}
+
+func TestWatchdog_IdleTimeout(t *testing.T) {
+	w := newWatchdog(context.Background(), RunOptions{IdleTimeout: 50 * time.Millisecond})
+	defer w.stop()
+
+	// Regular activity keeps the session alive past the idle limit.
+	for range 4 {
+		time.Sleep(20 * time.Millisecond)
+		w.activity()
+	}
+	assert.NoError(t, w.ctx.Err())
+
+	<-w.ctx.Done()
+
+	var timeoutErr *TimeoutError
+	assert.ErrorAs(t, w.err(), &timeoutErr)
+	assert.True(t, timeoutErr.Idle)
+}
+
+func TestWatchdog_PauseStopsIdleTimer(t *testing.T) {
+	w := newWatchdog(context.Background(), RunOptions{IdleTimeout: 30 * time.Millisecond})
+	defer w.stop()
+
+	// A paused timer lets a session wait for a follow-up past the idle limit.
+	w.pause()
+	time.Sleep(80 * time.Millisecond)
+	assert.NoError(t, w.ctx.Err())
+
+	// The next message restarts it.
+	in := activityWriter{WriteCloser: &nopWriteCloser{}, w: w}
+	_, _ = in.Write([]byte("answer\n"))
+	<-w.ctx.Done()
+	assert.ErrorIs(t, w.err(), ErrTimeout)
+}
+
+func TestWatchdog_ParentCancelIsNotTimeout(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	w := newWatchdog(parent, RunOptions{Timeout: time.Hour, IdleTimeout: time.Hour})
+	defer w.stop()
+
+	cancel()
+	<-w.ctx.Done()
+
+	assert.NoError(t, w.err())
+}
@@ -0,0 +1,252 @@
+package claude
+
+import (
+	"encoding/json"
+)
+
+// ToolInput is the typed input of a call to a known Claude Code tool, such
+// as [*BashInput] or [*EditInput]. Use a type switch to get at its fields.
+//
+// Calls to other tools, such as MCP tools, have no ToolInput; their input is
+// still available as [Event.ToolInputRaw].
+type ToolInput interface {
+	// Tool returns the name of the tool the input is for.
+	Tool() string
+}
+
+// BashInput is the input of the Bash tool.
+type BashInput struct {
+	Command         string `json:"command"`
+	Description     string `json:"description,omitempty"`
+	Timeout         int    `json:"timeout,omitempty"` // Milliseconds
+	RunInBackground bool   `json:"run_in_background,omitempty"`
+}
+
+// BashOutputInput is the input of the BashOutput tool, which reads the output
+// of a background shell.
+type BashOutputInput struct {
+	BashID string `json:"bash_id"`
+	Filter string `json:"filter,omitempty"`
+}
+
+// KillShellInput is the input of the KillShell tool, which stops a
+// background shell.
+type KillShellInput struct {
+	ShellID string `json:"shell_id"`
+}
+
+// ReadInput is the input of the Read tool. Offset and Limit select lines.
+type ReadInput struct {
+	FilePath string `json:"file_path"`
+	Offset   int    `json:"offset,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// WriteInput is the input of the Write tool.
+type WriteInput struct {
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+// EditInput is the input of the Edit tool.
+type EditInput struct {
+	FilePath   string `json:"file_path"`
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// Edit is one replacement of a [MultiEditInput].
+type Edit struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// MultiEditInput is the input of the MultiEdit tool, which applies several
+// replacements to one file in order.
+type MultiEditInput struct {
+	FilePath string `json:"file_path"`
+	Edits    []Edit `json:"edits"`
+}
+
+// NotebookEditInput is the input of the NotebookEdit tool.
+type NotebookEditInput struct {
+	NotebookPath string `json:"notebook_path"`
+	CellID       string `json:"cell_id,omitempty"`
+	NewSource    string `json:"new_source"`
+	CellType     string `json:"cell_type,omitempty"` // "code" or "markdown"
+	EditMode     string `json:"edit_mode,omitempty"` // "replace", "insert" or "delete"
+}
+
+// GlobInput is the input of the Glob tool. An empty Path is the working
+// directory.
+type GlobInput struct {
+	Pattern string `json:"pattern"`
+	Path    string `json:"path,omitempty"`
+}
+
+// GrepInput is the input of the Grep tool. An empty Path is the working
+// directory.
+type GrepInput struct {
+	Pattern         string `json:"pattern"`
+	Path            string `json:"path,omitempty"`
+	Glob            string `json:"glob,omitempty"`
+	Type            string `json:"type,omitempty"`
+	OutputMode      string `json:"output_mode,omitempty"` // "content", "files_with_matches" or "count"
+	CaseInsensitive bool   `json:"-i,omitempty"`
+	Multiline       bool   `json:"multiline,omitempty"`
+}
+
+// LSInput is the input of the LS tool.
+type LSInput struct {
+	Path   string   `json:"path"`
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// WebFetchInput is the input of the WebFetch tool. Prompt says what to
+// extract from the page.
+type WebFetchInput struct {
+	URL    string `json:"url"`
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// WebSearchInput is the input of the WebSearch tool.
+type WebSearchInput struct {
+	Query          string   `json:"query"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+	BlockedDomains []string `json:"blocked_domains,omitempty"`
+}
+
+// TaskInput is the input of the Task tool, which runs a subagent.
+type TaskInput struct {
+	Description  string `json:"description"`
+	Prompt       string `json:"prompt"`
+	SubagentType string `json:"subagent_type,omitempty"`
+}
+
+// Todo statuses of a [Todo].
+const (
+	TodoPending    = "pending"
+	TodoInProgress = "in_progress"
+	TodoCompleted  = "completed"
+)
+
+// Todo is an item of Claude's task list.
+type Todo struct {
+	Content    string `json:"content"`
+	Status     string `json:"status"` // TodoPending, TodoInProgress or TodoCompleted
+	ActiveForm string `json:"activeForm,omitempty"`
+}
+
+// TodoWriteInput is the input of the TodoWrite tool. Each call replaces the
+// whole task list.
+type TodoWriteInput struct {
+	Todos []Todo `json:"todos"`
+}
+
+// ExitPlanModeInput is the input of the ExitPlanMode tool, which presents a
+// plan for approval.
+type ExitPlanModeInput struct {
+	Plan string `json:"plan"`
+}
+
+// Todos returns the task list set by a TodoWrite call, or nil for any other
+// event. The list replaces the one set by the previous call.
+func (e Event) Todos() []Todo {
+	if in, ok := e.ToolInput.(*TodoWriteInput); ok {
+		return in.Todos
+	}
+	return nil
+}
+
+// Tool returns "Bash".
+func (*BashInput) Tool() string { return "Bash" }
+
+// Tool returns "BashOutput".
+func (*BashOutputInput) Tool() string { return "BashOutput" }
+
+// Tool returns "KillShell".
+func (*KillShellInput) Tool() string { return "KillShell" }
+
+// Tool returns "Read".
+func (*ReadInput) Tool() string { return "Read" }
+
+// Tool returns "Write".
+func (*WriteInput) Tool() string { return "Write" }
+
+// Tool returns "Edit".
+func (*EditInput) Tool() string { return "Edit" }
+
+// Tool returns "MultiEdit".
+func (*MultiEditInput) Tool() string { return "MultiEdit" }
+
+// Tool returns "NotebookEdit".
+func (*NotebookEditInput) Tool() string { return "NotebookEdit" }
+
+// Tool returns "Glob".
+func (*GlobInput) Tool() string { return "Glob" }
+
+// Tool returns "Grep".
+func (*GrepInput) Tool() string { return "Grep" }
+
+// Tool returns "LS".
+func (*LSInput) Tool() string { return "LS" }
+
+// Tool returns "WebFetch".
+func (*WebFetchInput) Tool() string { return "WebFetch" }
+
+// Tool returns "WebSearch".
+func (*WebSearchInput) Tool() string { return "WebSearch" }
+
+// Tool returns "Task".
+func (*TaskInput) Tool() string { return "Task" }
+
+// Tool returns "TodoWrite".
+func (*TodoWriteInput) Tool() string { return "TodoWrite" }
+
+// Tool returns "ExitPlanMode".
+func (*ExitPlanModeInput) Tool() string { return "ExitPlanMode" }
+
+// toolInputs creates an empty input for each known tool.
+var toolInputs = map[string]func() ToolInput{
+	"Bash":         func() ToolInput { return &BashInput{} },
+	"BashOutput":   func() ToolInput { return &BashOutputInput{} },
+	"KillShell":    func() ToolInput { return &KillShellInput{} },
+	"KillBash":     func() ToolInput { return &KillShellInput{} }, // Older name of KillShell
+	"Read":         func() ToolInput { return &ReadInput{} },
+	"Write":        func() ToolInput { return &WriteInput{} },
+	"Edit":         func() ToolInput { return &EditInput{} },
+	"MultiEdit":    func() ToolInput { return &MultiEditInput{} },
+	"NotebookEdit": func() ToolInput { return &NotebookEditInput{} },
+	"Glob":         func() ToolInput { return &GlobInput{} },
+	"Grep":         func() ToolInput { return &GrepInput{} },
+	"LS":           func() ToolInput { return &LSInput{} },
+	"WebFetch":     func() ToolInput { return &WebFetchInput{} },
+	"WebSearch":    func() ToolInput { return &WebSearchInput{} },
+	"Task":         func() ToolInput { return &TaskInput{} },
+	"TodoWrite":    func() ToolInput { return &TodoWriteInput{} },
+	"ExitPlanMode": func() ToolInput { return &ExitPlanModeInput{} },
+}
+
+// DecodeToolInput decodes the JSON input of a call to the named tool. It
+// returns the typed input, or nil if the tool is not known or the input does
+// not fit its type, and the input's fields, or nil if it is not a JSON object.
+func DecodeToolInput(name string, data json.RawMessage) (ToolInput, map[string]any) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil
+	}
+	newInput, ok := toolInputs[name]
+	if !ok {
+		return nil, raw
+	}
+	input := newInput()
+	if err := json.Unmarshal(data, input); err != nil {
+		return nil, raw
+	}
+	return input, raw
+}
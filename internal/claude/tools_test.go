@@ -0,0 +1,65 @@
+package claude
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeToolInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		tool  string
+		input string
+		want  ToolInput
+	}{
+		{"bash", "Bash", `{"command":"go test ./...","run_in_background":true}`, &BashInput{Command: "go test ./...", RunInBackground: true}},
+		{"read", "Read", `{"file_path":"main.go","offset":10,"limit":50}`, &ReadInput{FilePath: "main.go", Offset: 10, Limit: 50}},
+		{"edit", "Edit", `{"file_path":"a.go","old_string":"foo","new_string":"bar","replace_all":true}`, &EditInput{FilePath: "a.go", OldString: "foo", NewString: "bar", ReplaceAll: true}},
+		{"multi edit", "MultiEdit", `{"file_path":"a.go","edits":[{"old_string":"a","new_string":"b"},{"old_string":"c","new_string":"d"}]}`,
+			&MultiEditInput{FilePath: "a.go", Edits: []Edit{{OldString: "a", NewString: "b"}, {OldString: "c", NewString: "d"}}}},
+		{"grep", "Grep", `{"pattern":"foo","path":"internal","glob":"*.go","-i":true,"output_mode":"content"}`,
+			&GrepInput{Pattern: "foo", Path: "internal", Glob: "*.go", CaseInsensitive: true, OutputMode: "content"}},
+		{"glob", "Glob", `{"pattern":"**/*.go"}`, &GlobInput{Pattern: "**/*.go"}},
+		{"web fetch", "WebFetch", `{"url":"https://go.dev","prompt":"Summarize"}`, &WebFetchInput{URL: "https://go.dev", Prompt: "Summarize"}},
+		{"task", "Task", `{"description":"Find callers","prompt":"Find all callers","subagent_type":"Explore"}`,
+			&TaskInput{Description: "Find callers", Prompt: "Find all callers", SubagentType: "Explore"}},
+		{"todo write", "TodoWrite", `{"todos":[{"content":"Write tests","status":"in_progress","activeForm":"Writing tests"}]}`,
+			&TodoWriteInput{Todos: []Todo{{Content: "Write tests", Status: TodoInProgress, ActiveForm: "Writing tests"}}}},
+		{"older name", "KillBash", `{"shell_id":"bash_1"}`, &KillShellInput{ShellID: "bash_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, raw := DecodeToolInput(tt.tool, json.RawMessage(tt.input))
+			assert.Equal(t, tt.want, input)
+			assert.NotEmpty(t, raw)
+		})
+	}
+}
+
+func TestEvent_Todos(t *testing.T) {
+	todos := []Todo{{Content: "Write tests", Status: TodoPending}}
+
+	assert.Equal(t, todos, Event{ToolName: "TodoWrite", ToolInput: &TodoWriteInput{Todos: todos}}.Todos())
+	assert.Nil(t, Event{ToolName: "Bash", ToolInput: &BashInput{Command: "ls"}}.Todos())
+	assert.Nil(t, Event{Text: "done"}.Todos())
+}
+
+func TestDecodeToolInput_Fallback(t *testing.T) {
+	input, raw := DecodeToolInput("mcp__github__create_issue", json.RawMessage(`{"title":"Bug","labels":["p1"]}`))
+	assert.Nil(t, input, "unknown tools have no typed input")
+	assert.Equal(t, map[string]any{"title": "Bug", "labels": []any{"p1"}}, raw)
+
+	input, raw = DecodeToolInput("Read", json.RawMessage(`{"file_path":42}`))
+	assert.Nil(t, input, "input that does not fit the type")
+	assert.Equal(t, map[string]any{"file_path": 42.0}, raw)
+
+	input, raw = DecodeToolInput("Read", nil)
+	assert.Nil(t, input)
+	assert.Nil(t, raw)
+
+	input, raw = DecodeToolInput("Read", json.RawMessage(`"not an object"`))
+	assert.Nil(t, input)
+	assert.Nil(t, raw)
+}
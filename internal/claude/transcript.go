@@ -0,0 +1,275 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A transcript is the record of one session, kept in two files:
+//
+//   - NAME.jsonl holds the session's stream-json events, one per line, so it
+//     can be read back by [DefaultParser] like live Claude output.
+//   - NAME.json holds the [TranscriptInfo]: the prompt, the outcome, and when
+//     each event arrived.
+//
+// Transcripts are written by [TranscriptWriter] and read by [ReadTranscript].
+const (
+	// TranscriptExt is the extension of a transcript's event file.
+	TranscriptExt = ".jsonl"
+
+	// transcriptInfoExt is the extension of a transcript's info file.
+	transcriptInfoExt = ".json"
+)
+
+// Kinds of error recorded in [TranscriptInfo.ErrorKind].
+const (
+	ErrorKindExit    = "exit"
+	ErrorKindTimeout = "timeout"
+)
+
+// TranscriptInfo describes a recorded session.
+type TranscriptInfo struct {
+	// Prompt is the prompt the session was started with.
+	Prompt string `json:"prompt"`
+
+	// ResumeSessionID is the session that was resumed, if any.
+	ResumeSessionID string `json:"resume_session_id,omitempty"`
+
+	// Agent is the agent the session ran on, empty for Claude.
+	Agent string `json:"agent,omitempty"`
+
+	// StartedAt is when the session started.
+	StartedAt time.Time `json:"started_at"`
+
+	// DurationMS is the wall-clock duration of the session in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+
+	// ExitCode is the session's exit code.
+	ExitCode int `json:"exit_code"`
+
+	// Error is the message of the error the session ended with, if any.
+	Error string `json:"error,omitempty"`
+
+	// ErrorKind is the type of that error: [ErrorKindExit] for an
+	// [ExitError], [ErrorKindTimeout] for a [TimeoutError], and empty for
+	// any other error.
+	ErrorKind string `json:"error_kind,omitempty"`
+
+	// Stderr holds the stderr lines of an [ExitError].
+	Stderr []string `json:"stderr,omitempty"`
+
+	// TimeoutMS is the limit of a [TimeoutError] in milliseconds, and
+	// IdleTimeout its Idle field.
+	TimeoutMS   int64 `json:"timeout_ms,omitempty"`
+	IdleTimeout bool  `json:"idle_timeout,omitempty"`
+
+	// OffsetsMS holds, for each line of the event file, the milliseconds
+	// between the start of the session and the arrival of the event.
+	OffsetsMS []int64 `json:"offsets_ms"`
+}
+
+// Duration returns the session's wall-clock duration.
+func (t TranscriptInfo) Duration() time.Duration {
+	return time.Duration(t.DurationMS) * time.Millisecond
+}
+
+// Err returns the error the session ended with, rebuilt as the type it had
+// when it was recorded: a [TimeoutError], an [ExitError], or a plain error.
+// It returns nil for a session that ended without an error.
+func (t TranscriptInfo) Err() error {
+	switch {
+	case t.Error == "":
+		return nil
+	case t.ErrorKind == ErrorKindTimeout:
+		return &TimeoutError{Idle: t.IdleTimeout, After: time.Duration(t.TimeoutMS) * time.Millisecond}
+	case t.ErrorKind == ErrorKindExit:
+		return &ExitError{Code: t.ExitCode, Stderr: t.Stderr}
+	default:
+		return errors.New(t.Error)
+	}
+}
+
+// setErr records the error a session ended with.
+func (t *TranscriptInfo) setErr(err error) {
+	if err == nil {
+		return
+	}
+	t.Error = err.Error()
+
+	var timeoutErr *TimeoutError
+	var exitErr *ExitError
+	switch {
+	case errors.As(err, &timeoutErr):
+		t.ErrorKind = ErrorKindTimeout
+		t.TimeoutMS = timeoutErr.After.Milliseconds()
+		t.IdleTimeout = timeoutErr.Idle
+	case errors.As(err, &exitErr):
+		t.ErrorKind = ErrorKindExit
+		t.Stderr = exitErr.Stderr
+	}
+}
+
+// Transcript is a recorded session read by [ReadTranscript].
+type Transcript struct {
+	TranscriptInfo
+
+	// Path is the path of the event file.
+	Path string
+
+	// Lines holds the stream-json lines of the event file.
+	Lines [][]byte
+}
+
+// ReadTranscript reads the transcript whose event file is at path.
+//
+// The info file is optional; without it only Path and Lines are set and the
+// session is taken to have succeeded.
+func ReadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading transcript: %w", err)
+	}
+
+	t := &Transcript{Path: path}
+	for line := range bytes.Lines(data) {
+		if line = bytes.TrimRight(line, "\r\n"); len(bytes.TrimSpace(line)) > 0 {
+			t.Lines = append(t.Lines, line)
+		}
+	}
+
+	info, err := os.ReadFile(transcriptInfoPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading transcript info: %w", err)
+	}
+	if err := json.Unmarshal(info, &t.TranscriptInfo); err != nil {
+		return nil, fmt.Errorf("reading transcript info %s: %w", transcriptInfoPath(path), err)
+	}
+	return t, nil
+}
+
+// FindTranscripts returns the event files of the transcripts in dir, in
+// name order.
+func FindTranscripts(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+TranscriptExt))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// transcriptInfoPath returns the info file of the transcript with the given
+// event file.
+func transcriptInfoPath(path string) string {
+	return strings.TrimSuffix(path, TranscriptExt) + transcriptInfoExt
+}
+
+// TranscriptWriter records one session as a transcript.
+//
+// Pass each event of the session to [TranscriptWriter.Write], then call
+// [TranscriptWriter.Close] with the outcome. Events are written as the
+// output line they were read from ([Event.Line]), byte for byte, including
+// fields the parser ignores and lines it could not parse. Events that were
+// not read from a stream are written as their [StreamEvent]; events with
+// neither, such as read errors, are skipped.
+type TranscriptWriter struct {
+	path  string
+	file  *os.File
+	buf   *bufio.Writer
+	info  TranscriptInfo
+	start time.Time
+	last  *StreamEvent
+	err   error
+}
+
+// NewTranscriptWriter creates the event file at path, which should end in
+// [TranscriptExt], and starts recording a session with the given prompt and
+// options.
+func NewTranscriptWriter(path, prompt string, opts RunOptions) (*TranscriptWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating transcript directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating transcript: %w", err)
+	}
+
+	start := time.Now()
+	return &TranscriptWriter{
+		path:  path,
+		file:  file,
+		buf:   bufio.NewWriter(file),
+		start: start,
+		info: TranscriptInfo{
+			Prompt:          prompt,
+			ResumeSessionID: opts.ResumeSessionID,
+			Agent:           opts.Agent,
+			StartedAt:       start,
+			OffsetsMS:       []int64{},
+		},
+	}, nil
+}
+
+// Path returns the path of the event file.
+func (w *TranscriptWriter) Path() string {
+	return w.path
+}
+
+// Write records an event. Events that share a [StreamEvent], such as the
+// content blocks of one message, are written once.
+func (w *TranscriptWriter) Write(event Event) {
+	if w.err != nil || (event.Line == nil && event.Raw == nil) || (event.Raw != nil && event.Raw == w.last) {
+		return
+	}
+	w.last = event.Raw
+
+	line := event.Line
+	if line == nil {
+		var err error
+		if line, err = json.Marshal(event.Raw); err != nil {
+			w.err = fmt.Errorf("encoding event: %w", err)
+			return
+		}
+	}
+	if _, err := fmt.Fprintf(w.buf, "%s\n", line); err != nil {
+		w.err = fmt.Errorf("writing transcript: %w", err)
+		return
+	}
+	w.info.OffsetsMS = append(w.info.OffsetsMS, time.Since(w.start).Milliseconds())
+}
+
+// Close records the session's outcome and closes the transcript. It returns
+// the first error met while writing.
+func (w *TranscriptWriter) Close(exitCode int, sessionErr error) error {
+	w.info.DurationMS = time.Since(w.start).Milliseconds()
+	w.info.ExitCode = exitCode
+	w.info.setErr(sessionErr)
+
+	err := w.err
+	if flushErr := w.buf.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("writing transcript: %w", flushErr)
+	}
+	if closeErr := w.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("writing transcript: %w", closeErr)
+	}
+
+	info, jsonErr := json.MarshalIndent(w.info, "", "  ")
+	if jsonErr != nil {
+		return errors.Join(err, fmt.Errorf("encoding transcript info: %w", jsonErr))
+	}
+	if writeErr := os.WriteFile(transcriptInfoPath(w.path), append(info, '\n'), 0o644); err == nil && writeErr != nil {
+		err = fmt.Errorf("writing transcript info: %w", writeErr)
+	}
+	return err
+}
@@ -5,13 +5,25 @@
 //
 // Key types:
 //   - [Executor]: Interface for running Claude CLI commands
+//   - [Session]: A running session with its events, result, and live counters
+//   - [AgentExecutor]: Executor that runs each session on the configured coding agent
 //   - [Parser]: Interface for parsing streaming JSON output
 //   - [Event]: Parsed event with convenience methods for common checks
+//   - [ResultStats]: Usage, cost, and timing reported when a session completes
+//   - [RecordingExecutor]: Executor that records each session as a [Transcript]
+//   - [Middleware]: Executor decorators such as retries and policies, composed by [Chain]
 //
 // For testing, use [MockExecutor] which implements [Executor] without spawning
-// real processes.
+// real processes, or [ReplayExecutor] to play back recorded transcripts.
 package claude
 
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 // StreamEvent represents a raw JSON event from Claude's streaming output.
 //
 // This is the low-level structure that maps directly to Claude's stream-json format.
@@ -22,8 +34,84 @@ package claude
 type StreamEvent struct {
 	Type          string          `json:"type"`
 	Subtype       string          `json:"subtype,omitempty"`
+	SessionID     string          `json:"session_id,omitempty"`
 	Message       *MessageContent `json:"message,omitempty"`
 	ToolUseResult *ToolResult     `json:"tool_use_result,omitempty"`
+
+	// ResultStats holds the top-level statistics fields of result events.
+	// They are zero for all other event types.
+	ResultStats
+}
+
+// ResultStats holds the usage, cost, and timing statistics from a result event.
+//
+// Claude reports these once per session, in the final result event. They are
+// available on [Event.Stats] for result events.
+type ResultStats struct {
+	// TotalCostUSD is the total API cost of the session in US dollars.
+	TotalCostUSD float64 `json:"total_cost_usd,omitempty"`
+
+	// Usage is the token usage of the session.
+	Usage Usage `json:"usage"`
+
+	// NumTurns is the number of conversation turns in the session.
+	NumTurns int `json:"num_turns,omitempty"`
+
+	// DurationMS is the wall-clock duration of the session in milliseconds.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+
+	// DurationAPIMS is the time spent waiting on the API in milliseconds.
+	DurationAPIMS int64 `json:"duration_api_ms,omitempty"`
+
+	// IsError is true if the session ended in an error.
+	IsError bool `json:"is_error,omitempty"`
+
+	// Result is the final result text (or error message) of the session.
+	Result string `json:"result,omitempty"`
+}
+
+// Duration returns the session's wall-clock duration.
+func (s ResultStats) Duration() time.Duration {
+	return time.Duration(s.DurationMS) * time.Millisecond
+}
+
+// Add accumulates other into s, for totals across several sessions.
+//
+// Costs, tokens, turns, and durations are summed. IsError becomes true if
+// either side is an error; Result keeps the most recent non-empty text.
+func (s *ResultStats) Add(other ResultStats) {
+	s.TotalCostUSD += other.TotalCostUSD
+	s.Usage.InputTokens += other.Usage.InputTokens
+	s.Usage.OutputTokens += other.Usage.OutputTokens
+	s.Usage.CacheCreationInputTokens += other.Usage.CacheCreationInputTokens
+	s.Usage.CacheReadInputTokens += other.Usage.CacheReadInputTokens
+	s.NumTurns += other.NumTurns
+	s.DurationMS += other.DurationMS
+	s.DurationAPIMS += other.DurationAPIMS
+	s.IsError = s.IsError || other.IsError
+	if other.Result != "" {
+		s.Result = other.Result
+	}
+}
+
+// Usage holds token counts reported by Claude.
+type Usage struct {
+	// InputTokens is the number of uncached input tokens.
+	InputTokens int64 `json:"input_tokens"`
+
+	// OutputTokens is the number of generated output tokens.
+	OutputTokens int64 `json:"output_tokens"`
+
+	// CacheCreationInputTokens is the number of input tokens written to the prompt cache.
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+
+	// CacheReadInputTokens is the number of input tokens read from the prompt cache.
+	CacheReadInputTokens int64 `json:"cache_read_input_tokens"`
+}
+
+// TotalTokens returns the sum of all input, cache, and output tokens.
+func (u Usage) TotalTokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
 }
 
 // MessageContent represents the content of a message in Claude's streaming output.
@@ -32,38 +120,91 @@ type StreamEvent struct {
 // and/or tool invocations. This structure appears in assistant-type events
 // within [StreamEvent.Message].
 type MessageContent struct {
+	// ID identifies the API message. Claude may emit several events for
+	// one message, each repeating the same ID and usage.
+	ID      string         `json:"id,omitempty"`
 	Content []ContentBlock `json:"content,omitempty"`
+	Usage   *Usage         `json:"usage,omitempty"`
+}
+
+// UnmarshalJSON decodes a message, accepting content given either as an
+// array of blocks or as a plain string, which Claude uses for simple user
+// messages. A string becomes a single "text" block.
+func (m *MessageContent) UnmarshalJSON(data []byte) error {
+	type plain MessageContent
+	var aux struct {
+		plain
+		Content json.RawMessage `json:"content,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*m = MessageContent(aux.plain)
+
+	content := bytes.TrimSpace(aux.Content)
+	switch {
+	case len(content) == 0 || bytes.Equal(content, []byte("null")):
+		m.Content = nil
+	case content[0] == '"':
+		var text string
+		if err := json.Unmarshal(content, &text); err != nil {
+			return err
+		}
+		m.Content = []ContentBlock{{Type: "text", Text: text}}
+	default:
+		return json.Unmarshal(content, &m.Content)
+	}
+	return nil
 }
 
 // ContentBlock represents a single block of content within a [MessageContent].
 //
 // The Type field indicates the kind of content:
 //   - "text": Contains text output in the Text field
-//   - "tool_use": Contains a tool invocation with Name and Input fields
-//
-// For text blocks, only Type and Text are populated. For tool_use blocks,
-// Type, Name, and Input are populated.
+//   - "thinking": Contains Claude's extended thinking in the Thinking field
+//   - "tool_use": Contains a tool invocation with ID, Name, and Input fields;
+//     Input is the tool's JSON input, decoded by [DecodeToolInput]
+//   - "tool_result": Appears in user messages; ToolUseID names the tool_use
+//     block it answers, Content holds the output as Claude sees it, and
+//     IsError reports a failed tool call
 type ContentBlock struct {
-	Type  string     `json:"type"`
-	Text  string     `json:"text,omitempty"`
-	Name  string     `json:"name,omitempty"`
-	Input *ToolInput `json:"input,omitempty"`
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
 }
 
-// ToolInput represents the input parameters for a tool invocation.
-//
-// Different tools use different fields:
-//   - Command: Used by bash/shell tools for the command to execute
-//   - Description: Human-readable description of what the tool is doing
-//   - FilePath: Used by file operations (read, write, edit) for the target path
-//   - Content: Used by write operations for the content to write
-//
-// All fields are optional; which fields are populated depends on the specific tool.
-type ToolInput struct {
-	Command     string `json:"command,omitempty"`
-	Description string `json:"description,omitempty"`
-	FilePath    string `json:"file_path,omitempty"`
-	Content     string `json:"content,omitempty"`
+// ResultText returns the text of a tool_result block's Content, which is
+// either a string or a list of content blocks. Blocks other than text, such
+// as images, are skipped.
+func (b ContentBlock) ResultText() string {
+	content := bytes.TrimSpace(b.Content)
+	if len(content) == 0 {
+		return ""
+	}
+	if content[0] == '"' {
+		var s string
+		if json.Unmarshal(content, &s) == nil {
+			return s
+		}
+		return ""
+	}
+	var blocks []ContentBlock
+	if json.Unmarshal(content, &blocks) != nil {
+		return ""
+	}
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == "text" && block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
 }
 
 // ToolResult represents the result of a tool execution.
@@ -81,6 +222,30 @@ type ToolResult struct {
 	Interrupted bool   `json:"interrupted,omitempty"`
 }
 
+// UnmarshalJSON decodes a tool result in any of the shapes Claude emits.
+//
+// Failed tool calls report tool_use_result as a plain error string, which is
+// stored in Stderr. Other non-object shapes (such as the arrays some tools
+// return) carry no stdout/stderr and decode to the zero value rather than
+// failing the whole line.
+func (r *ToolResult) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	switch data[0] {
+	case '{':
+		type plain ToolResult
+		return json.Unmarshal(data, (*plain)(r))
+	case '"':
+		*r = ToolResult{}
+		return json.Unmarshal(data, &r.Stderr)
+	default:
+		*r = ToolResult{}
+		return nil
+	}
+}
+
 // EventType represents the type of event received from Claude's streaming output.
 //
 // Events flow through the stream in a typical order: system (init), then alternating
@@ -103,6 +268,12 @@ const (
 	// EventTypeResult indicates the session has completed.
 	// Check [Event.SessionComplete] which will be true for result events.
 	EventTypeResult EventType = "result"
+
+	// EventTypeParseError is a synthetic event emitted by [Parser] rather than
+	// Claude. It reports a line that could not be parsed, a line whose
+	// oversized fields were truncated, or a failure reading the stream.
+	// [Event.Err] holds the details.
+	EventTypeParseError EventType = "parse_error"
 )
 
 // SubtypeInit is the subtype value for system initialization events.
@@ -123,6 +294,12 @@ type Event struct {
 	// the parsed fields are insufficient.
 	Raw *StreamEvent
 
+	// Line holds the bytes of the output line the event was read from,
+	// without the newline, as set by [Parser.Parse]. Events from one line
+	// share it, and the parse error event of a malformed line carries that
+	// line. Nil for events that were not read from a stream.
+	Line []byte
+
 	// Type is the parsed event type (system, assistant, user, or result).
 	Type EventType
 
@@ -130,14 +307,44 @@ type Event struct {
 	// For system events, this may be "init" (see [SubtypeInit]).
 	Subtype string
 
+	// SessionID is the Claude session identifier carried by the event.
+	// It is always present on system init events and can be passed back to
+	// Claude via [RunOptions.ResumeSessionID] to continue the session.
+	SessionID string
+
 	// Text contains the text content when Type is [EventTypeAssistant]
 	// and the content block is of type "text". Empty otherwise.
 	Text string
 
+	// Thinking contains Claude's extended thinking when Type is
+	// [EventTypeAssistant] and the content block is of type "thinking".
+	Thinking string
+
+	// ToolUseID identifies a tool call. It is set on tool_use events and on
+	// the tool result events that answer them, so the two can be paired.
+	ToolUseID string
+
+	// MessageID identifies the API message an assistant event belongs to.
+	// Several events may share one MessageID.
+	MessageID string
+
+	// Usage is the token usage reported for the API message of an assistant
+	// event, if any. Events sharing a MessageID repeat the same usage, so
+	// deduplicate by MessageID when summing.
+	Usage *Usage
+
 	// ToolName is the name of the tool being invoked when Type is
 	// [EventTypeAssistant] and the content block is of type "tool_use".
 	ToolName string
 
+	// ToolInput is the typed input of a tool_use event for a known tool,
+	// such as [*GrepInput] for Grep, and nil for other tools.
+	ToolInput ToolInput
+
+	// ToolInputRaw holds every field of a tool_use event's input, for any
+	// tool, as decoded from JSON.
+	ToolInputRaw map[string]any
+
 	// ToolDescription is a human-readable description of what the tool
 	// is doing. Populated for tool_use events.
 	ToolDescription string
@@ -158,6 +365,9 @@ type Event struct {
 	// ToolInterrupted indicates whether tool execution was interrupted.
 	ToolInterrupted bool
 
+	// ToolIsError indicates the tool call failed.
+	ToolIsError bool
+
 	// SessionStarted is true for system init events, indicating the
 	// Claude session has begun.
 	SessionStarted bool
@@ -165,55 +375,123 @@ type Event struct {
 	// SessionComplete is true for result events, indicating the
 	// Claude session has finished.
 	SessionComplete bool
+
+	// Stats holds the usage, cost, and timing statistics of the session.
+	// Populated only for result events; nil otherwise.
+	Stats *ResultStats
+
+	// Err describes the problem reported by an [EventTypeParseError] event.
+	// Nil for all other event types.
+	Err error
 }
 
-// NewEventFromStream creates an [Event] from a raw [StreamEvent].
+// NewEventsFromStream creates the [Event] values for a raw [StreamEvent].
 //
-// This function parses the StreamEvent and extracts relevant fields into the
-// Event's convenience properties based on the event type. It handles all event
-// types (system, assistant, user, result) and populates the appropriate fields.
-func NewEventFromStream(raw *StreamEvent) Event {
-	e := Event{
-		Raw:     raw,
-		Type:    EventType(raw.Type),
-		Subtype: raw.Subtype,
+// One stream line may carry several content blocks: an assistant message can
+// hold text, thinking, and any number of tool_use blocks, and a user message
+// can hold several tool_result blocks. NewEventsFromStream returns one Event
+// per block, in order, so none are lost. Events from the same message share
+// Raw, MessageID, and Usage. Lines without content blocks produce exactly one
+// Event, so the result is never empty.
+func NewEventsFromStream(raw *StreamEvent) []Event {
+	base := Event{
+		Raw:       raw,
+		Type:      EventType(raw.Type),
+		Subtype:   raw.Subtype,
+		SessionID: raw.SessionID,
 	}
 
-	switch e.Type {
+	switch base.Type {
 	case EventTypeSystem:
 		if raw.Subtype == SubtypeInit {
-			e.SessionStarted = true
+			base.SessionStarted = true
 		}
 
 	case EventTypeAssistant:
+		if raw.Message == nil {
+			break
+		}
+		base.MessageID = raw.Message.ID
+		base.Usage = raw.Message.Usage
+		if len(raw.Message.Content) == 0 {
+			break
+		}
+
+		events := make([]Event, 0, len(raw.Message.Content))
+		for _, block := range raw.Message.Content {
+			e := base
+			switch block.Type {
+			case "text":
+				e.Text = block.Text
+			case "thinking":
+				e.Thinking = block.Thinking
+			case "tool_use":
+				e.ToolUseID = block.ID
+				e.ToolName = block.Name
+				e.ToolInput, e.ToolInputRaw = DecodeToolInput(block.Name, block.Input)
+				e.ToolDescription, _ = e.ToolInputRaw["description"].(string)
+				e.ToolCommand, _ = e.ToolInputRaw["command"].(string)
+				e.ToolFilePath, _ = e.ToolInputRaw["file_path"].(string)
+			}
+			events = append(events, e)
+		}
+		return events
+
+	case EventTypeUser:
+		var results []ContentBlock
 		if raw.Message != nil {
 			for _, block := range raw.Message.Content {
-				switch block.Type {
-				case "text":
-					e.Text = block.Text
-				case "tool_use":
-					e.ToolName = block.Name
-					if block.Input != nil {
-						e.ToolDescription = block.Input.Description
-						e.ToolCommand = block.Input.Command
-						e.ToolFilePath = block.Input.FilePath
-					}
+				if block.Type == "tool_result" {
+					results = append(results, block)
 				}
 			}
 		}
+		if len(results) == 0 {
+			if raw.ToolUseResult != nil {
+				base.ToolStdout = raw.ToolUseResult.Stdout
+				base.ToolStderr = raw.ToolUseResult.Stderr
+				base.ToolInterrupted = raw.ToolUseResult.Interrupted
+			}
+			break
+		}
 
-	case EventTypeUser:
-		if raw.ToolUseResult != nil {
-			e.ToolStdout = raw.ToolUseResult.Stdout
-			e.ToolStderr = raw.ToolUseResult.Stderr
-			e.ToolInterrupted = raw.ToolUseResult.Interrupted
+		events := make([]Event, 0, len(results))
+		for _, block := range results {
+			e := base
+			e.ToolUseID = block.ToolUseID
+			e.ToolIsError = block.IsError
+			if text := block.ResultText(); block.IsError {
+				e.ToolStderr = text
+			} else {
+				e.ToolStdout = text
+			}
+			// The top-level tool_use_result describes a single tool call and
+			// separates stdout from stderr; prefer it when it is unambiguous.
+			if r := raw.ToolUseResult; r != nil && len(results) == 1 {
+				if r.Stdout != "" || r.Stderr != "" {
+					e.ToolStdout, e.ToolStderr = r.Stdout, r.Stderr
+				}
+				e.ToolInterrupted = r.Interrupted
+			}
+			events = append(events, e)
 		}
+		return events
 
 	case EventTypeResult:
-		e.SessionComplete = true
+		base.SessionComplete = true
+		stats := raw.ResultStats
+		base.Stats = &stats
 	}
 
-	return e
+	return []Event{base}
+}
+
+// NewEventFromStream creates an [Event] from a raw [StreamEvent].
+//
+// Deprecated: A stream line may carry several content blocks, and this
+// function returns only the first. Use [NewEventsFromStream] instead.
+func NewEventFromStream(raw *StreamEvent) Event {
+	return NewEventsFromStream(raw)[0]
 }
 
 // IsText returns true if this event contains text content from Claude.
@@ -234,11 +512,26 @@ func (e Event) IsToolUse() bool {
 	return e.Type == EventTypeAssistant && e.ToolName != ""
 }
 
-// IsToolResult returns true if this event contains output from a tool execution.
+// IsThinking returns true if this event contains Claude's extended thinking.
+func (e Event) IsThinking() bool {
+	return e.Type == EventTypeAssistant && e.Thinking != ""
+}
+
+// IsParseError returns true if this event reports a problem reading
+// Claude's output rather than output from Claude itself.
+//
+// Parse errors are warnings: the stream continues after them unless the
+// underlying read failed.
+func (e Event) IsParseError() bool {
+	return e.Type == EventTypeParseError
+}
+
+// IsToolResult returns true if this event is the result of a tool execution.
 //
-// Use this method to detect tool execution results. When true, ToolStdout
-// and/or ToolStderr will contain the tool's output. Check ToolInterrupted
-// to determine if the tool was interrupted before completion.
+// Use this method to detect tool execution results. Every tool_result block
+// is a result, even one with no output; ToolUseID names the call it answers.
+// ToolStdout and/or ToolStderr hold the tool's output, if any. Check
+// ToolInterrupted to determine if the tool was interrupted before completion.
 func (e Event) IsToolResult() bool {
-	return e.Type == EventTypeUser && (e.ToolStdout != "" || e.ToolStderr != "")
+	return e.Type == EventTypeUser && (e.ToolUseID != "" || e.ToolStdout != "" || e.ToolStderr != "")
 }
@@ -1,9 +1,12 @@
 package claude
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewEventFromStream_SystemInit(t *testing.T) {
@@ -20,6 +23,14 @@ func TestNewEventFromStream_SystemInit(t *testing.T) {
 	assert.False(t, event.SessionComplete)
 }
 
+func TestNewEventFromStream_SessionID(t *testing.T) {
+	event, err := ParseSingle(`{"type":"system","subtype":"init","session_id":"abc-123"}`)
+
+	assert.NoError(t, err)
+	assert.True(t, event.SessionStarted)
+	assert.Equal(t, "abc-123", event.SessionID)
+}
+
 func TestNewEventFromStream_AssistantText(t *testing.T) {
 	raw := &StreamEvent{
 		Type: "assistant",
@@ -47,12 +58,9 @@ func TestNewEventFromStream_AssistantToolUse(t *testing.T) {
 		Message: &MessageContent{
 			Content: []ContentBlock{
 				{
-					Type: "tool_use",
-					Name: "Bash",
-					Input: &ToolInput{
-						Command:     "ls -la",
-						Description: "List files",
-					},
+					Type:  "tool_use",
+					Name:  "Bash",
+					Input: json.RawMessage(`{"command":"ls -la","description":"List files","timeout":5000}`),
 				},
 			},
 		},
@@ -64,6 +72,8 @@ func TestNewEventFromStream_AssistantToolUse(t *testing.T) {
 	assert.Equal(t, "Bash", event.ToolName)
 	assert.Equal(t, "ls -la", event.ToolCommand)
 	assert.Equal(t, "List files", event.ToolDescription)
+	assert.Equal(t, &BashInput{Command: "ls -la", Description: "List files", Timeout: 5000}, event.ToolInput)
+	assert.Equal(t, map[string]any{"command": "ls -la", "description": "List files", "timeout": 5000.0}, event.ToolInputRaw)
 	assert.True(t, event.IsToolUse())
 	assert.False(t, event.IsText())
 }
@@ -155,6 +165,148 @@ func TestEvent_IsToolUse(t *testing.T) {
 	}
 }
 
+func TestNewEventFromStream_ResultStats(t *testing.T) {
+	line := `{"type":"result","subtype":"success","is_error":false,"duration_ms":12500,"duration_api_ms":9800,"num_turns":7,"result":"Done.","session_id":"sess-1","total_cost_usd":0.4213,"usage":{"input_tokens":120,"cache_creation_input_tokens":2000,"cache_read_input_tokens":30000,"output_tokens":850}}`
+
+	event, err := ParseSingle(line)
+
+	assert.NoError(t, err)
+	assert.True(t, event.SessionComplete)
+	if assert.NotNil(t, event.Stats) {
+		assert.InDelta(t, 0.4213, event.Stats.TotalCostUSD, 1e-9)
+		assert.Equal(t, Usage{InputTokens: 120, OutputTokens: 850, CacheCreationInputTokens: 2000, CacheReadInputTokens: 30000}, event.Stats.Usage)
+		assert.Equal(t, int64(32970), event.Stats.Usage.TotalTokens())
+		assert.Equal(t, 7, event.Stats.NumTurns)
+		assert.Equal(t, 12500*time.Millisecond, event.Stats.Duration())
+		assert.Equal(t, int64(9800), event.Stats.DurationAPIMS)
+		assert.False(t, event.Stats.IsError)
+		assert.Equal(t, "Done.", event.Stats.Result)
+	}
+}
+
+func TestNewEventFromStream_MessageUsage(t *testing.T) {
+	event, err := ParseSingle(`{"type":"assistant","message":{"id":"msg_1","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":5,"output_tokens":7,"cache_read_input_tokens":100}}}`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "msg_1", event.MessageID)
+	if assert.NotNil(t, event.Usage) {
+		assert.Equal(t, int64(112), event.Usage.TotalTokens())
+	}
+}
+
+func TestNewEventFromStream_StatsOnlyOnResult(t *testing.T) {
+	event, err := ParseSingle(`{"type":"assistant","message":{"content":[{"type":"text","text":"hi"}]}}`)
+
+	assert.NoError(t, err)
+	assert.Nil(t, event.Stats)
+}
+
+func TestResultStats_Add(t *testing.T) {
+	total := ResultStats{TotalCostUSD: 0.5, Usage: Usage{InputTokens: 10, OutputTokens: 5}, NumTurns: 2, DurationMS: 1000, Result: "first"}
+	total.Add(ResultStats{TotalCostUSD: 0.25, Usage: Usage{InputTokens: 1, CacheReadInputTokens: 100}, NumTurns: 3, DurationMS: 500, IsError: true})
+
+	assert.InDelta(t, 0.75, total.TotalCostUSD, 1e-9)
+	assert.Equal(t, Usage{InputTokens: 11, OutputTokens: 5, CacheReadInputTokens: 100}, total.Usage)
+	assert.Equal(t, 5, total.NumTurns)
+	assert.Equal(t, int64(1500), total.DurationMS)
+	assert.True(t, total.IsError)
+	assert.Equal(t, "first", total.Result, "empty result text does not overwrite")
+}
+
+func TestNewEventsFromStream_MultipleBlocks(t *testing.T) {
+	line := `{"type":"assistant","message":{"id":"msg_1","content":[` +
+		`{"type":"thinking","thinking":"Two files to read."},` +
+		`{"type":"text","text":"Reading both files."},` +
+		`{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"a.go"}},` +
+		`{"type":"tool_use","id":"toolu_2","name":"Read","input":{"file_path":"b.go"}}` +
+		`],"usage":{"input_tokens":10,"output_tokens":20}}}`
+
+	events, err := ParseLine(line)
+
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	assert.True(t, events[0].IsThinking())
+	assert.Equal(t, "Two files to read.", events[0].Thinking)
+	assert.False(t, events[0].IsText())
+
+	assert.True(t, events[1].IsText())
+	assert.Equal(t, "Reading both files.", events[1].Text)
+	assert.False(t, events[1].IsToolUse())
+
+	assert.True(t, events[2].IsToolUse())
+	assert.Equal(t, "toolu_1", events[2].ToolUseID)
+	assert.Equal(t, "a.go", events[2].ToolFilePath)
+	assert.Empty(t, events[2].Text)
+
+	assert.True(t, events[3].IsToolUse())
+	assert.Equal(t, "toolu_2", events[3].ToolUseID)
+	assert.Equal(t, "b.go", events[3].ToolFilePath)
+
+	for _, e := range events {
+		assert.Equal(t, "msg_1", e.MessageID)
+		assert.Equal(t, int64(30), e.Usage.TotalTokens())
+	}
+}
+
+func TestNewEventsFromStream_ToolResultIDs(t *testing.T) {
+	line := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"ok"}]},` +
+		`"tool_use_result":{"stdout":"ok","stderr":""}}`
+
+	events, err := ParseLine(line)
+
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "toolu_1", events[0].ToolUseID)
+	assert.Equal(t, "ok", events[0].ToolStdout)
+	assert.False(t, events[0].ToolIsError)
+	assert.True(t, events[0].IsToolResult())
+}
+
+func TestNewEventsFromStream_SeveralToolResults(t *testing.T) {
+	line := `{"type":"user","message":{"content":[` +
+		`{"type":"tool_result","tool_use_id":"toolu_1","content":"ok"},` +
+		`{"type":"tool_result","tool_use_id":"toolu_2","content":"boom","is_error":true},` +
+		`{"type":"tool_result","tool_use_id":"toolu_3","content":[{"type":"text","text":"line 1"},{"type":"image"},{"type":"text","text":"line 2"}]},` +
+		`{"type":"tool_result","tool_use_id":"toolu_4"}` +
+		`]},"tool_use_result":{"stdout":"first"}}`
+
+	events, err := ParseLine(line)
+
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, "toolu_1", events[0].ToolUseID)
+	assert.Equal(t, "ok", events[0].ToolStdout, "each result has its own content")
+	assert.Equal(t, "toolu_2", events[1].ToolUseID)
+	assert.True(t, events[1].ToolIsError)
+	assert.Equal(t, "boom", events[1].ToolStderr)
+	assert.Empty(t, events[1].ToolStdout)
+	assert.Equal(t, "line 1\nline 2", events[2].ToolStdout)
+	assert.Equal(t, "toolu_4", events[3].ToolUseID)
+	assert.Empty(t, events[3].ToolStdout)
+	for _, e := range events {
+		assert.True(t, e.IsToolResult(), "%s is a result", e.ToolUseID)
+	}
+}
+
+func TestNewEventsFromStream_ToolResultContentFallback(t *testing.T) {
+	line := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_1",` +
+		`"content":[{"type":"text","text":"     1\tpackage main"}]}]},"tool_use_result":{"type":"text","file":{"filePath":"main.go"}}}`
+
+	events, err := ParseLine(line)
+
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "     1\tpackage main", events[0].ToolStdout, "content is used when tool_use_result has no output")
+}
+
+func TestNewEventsFromStream_NoContentYieldsOneEvent(t *testing.T) {
+	events := NewEventsFromStream(&StreamEvent{Type: "assistant", Message: &MessageContent{ID: "msg_1"}})
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "msg_1", events[0].MessageID)
+}
+
 func TestEvent_IsToolResult(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -172,7 +324,12 @@ func TestEvent_IsToolResult(t *testing.T) {
 			expected: true,
 		},
 		{
-			name:     "empty result",
+			name:     "result without output",
+			event:    Event{Type: EventTypeUser, ToolUseID: "toolu_1"},
+			expected: true,
+		},
+		{
+			name:     "user message without result",
 			event:    Event{Type: EventTypeUser},
 			expected: false,
 		},
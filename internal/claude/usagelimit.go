@@ -0,0 +1,132 @@
+package claude
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UsageLimitError reports a session stopped because the Claude subscription
+// reached its usage limit.
+//
+// ResetAt is when the limit resets, parsed from Claude's message; it is zero
+// if the message did not say. Err is the underlying failure.
+type UsageLimitError struct {
+	ResetAt time.Time
+	Err     error
+}
+
+// Error implements the error interface.
+func (e *UsageLimitError) Error() string {
+	msg := "claude usage limit reached"
+	if !e.ResetAt.IsZero() {
+		msg += "; resets at " + e.ResetAt.Format("2006-01-02 15:04 MST")
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying failure.
+func (e *UsageLimitError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// usageLimitPattern recognizes Claude's usage-limit messages, e.g.
+	// "Claude AI usage limit reached|1760000000" or
+	// "5-hour limit reached ∙ resets 3pm".
+	usageLimitPattern = regexp.MustCompile(`(?i)usage limit|(5-hour|weekly|session|opus)[a-z ]* limit reached`)
+
+	// usageLimitEpochPattern matches the reset time as Unix seconds after "|".
+	usageLimitEpochPattern = regexp.MustCompile(`(?i)limit reached\|(\d{10})\b`)
+
+	// usageLimitClockPattern matches a wall-clock reset time such as
+	// "resets 3pm", "reset at 3:30 pm (Europe/Paris)" or "resets 15:00".
+	usageLimitClockPattern = regexp.MustCompile(`(?i)resets?\s+(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)?(?:\s*\(([^)]+)\))?`)
+)
+
+// NewUsageLimitError returns a [UsageLimitError] wrapping err if err reports a
+// usage limit, or nil otherwise.
+//
+// Wall-clock reset times such as "resets 3pm" are taken as the next such
+// time after now, in the time zone named in the message if there is one and
+// in now's location otherwise.
+func NewUsageLimitError(err error, now time.Time) *UsageLimitError {
+	var usageErr *UsageLimitError
+	if errors.As(err, &usageErr) {
+		return usageErr
+	}
+
+	text := failureText(err)
+	if text == "" || !usageLimitPattern.MatchString(text) {
+		return nil
+	}
+	return &UsageLimitError{ResetAt: parseUsageLimitReset(text, now), Err: err}
+}
+
+// parseUsageLimitReset returns the reset time named in a usage-limit message,
+// or the zero time if it names none.
+func parseUsageLimitReset(text string, now time.Time) time.Time {
+	if m := usageLimitEpochPattern.FindStringSubmatch(text); m != nil {
+		secs, err := strconv.ParseInt(m[1], 10, 64)
+		if err == nil {
+			return time.Unix(secs, 0).In(now.Location())
+		}
+	}
+
+	m := usageLimitClockPattern.FindStringSubmatch(text)
+	if m == nil || (m[2] == "" && m[3] == "") {
+		return time.Time{} // a bare number is not a clock time
+	}
+
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	switch strings.ToLower(m[3]) {
+	case "am":
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 12 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return time.Time{}
+	}
+
+	loc := now.Location()
+	if m[4] != "" {
+		if named, err := time.LoadLocation(strings.TrimSpace(m[4])); err == nil {
+			loc = named
+		}
+	}
+
+	local := now.In(loc)
+	reset := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !reset.After(local) {
+		reset = reset.AddDate(0, 0, 1)
+	}
+	return reset
+}
+
+// failureText returns the text of err together with any stderr lines of an
+// [ExitError] in its chain.
+func failureText(err error) string {
+	if err == nil {
+		return ""
+	}
+	text := []string{err.Error()}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		text = append(text, exitErr.Stderr...)
+	}
+	return strings.Join(text, "\n")
+}
@@ -0,0 +1,70 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUsageLimitError(t *testing.T) {
+	utc := time.Date(2025, 10, 9, 13, 20, 0, 0, time.UTC)
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		err  error
+		want time.Time
+	}{
+		{"epoch", &ResultError{Message: "Claude AI usage limit reached|1760025600"}, time.Unix(1760025600, 0)},
+		{"clock later today", &ExitError{Code: 1, Stderr: []string{"5-hour limit reached ∙ resets 3pm"}},
+			time.Date(2025, 10, 9, 15, 0, 0, 0, time.UTC)},
+		{"clock tomorrow", &ResultError{Message: "Claude usage limit reached. Your limit will reset at 9:30 am"},
+			time.Date(2025, 10, 10, 9, 30, 0, 0, time.UTC)},
+		{"24-hour clock", &ResultError{Message: "usage limit reached, resets 18:45"},
+			time.Date(2025, 10, 9, 18, 45, 0, 0, time.UTC)},
+		{"named zone", &ResultError{Message: "Weekly limit reached ∙ resets 4pm (Europe/Paris)"},
+			time.Date(2025, 10, 9, 16, 0, 0, 0, paris)},
+		{"no reset time", &ResultError{Message: "Claude usage limit reached"}, time.Time{}},
+		{"bare number", &ResultError{Message: "usage limit reached, resets 3"}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usageErr := NewUsageLimitError(tt.err, utc)
+			require.NotNil(t, usageErr)
+			assert.True(t, tt.want.Equal(usageErr.ResetAt), "got %v, want %v", usageErr.ResetAt, tt.want)
+			assert.ErrorIs(t, usageErr, tt.err)
+		})
+	}
+}
+
+func TestNewUsageLimitError_Other(t *testing.T) {
+	now := time.Now()
+	assert.Nil(t, NewUsageLimitError(nil, now))
+	assert.Nil(t, NewUsageLimitError(&ResultError{Message: "API Error: 429 rate_limit_error"}, now))
+
+	existing := &UsageLimitError{ResetAt: now}
+	assert.Same(t, existing, NewUsageLimitError(fmt.Errorf("step: %w", existing), now.Add(time.Hour)),
+		"an error already in the chain is returned as is")
+}
+
+func TestUsageLimitError_Error(t *testing.T) {
+	err := &UsageLimitError{
+		ResetAt: time.Date(2025, 10, 9, 15, 0, 0, 0, time.UTC),
+		Err:     errors.New("claude exited with code 1"),
+	}
+	assert.Equal(t, "claude usage limit reached; resets at 2025-10-09 15:00 UTC: claude exited with code 1", err.Error())
+	assert.Equal(t, "claude usage limit reached", (&UsageLimitError{}).Error())
+}
+
+func TestClassify_UsageLimit(t *testing.T) {
+	assert.Equal(t, FailureUsageLimit, Classify(&UsageLimitError{}))
+	assert.Equal(t, FailureUsageLimit, Classify(&ResultError{Message: "Claude AI usage limit reached|1760025600"}))
+	assert.Equal(t, FailureUsageLimit, Classify(&ExitError{Code: 1, Stderr: []string{"5-hour limit reached ∙ resets 3pm"}}),
+		"usage limits are not mistaken for rate limits")
+}
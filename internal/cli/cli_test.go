@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"bmad-automate/internal/api"
 	"bmad-automate/internal/claude"
 	"bmad-automate/internal/config"
 	"bmad-automate/internal/output"
@@ -51,6 +52,24 @@ func TestNewApp(t *testing.T) {
 	assert.Equal(t, cfg, app.Config)
 }
 
+func TestNewApp_Agents(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents = map[string]config.AgentConfig{
+		"codex": {Command: "codex", Args: []string{"exec", "{{.Prompt}}"}},
+	}
+	app := NewApp(cfg)
+
+	assert.IsType(t, &claude.AgentExecutor{}, app.Executor)
+}
+
+func TestNewApp_APIBackend(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Claude.Backend = "api"
+	app := NewApp(cfg)
+
+	assert.IsType(t, &api.Executor{}, app.Executor)
+}
+
 func TestNewRootCommand(t *testing.T) {
 	app := setupTestApp()
 	rootCmd := NewRootCommand(app)
@@ -72,6 +91,8 @@ func TestNewRootCommand_HasAllSubcommands(t *testing.T) {
 		"run",
 		"queue",
 		"raw",
+		"replay",
+		"runs",
 	}
 
 	commands := rootCmd.Commands()
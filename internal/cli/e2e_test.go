@@ -0,0 +1,456 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/archive"
+	"bmad-automate/internal/config"
+	"bmad-automate/internal/fakeclaude"
+	"bmad-automate/internal/output"
+	"bmad-automate/internal/state"
+	"bmad-automate/internal/status"
+	"bmad-automate/internal/workflow"
+)
+
+// The end-to-end tests run whole commands against the fake-claude binary
+// from cmd/fake-claude, selected with BMAD_CLAUDE_PATH as a user would.
+
+var (
+	fakeClaudeOnce sync.Once
+	fakeClaudePath string
+	fakeClaudeErr  error
+)
+
+// buildFakeClaude builds cmd/fake-claude once per test binary.
+func buildFakeClaude(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("end-to-end test skipped in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found")
+	}
+
+	fakeClaudeOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "fake-claude")
+		if err != nil {
+			fakeClaudeErr = err
+			return
+		}
+		fakeClaudePath = filepath.Join(dir, "fake-claude")
+		out, err := exec.Command(goBin, "build", "-o", fakeClaudePath, "bmad-automate/cmd/fake-claude").CombinedOutput()
+		if err != nil {
+			fakeClaudeErr = fmt.Errorf("%w: %s", err, out)
+		}
+	})
+	require.NoError(t, fakeClaudeErr, "building fake-claude")
+	return fakeClaudePath
+}
+
+// e2eProject is a project directory driven by fake-claude.
+type e2eProject struct {
+	dir string
+	cfg *config.Config
+
+	// responder is set on the runner when not nil.
+	responder workflow.Responder
+}
+
+// newE2EProject makes a temporary project with the given sprint status and
+// scenario the working directory, and loads the configuration from the
+// environment with fake-claude as the Claude binary.
+func newE2EProject(t *testing.T, statusYAML, scenario string) *e2eProject {
+	t.Helper()
+	binary := buildFakeClaude(t)
+
+	dir := t.TempDir()
+	statusPath := filepath.Join(dir, status.DefaultStatusPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(statusPath), 0o755))
+	require.NoError(t, os.WriteFile(statusPath, []byte(statusYAML), 0o644))
+	scenarioPath := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(scenario), 0o644))
+
+	t.Chdir(dir)
+	t.Setenv("BMAD_CONFIG_PATH", "")
+	t.Setenv("BMAD_CLAUDE_PATH", binary)
+	t.Setenv(fakeclaude.EnvScenario, scenarioPath)
+	t.Setenv(fakeclaude.EnvState, "")
+	t.Setenv(fakeclaude.EnvLog, filepath.Join(dir, "invocations.jsonl"))
+
+	cfg, err := config.NewLoader().Load()
+	require.NoError(t, err)
+	require.Equal(t, binary, cfg.Claude.BinaryPath)
+	cfg.Retry.InitialBackoff = time.Millisecond
+	cfg.Retry.MaxBackoff = time.Millisecond
+
+	return &e2eProject{dir: dir, cfg: cfg}
+}
+
+// run runs bmad-automate with the given arguments and returns its exit code
+// and output.
+func (p *e2eProject) run(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	var out bytes.Buffer
+	app := NewApp(p.cfg)
+	printer := output.NewPrinterWithWriter(&out)
+	app.Printer = printer
+	runner := newRunner(p.cfg, app.Executor, printer)
+	if p.responder != nil {
+		runner.SetResponder(p.responder)
+	}
+	app.Runner = runner
+
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(args)
+
+	err := rootCmd.ExecuteContext(context.Background())
+	if err == nil {
+		return 0, out.String()
+	}
+	code, ok := IsExitError(err)
+	if !ok {
+		code = 1
+	}
+	return code, out.String()
+}
+
+// prompts returns the prompts fake-claude was run with, in order.
+func (p *e2eProject) prompts(t *testing.T) []string {
+	t.Helper()
+	invocations, err := fakeclaude.ReadLog(filepath.Join(p.dir, "invocations.jsonl"))
+	require.NoError(t, err)
+	prompts := make([]string, len(invocations))
+	for i, inv := range invocations {
+		prompts[i] = inv.Prompt
+	}
+	return prompts
+}
+
+// storyStatus returns the story's status in sprint-status.yaml.
+func (p *e2eProject) storyStatus(t *testing.T, storyKey string) status.Status {
+	t.Helper()
+	got, err := status.NewReader(p.dir).GetStoryStatus(storyKey)
+	require.NoError(t, err)
+	return got
+}
+
+// lifecyclePrompts returns the prompts of the full lifecycle of a backlog story.
+func lifecyclePrompts(cfg *config.Config, storyKey string) []string {
+	var prompts []string
+	for _, name := range []string{"create-story", "dev-story", "code-review", "git-commit"} {
+		prompt, err := cfg.GetPrompt(name, storyKey)
+		if err != nil {
+			panic(err)
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts
+}
+
+// successScenario answers every prompt with a short successful session.
+// Prompts without a story key are those of resumed sessions.
+const successScenario = `
+sessions:
+  - match: "(\\d+-\\d+-[\\w-]+)"
+    events:
+      - text: "Working on $1"
+      - tool: Bash
+        command: go test ./...
+        stdout: ok
+    result: {text: "$1 done", cost_usd: 0.01}
+  - events: [{text: "Picking up where I left off"}]
+`
+
+func TestE2E_Run(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: backlog\n", successScenario)
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	require.Equal(t, 0, code, out)
+	assert.Contains(t, out, "Working on 1-1-setup")
+	assert.Equal(t, lifecyclePrompts(p.cfg, "1-1-setup"), p.prompts(t))
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "1-1-setup"))
+	assert.NoFileExists(t, filepath.Join(p.dir, state.StateFileName), "progress is cleared on success")
+
+	runs, err := archive.New(archive.Config{Dir: p.cfg.Output.Archive.Dir}).List()
+	require.NoError(t, err)
+	require.Len(t, runs, 4, "every session is archived")
+	for i, workflow := range []string{"create-story", "dev-story", "code-review", "git-commit"} {
+		assert.Equal(t, workflow, runs[i].Workflow)
+		assert.Equal(t, "1-1-setup", runs[i].StoryKey)
+		assert.True(t, runs[i].Success)
+		assert.Equal(t, "fake-session-"+strconv.Itoa(i+1), runs[i].SessionID)
+	}
+}
+
+func TestE2E_Queue(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: backlog\n  1-2-api: review\n", successScenario)
+
+	code, out := p.run(t, "queue", "1-1-setup", "1-2-api")
+
+	require.Equal(t, 0, code, out)
+	want := append(lifecyclePrompts(p.cfg, "1-1-setup"), lifecyclePrompts(p.cfg, "1-2-api")[2:]...)
+	assert.Equal(t, want, p.prompts(t))
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "1-1-setup"))
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "1-2-api"))
+}
+
+func TestE2E_Epic(t *testing.T) {
+	p := newE2EProject(t, `development_status:
+  2-2-api: ready-for-dev
+  2-1-setup: done
+  3-1-other: backlog
+`, successScenario)
+
+	code, out := p.run(t, "epic", "2")
+
+	require.Equal(t, 0, code, out)
+	assert.Equal(t, lifecyclePrompts(p.cfg, "2-2-api")[1:], p.prompts(t), "done and other epics' stories are skipped")
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "2-2-api"))
+	assert.Equal(t, status.StatusBacklog, p.storyStatus(t, "3-1-other"))
+}
+
+func TestE2E_RetriesOverloadedStep(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: review\n", `
+sessions:
+  - match: code-review
+    times: 1
+    result: {text: "API Error: 529 {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}", is_error: true}
+    stderr: ["API Error: 529 overloaded_error"]
+    exit_code: 1
+`+successScenario[len("\nsessions:\n"):])
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	require.Equal(t, 0, code, out)
+	assert.Contains(t, out, "code-review failed (overloaded) on attempt 1")
+	invocations, err := fakeclaude.ReadLog(filepath.Join(p.dir, "invocations.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, invocations, 3)
+	assert.Equal(t, "fake-session-1", invocations[1].ResumeSessionID, "the retry resumes the failed session")
+	assert.Equal(t, p.cfg.Claude.ResumePrompt, invocations[1].Prompt)
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "1-1-setup"))
+}
+
+func TestE2E_FailureSavesProgress(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: ready-for-dev\n", `
+sessions:
+  - match: code-review
+    events: [{text: "Found a problem"}]
+    result: {text: "review could not complete", is_error: true}
+    stderr: ["fatal: review failed"]
+    exit_code: 3
+`+successScenario[len("\nsessions:\n"):])
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	assert.NotEqual(t, 0, code)
+	assert.Len(t, p.prompts(t), 2, "the run stops at the failed step")
+	runs, err := archive.New(archive.Config{Dir: p.cfg.Output.Archive.Dir}).List()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	stderr, err := os.ReadFile(runs[1].Path(archive.StderrFile))
+	require.NoError(t, err)
+	assert.Equal(t, "fatal: review failed\n", string(stderr))
+	assert.False(t, runs[1].Success)
+	assert.Contains(t, runs[1].Error, "review could not complete")
+
+	assert.Equal(t, status.StatusReview, p.storyStatus(t, "1-1-setup"), out)
+
+	saved, err := state.NewManager(p.dir).Load()
+	require.NoError(t, err)
+	assert.Equal(t, "1-1-setup", saved.StoryKey)
+	assert.Equal(t, 1, saved.StepIndex)
+
+	// With the failure gone, --resume continues at the failed step.
+	require.NoError(t, os.WriteFile(os.Getenv(fakeclaude.EnvScenario), []byte(successScenario), 0o644))
+	code, out = p.run(t, "run", "--resume")
+	require.Equal(t, 0, code, out)
+	assert.Len(t, p.prompts(t), 4)
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "1-1-setup"))
+}
+
+func TestE2E_IdleTimeout(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: ready-for-dev\n", `
+sessions:
+  - events: [{text: "Starting"}]
+    hang: 1h
+`)
+	wf := p.cfg.Workflows["dev-story"]
+	wf.IdleTimeout = 200 * time.Millisecond
+	p.cfg.Workflows["dev-story"] = wf
+
+	start := time.Now()
+	code, out := p.run(t, "run", "1-1-setup")
+
+	assert.NotEqual(t, 0, code, out)
+	assert.Less(t, time.Since(start), 30*time.Second, "the stalled session is killed")
+	assert.Equal(t, status.StatusReadyForDev, p.storyStatus(t, "1-1-setup"))
+}
+
+func TestE2E_FollowUpMessages(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: ready-for-dev\n", `
+sessions:
+  - match: "^/bmad-bmm-dev-story"
+    events: [{text: "Should I add a helper?"}]
+  - match: "^Yes"
+    events: [{tool: Write, file_path: helper.go}]
+    result: {text: "Added the helper"}
+  - events: [{text: "Done"}]
+`)
+	p.cfg.Claude.InputFormat = "stream-json"
+	var turns []workflow.Turn
+	p.responder = func(ctx context.Context, turn workflow.Turn) (string, bool) {
+		turns = append(turns, turn)
+		if turn.Workflow == "dev-story" && turn.Number == 1 {
+			return "Yes, add it.", true
+		}
+		return "", false
+	}
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	require.Equal(t, 0, code, out)
+	prompts := p.prompts(t)
+	require.Len(t, prompts, 4, "dev-story, its follow-up, code-review and git-commit")
+	assert.Equal(t, "Yes, add it.", prompts[1])
+	require.Len(t, turns, 4)
+	assert.Equal(t, "Should I add a helper?", turns[0].LastText)
+	assert.Equal(t, 2, turns[1].Number)
+	assert.Equal(t, "Added the helper", turns[1].Result.Stats.Result)
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "1-1-setup"))
+}
+
+func TestE2E_AutoAnswer(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: ready-for-dev\n", `
+sessions:
+  - match: "^/bmad-bmm-dev-story"
+    events: [{text: "Should I add a helper?"}]
+  - match: "^Proceed"
+    times: 1
+    events: [{text: "Added it. Should I also update the docs?"}]
+  - match: "^Proceed"
+    events: [{text: "Docs updated."}]
+  - events: [{text: "Done"}]
+`)
+	p.cfg.Claude.InputFormat = "stream-json"
+	p.cfg.AutoAnswer.Enabled = true
+	p.cfg.AutoAnswer.Answer = "Proceed."
+	p.cfg.AutoAnswer.MaxExchanges = 2
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	require.Equal(t, 0, code, out)
+	assert.Contains(t, out, "Answer: Proceed.")
+	prompts := p.prompts(t)
+	require.Len(t, prompts, 5, "dev-story, two answers, code-review and git-commit")
+	assert.Equal(t, []string{"Proceed.", "Proceed."}, prompts[1:3])
+}
+
+func TestE2E_SlowAnswererDoesNotStallSession(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: ready-for-dev\n", `
+sessions:
+  - match: "^/bmad-bmm-dev-story"
+    events: [{text: "Should I add a helper?"}]
+  - match: "^Answer for dev-story"
+    hang: 1s
+    result: {text: "Yes, add it."}
+  - match: "^Yes"
+    events: [{text: "Added the helper"}]
+  - events: [{text: "Done"}]
+`)
+	p.cfg.Claude.InputFormat = "stream-json"
+	p.cfg.AutoAnswer.Enabled = true
+	p.cfg.AutoAnswer.AnswererPrompt = "Answer for {{.Workflow}}: {{.Question}}"
+	wf := p.cfg.Workflows["dev-story"]
+	wf.IdleTimeout = 300 * time.Millisecond
+	p.cfg.Workflows["dev-story"] = wf
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	require.Equal(t, 0, code, "waiting for the answer is not idleness: %s", out)
+	assert.Contains(t, out, "Answer: Yes, add it.")
+	assert.Equal(t, "Yes, add it.", p.prompts(t)[2])
+	assert.Equal(t, status.StatusDone, p.storyStatus(t, "1-1-setup"))
+}
+
+func TestE2E_AutoAnswerGivesUp(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: ready-for-dev\n", `
+sessions:
+  - match: "^/bmad-bmm-dev-story|^Proceed"
+    events: [{text: "Should I add a helper?"}]
+  - events: [{text: "Done"}]
+`)
+	p.cfg.Claude.InputFormat = "stream-json"
+	p.cfg.AutoAnswer.Enabled = true
+	p.cfg.AutoAnswer.Answer = "Proceed."
+	p.cfg.AutoAnswer.MaxExchanges = 1
+
+	_, out := p.run(t, "run", "1-1-setup")
+
+	assert.Contains(t, out, "not answering Claude's question: gave up answering questions: still asking after 1 answers")
+	assert.Equal(t, "Proceed.", p.prompts(t)[1])
+	assert.NotEqual(t, "Proceed.", p.prompts(t)[2], "the session ends after the last answer")
+}
+
+func TestE2E_MiddlewareRetriesSession(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: review\n", `
+sessions:
+  - match: code-review
+    times: 1
+    stderr: ["API Error: 529 overloaded_error"]
+    exit_code: 1
+`+successScenario[len("\nsessions:\n"):])
+	p.cfg.Retry.MaxAttempts = 1
+	p.cfg.Executor.Middleware = []string{"retry", "recording"}
+	p.cfg.Executor.Retry.Backoff = time.Millisecond
+	p.cfg.Executor.Recording.Dir = filepath.Join(p.dir, "transcripts")
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	require.Equal(t, 0, code, out)
+	invocations, err := fakeclaude.ReadLog(filepath.Join(p.dir, "invocations.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, invocations, 3)
+	assert.Equal(t, invocations[0].Prompt, invocations[1].Prompt, "the session starts again from the prompt")
+	assert.Empty(t, invocations[1].ResumeSessionID)
+
+	transcripts, err := filepath.Glob(filepath.Join(p.dir, "transcripts", "session-*.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, transcripts, 3, "recording sits inside retry, so every attempt is recorded")
+}
+
+func TestE2E_MiddlewarePolicyStopsSession(t *testing.T) {
+	p := newE2EProject(t, "development_status:\n  1-1-setup: review\n", `
+sessions:
+  - match: "^Commit"
+    events:
+      - tool: Bash
+        command: git push --force origin main
+    result: {text: "Pushed"}
+`+successScenario[len("\nsessions:\n"):])
+	p.cfg.Executor.Middleware = []string{"policy"}
+	p.cfg.Executor.Policy.DenyCommands = []string{`git push .*--force`}
+
+	code, out := p.run(t, "run", "1-1-setup")
+
+	assert.NotEqual(t, 0, code, out)
+	assert.Contains(t, out, "$ git push --force origin main")
+	assert.Contains(t, out, "FAILED")
+	assert.FileExists(t, filepath.Join(p.dir, state.StateFileName), "the failed step can be resumed")
+}
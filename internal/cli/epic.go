@@ -3,15 +3,19 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/spf13/cobra"
 
 	"bmad-automate/internal/lifecycle"
 	"bmad-automate/internal/router"
+	"bmad-automate/internal/state"
 )
 
 func newEpicCommand(app *App) *cobra.Command {
 	var dryRun bool
+	var resumeRetries int
+	var resume bool
 
 	cmd := &cobra.Command{
 		Use:   "epic <epic-id>",
@@ -33,12 +37,34 @@ Status is updated in sprint-status.yaml after each successful workflow.
 
 Use --dry-run to preview workflows without executing them.
 
+Progress, including the position in the epic, is saved to .bmad-state.json after
+each step. Use --resume to continue a failed or interrupted epic from the step
+where it stopped and then run the remaining stories; the epic ID may be omitted.
+Resume is refused if the epic's stories have changed since the run stopped.
+
 Example:
   bmad-automate epic 6
-  # Runs 6-1-*, 6-2-*, 6-3-*, etc. each to completion in order`,
-		Args: cobra.ExactArgs(1),
+  # Runs 6-1-*, 6-2-*, 6-3-*, etc. each to completion in order
+  bmad-automate epic --resume`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if resume {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := cmd.Context()
+			var resumeFrom *state.State
+			if resume {
+				saved, err := loadResumeState(app, "epic")
+				if err == nil && len(args) == 1 && args[0] != saved.EpicID {
+					err = fmt.Errorf("%w: it is for epic %s, not %s", errStateMismatch, saved.EpicID, args[0])
+				}
+				if err != nil {
+					return refuseResume(cmd, err)
+				}
+				args = []string{saved.EpicID}
+				resumeFrom = &saved
+			}
 			epicID := args[0]
 
 			// Get all stories for this epic
@@ -48,8 +74,12 @@ Example:
 				return NewExitError(1)
 			}
 
+			if resumeFrom != nil && !slices.Equal(storyKeys, resumeFrom.Stories) {
+				return refuseResume(cmd, fmt.Errorf("%w: the stories of epic %s have changed since the run stopped", errStateMismatch, epicID))
+			}
+
 			// Create lifecycle executor with app dependencies
-			executor := lifecycle.NewExecutor(app.Runner, app.StatusReader, app.StatusWriter)
+			executor := newLifecycleExecutor(app, resumeRetries, state.State{Command: "epic", EpicID: epicID, Stories: storyKeys})
 
 			// Handle dry-run mode
 			if dryRun {
@@ -57,26 +87,14 @@ Example:
 			}
 
 			// Execute full lifecycle for each story in order
-			for _, storyKey := range storyKeys {
-				err := executor.Execute(ctx, storyKey)
-				if err != nil {
-					cmd.SilenceUsage = true
-					if errors.Is(err, router.ErrStoryComplete) {
-						fmt.Printf("Story %s is already complete, skipping\n", storyKey)
-						continue
-					}
-					fmt.Printf("Error running lifecycle for story %s: %v\n", storyKey, err)
-					return NewExitError(1)
-				}
-				fmt.Printf("Story %s completed successfully\n", storyKey)
-			}
-
-			fmt.Printf("All %d stories processed\n", len(storyKeys))
-			return nil
+			return runStories(cmd, app, executor, "epic", storyKeys, resumeFrom)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview workflows without executing them")
+	cmd.Flags().IntVar(&resumeRetries, "resume-retries", 0, "Retry a failed step up to N times by resuming its Claude session")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Continue a failed or interrupted epic from its saved progress")
+	cmd.MarkFlagsMutuallyExclusive("resume", "dry-run")
 
 	return cmd
 }
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"bmad-automate/internal/lifecycle"
+)
+
+// ExitCodeInterrupted is the exit code for runs stopped by SIGINT or SIGTERM,
+// following the shell convention of 128 + SIGINT.
+const ExitCodeInterrupted = 130
+
+// watchInterrupts implements two-stage interrupt handling for a command run.
+//
+// The returned context carries a drain signal ([lifecycle.WithDrain]). The first
+// signal received on signals closes it, so lifecycles finish the current step
+// and stop. The second cancels the context, which kills any running Claude
+// process group at once. Messages explaining each stage are written to out.
+//
+// Call the returned stop function when the run ends to release the watcher.
+func watchInterrupts(parent context.Context, signals <-chan os.Signal, out io.Writer) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	drain := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+			close(drain)
+			fmt.Fprintln(out, "\nInterrupt received: finishing the current step, then stopping. Press Ctrl+C again to stop immediately.")
+		case <-done:
+			return
+		}
+
+		select {
+		case <-signals:
+			fmt.Fprintln(out, "\nSecond interrupt: stopping Claude now.")
+			cancel()
+		case <-done:
+		}
+	}()
+
+	stop := func() {
+		close(done)
+		cancel()
+	}
+	return lifecycle.WithDrain(ctx, drain), stop
+}
+
+// interruptedExit reports a lifecycle stopped by an interrupt and returns the
+// exit error for it.
+func interruptedExit(storyKey string, err error) error {
+	fmt.Printf("Story %s interrupted: %v\n", storyKey, err)
+	printResumeHint(storyKey, err)
+	return NewExitError(ExitCodeInterrupted)
+}
+
+// stopRequested reports whether a batch command should stop before starting
+// storyKey because of an interrupt, printing a notice if so.
+func stopRequested(ctx context.Context, storyKey string) bool {
+	if !lifecycle.Draining(ctx) && ctx.Err() == nil {
+		return false
+	}
+	fmt.Printf("Interrupted: stopping before story %s\n", storyKey)
+	return true
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/config"
+	"bmad-automate/internal/lifecycle"
+	"bmad-automate/internal/output"
+	"bmad-automate/internal/state"
+	"bmad-automate/internal/status"
+)
+
+func TestWatchInterrupts(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	var out bytes.Buffer
+
+	ctx, stop := watchInterrupts(context.Background(), signals, &out)
+	defer stop()
+
+	assert.False(t, lifecycle.Draining(ctx))
+
+	// First signal drains without canceling
+	signals <- os.Interrupt
+	assert.Eventually(t, func() bool { return lifecycle.Draining(ctx) }, time.Second, time.Millisecond)
+	assert.NoError(t, ctx.Err())
+
+	// Second signal cancels
+	signals <- os.Interrupt
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not canceled by second interrupt")
+	}
+
+	assert.Contains(t, out.String(), "finishing the current step")
+	assert.Contains(t, out.String(), "stopping Claude now")
+}
+
+func TestWatchInterrupts_StopCancels(t *testing.T) {
+	ctx, stop := watchInterrupts(context.Background(), make(chan os.Signal), &bytes.Buffer{})
+	stop()
+
+	assert.Error(t, ctx.Err())
+	assert.False(t, lifecycle.Draining(ctx))
+}
+
+func TestQueueCommand_DrainFinishesStepAndSavesState(t *testing.T) {
+	tmpDir := t.TempDir()
+	createSprintStatusFile(t, tmpDir, `development_status:
+  STORY-1: backlog
+  STORY-2: backlog`)
+
+	drain := make(chan struct{})
+	mockRunner := &MockWorkflowRunner{
+		OnRun: func(workflowName, storyKey string) int {
+			if workflowName == "dev-story" {
+				close(drain) // Ctrl+C while dev-story is running
+			}
+			return 0
+		},
+	}
+	mockWriter := &MockStatusWriter{}
+	stateManager := state.NewManager(tmpDir)
+
+	app := &App{
+		Config:       config.DefaultConfig(),
+		Printer:      output.NewPrinterWithWriter(&bytes.Buffer{}),
+		StatusReader: status.NewReader(tmpDir),
+		StatusWriter: mockWriter,
+		Runner:       mockRunner,
+		StateStore:   stateManager,
+	}
+
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetArgs([]string{"queue", "STORY-1", "STORY-2"})
+	err := rootCmd.ExecuteContext(lifecycle.WithDrain(context.Background(), drain))
+
+	require.Error(t, err)
+	code, ok := IsExitError(err)
+	require.True(t, ok)
+	assert.Equal(t, ExitCodeInterrupted, code)
+
+	// dev-story finished and its status was written; nothing else ran
+	assert.Equal(t, []string{"create-story", "dev-story"}, mockRunner.ExecutedWorkflows)
+	require.Len(t, mockWriter.Updates, 2)
+	assert.Equal(t, status.StatusReview, mockWriter.Updates[1].NewStatus)
+
+	saved, loadErr := stateManager.Load()
+	require.NoError(t, loadErr)
+	assert.Equal(t, state.State{
+		StoryKey: "STORY-1", StepIndex: 2, TotalSteps: 4, StartStatus: "backlog",
+		Command: "queue", Stories: []string{"STORY-1", "STORY-2"},
+	}, saved)
+}
+
+func TestRunCommand_CancelAbortsStepAndSavesState(t *testing.T) {
+	tmpDir := t.TempDir()
+	createSprintStatusFile(t, tmpDir, `development_status:
+  STORY-1: ready-for-dev`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockRunner := &MockWorkflowRunner{
+		OnRun: func(workflowName, storyKey string) int {
+			cancel() // second Ctrl+C kills Claude mid-step
+			return -1
+		},
+	}
+	stateManager := state.NewManager(tmpDir)
+
+	app := &App{
+		Config:       config.DefaultConfig(),
+		Printer:      output.NewPrinterWithWriter(&bytes.Buffer{}),
+		StatusReader: status.NewReader(tmpDir),
+		StatusWriter: &MockStatusWriter{},
+		Runner:       mockRunner,
+		StateStore:   stateManager,
+	}
+
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetArgs([]string{"run", "STORY-1"})
+	err := rootCmd.ExecuteContext(ctx)
+
+	code, ok := IsExitError(err)
+	require.True(t, ok)
+	assert.Equal(t, ExitCodeInterrupted, code)
+
+	saved, loadErr := stateManager.Load()
+	require.NoError(t, loadErr)
+	assert.Equal(t, state.State{StoryKey: "STORY-1", StepIndex: 0, TotalSteps: 3, StartStatus: "ready-for-dev", Command: "run"}, saved)
+}
+
+func TestRunCommand_SuccessClearsState(t *testing.T) {
+	tmpDir := t.TempDir()
+	createSprintStatusFile(t, tmpDir, `development_status:
+  STORY-1: review`)
+
+	stateManager := state.NewManager(tmpDir)
+	require.NoError(t, stateManager.Save(state.State{StoryKey: "STORY-1", StepIndex: 1, TotalSteps: 2, StartStatus: "review"}))
+
+	app := &App{
+		Config:       config.DefaultConfig(),
+		Printer:      output.NewPrinterWithWriter(&bytes.Buffer{}),
+		StatusReader: status.NewReader(tmpDir),
+		StatusWriter: &MockStatusWriter{},
+		Runner:       &MockWorkflowRunner{},
+		StateStore:   stateManager,
+	}
+
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetArgs([]string{"run", "STORY-1"})
+	require.NoError(t, rootCmd.Execute())
+
+	assert.False(t, stateManager.Exists())
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"bmad-automate/internal/claude"
+	"bmad-automate/internal/config"
+	"bmad-automate/internal/output"
+)
+
+// withMiddleware wraps executor in the middleware listed in cfg.Middleware,
+// outermost first. A middleware that cannot be set up is left out with a
+// warning, so the sessions still run.
+func withMiddleware(executor claude.Executor, cfg config.ExecutorConfig, printer output.Printer) claude.Executor {
+	chain := make([]claude.Middleware, 0, len(cfg.Middleware))
+	for _, name := range cfg.Middleware {
+		m, err := middleware(name, cfg, printer)
+		if err != nil {
+			printer.Warning(fmt.Sprintf("executor middleware %s disabled: %v", name, err))
+			continue
+		}
+		chain = append(chain, m)
+	}
+	return claude.Chain(executor, chain...)
+}
+
+// middleware creates the named middleware from its settings.
+func middleware(name string, cfg config.ExecutorConfig, printer output.Printer) (claude.Middleware, error) {
+	switch name {
+	case config.MiddlewareLogging:
+		w, err := logWriter(cfg.Logging.File)
+		if err != nil {
+			return nil, err
+		}
+		return claude.WithLogging(w), nil
+	case config.MiddlewarePolicy:
+		p := cfg.Policy
+		policy, err := claude.NewToolPolicy(p.DenyTools, p.DenyCommands, p.DenyPaths)
+		if err != nil {
+			return nil, err
+		}
+		return claude.WithPolicy(policy), nil
+	case config.MiddlewareRetry:
+		r := cfg.Retry
+		return claude.WithRetry(claude.RetryConfig{
+			MaxAttempts: r.MaxAttempts,
+			Backoff:     r.Backoff,
+			RetryOn:     failureClasses(r.RetryOn),
+			OnRetry: func(attempt int, err error) {
+				printer.Warning(fmt.Sprintf("Claude session failed (%s) on attempt %d of %d; starting it again in %s: %v",
+					claude.Classify(err), attempt, r.MaxAttempts, r.Backoff, err))
+			},
+		}), nil
+	case config.MiddlewareRecording:
+		return claude.WithRecording(cfg.Recording.Dir, func(err error) {
+			printer.Warning(fmt.Sprintf("not recording Claude session: %v", err))
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown middleware %q", name)
+	}
+}
+
+// logWriter opens the session log for appending, or returns stderr for an
+// empty path. The file stays open for the life of the process.
+func logWriter(path string) (io.Writer, error) {
+	if path == "" {
+		return os.Stderr, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// failureClasses converts configured failure class names.
+func failureClasses(names []string) []claude.FailureClass {
+	classes := make([]claude.FailureClass, len(names))
+	for i, name := range names {
+		classes[i] = claude.FailureClass(name)
+	}
+	return classes
+}
@@ -3,15 +3,20 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"bmad-automate/internal/lifecycle"
 	"bmad-automate/internal/router"
+	"bmad-automate/internal/state"
 )
 
 func newQueueCommand(app *App) *cobra.Command {
 	var dryRun bool
+	var resumeRetries int
+	var resume bool
 
 	cmd := &cobra.Command{
 		Use:   "queue <story-key> [story-key...]",
@@ -32,41 +37,53 @@ Status is updated in sprint-status.yaml after each successful workflow.
 
 Use --dry-run to preview workflows without executing them.
 
+Progress, including the queue position, is saved to .bmad-state.json after each
+step. Use --resume to continue a failed or interrupted queue from the step where
+it stopped and then run the remaining stories; the story keys may be omitted,
+but if given they must match the saved queue.
+
 Example:
-  bmad-automate queue 6-5 6-6 6-7 6-8`,
-		Args: cobra.MinimumNArgs(1),
+  bmad-automate queue 6-5 6-6 6-7 6-8
+  bmad-automate queue --resume`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if resume {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := cmd.Context()
+			storyKeys := args
+
+			var resumeFrom *state.State
+			if resume {
+				saved, err := loadResumeState(app, "queue")
+				if err == nil && len(args) > 0 && !slices.Equal(args, saved.Stories) {
+					err = fmt.Errorf("%w: it is for the queue %s", errStateMismatch, strings.Join(saved.Stories, " "))
+				}
+				if err != nil {
+					return refuseResume(cmd, err)
+				}
+				storyKeys = saved.Stories
+				resumeFrom = &saved
+			}
 
 			// Create lifecycle executor with app dependencies
-			executor := lifecycle.NewExecutor(app.Runner, app.StatusReader, app.StatusWriter)
+			executor := newLifecycleExecutor(app, resumeRetries, state.State{Command: "queue", Stories: storyKeys})
 
 			// Handle dry-run mode
 			if dryRun {
-				return runQueueDryRun(cmd, executor, args)
+				return runQueueDryRun(cmd, executor, storyKeys)
 			}
 
 			// Execute full lifecycle for each story in order
-			for _, storyKey := range args {
-				err := executor.Execute(ctx, storyKey)
-				if err != nil {
-					cmd.SilenceUsage = true
-					if errors.Is(err, router.ErrStoryComplete) {
-						fmt.Printf("Story %s is already complete, skipping\n", storyKey)
-						continue
-					}
-					fmt.Printf("Error running lifecycle for story %s: %v\n", storyKey, err)
-					return NewExitError(1)
-				}
-				fmt.Printf("Story %s completed successfully\n", storyKey)
-			}
-
-			fmt.Printf("All %d stories processed\n", len(args))
-			return nil
+			return runStories(cmd, app, executor, "queue", storyKeys, resumeFrom)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview workflows without executing them")
+	cmd.Flags().IntVar(&resumeRetries, "resume-retries", 0, "Retry a failed step up to N times by resuming its Claude session")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Continue a failed or interrupted queue from its saved progress")
+	cmd.MarkFlagsMutuallyExclusive("resume", "dry-run")
 
 	return cmd
 }
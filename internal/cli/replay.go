@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"bmad-automate/internal/archive"
+	"bmad-automate/internal/workflow"
+)
+
+func newReplayCommand(app *App) *cobra.Command {
+	var opts workflow.ReplayOptions
+	var realtime bool
+
+	cmd := &cobra.Command{
+		Use:   "replay <run-dir|transcript>",
+		Short: "Re-render an archived session",
+		Long: `Re-render a recorded Claude session exactly as it was shown live.
+
+The argument is a run directory of the run archive, or a transcript file
+such as a run's events.jsonl.
+
+By default the whole session is shown at once. Use --realtime to replay it
+with its recorded timing, or --speed to fast-forward it. --from-tool N
+skips straight to the Nth tool call.
+
+Filters hide text and thinking and show only matching tool calls with their
+results: --tool shows calls to the named tool (repeatable), --failures only
+calls whose result was an error.
+
+Example:
+  bmad-automate replay _bmad-output/automation-runs/1-2-login/20260314-092653-dev-story
+  bmad-automate replay --speed 10 --from-tool 5 <run-dir>
+  bmad-automate replay --tool Bash --failures <run-dir>`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if realtime {
+				opts.Speed = 1
+			}
+
+			path, label, err := replayTarget(args[0])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return NewExitError(1)
+			}
+			if opts.Label == "" {
+				opts.Label = label
+			}
+
+			runner := workflow.NewRunner(app.Executor, app.Printer, app.Config)
+			if _, err := runner.Replay(cmd.Context(), path, opts); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return NewExitError(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64Var(&opts.Speed, "speed", 0, "Replay with the recorded timing sped up N times (0 shows everything at once)")
+	cmd.Flags().BoolVar(&realtime, "realtime", false, "Replay with the recorded timing (same as --speed 1)")
+	cmd.Flags().IntVar(&opts.FromTool, "from-tool", 0, "Start at the Nth tool call")
+	cmd.Flags().StringArrayVar(&opts.Tools, "tool", nil, "Show only calls to this tool, with their results (repeatable)")
+	cmd.Flags().BoolVar(&opts.FailuresOnly, "failures", false, "Show only tool calls that failed, with their results")
+	cmd.MarkFlagsMutuallyExclusive("speed", "realtime")
+
+	return cmd
+}
+
+// replayTarget resolves the argument of replay to a transcript and a label
+// for its header: a run directory replays its events with the run's
+// workflow and story, any other path is a transcript.
+func replayTarget(arg string) (path, label string, err error) {
+	info, err := os.Stat(arg)
+	if err != nil {
+		return "", "", err
+	}
+	if !info.IsDir() {
+		return arg, filepath.Base(arg), nil
+	}
+
+	run, err := archive.Load(arg)
+	if err != nil {
+		return "", "", err
+	}
+	label = run.Workflow
+	if run.StoryKey != "" {
+		label += ": " + run.StoryKey
+	}
+	return run.Path(archive.EventsFile), label, nil
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/archive"
+	"bmad-automate/internal/claude"
+	"bmad-automate/internal/output"
+)
+
+// archiveRun archives a session with the given stream-json lines and returns
+// its run directory.
+func archiveRun(t *testing.T, lines ...string) string {
+	t.Helper()
+	rec, err := archive.New(archive.Config{Dir: t.TempDir()}).Start("1-2-login", "dev-story", "/bmad-bmm-dev-story 1-2-login", claude.RunOptions{})
+	require.NoError(t, err)
+	for _, line := range lines {
+		var raw claude.StreamEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &raw))
+		for _, event := range claude.NewEventsFromStream(&raw) {
+			rec.Event(event)
+		}
+	}
+	require.NoError(t, rec.Finish(archive.Outcome{}))
+	return rec.Dir()
+}
+
+// runReplay runs the replay command and returns its output and error.
+func runReplay(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	app := setupTestApp()
+	app.Printer = output.NewPrinterWithWriter(&out)
+
+	cmd := NewRootCommand(app)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"replay"}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestReplayCommand(t *testing.T) {
+	dir := archiveRun(t,
+		`{"type":"system","subtype":"init","session_id":"s"}`,
+		`{"type":"assistant","session_id":"s","message":{"id":"m1","content":[{"type":"text","text":"Reading the story"}]}}`,
+		`{"type":"assistant","session_id":"s","message":{"id":"m2","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"story.md"}}]}}`,
+		`{"type":"assistant","session_id":"s","message":{"id":"m3","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+		`{"type":"result","subtype":"success","session_id":"s","result":"done"}`,
+	)
+
+	t.Run("run directory", func(t *testing.T) {
+		out, err := runReplay(t, dir)
+		require.NoError(t, err)
+		assert.Contains(t, out, "dev-story: 1-2-login")
+		assert.Contains(t, out, "Reading the story")
+		assert.Contains(t, out, "go test ./...")
+	})
+
+	t.Run("transcript file with filter", func(t *testing.T) {
+		out, err := runReplay(t, "--tool", "Bash", filepath.Join(dir, archive.EventsFile))
+		require.NoError(t, err)
+		assert.Contains(t, out, archive.EventsFile)
+		assert.Contains(t, out, "go test ./...")
+		assert.NotContains(t, out, "Reading the story")
+		assert.NotContains(t, out, "story.md")
+	})
+
+	t.Run("beyond the last tool call", func(t *testing.T) {
+		_, err := runReplay(t, "--from-tool", "3", dir)
+		code, ok := IsExitError(err)
+		require.True(t, ok)
+		assert.Equal(t, 1, code)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := runReplay(t, filepath.Join(t.TempDir(), "none"))
+		_, ok := IsExitError(err)
+		assert.True(t, ok)
+	})
+}
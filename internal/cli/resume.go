@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"bmad-automate/internal/lifecycle"
+	"bmad-automate/internal/router"
+	"bmad-automate/internal/state"
+)
+
+// errStateMismatch is returned when --resume finds saved progress that belongs
+// to a different command, story, or batch.
+var errStateMismatch = errors.New("saved state does not match this command")
+
+// commandStateStore stamps saved lifecycle progress with the command that
+// produced it, so --resume can refuse progress from a different invocation.
+//
+// For queue and epic batches, Clear is a no-op: finishing one story must not
+// forget the batch position. The batch clears the underlying store itself once
+// every story has been processed.
+type commandStateStore struct {
+	StateStore
+	scope state.State
+}
+
+// Save records st together with the command, epic, and batch stories.
+func (s *commandStateStore) Save(st state.State) error {
+	st.Command = s.scope.Command
+	st.EpicID = s.scope.EpicID
+	st.Stories = s.scope.Stories
+	return s.StateStore.Save(st)
+}
+
+// Clear removes the saved record unless it belongs to a batch.
+func (s *commandStateStore) Clear() error {
+	if s.scope.Command != "run" {
+		return nil
+	}
+	return s.StateStore.Clear()
+}
+
+// savedCommand returns the command that saved st. State saved without a
+// command came from a single-story run.
+func savedCommand(st state.State) string {
+	if st.Command == "" {
+		return "run"
+	}
+	return st.Command
+}
+
+// loadResumeState loads the saved progress for a --resume of command.
+func loadResumeState(app *App, command string) (state.State, error) {
+	if app.StateStore == nil {
+		return state.State{}, errors.New("nothing to resume: lifecycle state is not being recorded")
+	}
+
+	saved, err := app.StateStore.Load()
+	if errors.Is(err, state.ErrNoState) {
+		return state.State{}, fmt.Errorf("nothing to resume: no %s found", state.StateFileName)
+	}
+	if err != nil {
+		return state.State{}, err
+	}
+
+	if got := savedCommand(saved); got != command {
+		return state.State{}, fmt.Errorf("%w: it was saved by %q; continue it with: bmad-automate %s --resume",
+			errStateMismatch, got, got)
+	}
+	if command != "run" && !slices.Contains(saved.Stories, saved.StoryKey) {
+		return state.State{}, fmt.Errorf("%w: story %s is not part of the saved %s", errStateMismatch, saved.StoryKey, command)
+	}
+	return saved, nil
+}
+
+// refuseResume reports a --resume that cannot continue and returns the exit error.
+func refuseResume(cmd *cobra.Command, err error) error {
+	cmd.SilenceUsage = true
+	fmt.Printf("Error: %v\n", err)
+	printStateHint(err)
+	return NewExitError(1)
+}
+
+// printStateHint explains how to discard saved progress that cannot be resumed.
+func printStateHint(err error) {
+	if errors.Is(err, errStateMismatch) || errors.Is(err, lifecycle.ErrStaleState) {
+		fmt.Printf("Delete %s to discard the saved progress and start over.\n", state.StateFileName)
+	}
+}
+
+// printContinueHint prints the command that continues a stopped lifecycle from
+// its saved progress, if progress is being recorded.
+func printContinueHint(app *App, command string) {
+	if app.StateStore != nil {
+		fmt.Printf("Continue with: bmad-automate %s --resume\n", command)
+	}
+}
+
+// runStories runs the full lifecycle for each story of a queue or epic batch
+// in order, stopping at the first failure.
+//
+// If resume is set, the batch continues with resume.StoryKey from its saved
+// step, followed by the stories after it. Saved progress is cleared once
+// every story has been processed.
+func runStories(cmd *cobra.Command, app *App, executor *lifecycle.Executor, command string, storyKeys []string, resume *state.State) error {
+	ctx := cmd.Context()
+
+	start := 0
+	if resume != nil {
+		start = slices.Index(storyKeys, resume.StoryKey)
+	}
+
+	for i := start; i < len(storyKeys); i++ {
+		storyKey := storyKeys[i]
+		if stopRequested(ctx, storyKey) {
+			cmd.SilenceUsage = true
+			printContinueHint(app, command)
+			return NewExitError(ExitCodeInterrupted)
+		}
+
+		var err error
+		if resume != nil && i == start {
+			fmt.Printf("Resuming story %s at step %d of %d\n", storyKey, resume.StepIndex+1, resume.TotalSteps)
+			err = executor.ExecuteFromState(ctx, *resume)
+		} else {
+			err = executor.Execute(ctx, storyKey)
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			if errors.Is(err, router.ErrStoryComplete) {
+				fmt.Printf("Story %s is already complete, skipping\n", storyKey)
+				continue
+			}
+			if errors.Is(err, lifecycle.ErrInterrupted) {
+				err = interruptedExit(storyKey, err)
+				printContinueHint(app, command)
+				return err
+			}
+			fmt.Printf("Error running lifecycle for story %s: %v\n", storyKey, err)
+			printResumeHint(storyKey, err)
+			printStateHint(err)
+			if !errors.Is(err, lifecycle.ErrStaleState) {
+				printContinueHint(app, command)
+			}
+			return NewExitError(1)
+		}
+		fmt.Printf("Story %s completed successfully\n", storyKey)
+	}
+
+	if app.StateStore != nil {
+		if err := app.StateStore.Clear(); err != nil {
+			fmt.Printf("Warning: could not clear %s: %v\n", state.StateFileName, err)
+		}
+	}
+
+	fmt.Printf("All %d stories processed\n", len(storyKeys))
+	return nil
+}
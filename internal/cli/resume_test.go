@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/config"
+	"bmad-automate/internal/output"
+	"bmad-automate/internal/state"
+	"bmad-automate/internal/status"
+)
+
+func setupResumeTestApp(t *testing.T, sprintStatus string, saved *state.State) (*App, *MockWorkflowRunner, *state.Manager) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	createSprintStatusFile(t, tmpDir, sprintStatus)
+
+	stateManager := state.NewManager(tmpDir)
+	if saved != nil {
+		require.NoError(t, stateManager.Save(*saved))
+	}
+	runner := &MockWorkflowRunner{}
+
+	return &App{
+		Config:       config.DefaultConfig(),
+		Printer:      output.NewPrinterWithWriter(&bytes.Buffer{}),
+		StatusReader: status.NewReader(tmpDir),
+		StatusWriter: &MockStatusWriter{},
+		Runner:       runner,
+		StateStore:   stateManager,
+	}, runner, stateManager
+}
+
+func executeCommand(app *App, args ...string) error {
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	return rootCmd.Execute()
+}
+
+func TestRunCommand_Resume(t *testing.T) {
+	saved := &state.State{StoryKey: "STORY-1", StepIndex: 2, TotalSteps: 4, StartStatus: "backlog", Command: "run"}
+
+	for _, args := range [][]string{{"run", "--resume"}, {"run", "STORY-1", "--resume"}} {
+		app, runner, stateManager := setupResumeTestApp(t, `development_status:
+  STORY-1: review`, saved)
+
+		require.NoError(t, executeCommand(app, args...))
+		assert.Equal(t, []string{"code-review", "git-commit"}, runner.ExecutedWorkflows)
+		assert.False(t, stateManager.Exists(), "state is cleared on success")
+	}
+}
+
+func TestRunCommand_ResumeRefused(t *testing.T) {
+	tests := []struct {
+		name   string
+		saved  *state.State
+		status string
+		args   []string
+	}{
+		{
+			name:   "no saved state",
+			status: "review",
+			args:   []string{"run", "--resume"},
+		},
+		{
+			name:   "different story",
+			saved:  &state.State{StoryKey: "STORY-1", StepIndex: 1, TotalSteps: 2, StartStatus: "review", Command: "run"},
+			status: "done",
+			args:   []string{"run", "STORY-2", "--resume"},
+		},
+		{
+			name:   "saved by queue",
+			saved:  &state.State{StoryKey: "STORY-1", StepIndex: 1, TotalSteps: 2, StartStatus: "review", Command: "queue", Stories: []string{"STORY-1"}},
+			status: "done",
+			args:   []string{"run", "--resume"},
+		},
+		{
+			name:   "status no longer matches",
+			saved:  &state.State{StoryKey: "STORY-1", StepIndex: 1, TotalSteps: 4, StartStatus: "backlog", Command: "run"},
+			status: "done",
+			args:   []string{"run", "--resume"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, runner, stateManager := setupResumeTestApp(t, "development_status:\n  STORY-1: "+tt.status, tt.saved)
+
+			err := executeCommand(app, tt.args...)
+
+			code, ok := IsExitError(err)
+			require.True(t, ok)
+			assert.Equal(t, 1, code)
+			assert.Empty(t, runner.ExecutedWorkflows)
+			assert.Equal(t, tt.saved != nil, stateManager.Exists(), "refused state is kept")
+		})
+	}
+}
+
+func TestRunCommand_ResumeFlagConflicts(t *testing.T) {
+	app, _, _ := setupResumeTestApp(t, "development_status:\n  STORY-1: review", nil)
+
+	assert.Error(t, executeCommand(app, "run", "STORY-1", "--resume", "--resume-session", "abc"))
+	assert.Error(t, executeCommand(app, "run", "STORY-1", "--resume", "--dry-run"))
+	assert.Error(t, executeCommand(app, "run"), "story key is required without --resume")
+}
+
+func TestQueueCommand_ResumeContinuesQueue(t *testing.T) {
+	saved := &state.State{
+		StoryKey: "STORY-2", StepIndex: 1, TotalSteps: 2, StartStatus: "review",
+		Command: "queue", Stories: []string{"STORY-1", "STORY-2", "STORY-3"},
+	}
+	app, runner, stateManager := setupResumeTestApp(t, `development_status:
+  STORY-1: done
+  STORY-2: done
+  STORY-3: review`, saved)
+
+	require.NoError(t, executeCommand(app, "queue", "--resume"))
+
+	// STORY-1 is not revisited; STORY-2 commits; STORY-3 runs in full
+	assert.Equal(t, []string{"git-commit", "code-review", "git-commit"}, runner.ExecutedWorkflows)
+	assert.False(t, stateManager.Exists())
+}
+
+func TestQueueCommand_KeepsStateBetweenStories(t *testing.T) {
+	app, runner, stateManager := setupResumeTestApp(t, `development_status:
+  STORY-1: review
+  STORY-2: review`, nil)
+	runner.OnRun = func(workflowName, storyKey string) int {
+		if storyKey == "STORY-2" {
+			return 1
+		}
+		return 0
+	}
+
+	err := executeCommand(app, "queue", "STORY-1", "STORY-2")
+	_, ok := IsExitError(err)
+	require.True(t, ok)
+
+	saved, loadErr := stateManager.Load()
+	require.NoError(t, loadErr)
+	assert.Equal(t, state.State{
+		StoryKey: "STORY-2", StepIndex: 0, TotalSteps: 2, StartStatus: "review",
+		Command: "queue", Stories: []string{"STORY-1", "STORY-2"},
+	}, saved)
+}
+
+func TestQueueCommand_ResumeRefusesDifferentQueue(t *testing.T) {
+	saved := &state.State{
+		StoryKey: "STORY-1", StepIndex: 0, TotalSteps: 2, StartStatus: "review",
+		Command: "queue", Stories: []string{"STORY-1", "STORY-2"},
+	}
+	app, runner, stateManager := setupResumeTestApp(t, `development_status:
+  STORY-1: review
+  STORY-2: review`, saved)
+
+	err := executeCommand(app, "queue", "STORY-2", "STORY-1", "--resume")
+
+	code, ok := IsExitError(err)
+	require.True(t, ok)
+	assert.Equal(t, 1, code)
+	assert.Empty(t, runner.ExecutedWorkflows)
+	assert.True(t, stateManager.Exists())
+}
+
+func TestEpicCommand_Resume(t *testing.T) {
+	sprintStatus := `development_status:
+  6-1-first: done
+  6-2-second: in-progress
+  6-3-third: backlog`
+
+	t.Run("continues the epic", func(t *testing.T) {
+		saved := &state.State{
+			StoryKey: "6-2-second", StepIndex: 0, TotalSteps: 3, StartStatus: "in-progress",
+			Command: "epic", EpicID: "6", Stories: []string{"6-1-first", "6-2-second", "6-3-third"},
+		}
+		app, runner, stateManager := setupResumeTestApp(t, sprintStatus, saved)
+
+		require.NoError(t, executeCommand(app, "epic", "--resume"))
+		assert.Equal(t, []string{
+			"dev-story", "code-review", "git-commit",
+			"create-story", "dev-story", "code-review", "git-commit",
+		}, runner.ExecutedWorkflows)
+		assert.False(t, stateManager.Exists())
+	})
+
+	t.Run("refuses when the epic's stories changed", func(t *testing.T) {
+		saved := &state.State{
+			StoryKey: "6-2-second", StepIndex: 0, TotalSteps: 3, StartStatus: "in-progress",
+			Command: "epic", EpicID: "6", Stories: []string{"6-1-first", "6-2-second"},
+		}
+		app, runner, _ := setupResumeTestApp(t, sprintStatus, saved)
+
+		err := executeCommand(app, "epic", "6", "--resume")
+		code, ok := IsExitError(err)
+		require.True(t, ok)
+		assert.Equal(t, 1, code)
+		assert.Empty(t, runner.ExecutedWorkflows)
+	})
+
+	t.Run("refuses a different epic", func(t *testing.T) {
+		saved := &state.State{
+			StoryKey: "6-2-second", StepIndex: 0, TotalSteps: 3, StartStatus: "in-progress",
+			Command: "epic", EpicID: "6", Stories: []string{"6-1-first", "6-2-second", "6-3-third"},
+		}
+		app, runner, _ := setupResumeTestApp(t, sprintStatus, saved)
+
+		err := executeCommand(app, "epic", "7", "--resume")
+		code, ok := IsExitError(err)
+		require.True(t, ok)
+		assert.Equal(t, 1, code)
+		assert.Empty(t, runner.ExecutedWorkflows)
+	})
+}
@@ -17,6 +17,8 @@
 //   - queue - Run lifecycle for multiple stories sequentially
 //   - epic - Run all stories in an epic
 //   - raw - Execute a raw prompt directly
+//   - replay - Re-render an archived session
+//   - runs - List, show and search archived runs
 //   - create-story, dev-story, code-review, git-commit - Individual workflow commands
 package cli
 
@@ -24,12 +26,20 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"bmad-automate/internal/api"
+	"bmad-automate/internal/archive"
+	"bmad-automate/internal/autoanswer"
 	"bmad-automate/internal/claude"
 	"bmad-automate/internal/config"
+	"bmad-automate/internal/lifecycle"
 	"bmad-automate/internal/output"
+	"bmad-automate/internal/state"
 	"bmad-automate/internal/status"
 	"bmad-automate/internal/workflow"
 )
@@ -78,6 +88,22 @@ type StatusWriter interface {
 	UpdateStatus(storyKey string, newStatus status.Status) error
 }
 
+// StateStore is the interface for persisting lifecycle progress.
+//
+// The production implementation is [state.Manager], which keeps a single
+// record in .bmad-state.json in the working directory.
+type StateStore interface {
+	// Save records the progress of a lifecycle after each step, and where a
+	// failed or interrupted lifecycle stopped.
+	Save(st state.State) error
+
+	// Load returns the saved record, or [state.ErrNoState] if there is none.
+	Load() (state.State, error)
+
+	// Clear removes the saved record. It is not an error if none exists.
+	Clear() error
+}
+
 // App is the main application container with dependency injection.
 //
 // All dependencies are injected via struct fields, enabling comprehensive
@@ -92,6 +118,7 @@ type StatusWriter interface {
 //   - Runner: Workflow execution engine
 //   - StatusReader: Sprint status file reader
 //   - StatusWriter: Sprint status file writer
+//   - StateStore: Lifecycle progress store for failed or interrupted runs
 type App struct {
 	// Config holds application configuration including workflow definitions.
 	Config *config.Config
@@ -110,30 +137,50 @@ type App struct {
 
 	// StatusWriter updates story status in sprint-status.yaml.
 	StatusWriter StatusWriter
+
+	// StateStore records lifecycle progress so a failed or interrupted run
+	// can be continued with --resume. If nil, no progress is recorded.
+	StateStore StateStore
 }
 
 // NewApp creates a new [App] with all production dependencies wired up.
 //
 // This constructor initializes:
-//   - A [claude.Executor] configured from cfg.Claude settings
-//   - A [workflow.Runner] for workflow execution
+//   - A [claude.Executor] configured from cfg.Claude settings, either the
+//     Claude CLI or, with backend "api", an [api.Executor]; it is wrapped in a
+//     [claude.AgentExecutor] when cfg.Agents defines other coding agents, and
+//     in the middleware listed in cfg.Executor
+//   - A [workflow.Runner] for workflow execution, archiving every session
+//     when cfg.Output.Archive is enabled
 //   - A [status.Reader] and [status.Writer] for sprint status management
+//   - A [state.Manager] for lifecycle progress in the working directory
 //   - An [output.Printer] for terminal output
 //
 // For testing, construct [App] directly with mock dependencies instead.
 func NewApp(cfg *config.Config) *App {
 	printer := output.NewPrinter()
 
-	executor := claude.NewExecutor(claude.ExecutorConfig{
-		BinaryPath:   cfg.Claude.BinaryPath,
-		OutputFormat: cfg.Claude.OutputFormat,
-		StderrHandler: func(line string) {
-			// Print stderr to stderr
-			os.Stderr.WriteString("[stderr] " + line + "\n")
-		},
-	})
+	stderrHandler := func(line string) {
+		// Print stderr to stderr
+		os.Stderr.WriteString("[stderr] " + line + "\n")
+	}
+	var executor claude.Executor
+	if cfg.Claude.Backend == "api" {
+		executor = apiExecutor(cfg.Claude.API)
+	} else {
+		executor = claude.NewExecutor(claude.ExecutorConfig{
+			BinaryPath:    cfg.Claude.BinaryPath,
+			OutputFormat:  cfg.Claude.OutputFormat,
+			InputFormat:   cfg.Claude.InputFormat,
+			StderrHandler: stderrHandler,
+		})
+	}
+	if len(cfg.Agents) > 0 {
+		executor = claude.NewAgentExecutor(executor, agentExecutors(cfg.Agents, stderrHandler))
+	}
+	executor = withMiddleware(executor, cfg.Executor, printer)
 
-	runner := workflow.NewRunner(executor, printer, cfg)
+	runner := newRunner(cfg, executor, printer)
 	statusReader := status.NewReader("")
 	statusWriter := status.NewWriter("")
 
@@ -144,9 +191,68 @@ func NewApp(cfg *config.Config) *App {
 		Runner:       runner,
 		StatusReader: statusReader,
 		StatusWriter: statusWriter,
+		StateStore:   state.NewManager("."),
 	}
 }
 
+// newRunner creates the workflow runner, with the run archive and automatic
+// answers if enabled.
+func newRunner(cfg *config.Config, executor claude.Executor, printer output.Printer) *workflow.Runner {
+	runner := workflow.NewRunner(executor, printer, cfg)
+	if a := cfg.Output.Archive; a.Enabled {
+		runner.SetArchive(archive.New(archive.Config{Dir: a.Dir, MaxRuns: a.MaxRuns, MaxAge: a.MaxAge}))
+	}
+	if a := cfg.AutoAnswer; a.Enabled {
+		policy, err := autoanswer.New(autoanswer.Config{
+			Patterns:       a.Patterns,
+			Answer:         a.Answer,
+			AnswererPrompt: a.AnswererPrompt,
+			MaxExchanges:   a.MaxExchanges,
+		}, executor)
+		if err != nil {
+			printer.Warning(fmt.Sprintf("auto_answer disabled: %v", err))
+		} else {
+			runner.SetAutoAnswer(policy)
+		}
+	}
+	return runner
+}
+
+// apiExecutor creates the Messages API executor, reading the API key from the
+// configured environment variable.
+func apiExecutor(cfg config.APIConfig) *api.Executor {
+	var apiKey string
+	if cfg.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.APIKeyEnv)
+	}
+	return api.NewExecutor(api.Config{
+		BaseURL:     cfg.BaseURL,
+		APIKey:      apiKey,
+		Model:       cfg.Model,
+		MaxTokens:   cfg.MaxTokens,
+		MaxTurns:    cfg.MaxTurns,
+		BashTimeout: cfg.BashTimeout,
+	})
+}
+
+// agentExecutors creates an executor for each configured agent.
+func agentExecutors(agents map[string]config.AgentConfig, stderrHandler func(string)) map[string]claude.Executor {
+	executors := make(map[string]claude.Executor, len(agents))
+	for name, agent := range agents {
+		format := agent.Output
+		if format == "" {
+			format = claude.FormatText
+		}
+		executors[name] = claude.NewExecutor(claude.ExecutorConfig{
+			BinaryPath:    agent.Command,
+			OutputFormat:  format,
+			Args:          agent.Args,
+			StderrHandler: stderrHandler,
+		})
+	}
+	return executors
+}
+
 // NewRootCommand creates the root Cobra command with all subcommands attached.
 //
 // The command tree includes:
@@ -154,6 +260,8 @@ func NewApp(cfg *config.Config) *App {
 //   - queue: Run lifecycle for multiple stories sequentially
 //   - epic: Run all stories in an epic
 //   - raw: Execute a raw prompt directly
+//   - replay: Re-render an archived session
+//   - runs: List, show and search archived runs
 //   - create-story: Create a new story from backlog status
 //   - dev-story: Develop a story (ready-for-dev or in-progress status)
 //   - code-review: Review code (review status)
@@ -178,6 +286,8 @@ story creation, development, code review, and git operations.`,
 		newQueueCommand(app),
 		newEpicCommand(app),
 		newRawCommand(app),
+		newReplayCommand(app),
+		newRunsCommand(app),
 	)
 
 	return rootCmd
@@ -196,6 +306,59 @@ type ExecuteResult struct {
 	Err error
 }
 
+// newLifecycleExecutor creates a [lifecycle.Executor] wired to the app's dependencies.
+//
+// Saved progress is stamped with the Command, EpicID, and Stories of scope so
+// that --resume can continue the same invocation.
+func newLifecycleExecutor(app *App, resumeRetries int, scope state.State) *lifecycle.Executor {
+	executor := lifecycle.NewExecutor(app.Runner, app.StatusReader, app.StatusWriter)
+	executor.SetResumeRetries(resumeRetries)
+	if app.Config != nil {
+		executor.SetRetryPolicy(retryPolicy(app.Config.Retry))
+	}
+	executor.SetRetryCallback(func(event lifecycle.RetryEvent) {
+		app.Printer.Warning(retryMessage(event))
+	})
+	executor.SetUsageLimitCallback(func(workflow string, resumeAt time.Time, remaining time.Duration) {
+		app.Printer.Countdown(usageLimitMessage(workflow, resumeAt), remaining)
+	})
+	if app.StateStore != nil {
+		executor.SetStateStore(&commandStateStore{StateStore: app.StateStore, scope: scope})
+	}
+	return executor
+}
+
+// retryPolicy converts the configured retry settings to a [lifecycle.RetryPolicy].
+func retryPolicy(c config.RetryConfig) lifecycle.RetryPolicy {
+	return lifecycle.RetryPolicy{
+		MaxAttempts:    c.MaxAttempts,
+		InitialBackoff: c.InitialBackoff,
+		MaxBackoff:     c.MaxBackoff,
+		Multiplier:     c.Multiplier,
+		RetryOn:        failureClasses(c.RetryOn),
+
+		WaitForUsageLimit: c.UsageLimit.Wait,
+		MaxUsageLimitWait: c.UsageLimit.MaxWait,
+		UsageLimitMargin:  c.UsageLimit.Margin,
+	}
+}
+
+// usageLimitMessage describes a step waiting for the usage limit to reset.
+func usageLimitMessage(workflow string, resumeAt time.Time) string {
+	return fmt.Sprintf("%s hit the Claude usage limit; resuming at %s",
+		workflow, resumeAt.Format("Mon 15:04 MST"))
+}
+
+// retryMessage describes a failed attempt of a retryable step.
+func retryMessage(event lifecycle.RetryEvent) string {
+	if event.GivingUp {
+		return fmt.Sprintf("%s failed (%s) on attempt %d of %d; giving up",
+			event.Workflow, event.Class, event.Attempt, event.MaxAttempts)
+	}
+	return fmt.Sprintf("%s failed (%s) on attempt %d of %d; retrying in %s: %v",
+		event.Workflow, event.Class, event.Attempt, event.MaxAttempts, event.Delay, event.Err)
+}
+
 // RunWithConfig creates the app and executes the root command with a pre-loaded config.
 //
 // This is the testable core of [Execute], accepting an already-loaded [config.Config]
@@ -210,7 +373,16 @@ func RunWithConfig(cfg *config.Config) ExecuteResult {
 	app := NewApp(cfg)
 	rootCmd := NewRootCommand(app)
 
-	if err := rootCmd.Execute(); err != nil {
+	// First interrupt drains the current step; the second cancels, killing
+	// any running Claude process group rather than leaving it behind.
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ctx, stop := watchInterrupts(context.Background(), signals, os.Stderr)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		// Check if it's an ExitError from a command
 		if code, ok := IsExitError(err); ok {
 			return ExecuteResult{ExitCode: code, Err: err}
@@ -6,12 +6,17 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"bmad-automate/internal/claude"
 	"bmad-automate/internal/lifecycle"
 	"bmad-automate/internal/router"
+	"bmad-automate/internal/state"
 )
 
 func newRunCommand(app *App) *cobra.Command {
 	var dryRun bool
+	var resumeSession string
+	var resumeRetries int
+	var resume bool
 
 	cmd := &cobra.Command{
 		Use:   "run <story-key>",
@@ -27,14 +32,41 @@ The command executes all remaining workflows based on the story's current status
 
 Status is updated in sprint-status.yaml after each successful workflow.
 
-Use --dry-run to preview workflows without executing them.`,
-		Args: cobra.ExactArgs(1),
+Use --dry-run to preview workflows without executing them.
+
+Use --resume-session to continue a Claude session that died part-way through
+the current step, using the session ID printed when the step failed. Use
+--resume-retries to automatically resume a failed step's session up to N times.
+
+Progress is saved to .bmad-state.json after each step. Use --resume to continue
+a failed or interrupted run from the step where it stopped; the story key may
+be omitted. Resume is refused if the story's status no longer matches the
+saved progress.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if resume {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			storyKey := args[0]
 			ctx := cmd.Context()
 
+			var saved state.State
+			if resume {
+				var err error
+				saved, err = loadResumeState(app, "run")
+				if err == nil && len(args) == 1 && args[0] != saved.StoryKey {
+					err = fmt.Errorf("%w: it is for story %s, not %s", errStateMismatch, saved.StoryKey, args[0])
+				}
+				if err != nil {
+					return refuseResume(cmd, err)
+				}
+				args = []string{saved.StoryKey}
+			}
+			storyKey := args[0]
+
 			// Create lifecycle executor with app dependencies
-			executor := lifecycle.NewExecutor(app.Runner, app.StatusReader, app.StatusWriter)
+			executor := newLifecycleExecutor(app, resumeRetries, state.State{Command: "run"})
 
 			// Handle dry-run mode
 			if dryRun {
@@ -56,20 +88,43 @@ Use --dry-run to preview workflows without executing them.`,
 				return nil
 			}
 
-			// Set up progress callback to show step progress
+			// Set up progress callback to show step progress, including the
+			// task list the previous step left behind
+			var todos []claude.Todo
+			executor.SetStepCallback(func(result lifecycle.StepResult) {
+				todos = result.Todos
+			})
 			executor.SetProgressCallback(func(stepIndex, totalSteps int, workflow string) {
-				app.Printer.StepStart(stepIndex, totalSteps, workflow)
+				app.Printer.StepStart(stepIndex, totalSteps, workflow, todos)
 			})
 
 			// Execute the full lifecycle
-			err := executor.Execute(ctx, storyKey)
+			var err error
+			if resume {
+				fmt.Printf("Resuming story %s at step %d of %d\n", storyKey, saved.StepIndex+1, saved.TotalSteps)
+				err = executor.ExecuteFromState(ctx, saved)
+			} else if resumeSession != "" {
+				err = executor.ExecuteResume(ctx, storyKey, resumeSession)
+			} else {
+				err = executor.Execute(ctx, storyKey)
+			}
 			if err != nil {
 				cmd.SilenceUsage = true
 				if errors.Is(err, router.ErrStoryComplete) {
 					fmt.Printf("Story %s is already complete, no action needed\n", storyKey)
 					return nil
 				}
+				if errors.Is(err, lifecycle.ErrInterrupted) {
+					err = interruptedExit(storyKey, err)
+					printContinueHint(app, "run")
+					return err
+				}
 				fmt.Printf("Error: %v\n", err)
+				printResumeHint(storyKey, err)
+				printStateHint(err)
+				if !errors.Is(err, lifecycle.ErrStaleState) {
+					printContinueHint(app, "run")
+				}
 				return NewExitError(1)
 			}
 
@@ -78,6 +133,19 @@ Use --dry-run to preview workflows without executing them.`,
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview workflows without executing them")
+	cmd.Flags().StringVar(&resumeSession, "resume-session", "", "Resume this Claude session for the first remaining step")
+	cmd.Flags().IntVar(&resumeRetries, "resume-retries", 0, "Retry a failed step up to N times by resuming its Claude session")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Continue a failed or interrupted run from its saved progress")
+	cmd.MarkFlagsMutuallyExclusive("resume", "resume-session")
+	cmd.MarkFlagsMutuallyExclusive("resume", "dry-run")
 
 	return cmd
 }
+
+// printResumeHint prints the command to resume a failed step's Claude session, if known.
+func printResumeHint(storyKey string, err error) {
+	var stepErr *lifecycle.StepError
+	if errors.As(err, &stepErr) && stepErr.SessionID != "" {
+		fmt.Printf("Resume with: bmad-automate run %s --resume-session %s\n", storyKey, stepErr.SessionID)
+	}
+}
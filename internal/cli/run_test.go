@@ -7,12 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"bmad-automate/internal/claude"
 	"bmad-automate/internal/config"
+	"bmad-automate/internal/lifecycle"
 	"bmad-automate/internal/output"
 	"bmad-automate/internal/status"
 	"bmad-automate/internal/workflow"
@@ -22,11 +24,17 @@ import (
 type MockWorkflowRunner struct {
 	ExecutedWorkflows []string
 	ReturnExitCode    int
-	FailOnWorkflow    string // If set, fail when this workflow is called
+	FailOnWorkflow    string                                  // If set, fail when this workflow is called
+	OnRun             func(workflowName, storyKey string) int // If set, called for each workflow; non-zero fails it
 }
 
 func (m *MockWorkflowRunner) RunSingle(ctx context.Context, workflowName, storyKey string) int {
 	m.ExecutedWorkflows = append(m.ExecutedWorkflows, workflowName)
+	if m.OnRun != nil {
+		if code := m.OnRun(workflowName, storyKey); code != 0 {
+			return code
+		}
+	}
 	if m.FailOnWorkflow == workflowName {
 		return 1
 	}
@@ -286,3 +294,111 @@ func TestRunCommand_LifecycleStoryNotFound(t *testing.T) {
 	// No workflows should have been executed
 	assert.Empty(t, mockRunner.ExecutedWorkflows)
 }
+
+func TestRunCommand_ResumeSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	createSprintStatusFile(t, tmpDir, "development_status:\n  STORY-1: review")
+
+	app, mockExecutor, _ := setupRunTestApp(tmpDir)
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{"run", "STORY-1", "--resume-session", "sess-42"})
+
+	err := rootCmd.Execute()
+
+	require.NoError(t, err)
+	require.Len(t, mockExecutor.RecordedOptions, 2)
+	assert.Equal(t, "sess-42", mockExecutor.RecordedOptions[0].ResumeSessionID, "code-review resumes the session")
+	assert.Equal(t, app.Config.Claude.ResumePrompt, mockExecutor.RecordedPrompts[0])
+	assert.Empty(t, mockExecutor.RecordedOptions[1].ResumeSessionID, "git-commit starts fresh")
+}
+
+func TestRunCommand_ResumeSessionUnsupportedRunner(t *testing.T) {
+	tmpDir := t.TempDir()
+	createSprintStatusFile(t, tmpDir, "development_status:\n  STORY-1: review")
+
+	mockRunner := &MockWorkflowRunner{}
+	app := &App{
+		Config:       config.DefaultConfig(),
+		StatusReader: status.NewReader(tmpDir),
+		StatusWriter: &MockStatusWriter{},
+		Runner:       mockRunner,
+		Printer:      output.NewPrinterWithWriter(&bytes.Buffer{}),
+	}
+
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{"run", "STORY-1", "--resume-session", "sess-42"})
+
+	err := rootCmd.Execute()
+
+	require.Error(t, err)
+	code, ok := IsExitError(err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, code)
+	assert.Empty(t, mockRunner.ExecutedWorkflows)
+}
+
+// reportingRunner is a MockWorkflowRunner that explains its failures.
+type reportingRunner struct {
+	MockWorkflowRunner
+	Err error
+}
+
+func (r *reportingRunner) LastError() error {
+	return r.Err
+}
+
+func TestRunCommand_RetriesTransientFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	createSprintStatusFile(t, tmpDir, `development_status:
+  STORY-1: review`)
+
+	runner := &reportingRunner{Err: &claude.ResultError{Message: "API Error: Overloaded"}}
+	failures := 1
+	runner.OnRun = func(workflowName, storyKey string) int {
+		if workflowName == "code-review" && failures > 0 {
+			failures--
+			return 1
+		}
+		return 0
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Retry.InitialBackoff = time.Millisecond
+	out := &bytes.Buffer{}
+	app := &App{
+		Config:       cfg,
+		Printer:      output.NewPrinterWithWriter(out),
+		StatusReader: status.NewReader(tmpDir),
+		StatusWriter: &MockStatusWriter{},
+		Runner:       runner,
+	}
+
+	rootCmd := NewRootCommand(app)
+	rootCmd.SetArgs([]string{"run", "STORY-1"})
+	require.NoError(t, rootCmd.Execute())
+
+	assert.Equal(t, []string{"code-review", "code-review", "git-commit"}, runner.ExecutedWorkflows)
+	assert.Contains(t, out.String(), "code-review failed (overloaded) on attempt 1 of 3; retrying in 1ms")
+}
+
+func TestRetryMessage(t *testing.T) {
+	assert.Equal(t, "dev-story failed (network) on attempt 3 of 3; giving up",
+		retryMessage(lifecycle.RetryEvent{Workflow: "dev-story", Attempt: 3, MaxAttempts: 3, Class: claude.FailureNetwork, GivingUp: true}))
+}
+
+func TestUsageLimitMessage(t *testing.T) {
+	resumeAt := time.Date(2025, 10, 9, 15, 1, 0, 0, time.UTC)
+	assert.Equal(t, "dev-story hit the Claude usage limit; resuming at Thu 15:01 UTC",
+		usageLimitMessage("dev-story", resumeAt))
+}
+
+func TestRetryPolicy_UsageLimit(t *testing.T) {
+	policy := retryPolicy(config.DefaultConfig().Retry)
+	assert.True(t, policy.WaitForUsageLimit)
+	assert.Equal(t, 6*time.Hour, policy.MaxUsageLimitWait)
+	assert.Equal(t, time.Minute, policy.UsageLimitMargin)
+}
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"bmad-automate/internal/archive"
+)
+
+func newRunsCommand(app *App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Search the run archive",
+		Long: `List, inspect and search the Claude sessions recorded in the run archive.
+
+Runs are identified by their directory in the archive, <story>/<timestamp>-<workflow>,
+as shown by "runs list".
+
+Example:
+  bmad-automate runs list --epic 1 --status failed
+  bmad-automate runs show 1-2-login/20260314-092653-dev-story
+  bmad-automate runs grep "go mod tidy"`,
+	}
+
+	cmd.AddCommand(
+		newRunsListCommand(app),
+		newRunsShowCommand(app),
+		newRunsGrepCommand(app),
+	)
+	return cmd
+}
+
+// runsFilter holds the filter flags shared by runs list and runs grep.
+type runsFilter struct {
+	story  string
+	epic   string
+	status string
+	since  string
+	until  string
+}
+
+func (f *runsFilter) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.story, "story", "", "Only runs of this story")
+	cmd.Flags().StringVar(&f.epic, "epic", "", "Only runs of the stories of this epic")
+	cmd.Flags().StringVar(&f.status, "status", "", "Only runs with this outcome: "+strings.Join(archive.Statuses, ", "))
+	cmd.Flags().StringVar(&f.since, "since", "", "Only runs started on or after this date (YYYY-MM-DD) or this long ago (e.g. 24h)")
+	cmd.Flags().StringVar(&f.until, "until", "", "Only runs started on or before this date (YYYY-MM-DD) or before this long ago")
+}
+
+// filter converts the flags to an [archive.Filter].
+func (f *runsFilter) filter(now time.Time) (archive.Filter, error) {
+	filter := archive.Filter{StoryKey: f.story, Epic: f.epic, Status: f.status}
+	if f.status != "" && !slices.Contains(archive.Statuses, f.status) {
+		return filter, fmt.Errorf("invalid --status %q: must be one of %s", f.status, strings.Join(archive.Statuses, ", "))
+	}
+	var err error
+	if filter.Since, err = parseRunTime("since", f.since, now, false); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseRunTime("until", f.until, now, true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseRunTime parses a --since or --until value: a date, in local time, or
+// a duration before now. A date ending a range includes the whole day.
+func parseRunTime(flag, value string, now time.Time, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		if end {
+			date = date.AddDate(0, 0, 1)
+		}
+		return date, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil && ago >= 0 {
+		return now.Add(-ago), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s %q: want a date (YYYY-MM-DD) or a duration (e.g. 24h)", flag, value)
+}
+
+// runArchive returns the configured run archive.
+func runArchive(app *App) *archive.Archive {
+	a := app.Config.Output.Archive
+	return archive.New(archive.Config{Dir: a.Dir, MaxRuns: a.MaxRuns, MaxAge: a.MaxAge})
+}
+
+// selectRuns returns the archived runs the filter flags select, oldest first.
+func selectRuns(app *App, flags *runsFilter) (*archive.Archive, []*archive.Run, error) {
+	filter, err := flags.filter(time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	runs := runArchive(app)
+	all, err := runs.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	return runs, filter.Select(all), nil
+}
+
+// runsError reports an error of a runs subcommand.
+func runsError(cmd *cobra.Command, err error) error {
+	cmd.SilenceUsage = true
+	fmt.Printf("Error: %v\n", err)
+	return NewExitError(1)
+}
+
+func newRunsListCommand(app *App) *cobra.Command {
+	var flags runsFilter
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List archived runs",
+		Long: `List archived runs, oldest first, with their story, workflow, outcome,
+duration and cost.
+
+Example:
+  bmad-automate runs list
+  bmad-automate runs list --story 1-2-login
+  bmad-automate runs list --epic 1 --status failed --since 2026-03-01`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runs, selected, err := selectRuns(app, &flags)
+			if err != nil {
+				return runsError(cmd, err)
+			}
+			out := cmd.OutOrStdout()
+			if len(selected) == 0 {
+				fmt.Fprintf(out, "No runs found in %s\n", runs.Dir())
+				return nil
+			}
+			printRunList(out, runs, selected)
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	return cmd
+}
+
+// printRunList prints a table of runs and their total cost.
+func printRunList(out io.Writer, runs *archive.Archive, selected []*archive.Run) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tSTORY\tWORKFLOW\tOUTCOME\tDURATION\tCOST\tRUN")
+	var cost float64
+	for _, run := range selected {
+		story := run.StoryKey
+		if story == "" {
+			story = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t$%.2f\t%s\n",
+			run.StartedAt.Format(time.DateTime), story, run.Workflow, run.Status(),
+			run.Duration().Round(time.Second), run.Stats.TotalCostUSD, runs.ID(run))
+		cost += run.Stats.TotalCostUSD
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\n%d runs, $%.2f\n", len(selected), cost)
+}
+
+func newRunsShowCommand(app *App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <run>",
+		Short: "Show an archived run",
+		Long: `Show the details of an archived run and the tool calls it made.
+
+The run is its ID, as shown by "runs list", or its directory. The numbers of the
+tool calls can be passed to "replay --from-tool".
+
+Example:
+  bmad-automate runs show 1-2-login/20260314-092653-dev-story`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runs := runArchive(app)
+			run, err := runs.Find(args[0])
+			if err != nil {
+				return runsError(cmd, err)
+			}
+			calls, err := run.ToolCalls()
+			if err != nil {
+				return runsError(cmd, err)
+			}
+			printRun(cmd.OutOrStdout(), runs, run, calls)
+			return nil
+		},
+	}
+}
+
+// printRun prints the details of a run and its tool calls.
+func printRun(out io.Writer, runs *archive.Archive, run *archive.Run, calls []archive.ToolCall) {
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Run:\t%s\n", runs.ID(run))
+	if run.StoryKey != "" {
+		fmt.Fprintf(w, "Story:\t%s\n", run.StoryKey)
+	}
+	fmt.Fprintf(w, "Workflow:\t%s\n", run.Workflow)
+	if run.Agent != "" {
+		fmt.Fprintf(w, "Agent:\t%s\n", run.Agent)
+	}
+	fmt.Fprintf(w, "Started:\t%s\n", run.StartedAt.Format(time.DateTime))
+	if run.Status() == archive.StatusFailed {
+		fmt.Fprintf(w, "Outcome:\t%s (exit code %d)\n", run.Status(), run.ExitCode)
+	} else {
+		fmt.Fprintf(w, "Outcome:\t%s\n", run.Status())
+	}
+	if run.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", run.Error)
+	}
+	fmt.Fprintf(w, "Duration:\t%s\n", run.Duration().Round(time.Second))
+	fmt.Fprintf(w, "Usage:\t%d tokens, $%.2f, %d turns\n",
+		run.Stats.Usage.TotalTokens(), run.Stats.TotalCostUSD, run.Stats.NumTurns)
+	if run.SessionID != "" {
+		session := run.SessionID
+		if run.ResumeSessionID != "" {
+			session += " (resumed from " + run.ResumeSessionID + ")"
+		}
+		fmt.Fprintf(w, "Session:\t%s\n", session)
+	}
+	fmt.Fprintf(w, "Directory:\t%s\n", run.Dir)
+	w.Flush()
+
+	if len(calls) == 0 {
+		fmt.Fprintln(out, "\nNo tool calls")
+		return
+	}
+	fmt.Fprintf(out, "\nTool calls:\n")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, call := range calls {
+		detail := call.Call.ToolFilePath
+		if call.Call.ToolCommand != "" {
+			detail = "$ " + call.Call.ToolCommand
+		}
+		detail, _, _ = strings.Cut(detail, "\n")
+		var note string
+		switch {
+		case call.Failed():
+			note = "failed"
+		case call.Result == nil:
+			note = "no result"
+		}
+		fmt.Fprintf(w, "%4d.\t%s\t%s\t%s\n", i+1, call.Call.ToolName, detail, note)
+	}
+	w.Flush()
+}
+
+func newRunsGrepCommand(app *App) *cobra.Command {
+	var flags runsFilter
+	var ignoreCase bool
+
+	cmd := &cobra.Command{
+		Use:   "grep <pattern>",
+		Short: "Search the tool calls of archived runs",
+		Long: `Search the commands, file paths and outputs of the tool calls of archived
+runs for lines matching a regular expression (Go syntax).
+
+Each match shows the run, the number of the tool call, the tool, and which part
+of the call matched (command, file, stdout or stderr). Exits 1 if nothing matches.
+
+Example:
+  bmad-automate runs grep "go mod tidy"
+  bmad-automate runs grep -i "panic:" --epic 2
+  bmad-automate runs grep "FAIL" --status failed --since 48h`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pattern := args[0]
+			if ignoreCase {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return runsError(cmd, fmt.Errorf("invalid pattern: %w", err))
+			}
+			runs, selected, err := selectRuns(app, &flags)
+			if err != nil {
+				return runsError(cmd, err)
+			}
+
+			matches := archive.Grep(selected, re)
+			if len(matches) == 0 {
+				cmd.SilenceUsage = true
+				return NewExitError(1)
+			}
+			out := cmd.OutOrStdout()
+			for _, m := range matches {
+				fmt.Fprintf(out, "%s #%d %s %s: %s\n", runs.ID(m.Run), m.Call, m.Tool, m.Field, m.Line)
+			}
+			return nil
+		},
+	}
+	flags.addFlags(cmd)
+	cmd.Flags().BoolVarP(&ignoreCase, "ignore-case", "i", false, "Match case-insensitively")
+	return cmd
+}
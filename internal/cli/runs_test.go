@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/archive"
+	"bmad-automate/internal/claude"
+)
+
+// runsApp returns a test app whose run archive holds a successful run of
+// 1-1-setup that ran go mod tidy and a failed run of 2-1-api.
+func runsApp(t *testing.T) (*App, *archive.Archive) {
+	t.Helper()
+	app := setupTestApp()
+	app.Config.Output.Archive.Dir = t.TempDir()
+	runs := runArchive(app)
+
+	record := func(story, workflow string, outcome archive.Outcome, lines ...string) {
+		rec, err := runs.Start(story, workflow, "p", claude.RunOptions{})
+		require.NoError(t, err)
+		for _, line := range lines {
+			events, err := claude.ParseLine(line)
+			require.NoError(t, err)
+			for _, event := range events {
+				rec.Event(event)
+			}
+		}
+		require.NoError(t, rec.Finish(outcome))
+	}
+	record("1-1-setup", "dev-story", archive.Outcome{Stats: claude.ResultStats{TotalCostUSD: 0.5}},
+		`{"type":"assistant","session_id":"s","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go mod tidy"}}]}}`,
+		`{"type":"user","session_id":"s","message":{"content":[{"type":"tool_result","tool_use_id":"t1"}]},"tool_use_result":{"stdout":"go: downloading golang.org/x/sync"}}`,
+	)
+	record("2-1-api", "code-review", archive.Outcome{ExitCode: 1, Err: assert.AnError, Stats: claude.ResultStats{TotalCostUSD: 0.25}},
+		`{"type":"assistant","session_id":"s","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"api.go"}}]}}`,
+		`{"type":"user","session_id":"s","message":{"content":[{"type":"tool_result","tool_use_id":"t1","is_error":true}]},"tool_use_result":{"stderr":"api.go: not found"}}`,
+	)
+	return app, runs
+}
+
+// runRuns runs a runs subcommand and returns its output and error.
+func runRuns(t *testing.T, app *App, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := NewRootCommand(app)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"runs"}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestRunsListCommand(t *testing.T) {
+	app, _ := runsApp(t)
+
+	out, err := runRuns(t, app, "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "STARTED")
+	assert.Regexp(t, `1-1-setup\s+dev-story\s+success\s+\S+\s+\$0\.50`, out)
+	assert.Regexp(t, `2-1-api\s+code-review\s+failed\s+\S+\s+\$0\.25`, out)
+	assert.Contains(t, out, "2 runs, $0.75")
+
+	out, err = runRuns(t, app, "list", "--status", "failed")
+	require.NoError(t, err)
+	assert.NotContains(t, out, "1-1-setup")
+	assert.Contains(t, out, "1 runs, $0.25")
+
+	out, err = runRuns(t, app, "list", "--epic", "1", "--since", "1h")
+	require.NoError(t, err)
+	assert.Contains(t, out, "1-1-setup")
+	assert.NotContains(t, out, "2-1-api")
+
+	out, err = runRuns(t, app, "list", "--until", "2000-01-01")
+	require.NoError(t, err)
+	assert.Contains(t, out, "No runs found")
+
+	_, err = runRuns(t, app, "list", "--status", "broken")
+	_, ok := IsExitError(err)
+	assert.True(t, ok)
+}
+
+func TestRunsShowCommand(t *testing.T) {
+	app, runs := runsApp(t)
+	list, err := runs.List()
+	require.NoError(t, err)
+
+	out, err := runRuns(t, app, "show", runs.ID(list[1]))
+
+	require.NoError(t, err)
+	assert.Regexp(t, `Story:\s+2-1-api`, out)
+	assert.Regexp(t, `Outcome:\s+failed \(exit code 1\)`, out)
+	assert.Regexp(t, `Error:\s+`+assert.AnError.Error(), out)
+	assert.Regexp(t, `1\.\s+Edit\s+api\.go\s+failed`, out)
+
+	_, err = runRuns(t, app, "show", "nope")
+	_, ok := IsExitError(err)
+	assert.True(t, ok)
+}
+
+func TestRunsGrepCommand(t *testing.T) {
+	app, runs := runsApp(t)
+	list, err := runs.List()
+	require.NoError(t, err)
+
+	out, err := runRuns(t, app, "grep", "go mod tidy")
+	require.NoError(t, err)
+	assert.Equal(t, runs.ID(list[0])+" #1 Bash command: go mod tidy\n", out)
+
+	out, err = runRuns(t, app, "grep", "-i", "NOT FOUND")
+	require.NoError(t, err)
+	assert.Equal(t, runs.ID(list[1])+" #1 Edit stderr: api.go: not found\n", out)
+
+	_, err = runRuns(t, app, "grep", "golang", "--story", "2-1-api")
+	code, ok := IsExitError(err)
+	require.True(t, ok, "no match exits 1")
+	assert.Equal(t, 1, code)
+
+	_, err = runRuns(t, app, "grep", "(")
+	_, ok = IsExitError(err)
+	assert.True(t, ok)
+}
+
+func TestParseRunTime(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 0, 0, 0, time.Local)
+
+	since, err := parseRunTime("since", "2026-03-01", now, false)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), since)
+
+	until, err := parseRunTime("until", "2026-03-01", now, true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local), until, "the end date is included")
+
+	ago, err := parseRunTime("since", "36h", now, false)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-36*time.Hour), ago)
+
+	_, err = parseRunTime("since", "yesterday", now, false)
+	assert.ErrorContains(t, err, `invalid --since "yesterday"`)
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -84,6 +87,9 @@ func (l *Loader) Load() (*Config, error) {
 		cfg.Claude.BinaryPath = binaryPath
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -108,6 +114,9 @@ func (l *Loader) LoadFromFile(path string) (*Config, error) {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -126,6 +135,225 @@ func (c *Config) GetPrompt(workflowName, storyKey string) (string, error) {
 	return expandTemplate(workflow.PromptTemplate, PromptData{StoryKey: storyKey})
 }
 
+// GetPermissions returns the effective permission settings for a workflow.
+//
+// Settings start from [ClaudeConfig.Permissions]; each field set on the
+// workflow's own [PermissionsConfig] replaces the corresponding default.
+// Tool lists are replaced, not appended, so a workflow can narrow the defaults.
+// Unknown workflows get the defaults unchanged.
+func (c *Config) GetPermissions(workflowName string) PermissionsConfig {
+	perms := c.Claude.Permissions
+
+	workflow, ok := c.Workflows[workflowName]
+	if !ok {
+		return perms
+	}
+
+	if workflow.Permissions.Mode != "" {
+		perms.Mode = workflow.Permissions.Mode
+	}
+	if workflow.Permissions.AllowedTools != nil {
+		perms.AllowedTools = workflow.Permissions.AllowedTools
+	}
+	if workflow.Permissions.DisallowedTools != nil {
+		perms.DisallowedTools = workflow.Permissions.DisallowedTools
+	}
+
+	return perms
+}
+
+// Validate checks the backend, archive, agent and retry settings: the Claude
+// backend must be "cli" or "api", an enabled archive needs a directory and
+// non-negative limits, every agent needs a command, a known output format and
+// valid argument templates, every workflow's agent must be defined in
+// [Config.Agents], the api backend only takes tool rules it can enforce,
+// retries need at least one attempt, non-negative waits and known failure
+// classes, and only one of step retries and the retry middleware is on.
+//
+// [Loader.Load] and [Loader.LoadFromFile] call Validate on the loaded config.
+func (c *Config) Validate() error {
+	switch c.Claude.Backend {
+	case "", "cli", "api":
+	default:
+		return fmt.Errorf("claude: unknown backend %q (want cli or api)", c.Claude.Backend)
+	}
+	switch c.Claude.InputFormat {
+	case "", "text":
+	case "stream-json":
+		if c.Claude.OutputFormat != "stream-json" {
+			return fmt.Errorf("claude: input_format stream-json requires output_format stream-json, got %q", c.Claude.OutputFormat)
+		}
+	default:
+		return fmt.Errorf("claude: unknown input_format %q (want text or stream-json)", c.Claude.InputFormat)
+	}
+
+	if archive := c.Output.Archive; archive.Enabled {
+		switch {
+		case archive.Dir == "":
+			return fmt.Errorf("output: archive dir is required when the archive is enabled")
+		case archive.MaxRuns < 0:
+			return fmt.Errorf("output: archive max_runs must not be negative, got %d", archive.MaxRuns)
+		case archive.MaxAge < 0:
+			return fmt.Errorf("output: archive max_age must not be negative, got %s", archive.MaxAge)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Agents)) {
+		agent := c.Agents[name]
+		if agent.Command == "" {
+			return fmt.Errorf("agent %s: command is required", name)
+		}
+		switch agent.Output {
+		case "", "text", "stream-json":
+		default:
+			return fmt.Errorf("agent %s: unknown output format %q (want text or stream-json)", name, agent.Output)
+		}
+		for _, arg := range agent.Args {
+			if _, err := template.New("arg").Parse(arg); err != nil {
+				return fmt.Errorf("agent %s: invalid argument template %q: %w", name, arg, err)
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Workflows)) {
+		agent := c.Workflows[name].Agent
+		if _, ok := c.Agents[agent]; agent != "" && !ok {
+			return fmt.Errorf("workflow %s: unknown agent %q", name, agent)
+		}
+	}
+
+	if c.Claude.Backend == "api" {
+		if err := c.Claude.Permissions.validateAPI("claude"); err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(c.Workflows)) {
+			if err := c.Workflows[name].Permissions.validateAPI("workflow " + name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if auto := c.AutoAnswer; auto.Enabled {
+		switch {
+		case c.Claude.Backend == "api":
+			return fmt.Errorf("auto_answer: not supported by the api backend")
+		case c.Claude.InputFormat != "stream-json":
+			return fmt.Errorf("auto_answer: requires claude input_format stream-json")
+		case auto.MaxExchanges < 1:
+			return fmt.Errorf("auto_answer: max_exchanges must be at least 1, got %d", auto.MaxExchanges)
+		case strings.TrimSpace(auto.Answer) == "" && auto.AnswererPrompt == "":
+			return fmt.Errorf("auto_answer: an answer or an answerer_prompt is required")
+		}
+		for _, pattern := range auto.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("auto_answer: invalid pattern %q: %w", pattern, err)
+			}
+		}
+		if _, err := template.New("answerer").Parse(auto.AnswererPrompt); err != nil {
+			return fmt.Errorf("auto_answer: invalid answerer_prompt: %w", err)
+		}
+	}
+	if err := c.Retry.validate(); err != nil {
+		return err
+	}
+	if err := c.Executor.validate(); err != nil {
+		return err
+	}
+	if slices.Contains(c.Executor.Middleware, MiddlewareRetry) && c.Retry.MaxAttempts > 1 {
+		return fmt.Errorf("executor: the retry middleware and step retries (retry max_attempts %d) would both retry a failed session; "+
+			"set retry max_attempts to 1 or remove the retry middleware", c.Retry.MaxAttempts)
+	}
+	return nil
+}
+
+// validateAPI checks that the api backend can enforce every tool rule. It
+// matches rules by tool name and matches Bash(...) rules against commands,
+// but has no argument patterns for other tools, such as "Edit(docs/**)".
+func (p PermissionsConfig) validateAPI(section string) error {
+	for _, rule := range slices.Concat(p.AllowedTools, p.DisallowedTools) {
+		name, _, hasArgs := strings.Cut(rule, "(")
+		if hasArgs && name != "Bash" {
+			return fmt.Errorf("%s: permission rule %q is not supported by the api backend (only Bash rules take arguments)", section, rule)
+		}
+	}
+	return nil
+}
+
+// validate checks the attempts, waits and failure classes of step retries.
+func (r RetryConfig) validate() error {
+	switch {
+	case r.MaxAttempts < 1:
+		return fmt.Errorf("retry: max_attempts must be at least 1, got %d", r.MaxAttempts)
+	case r.InitialBackoff < 0:
+		return fmt.Errorf("retry: initial_backoff must not be negative, got %s", r.InitialBackoff)
+	case r.MaxBackoff < 0:
+		return fmt.Errorf("retry: max_backoff must not be negative, got %s", r.MaxBackoff)
+	case r.Multiplier < 0:
+		return fmt.Errorf("retry: multiplier must not be negative, got %g", r.Multiplier)
+	case r.UsageLimit.MaxWait < 0:
+		return fmt.Errorf("retry: usage_limit max_wait must not be negative, got %s", r.UsageLimit.MaxWait)
+	case r.UsageLimit.Margin < 0:
+		return fmt.Errorf("retry: usage_limit margin must not be negative, got %s", r.UsageLimit.Margin)
+	}
+	return validateFailureClasses("retry", r.RetryOn)
+}
+
+// validateFailureClasses checks that every retry_on class is known. Usage
+// limits are not retried with backoff, only waited out (see
+// [UsageLimitConfig]), so "usage_limit" is refused with a pointer there.
+func validateFailureClasses(section string, classes []string) error {
+	for _, class := range classes {
+		if class == "usage_limit" {
+			return fmt.Errorf("%s: retry_on cannot list usage_limit: usage limits are waited out as set in retry.usage_limit", section)
+		}
+		if !slices.Contains(failureClasses, class) {
+			return fmt.Errorf("%s: unknown retry_on class %q (want one of %s)", section, class, strings.Join(failureClasses, ", "))
+		}
+	}
+	return nil
+}
+
+// failureClasses are the values accepted in retry_on lists.
+var failureClasses = []string{"unknown", "overloaded", "rate_limit", "network", "timeout"}
+
+// validate checks the middleware list and the settings of each listed middleware.
+func (e ExecutorConfig) validate() error {
+	seen := make(map[string]bool, len(e.Middleware))
+	for _, name := range e.Middleware {
+		if seen[name] {
+			return fmt.Errorf("executor: middleware %q is listed twice", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case MiddlewareLogging:
+		case MiddlewarePolicy:
+			for _, pattern := range slices.Concat(e.Policy.DenyCommands, e.Policy.DenyPaths) {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("executor: invalid policy pattern %q: %w", pattern, err)
+				}
+			}
+		case MiddlewareRetry:
+			if e.Retry.MaxAttempts < 1 {
+				return fmt.Errorf("executor: retry max_attempts must be at least 1, got %d", e.Retry.MaxAttempts)
+			}
+			if e.Retry.Backoff < 0 {
+				return fmt.Errorf("executor: retry backoff must not be negative, got %s", e.Retry.Backoff)
+			}
+			if err := validateFailureClasses("executor", e.Retry.RetryOn); err != nil {
+				return err
+			}
+		case MiddlewareRecording:
+			if e.Recording.Dir == "" {
+				return fmt.Errorf("executor: recording dir is required")
+			}
+		default:
+			return fmt.Errorf("executor: unknown middleware %q (want logging, policy, retry or recording)", name)
+		}
+	}
+	return nil
+}
+
 // GetFullCycleSteps returns the list of workflow steps for a full lifecycle.
 //
 // This returns the configured FullCycle.Steps slice, which defines the
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,8 +25,15 @@ func TestDefaultConfig(t *testing.T) {
 	// Check defaults
 	assert.Equal(t, "stream-json", cfg.Claude.OutputFormat)
 	assert.Equal(t, "claude", cfg.Claude.BinaryPath)
+	assert.Equal(t, "bypassPermissions", cfg.Claude.Permissions.Mode)
 	assert.Equal(t, 20, cfg.Output.TruncateLines)
 	assert.Equal(t, 60, cfg.Output.TruncateLength)
+	assert.Equal(t, 3, cfg.Retry.MaxAttempts)
+	assert.Equal(t, 30*time.Second, cfg.Retry.InitialBackoff)
+	assert.Equal(t, []string{"overloaded", "rate_limit", "network"}, cfg.Retry.RetryOn)
+	assert.True(t, cfg.Retry.UsageLimit.Wait)
+	assert.Equal(t, 6*time.Hour, cfg.Retry.UsageLimit.MaxWait)
+	assert.Equal(t, time.Minute, cfg.Retry.UsageLimit.Margin)
 }
 
 func TestConfig_GetPrompt(t *testing.T) {
@@ -110,6 +118,133 @@ output:
 	assert.Equal(t, 50, cfg.Output.TruncateLines)
 }
 
+func TestConfig_GetPermissions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Claude.Permissions = PermissionsConfig{
+		Mode:            "acceptEdits",
+		DisallowedTools: []string{"Bash(git push:*)"},
+	}
+	cfg.Workflows["git-commit"] = WorkflowConfig{
+		PromptTemplate: "commit {{.StoryKey}}",
+		Permissions: PermissionsConfig{
+			AllowedTools:    []string{"Bash(git:*)"},
+			DisallowedTools: []string{},
+		},
+	}
+	cfg.Workflows["code-review"] = WorkflowConfig{
+		PromptTemplate: "review {{.StoryKey}}",
+		Permissions:    PermissionsConfig{Mode: "plan"},
+	}
+
+	tests := []struct {
+		name         string
+		workflowName string
+		want         PermissionsConfig
+	}{
+		{
+			name:         "workflow without overrides inherits defaults",
+			workflowName: "dev-story",
+			want:         PermissionsConfig{Mode: "acceptEdits", DisallowedTools: []string{"Bash(git push:*)"}},
+		},
+		{
+			name:         "tool lists replace defaults",
+			workflowName: "git-commit",
+			want:         PermissionsConfig{Mode: "acceptEdits", AllowedTools: []string{"Bash(git:*)"}, DisallowedTools: []string{}},
+		},
+		{
+			name:         "mode overrides default",
+			workflowName: "code-review",
+			want:         PermissionsConfig{Mode: "plan", DisallowedTools: []string{"Bash(git push:*)"}},
+		},
+		{
+			name:         "unknown workflow gets defaults",
+			workflowName: "unknown",
+			want:         PermissionsConfig{Mode: "acceptEdits", DisallowedTools: []string{"Bash(git push:*)"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cfg.GetPermissions(tt.workflowName))
+		})
+	}
+}
+
+func TestLoader_LoadFromFile_Permissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "perms.yaml")
+
+	configContent := `
+workflows:
+  git-commit:
+    prompt_template: "Commit {{.StoryKey}}"
+    permissions:
+      allowed_tools:
+        - "Bash(git push:*)"
+claude:
+  permissions:
+    mode: acceptEdits
+    disallowed_tools:
+      - "Bash(git push:*)"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	cfg, err := NewLoader().LoadFromFile(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "acceptEdits", cfg.Claude.Permissions.Mode)
+	assert.Equal(t, []string{"Bash(git push:*)"}, cfg.Claude.Permissions.DisallowedTools)
+	assert.Equal(t, []string{"Bash(git push:*)"}, cfg.Workflows["git-commit"].Permissions.AllowedTools)
+}
+
+func TestLoader_LoadFromFile_Timeouts(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "timeouts.yaml")
+
+	configContent := `
+workflows:
+  code-review:
+    prompt_template: "Review {{.StoryKey}}"
+    timeout: 45m
+    idle_timeout: 10m
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	cfg, err := NewLoader().LoadFromFile(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, 45*time.Minute, cfg.Workflows["code-review"].Timeout)
+	assert.Equal(t, 10*time.Minute, cfg.Workflows["code-review"].IdleTimeout)
+	assert.Zero(t, cfg.Workflows["dev-story"].Timeout, "unset timeouts mean no limit")
+}
+
+func TestLoader_LoadFromFile_Retry(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "retry.yaml")
+
+	configContent := `
+retry:
+  max_attempts: 5
+  initial_backoff: 1m
+  retry_on: [overloaded, timeout]
+  usage_limit:
+    wait: false
+    max_wait: 2h
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	cfg, err := NewLoader().LoadFromFile(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, cfg.Retry.MaxAttempts)
+	assert.Equal(t, time.Minute, cfg.Retry.InitialBackoff)
+	assert.Equal(t, 5*time.Minute, cfg.Retry.MaxBackoff, "unset fields keep their defaults")
+	assert.Equal(t, []string{"overloaded", "timeout"}, cfg.Retry.RetryOn)
+	assert.False(t, cfg.Retry.UsageLimit.Wait)
+	assert.Equal(t, 2*time.Hour, cfg.Retry.UsageLimit.MaxWait)
+	assert.Equal(t, time.Minute, cfg.Retry.UsageLimit.Margin)
+}
+
 func TestLoader_Load_WithEnvOverride(t *testing.T) {
 	// Set environment variable
 	os.Setenv("BMAD_CLAUDE_PATH", "/env/claude")
@@ -339,3 +474,285 @@ func TestPromptData_StoryKey(t *testing.T) {
 	data := PromptData{StoryKey: "ABC-123"}
 	assert.Equal(t, "ABC-123", data.StoryKey)
 }
+
+func TestLoader_LoadFromFile_Agents(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "agents.yaml")
+
+	configContent := `
+agents:
+  codex:
+    command: codex
+    args: ["exec", "{{.Prompt}}"]
+  reviewer:
+    command: claude-wrapper
+    output: stream-json
+workflows:
+  code-review:
+    prompt_template: "review {{.StoryKey}}"
+    agent: codex
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	cfg, err := NewLoader().LoadFromFile(configPath)
+	require.NoError(t, err)
+
+	require.Contains(t, cfg.Agents, "codex")
+	assert.Equal(t, "codex", cfg.Agents["codex"].Command)
+	assert.Equal(t, []string{"exec", "{{.Prompt}}"}, cfg.Agents["codex"].Args)
+	assert.Equal(t, "stream-json", cfg.Agents["reviewer"].Output)
+	assert.Equal(t, "codex", cfg.Workflows["code-review"].Agent)
+	assert.Empty(t, cfg.Workflows["dev-story"].Agent, "workflows run on Claude by default")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		agents  map[string]AgentConfig
+		agent   string
+		wantErr string
+	}{
+		{"no agents", nil, "", ""},
+		{"valid agent", map[string]AgentConfig{"codex": {Command: "codex", Args: []string{"{{.Prompt}}"}}}, "codex", ""},
+		{"missing command", map[string]AgentConfig{"codex": {}}, "", "agent codex: command is required"},
+		{"unknown output", map[string]AgentConfig{"codex": {Command: "codex", Output: "json"}}, "", `unknown output format "json"`},
+		{"bad template", map[string]AgentConfig{"codex": {Command: "codex", Args: []string{"{{.Prompt"}}}, "", "invalid argument template"},
+		{"unknown agent", nil, "gemini", `workflow code-review: unknown agent "gemini"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Agents = tt.agents
+			wf := cfg.Workflows["code-review"]
+			wf.Agent = tt.agent
+			cfg.Workflows["code-review"] = wf
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoader_LoadFromFile_APIBackend(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "api.yaml")
+
+	configContent := `
+claude:
+  backend: api
+  api:
+    base_url: http://localhost:8080
+    model: my-model
+    max_turns: 20
+    bash_timeout: 30s
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	cfg, err := NewLoader().LoadFromFile(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "api", cfg.Claude.Backend)
+	assert.Equal(t, "http://localhost:8080", cfg.Claude.API.BaseURL)
+	assert.Equal(t, "my-model", cfg.Claude.API.Model)
+	assert.Equal(t, 20, cfg.Claude.API.MaxTurns)
+	assert.Equal(t, 30*time.Second, cfg.Claude.API.BashTimeout)
+	assert.Equal(t, "ANTHROPIC_API_KEY", cfg.Claude.API.APIKeyEnv, "unset fields keep their defaults")
+}
+
+func TestConfig_Validate_Backend(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, "cli", cfg.Claude.Backend)
+
+	cfg.Claude.Backend = "api"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Claude.Backend = "grpc"
+	assert.ErrorContains(t, cfg.Validate(), `unknown backend "grpc"`)
+}
+
+func TestConfig_Validate_APIPermissions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Claude.Backend = "api"
+	cfg.Claude.Permissions.AllowedTools = []string{"Read", "Bash(go test:*)"}
+	cfg.Claude.Permissions.DisallowedTools = []string{"Bash(git push:*)"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Workflows["dev-story"] = WorkflowConfig{
+		PromptTemplate: "x",
+		Permissions:    PermissionsConfig{DisallowedTools: []string{"Edit(docs/**)"}},
+	}
+	assert.ErrorContains(t, cfg.Validate(), `workflow dev-story: permission rule "Edit(docs/**)" is not supported by the api backend`)
+
+	cfg.Claude.Backend = "cli"
+	assert.NoError(t, cfg.Validate(), "the CLI enforces every rule itself")
+}
+
+func TestConfig_Validate_AutoAnswer(t *testing.T) {
+	valid := func() *Config {
+		cfg := DefaultConfig()
+		cfg.Claude.InputFormat = "stream-json"
+		cfg.AutoAnswer.Enabled = true
+		return cfg
+	}
+	assert.False(t, DefaultConfig().AutoAnswer.Enabled)
+	assert.Equal(t, 3, DefaultConfig().AutoAnswer.MaxExchanges)
+	assert.NoError(t, valid().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"text input", func(c *Config) { c.Claude.InputFormat = "text" }, "requires claude input_format stream-json"},
+		{"api backend", func(c *Config) { c.Claude.Backend = "api" }, "not supported by the api backend"},
+		{"no exchanges", func(c *Config) { c.AutoAnswer.MaxExchanges = 0 }, "max_exchanges must be at least 1"},
+		{"no answer", func(c *Config) { c.AutoAnswer.Answer = " " }, "an answer or an answerer_prompt is required"},
+		{"bad pattern", func(c *Config) { c.AutoAnswer.Patterns = []string{"("} }, `invalid pattern "("`},
+		{"bad answerer prompt", func(c *Config) { c.AutoAnswer.AnswererPrompt = "{{.Question" }, "invalid answerer_prompt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			assert.ErrorContains(t, cfg.Validate(), "auto_answer: "+tt.want)
+		})
+	}
+
+	cfg := valid()
+	cfg.Claude.InputFormat = "text"
+	cfg.AutoAnswer.Enabled = false
+	assert.NoError(t, cfg.Validate(), "disabled settings are not checked")
+}
+
+func TestConfig_Validate_Executor(t *testing.T) {
+	valid := func() *Config {
+		cfg := DefaultConfig()
+		cfg.Executor.Middleware = []string{"logging", "policy", "retry", "recording"}
+		cfg.Retry.MaxAttempts = 1
+		return cfg
+	}
+	assert.Empty(t, DefaultConfig().Executor.Middleware)
+	assert.NoError(t, valid().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"unknown", func(c *Config) { c.Executor.Middleware = []string{"budget"} }, `unknown middleware "budget"`},
+		{"twice", func(c *Config) { c.Executor.Middleware = []string{"retry", "logging", "retry"} }, `middleware "retry" is listed twice`},
+		{"bad command pattern", func(c *Config) { c.Executor.Policy.DenyCommands = []string{"("} }, `invalid policy pattern "("`},
+		{"bad path pattern", func(c *Config) { c.Executor.Policy.DenyPaths = []string{"["} }, `invalid policy pattern "["`},
+		{"no attempts", func(c *Config) { c.Executor.Retry.MaxAttempts = 0 }, "retry max_attempts must be at least 1"},
+		{"negative backoff", func(c *Config) { c.Executor.Retry.Backoff = -time.Second }, "retry backoff must not be negative"},
+		{"unknown class", func(c *Config) { c.Executor.Retry.RetryOn = []string{"flaky"} }, `unknown retry_on class "flaky"`},
+		{"no recording dir", func(c *Config) { c.Executor.Recording.Dir = "" }, "recording dir is required"},
+		{"step retries too", func(c *Config) { c.Retry.MaxAttempts = 3 }, "the retry middleware and step retries (retry max_attempts 3) would both retry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			assert.ErrorContains(t, cfg.Validate(), "executor: "+tt.want)
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.Executor.Retry.MaxAttempts = 0
+	cfg.Executor.Recording.Dir = ""
+	assert.NoError(t, cfg.Validate(), "settings of middleware not listed are not checked")
+}
+
+func TestConfig_Validate_Retry(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"no attempts", func(c *Config) { c.Retry.MaxAttempts = 0 }, "max_attempts must be at least 1"},
+		{"negative initial backoff", func(c *Config) { c.Retry.InitialBackoff = -time.Second }, "initial_backoff must not be negative"},
+		{"negative max backoff", func(c *Config) { c.Retry.MaxBackoff = -time.Second }, "max_backoff must not be negative"},
+		{"negative multiplier", func(c *Config) { c.Retry.Multiplier = -1 }, "multiplier must not be negative"},
+		{"negative max wait", func(c *Config) { c.Retry.UsageLimit.MaxWait = -time.Minute }, "usage_limit max_wait must not be negative"},
+		{"negative margin", func(c *Config) { c.Retry.UsageLimit.Margin = -time.Minute }, "usage_limit margin must not be negative"},
+		{"unknown class", func(c *Config) { c.Retry.RetryOn = []string{"overloaded", "rate-limit"} }, `unknown retry_on class "rate-limit"`},
+		{"usage limit class", func(c *Config) { c.Retry.RetryOn = []string{"usage_limit"} }, "retry_on cannot list usage_limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+			assert.ErrorContains(t, cfg.Validate(), "retry: "+tt.want)
+		})
+	}
+}
+
+func TestConfig_Validate_InputFormat(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, "text", cfg.Claude.InputFormat)
+
+	cfg.Claude.InputFormat = "stream-json"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Claude.OutputFormat = "text"
+	assert.ErrorContains(t, cfg.Validate(), "input_format stream-json requires output_format stream-json")
+
+	cfg.Claude.InputFormat = "xml"
+	assert.ErrorContains(t, cfg.Validate(), `unknown input_format "xml"`)
+}
+
+func TestLoader_LoadFromFile_Archive(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "archive.yaml")
+
+	configContent := `
+output:
+  archive:
+    dir: runs
+    max_runs: 50
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(configContent), 0644))
+
+	cfg, err := NewLoader().LoadFromFile(configPath)
+	require.NoError(t, err)
+
+	assert.True(t, cfg.Output.Archive.Enabled, "unset fields keep their defaults")
+	assert.Equal(t, "runs", cfg.Output.Archive.Dir)
+	assert.Equal(t, 50, cfg.Output.Archive.MaxRuns)
+	assert.Equal(t, 30*24*time.Hour, cfg.Output.Archive.MaxAge)
+}
+
+func TestConfig_Validate_Archive(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*ArchiveConfig)
+		wantErr string
+	}{
+		{"defaults", func(*ArchiveConfig) {}, ""},
+		{"no dir", func(a *ArchiveConfig) { a.Dir = "" }, "archive dir is required"},
+		{"negative max_runs", func(a *ArchiveConfig) { a.MaxRuns = -1 }, "max_runs must not be negative"},
+		{"negative max_age", func(a *ArchiveConfig) { a.MaxAge = -time.Hour }, "max_age must not be negative"},
+		{"disabled", func(a *ArchiveConfig) { a.Enabled = false; a.Dir = "" }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(&cfg.Output.Archive)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -10,6 +10,14 @@
 //   - [Loader] handles Viper-based configuration loading
 //   - [WorkflowConfig] defines a single workflow's prompt template
 //   - [ClaudeConfig] contains Claude CLI binary settings
+//   - [APIConfig] configures the Messages API backend
+//   - [AgentConfig] defines another coding-agent CLI a workflow can run on
+//   - [PermissionsConfig] controls which tools Claude may use
+//   - [BudgetConfig] caps spend per step, story, and invocation
+//   - [RetryConfig] retries steps that fail for transient reasons
+//   - [AutoAnswerConfig] answers questions Claude asks mid-session
+//   - [ExecutorConfig] wraps every session in middleware such as retries
+//   - [UsageLimitConfig] waits for the Claude usage limit to reset
 //
 // Configuration priority (highest to lowest):
 //  1. Environment variables (BMAD_ prefix)
@@ -18,6 +26,8 @@
 //  4. [DefaultConfig] defaults
 package config
 
+import "time"
+
 // Config represents the root configuration structure.
 //
 // This is the main configuration container loaded by [Loader] and used throughout
@@ -36,6 +46,23 @@ type Config struct {
 
 	// Output contains terminal output formatting configuration.
 	Output OutputConfig `mapstructure:"output"`
+
+	// Budget contains spending limits for Claude sessions.
+	Budget BudgetConfig `mapstructure:"budget"`
+
+	// Retry controls automatic retries of steps that fail for transient reasons.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// AutoAnswer controls automatic answers to questions Claude asks at the
+	// end of a turn.
+	AutoAnswer AutoAnswerConfig `mapstructure:"auto_answer"`
+
+	// Agents maps agent names to coding-agent CLIs that workflows can run on
+	// instead of Claude (see [WorkflowConfig.Agent]).
+	Agents map[string]AgentConfig `mapstructure:"agents"`
+
+	// Executor selects the middleware every Claude session runs through.
+	Executor ExecutorConfig `mapstructure:"executor"`
 }
 
 // WorkflowConfig represents a single workflow configuration.
@@ -47,6 +74,44 @@ type WorkflowConfig struct {
 	// Use {{.StoryKey}} to reference the story key.
 	// Example: "Work on story: {{.StoryKey}}"
 	PromptTemplate string `mapstructure:"prompt_template"`
+
+	// Permissions overrides the Claude permission settings for this workflow.
+	// Fields left unset fall back to [ClaudeConfig.Permissions].
+	// See [Config.GetPermissions] for the merge rules.
+	Permissions PermissionsConfig `mapstructure:"permissions"`
+
+	// Timeout limits the total run time of one Claude session for this
+	// workflow, e.g. "45m". Zero means no limit.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// IdleTimeout limits how long a session may go without producing any
+	// stream output before it is considered stalled, e.g. "10m".
+	// Zero means no limit.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+
+	// Agent names the entry in [Config.Agents] that runs this workflow.
+	// Empty runs it on Claude.
+	Agent string `mapstructure:"agent"`
+}
+
+// PermissionsConfig defines the Claude CLI permission settings for a session.
+//
+// Each field maps onto a Claude CLI flag:
+//   - Mode: --permission-mode
+//   - AllowedTools: --allowedTools
+//   - DisallowedTools: --disallowedTools
+//
+// Tool rules use Claude's syntax, e.g. "Read", "Edit" or "Bash(git push:*)".
+type PermissionsConfig struct {
+	// Mode is the Claude permission mode: "default", "acceptEdits",
+	// "bypassPermissions", or "plan". Empty inherits the default.
+	Mode string `mapstructure:"mode"`
+
+	// AllowedTools lists tool rules Claude may use without prompting.
+	AllowedTools []string `mapstructure:"allowed_tools"`
+
+	// DisallowedTools lists tool rules Claude must never use.
+	DisallowedTools []string `mapstructure:"disallowed_tools"`
 }
 
 // FullCycleConfig defines the steps for a full development cycle.
@@ -61,24 +126,100 @@ type FullCycleConfig struct {
 
 // ClaudeConfig contains Claude CLI configuration.
 //
-// These settings control how the Claude CLI binary is invoked.
+// These settings control how the Claude CLI binary is invoked, or, with
+// Backend "api", how the Messages API is called instead.
 type ClaudeConfig struct {
+	// Backend selects how sessions run: "cli" runs the Claude CLI binary,
+	// "api" calls the Messages API directly with a built-in tool loop.
+	// Default: "cli"
+	Backend string `mapstructure:"backend"`
+
 	// OutputFormat is the output format passed to Claude CLI.
 	// Should be "stream-json" for structured event parsing.
 	OutputFormat string `mapstructure:"output_format"`
 
+	// InputFormat is how the prompt is written to Claude CLI's stdin:
+	// "text" writes the prompt as is; "stream-json" writes it as a user
+	// message and keeps stdin open for follow-up messages. "stream-json"
+	// requires OutputFormat "stream-json".
+	// Default: "text"
+	InputFormat string `mapstructure:"input_format"`
+
 	// BinaryPath is the path to the Claude CLI binary.
 	// Default: "claude" (assumes Claude is in PATH).
 	// Can be overridden with BMAD_CLAUDE_PATH environment variable.
 	BinaryPath string `mapstructure:"binary_path"`
+
+	// Permissions are the default permission settings for every workflow
+	// and for raw prompts. Workflows may override them individually.
+	// Default: mode "bypassPermissions" with no tool rules.
+	Permissions PermissionsConfig `mapstructure:"permissions"`
+
+	// ResumePrompt is the message sent when resuming an interrupted session
+	// with --resume. It is not a template; the session already has context.
+	ResumePrompt string `mapstructure:"resume_prompt"`
+
+	// API configures the Messages API, used when Backend is "api".
+	API APIConfig `mapstructure:"api"`
+}
+
+// APIConfig configures the Messages API backend.
+//
+// Zero values fall back to the defaults of the api package.
+type APIConfig struct {
+	// BaseURL is the root of an Anthropic-compatible endpoint.
+	// Default: "https://api.anthropic.com"
+	BaseURL string `mapstructure:"base_url"`
+
+	// Model is the model to use.
+	// Default: "claude-sonnet-4-5"
+	Model string `mapstructure:"model"`
+
+	// APIKeyEnv names the environment variable holding the API key.
+	// Default: "ANTHROPIC_API_KEY"
+	APIKeyEnv string `mapstructure:"api_key_env"`
+
+	// MaxTokens is the maximum number of tokens per model response.
+	// Default: 8192
+	MaxTokens int `mapstructure:"max_tokens"`
+
+	// MaxTurns is the maximum number of model turns per session.
+	// Default: 100
+	MaxTurns int `mapstructure:"max_turns"`
+
+	// BashTimeout limits each shell command the model runs.
+	// Default: 2m
+	BashTimeout time.Duration `mapstructure:"bash_timeout"`
+}
+
+// AgentConfig defines a coding-agent CLI that workflows can run on.
+//
+// The command is run with Args, each a Go template expanded per session with
+// {{.Prompt}}, {{.ResumeSessionID}}, {{.PermissionMode}}, {{.AllowedTools}}
+// and {{.DisallowedTools}} (tool rules comma-separated). Arguments that
+// expand to an empty string are dropped, so a flag can be conditional:
+//
+//	args: ["{{if .ResumeSessionID}}--resume{{end}}", "{{.ResumeSessionID}}", "-p", "{{.Prompt}}"]
+type AgentConfig struct {
+	// Command is the agent binary, e.g. "codex". Required.
+	Command string `mapstructure:"command"`
+
+	// Args are the argument templates passed to Command.
+	Args []string `mapstructure:"args"`
+
+	// Output is the format of the agent's stdout: "text", shown line by
+	// line, or "stream-json", Claude's event format.
+	// Default: "text"
+	Output string `mapstructure:"output"`
 }
 
 // OutputConfig contains terminal output formatting configuration.
 //
 // These settings control how Claude's output is formatted in the terminal.
 type OutputConfig struct {
-	// TruncateLines is the maximum number of lines to display per event.
-	// Additional lines are hidden with a "... (N more lines)" indicator.
+	// TruncateLines is the maximum number of lines to display per event,
+	// such as a tool's output or the diff of a file edit. Additional lines
+	// are hidden with a "... (N lines omitted) ..." indicator.
 	// Default: 20
 	TruncateLines int `mapstructure:"truncate_lines"`
 
@@ -86,6 +227,228 @@ type OutputConfig struct {
 	// Longer lines are truncated with "..." suffix.
 	// Default: 60
 	TruncateLength int `mapstructure:"truncate_length"`
+
+	// Archive controls the on-disk record kept of every Claude session.
+	Archive ArchiveConfig `mapstructure:"archive"`
+}
+
+// ArchiveConfig controls the run archive.
+//
+// When enabled, each Claude session run by the workflow runner is archived
+// under Dir/<story>/<timestamp>-<workflow>/ with its stream-json events,
+// stderr, prompt, exit code, and timing. Runs beyond MaxRuns or older than
+// MaxAge are deleted after each run; zero disables either limit.
+type ArchiveConfig struct {
+	// Enabled turns the archive on. Default: true
+	Enabled bool `mapstructure:"enabled"`
+
+	// Dir is the archive directory, relative to the working directory.
+	// Default: _bmad-output/automation-runs
+	Dir string `mapstructure:"dir"`
+
+	// MaxRuns is the number of most recent runs to keep. Default: 0 (no limit)
+	MaxRuns int `mapstructure:"max_runs"`
+
+	// MaxAge is how long runs are kept, e.g. "720h". Default: 30 days
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// BudgetConfig contains spending limits at each scope.
+//
+// When a limit is exceeded the running Claude session is canceled, the step is
+// marked failed, and sprint-status is left untouched. Token limits are checked
+// as usage streams in; cost limits are checked when Claude reports the session
+// cost in its final result event. All limits default to zero (unlimited).
+type BudgetConfig struct {
+	// Step limits a single workflow step.
+	Step BudgetLimitsConfig `mapstructure:"step"`
+
+	// Story limits all steps run for one story.
+	Story BudgetLimitsConfig `mapstructure:"story"`
+
+	// Invocation limits everything run by one bmad-automate command.
+	Invocation BudgetLimitsConfig `mapstructure:"invocation"`
+}
+
+// BudgetLimitsConfig caps cost and tokens for one budget scope.
+//
+// A zero value means that dimension is unlimited.
+type BudgetLimitsConfig struct {
+	// MaxCostUSD is the maximum cost in US dollars.
+	MaxCostUSD float64 `mapstructure:"max_cost_usd"`
+
+	// MaxTokens is the maximum number of tokens (input, output, and cache).
+	MaxTokens int64 `mapstructure:"max_tokens"`
+}
+
+// RetryConfig controls automatic retries of failed lifecycle steps.
+//
+// Only failures in one of the RetryOn classes are retried; others fail the
+// step at once. The wait between attempts starts at InitialBackoff and is
+// multiplied by Multiplier after each further failure, up to MaxBackoff.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts per step, including the
+	// first. Set to 1 to disable retries.
+	// Default: 3
+	MaxAttempts int `mapstructure:"max_attempts"`
+
+	// InitialBackoff is the wait before the second attempt.
+	// Default: 30s
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+
+	// MaxBackoff caps the wait between attempts. Zero means no cap.
+	// Default: 5m
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+
+	// Multiplier scales the wait after each further failed attempt.
+	// Default: 2
+	Multiplier float64 `mapstructure:"multiplier"`
+
+	// RetryOn lists the failure classes to retry: "overloaded", "rate_limit",
+	// "network", "timeout", and "unknown" (any other failure). Usage limits
+	// are not listed here; they are waited out as set in UsageLimit.
+	// Default: ["overloaded", "rate_limit", "network"]
+	RetryOn []string `mapstructure:"retry_on"`
+
+	// UsageLimit controls waiting for the Claude usage limit to reset.
+	UsageLimit UsageLimitConfig `mapstructure:"usage_limit"`
+}
+
+// UsageLimitConfig controls what happens when a step stops because the Claude
+// subscription reached its usage limit.
+//
+// When Wait is set, the step waits until the limit resets (plus Margin) and
+// then resumes its session, rather than failing the story. Waits do not count
+// against [RetryConfig.MaxAttempts].
+type UsageLimitConfig struct {
+	// Wait enables waiting for the limit to reset.
+	// Default: true
+	Wait bool `mapstructure:"wait"`
+
+	// MaxWait is the longest wait for a reset; a limit that resets later
+	// fails the step. It is also the wait when Claude does not say when the
+	// limit resets. Zero means no cap.
+	// Default: 6h
+	MaxWait time.Duration `mapstructure:"max_wait"`
+
+	// Margin is added to the reset time before resuming.
+	// Default: 1m
+	Margin time.Duration `mapstructure:"margin"`
+}
+
+// AutoAnswerConfig controls automatic answers to questions Claude asks at the
+// end of a turn instead of finishing its task.
+//
+// A final message is a question when one of Patterns matches it or when the
+// built-in heuristics find it asking for input, such as a last paragraph
+// ending with a question mark. The question is answered in the same session,
+// which requires [ClaudeConfig.InputFormat] "stream-json". After MaxExchanges
+// answers the session is left to end.
+type AutoAnswerConfig struct {
+	// Enabled turns automatic answers on.
+	// Default: false
+	Enabled bool `mapstructure:"enabled"`
+
+	// Patterns are extra regular expressions (Go syntax) that mark a final
+	// message as a question.
+	Patterns []string `mapstructure:"patterns"`
+
+	// Answer is the reply sent to every question.
+	Answer string `mapstructure:"answer"`
+
+	// AnswererPrompt, if set, is a Go template for the prompt of a second,
+	// read-only Claude session whose answer is sent instead of Answer. It can
+	// use {{.Question}}, {{.StoryKey}} and {{.Workflow}}.
+	AnswererPrompt string `mapstructure:"answerer_prompt"`
+
+	// MaxExchanges is how many questions are answered per session.
+	// Default: 3
+	MaxExchanges int `mapstructure:"max_exchanges"`
+}
+
+// Middleware names accepted in [ExecutorConfig.Middleware].
+const (
+	MiddlewareLogging   = "logging"
+	MiddlewarePolicy    = "policy"
+	MiddlewareRetry     = "retry"
+	MiddlewareRecording = "recording"
+)
+
+// ExecutorConfig selects the middleware that wraps the executor running
+// Claude sessions, and configures each one.
+//
+// Middleware lists the enabled middleware, outermost first: with
+// ["logging", "policy", "recording"] the log covers sessions stopped by the
+// policy, and the recordings include the events the policy rejected. Each
+// name may appear once. Settings of middleware not listed are ignored.
+type ExecutorConfig struct {
+	// Middleware lists the enabled middleware by name, outermost first:
+	// "logging", "policy", "retry", and "recording".
+	// Default: [] (none)
+	Middleware []string `mapstructure:"middleware"`
+
+	// Logging configures the "logging" middleware.
+	Logging LoggingMiddlewareConfig `mapstructure:"logging"`
+
+	// Policy configures the "policy" middleware.
+	Policy PolicyMiddlewareConfig `mapstructure:"policy"`
+
+	// Retry configures the "retry" middleware.
+	Retry RetryMiddlewareConfig `mapstructure:"retry"`
+
+	// Recording configures the "recording" middleware.
+	Recording RecordingMiddlewareConfig `mapstructure:"recording"`
+}
+
+// LoggingMiddlewareConfig configures the middleware that logs the start and
+// end of every session.
+type LoggingMiddlewareConfig struct {
+	// File is the log file, appended to. Empty logs to stderr.
+	File string `mapstructure:"file"`
+}
+
+// PolicyMiddlewareConfig configures the middleware that stops a session as
+// soon as Claude calls a denied tool. Unlike
+// [PermissionsConfig.DisallowedTools], it works on every backend and agent,
+// but the call may already have run when the session is stopped.
+type PolicyMiddlewareConfig struct {
+	// DenyTools lists tool names, e.g. "WebFetch", whose calls stop the session.
+	DenyTools []string `mapstructure:"deny_tools"`
+
+	// DenyCommands are regular expressions (Go syntax); a Bash command
+	// matching one stops the session.
+	DenyCommands []string `mapstructure:"deny_commands"`
+
+	// DenyPaths are regular expressions (Go syntax); a file operation on a
+	// path matching one stops the session.
+	DenyPaths []string `mapstructure:"deny_paths"`
+}
+
+// RetryMiddlewareConfig configures the middleware that starts a session again
+// when it fails for a transient reason before Claude did any work. It is an
+// alternative to [RetryConfig], which retries whole steps: enabling it
+// requires RetryConfig.MaxAttempts to be 1, so attempts never multiply.
+type RetryMiddlewareConfig struct {
+	// MaxAttempts is the total number of attempts per session, including
+	// the first.
+	// Default: 3
+	MaxAttempts int `mapstructure:"max_attempts"`
+
+	// Backoff is the wait before each further attempt.
+	// Default: 10s
+	Backoff time.Duration `mapstructure:"backoff"`
+
+	// RetryOn lists the failure classes to retry, as in [RetryConfig.RetryOn].
+	// Default: ["overloaded", "rate_limit", "network"]
+	RetryOn []string `mapstructure:"retry_on"`
+}
+
+// RecordingMiddlewareConfig configures the middleware that records every
+// session as a transcript for the replay command.
+type RecordingMiddlewareConfig struct {
+	// Dir is the directory the transcripts are written to.
+	// Default: "_bmad-output/transcripts"
+	Dir string `mapstructure:"dir"`
 }
 
 // DefaultConfig returns a new [Config] with sensible defaults.
@@ -114,12 +477,52 @@ func DefaultConfig() *Config {
 			Steps: []string{"create-story", "dev-story", "code-review", "git-commit"},
 		},
 		Claude: ClaudeConfig{
+			Backend:      "cli",
 			OutputFormat: "stream-json",
+			InputFormat:  "text",
 			BinaryPath:   "claude",
+			Permissions: PermissionsConfig{
+				Mode: "bypassPermissions",
+			},
+			ResumePrompt: "Continue where you left off. Finish the remaining work for this task. Do not ask questions.",
+			API: APIConfig{
+				APIKeyEnv: "ANTHROPIC_API_KEY",
+			},
 		},
 		Output: OutputConfig{
 			TruncateLines:  20,
 			TruncateLength: 60,
+			Archive: ArchiveConfig{
+				Enabled: true,
+				Dir:     "_bmad-output/automation-runs",
+				MaxAge:  30 * 24 * time.Hour,
+			},
+		},
+		Retry: RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: 30 * time.Second,
+			MaxBackoff:     5 * time.Minute,
+			Multiplier:     2,
+			RetryOn:        []string{"overloaded", "rate_limit", "network"},
+			UsageLimit: UsageLimitConfig{
+				Wait:    true,
+				MaxWait: 6 * time.Hour,
+				Margin:  time.Minute,
+			},
+		},
+		AutoAnswer: AutoAnswerConfig{
+			Answer:       "Proceed with your best judgment, following the story's acceptance criteria and the project's existing conventions. Do not ask further questions.",
+			MaxExchanges: 3,
+		},
+		Executor: ExecutorConfig{
+			Retry: RetryMiddlewareConfig{
+				MaxAttempts: 3,
+				Backoff:     10 * time.Second,
+				RetryOn:     []string{"overloaded", "rate_limit", "network"},
+			},
+			Recording: RecordingMiddlewareConfig{
+				Dir: "_bmad-output/transcripts",
+			},
 		},
 	}
 }
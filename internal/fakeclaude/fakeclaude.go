@@ -0,0 +1,540 @@
+// Package fakeclaude implements fake-claude, a stand-in for the Claude CLI
+// that plays sessions scripted in a YAML [Scenario].
+//
+// Pointing BMAD_CLAUDE_PATH at the fake-claude binary exercises the whole
+// bmad-automate pipeline, including [claude.DefaultExecutor]'s process
+// handling, without a Claude account. The binary is configured through the
+// environment:
+//
+//   - FAKE_CLAUDE_SCENARIO: path of the scenario YAML file (required)
+//   - FAKE_CLAUDE_STATE: file counting the invocations so far, so sessions
+//     with [Session.Times] take turns across processes; defaults to the
+//     scenario path with ".state" appended
+//   - FAKE_CLAUDE_LOG: file to which each [Invocation] is appended as a
+//     JSON line, for tests to check the prompts and flags passed
+//
+// Like the Claude CLI, fake-claude reads the prompt from stdin unless it is
+// given on the command line. With --input-format stream-json, stdin holds
+// stream-json user messages: the first is the prompt, and each later one is
+// answered by another matching session, until stdin is closed.
+//
+// Key types:
+//   - [Scenario]: The scripted sessions
+//   - [Session]: Events, result, stderr, exit code and status edits of one session
+//   - [Invocation]: The parsed command line of one run
+package fakeclaude
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"bmad-automate/internal/claude"
+	"bmad-automate/internal/status"
+)
+
+// Environment variables read by [Run].
+const (
+	EnvScenario = "FAKE_CLAUDE_SCENARIO"
+	EnvState    = "FAKE_CLAUDE_STATE"
+	EnvLog      = "FAKE_CLAUDE_LOG"
+)
+
+// Exit codes of fake-claude's own failures, as opposed to scripted ones.
+const (
+	// ExitUsage reports a bad command line, scenario or environment.
+	ExitUsage = 2
+
+	// ExitInterrupted reports a session ended early by a signal.
+	ExitInterrupted = 130
+)
+
+// Invocation is the command line of one fake-claude run.
+type Invocation struct {
+	// Args are the raw arguments.
+	Args []string `json:"args"`
+
+	// Prompt is the prompt, from the command line or stdin. For a follow-up
+	// message it is the message.
+	Prompt string `json:"prompt"`
+
+	// FollowUp is set for the follow-up messages of a session with
+	// stream-json input, which are logged as invocations of their own.
+	FollowUp bool `json:"follow_up,omitempty"`
+
+	// ResumeSessionID is the --resume argument.
+	ResumeSessionID string `json:"resume_session_id,omitempty"`
+
+	// OutputFormat is the --output-format argument.
+	OutputFormat string `json:"output_format,omitempty"`
+
+	// InputFormat is the --input-format argument.
+	InputFormat string `json:"input_format,omitempty"`
+
+	// PermissionMode is the --permission-mode argument.
+	PermissionMode string `json:"permission_mode,omitempty"`
+
+	// AllowedTools and DisallowedTools collect the tool rule arguments.
+	AllowedTools    []string `json:"allowed_tools,omitempty"`
+	DisallowedTools []string `json:"disallowed_tools,omitempty"`
+
+	// Dir is the working directory.
+	Dir string `json:"dir"`
+}
+
+// valueFlags are the Claude CLI flags that take a value.
+var valueFlags = []string{
+	"--resume", "-r", "--output-format", "--input-format", "--permission-mode",
+	"--allowedTools", "--allowed-tools", "--disallowedTools", "--disallowed-tools",
+	"--model",
+}
+
+// ParseArgs parses a Claude CLI command line. The first argument that is not
+// a flag is the prompt. Unknown flags are ignored.
+func ParseArgs(args []string) (Invocation, error) {
+	inv := Invocation{Args: args}
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if !slices.Contains(valueFlags, flag) {
+			if inv.Prompt == "" && !strings.HasPrefix(flag, "-") {
+				inv.Prompt = flag
+			}
+			continue
+		}
+		if i+1 >= len(args) {
+			return inv, fmt.Errorf("flag %s needs a value", flag)
+		}
+		i++
+		value := args[i]
+		switch flag {
+		case "--resume", "-r":
+			inv.ResumeSessionID = value
+		case "--output-format":
+			inv.OutputFormat = value
+		case "--input-format":
+			inv.InputFormat = value
+		case "--permission-mode":
+			inv.PermissionMode = value
+		case "--allowedTools", "--allowed-tools":
+			inv.AllowedTools = append(inv.AllowedTools, value)
+		case "--disallowedTools", "--disallowed-tools":
+			inv.DisallowedTools = append(inv.DisallowedTools, value)
+		}
+	}
+	return inv, nil
+}
+
+// ReadLog reads the invocations appended to a FAKE_CLAUDE_LOG file.
+func ReadLog(path string) ([]Invocation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var invocations []Invocation
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var inv Invocation
+		if err := dec.Decode(&inv); err != nil {
+			return nil, fmt.Errorf("reading invocation log: %w", err)
+		}
+		invocations = append(invocations, inv)
+	}
+	return invocations, nil
+}
+
+// Run plays the scenario session matching the prompt, writing stream-json to
+// stdout, and returns the exit code. The prompt is taken from args, or else
+// read from stdin; with stream-json input, each further user message on stdin
+// plays another session, until stdin is closed or a session fails.
+//
+// Canceling ctx, as on SIGINT or SIGTERM, ends the session early with
+// [ExitInterrupted].
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "fake-claude: %v\n", err)
+		return ExitUsage
+	}
+
+	inv, err := ParseArgs(args)
+	if err != nil {
+		return fail(err)
+	}
+	inv.Dir, _ = os.Getwd()
+
+	scenarioPath := os.Getenv(EnvScenario)
+	if scenarioPath == "" {
+		return fail(fmt.Errorf("%s is not set", EnvScenario))
+	}
+	scenario, err := LoadScenario(scenarioPath)
+	if err != nil {
+		return fail(err)
+	}
+
+	streaming := inv.InputFormat == claude.FormatStreamJSON
+	messages := newMessageReader(stdin)
+	if inv.Prompt == "" {
+		if streaming {
+			inv.Prompt, err = messages.next()
+		} else {
+			inv.Prompt, err = readPrompt(stdin)
+		}
+		if err != nil {
+			return fail(fmt.Errorf("reading prompt: %w", err))
+		}
+	}
+
+	r := &runner{
+		scenario:     scenario,
+		scenarioPath: scenarioPath,
+		statePath:    os.Getenv(EnvState),
+		logPath:      os.Getenv(EnvLog),
+		player:       &player{ctx: ctx, out: stdout},
+	}
+	if r.statePath == "" {
+		r.statePath = scenarioPath + ".state"
+	}
+
+	code, err := r.turn(inv, stderr)
+	for err == nil && code == 0 && streaming {
+		text, readErr := messages.next()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return fail(readErr)
+		}
+		followUp := inv
+		followUp.Prompt = text
+		followUp.FollowUp = true
+		code, err = r.turn(followUp, stderr)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return code
+}
+
+// runner plays the turns of one fake-claude process.
+type runner struct {
+	scenario     *Scenario
+	scenarioPath string
+	statePath    string
+	logPath      string
+	player       *player
+}
+
+// turn logs the invocation and plays the session that answers its prompt.
+// The first turn starts the session; follow-ups continue it.
+func (r *runner) turn(inv Invocation, stderr io.Writer) (int, error) {
+	if r.logPath != "" {
+		if err := appendLog(r.logPath, inv); err != nil {
+			return 0, err
+		}
+	}
+
+	st, err := loadState(r.statePath)
+	if err != nil {
+		return 0, err
+	}
+	index, groups, ok := r.scenario.find(inv.Prompt, st.Uses)
+	if !ok {
+		return 0, fmt.Errorf("no session in %s matches prompt %q", r.scenarioPath, inv.Prompt)
+	}
+	st.Invocations++
+	st.Uses[index]++
+	if err := st.save(r.statePath); err != nil {
+		return 0, err
+	}
+
+	p := r.player
+	p.session = &r.scenario.Sessions[index]
+	p.prompt = inv.Prompt
+	p.groups = groups
+	p.lastText = ""
+	first := p.sessionID == ""
+	if first {
+		p.sessionID = p.session.SessionID
+		if p.sessionID == "" {
+			p.sessionID = inv.ResumeSessionID
+		}
+		if p.sessionID == "" {
+			p.sessionID = fmt.Sprintf("fake-session-%d", st.Invocations)
+		}
+	}
+	return p.play(stderr, first), nil
+}
+
+// readPrompt reads a text prompt from stdin, without its final newline.
+func readPrompt(stdin io.Reader) (string, error) {
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// messageReader reads the stream-json user messages on stdin.
+type messageReader struct {
+	scanner *bufio.Scanner
+}
+
+func newMessageReader(stdin io.Reader) *messageReader {
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	return &messageReader{scanner: scanner}
+}
+
+// next returns the text of the next message, or io.EOF once stdin is closed.
+func (m *messageReader) next() (string, error) {
+	for m.scanner.Scan() {
+		line := bytes.TrimSpace(m.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return claude.ReadUserMessage(line)
+	}
+	if err := m.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// player plays one session.
+type player struct {
+	ctx       context.Context
+	out       io.Writer
+	session   *Session
+	prompt    string
+	groups    []int
+	sessionID string
+	toolCount int
+	lastText  string
+}
+
+// play emits the session, starting with the init event if init is set, and
+// returns its exit code.
+func (p *player) play(stderr io.Writer, init bool) int {
+	s := p.session
+	if init {
+		p.emit(&claude.StreamEvent{Type: string(claude.EventTypeSystem), Subtype: claude.SubtypeInit, SessionID: p.sessionID})
+	}
+
+	for i, event := range s.Events {
+		delay := s.Delay
+		if event.Delay != nil {
+			delay = *event.Delay
+		}
+		if !p.sleep(delay) {
+			return ExitInterrupted
+		}
+		p.emitEvent(i, event)
+	}
+
+	if !p.sleep(s.Hang) || !p.sleep(s.Delay) {
+		return ExitInterrupted
+	}
+	if !s.NoResult {
+		p.emitResult()
+	}
+
+	for key, value := range s.SetStatus {
+		err := status.NewWriter("").UpdateStatus(p.expand(key), status.Status(p.expand(value)))
+		if err != nil {
+			fmt.Fprintf(stderr, "fake-claude: %v\n", err)
+			return ExitUsage
+		}
+	}
+	for _, line := range s.Stderr {
+		fmt.Fprintln(stderr, p.expand(line))
+	}
+	return s.ExitCode
+}
+
+// toolInput returns the JSON input of a scripted tool call.
+func (p *player) toolInput(event Event) json.RawMessage {
+	input := make(map[string]any, len(event.Input)+3)
+	for key, value := range event.Input {
+		if s, ok := value.(string); ok {
+			value = p.expand(s)
+		}
+		input[key] = value
+	}
+	for key, value := range map[string]string{
+		"command":     event.Command,
+		"description": event.Description,
+		"file_path":   event.FilePath,
+	} {
+		if value != "" {
+			input[key] = p.expand(value)
+		}
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		panic(fmt.Sprintf("fake-claude: tool input: %v", err)) // Scenario inputs come from YAML
+	}
+	return data
+}
+
+// emitEvent emits a scripted event.
+func (p *player) emitEvent(i int, event Event) {
+	switch {
+	case event.Raw != "":
+		fmt.Fprintln(p.out, event.Raw)
+
+	case event.Tool != "":
+		p.toolCount++
+		id := fmt.Sprintf("toolu_%02d", p.toolCount)
+		p.emitAssistant(i, claude.ContentBlock{
+			Type:  "tool_use",
+			ID:    id,
+			Name:  event.Tool,
+			Input: p.toolInput(event),
+		})
+		p.emit(&claude.StreamEvent{
+			Type:      string(claude.EventTypeUser),
+			SessionID: p.sessionID,
+			Message: &claude.MessageContent{Content: []claude.ContentBlock{
+				{Type: "tool_result", ToolUseID: id, IsError: event.IsError},
+			}},
+			ToolUseResult: &claude.ToolResult{Stdout: p.expand(event.Stdout), Stderr: p.expand(event.ToolStderr)},
+		})
+
+	case event.Thinking != "":
+		p.emitAssistant(i, claude.ContentBlock{Type: "thinking", Thinking: p.expand(event.Thinking)})
+
+	default:
+		p.lastText = p.expand(event.Text)
+		p.emitAssistant(i, claude.ContentBlock{Type: "text", Text: p.lastText})
+	}
+}
+
+// emitAssistant emits an assistant message with one content block.
+func (p *player) emitAssistant(i int, block claude.ContentBlock) {
+	p.emit(&claude.StreamEvent{
+		Type:      string(claude.EventTypeAssistant),
+		SessionID: p.sessionID,
+		Message: &claude.MessageContent{
+			ID:      fmt.Sprintf("msg_%02d", i+1),
+			Content: []claude.ContentBlock{block},
+		},
+	})
+}
+
+// emitResult emits the result event.
+func (p *player) emitResult() {
+	result := Result{Text: p.lastText}
+	if p.session.Result != nil {
+		result = *p.session.Result
+		result.Text = p.expand(result.Text)
+	}
+	if result.Subtype == "" {
+		result.Subtype = "success"
+		if result.IsError {
+			result.Subtype = "error_during_execution"
+		}
+	}
+
+	p.emit(&claude.StreamEvent{
+		Type:      string(claude.EventTypeResult),
+		Subtype:   result.Subtype,
+		SessionID: p.sessionID,
+		ResultStats: claude.ResultStats{
+			TotalCostUSD: result.CostUSD,
+			Usage:        claude.Usage{InputTokens: result.InputTokens, OutputTokens: result.OutputTokens},
+			NumTurns:     len(p.session.Events),
+			IsError:      result.IsError,
+			Result:       result.Text,
+		},
+	})
+}
+
+// emit writes a stream-json line.
+func (p *player) emit(event *claude.StreamEvent) {
+	line, _ := json.Marshal(event) //nolint:errcheck // StreamEvent always encodes
+	fmt.Fprintf(p.out, "%s\n", line)
+}
+
+// expand replaces references to the Match groups in s.
+func (p *player) expand(s string) string {
+	if s == "" || len(p.groups) <= 2 {
+		return s
+	}
+	return string(p.session.match.ExpandString(nil, s, p.prompt, p.groups))
+}
+
+// sleep waits for d, returning false if the context ends first.
+func (p *player) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return p.ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// state counts invocations across fake-claude processes.
+type state struct {
+	Invocations int         `json:"invocations"`
+	Uses        map[int]int `json:"uses"`
+}
+
+// loadState reads the state file, which need not exist yet.
+func loadState(path string) (*state, error) {
+	st := &state{Uses: map[int]int{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("reading state %s: %w", path, err)
+	}
+	if st.Uses == nil {
+		st.Uses = map[int]int{}
+	}
+	return st, nil
+}
+
+// save writes the state file.
+func (st *state) save(path string) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
+
+// appendLog appends an invocation to the log file.
+func appendLog(path string, inv Invocation) error {
+	line, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing invocation log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing invocation log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package fakeclaude
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/claude"
+	"bmad-automate/internal/status"
+)
+
+// setupScenario writes a scenario to a temporary directory, points the
+// environment at it, and makes that directory the working directory.
+func setupScenario(t *testing.T, scenario string) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(scenario), 0o644))
+	t.Setenv(EnvScenario, path)
+	t.Setenv(EnvState, "")
+	t.Setenv(EnvLog, filepath.Join(dir, "invocations.jsonl"))
+	t.Chdir(dir)
+	return dir
+}
+
+// run runs fake-claude with empty stdin and parses its output.
+func run(t *testing.T, ctx context.Context, args ...string) ([]claude.Event, string, int) {
+	t.Helper()
+	return runWithStdin(t, ctx, "", args...)
+}
+
+// runWithStdin runs fake-claude with the given stdin and parses its output.
+func runWithStdin(t *testing.T, ctx context.Context, stdin string, args ...string) ([]claude.Event, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(ctx, args, strings.NewReader(stdin), &stdout, &stderr)
+
+	var events []claude.Event
+	for event := range claude.NewParser().Parse(&stdout) {
+		events = append(events, event)
+	}
+	return events, stderr.String(), code
+}
+
+func TestParseArgs(t *testing.T) {
+	inv, err := ParseArgs([]string{
+		"--permission-mode", "acceptEdits",
+		"--allowedTools", "Read", "--allowedTools", "Edit",
+		"--disallowedTools", "Bash(git push:*)",
+		"--resume", "sess-1",
+		"--verbose", "-p", "do it", "--output-format", "stream-json",
+		"--input-format", "text",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "do it", inv.Prompt)
+	assert.Equal(t, "sess-1", inv.ResumeSessionID)
+	assert.Equal(t, "stream-json", inv.OutputFormat)
+	assert.Equal(t, "text", inv.InputFormat)
+	assert.Equal(t, "acceptEdits", inv.PermissionMode)
+	assert.Equal(t, []string{"Read", "Edit"}, inv.AllowedTools)
+	assert.Equal(t, []string{"Bash(git push:*)"}, inv.DisallowedTools)
+
+	inv, err = ParseArgs([]string{"-p", "--output-format", "stream-json"})
+	require.NoError(t, err)
+	assert.Empty(t, inv.Prompt, "the prompt is left for stdin")
+
+	_, err = ParseArgs([]string{"-p", "x", "--resume"})
+	assert.ErrorContains(t, err, "flag --resume needs a value")
+}
+
+func TestRun_PlaysSession(t *testing.T) {
+	setupScenario(t, `
+sessions:
+  - match: "review (\\S+)"
+    events:
+      - text: "Reviewing $1"
+      - tool: Bash
+        command: go test ./...
+        description: Run tests
+        stdout: ok
+      - thinking: "Looks fine"
+    result: {text: "Reviewed $1", cost_usd: 0.25, input_tokens: 100, output_tokens: 20}
+    stderr: ["warning: $1 is big"]
+`)
+
+	events, stderr, code := run(t, context.Background(), "-p", "review 1-2", "--output-format", "stream-json")
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "warning: 1-2 is big\n", stderr)
+	require.Len(t, events, 6)
+
+	assert.True(t, events[0].SessionStarted)
+	assert.Equal(t, "fake-session-1", events[0].SessionID)
+	assert.Equal(t, "Reviewing 1-2", events[1].Text)
+	assert.Equal(t, "Bash", events[2].ToolName)
+	assert.Equal(t, "go test ./...", events[2].ToolCommand)
+	assert.Equal(t, "Run tests", events[2].ToolDescription)
+	assert.Equal(t, "ok", events[3].ToolStdout)
+	assert.Equal(t, events[2].ToolUseID, events[3].ToolUseID)
+	assert.Equal(t, "Looks fine", events[4].Thinking)
+
+	result := events[5]
+	require.True(t, result.SessionComplete)
+	assert.Equal(t, "Reviewed 1-2", result.Stats.Result)
+	assert.InDelta(t, 0.25, result.Stats.TotalCostUSD, 1e-9)
+	assert.Equal(t, int64(100), result.Stats.Usage.InputTokens)
+	assert.False(t, result.Stats.IsError)
+}
+
+func TestRun_ToolInput(t *testing.T) {
+	setupScenario(t, `
+sessions:
+  - match: "fix (\\S+)"
+    events:
+      - tool: Edit
+        file_path: $1.go
+        input: {old_string: "return nil", new_string: "return err", replace_all: true}
+`)
+
+	events, _, code := run(t, context.Background(), "-p", "fix parser", "--output-format", "stream-json")
+
+	assert.Equal(t, 0, code)
+	require.GreaterOrEqual(t, len(events), 2)
+	assert.Equal(t, &claude.EditInput{FilePath: "parser.go", OldString: "return nil", NewString: "return err", ReplaceAll: true}, events[1].ToolInput)
+	assert.Equal(t, "parser.go", events[1].ToolFilePath)
+}
+
+func TestRun_TimesAndResume(t *testing.T) {
+	dir := setupScenario(t, `
+sessions:
+  - times: 1
+    result: {text: "API Error: 529 overloaded", is_error: true}
+    exit_code: 1
+  - events: [{text: "done"}]
+`)
+
+	events, _, code := run(t, context.Background(), "-p", "work")
+	assert.Equal(t, 1, code)
+	result := events[len(events)-1]
+	assert.True(t, result.Stats.IsError)
+	assert.Equal(t, "error_during_execution", result.Subtype)
+
+	events, _, code = run(t, context.Background(), "--resume", "fake-session-1", "-p", "continue")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "fake-session-1", events[0].SessionID, "a resumed session keeps its ID")
+	assert.Equal(t, "done", events[len(events)-1].Stats.Result, "the result defaults to the last text")
+
+	invocations, err := ReadLog(filepath.Join(dir, "invocations.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, invocations, 2)
+	assert.Equal(t, "work", invocations[0].Prompt)
+	assert.Equal(t, "fake-session-1", invocations[1].ResumeSessionID)
+	assert.Equal(t, dir, invocations[1].Dir)
+}
+
+func TestRun_PromptOnStdin(t *testing.T) {
+	dir := setupScenario(t, `
+sessions:
+  - match: "^review (\\S+)$"
+    result: {text: "Reviewed $1"}
+`)
+
+	events, _, code := runWithStdin(t, context.Background(), "review 1-2\n", "-p", "--output-format", "stream-json")
+
+	require.Equal(t, 0, code)
+	assert.Equal(t, "Reviewed 1-2", events[len(events)-1].Stats.Result)
+	invocations, err := ReadLog(filepath.Join(dir, "invocations.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, invocations, 1)
+	assert.Equal(t, "review 1-2", invocations[0].Prompt, "the final newline is dropped")
+}
+
+// userLine is a stream-json user message line.
+func userLine(text string) string {
+	return `{"type":"user","message":{"role":"user","content":[{"type":"text","text":"` + text + `"}]}}` + "\n"
+}
+
+func TestRun_StreamJSONInput(t *testing.T) {
+	dir := setupScenario(t, `
+sessions:
+  - match: "^dev-story"
+    events: [{text: "Should I add a helper?"}]
+  - match: "^Yes"
+    events: [{tool: Write, file_path: helper.go}]
+    result: {text: "Added the helper"}
+`)
+	stdin := userLine("dev-story 1-1") + "\n" + userLine("Yes, add it.")
+
+	events, _, code := runWithStdin(t, context.Background(), stdin,
+		"-p", "--output-format", "stream-json", "--input-format", "stream-json")
+
+	require.Equal(t, 0, code)
+	var inits, results []claude.Event
+	for _, event := range events {
+		switch {
+		case event.SessionStarted:
+			inits = append(inits, event)
+		case event.SessionComplete:
+			results = append(results, event)
+		}
+	}
+	require.Len(t, inits, 1, "the session starts once")
+	require.Len(t, results, 2, "each message is answered with a result")
+	assert.Equal(t, "Should I add a helper?", results[0].Stats.Result)
+	assert.Equal(t, "Added the helper", results[1].Stats.Result)
+	assert.Equal(t, inits[0].SessionID, results[1].SessionID)
+
+	invocations, err := ReadLog(filepath.Join(dir, "invocations.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, invocations, 2)
+	assert.Equal(t, "dev-story 1-1", invocations[0].Prompt)
+	assert.False(t, invocations[0].FollowUp)
+	assert.Equal(t, "Yes, add it.", invocations[1].Prompt)
+	assert.True(t, invocations[1].FollowUp)
+
+	_, stderr, code := runWithStdin(t, context.Background(), "not json\n",
+		"-p", "--input-format", "stream-json")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, "invalid stream-json input")
+}
+
+func TestRun_SetStatus(t *testing.T) {
+	dir := setupScenario(t, `
+sessions:
+  - match: "create-story (?P<story>\\S+)"
+    set_status: {"${story}": ready-for-dev}
+`)
+	statusPath := filepath.Join(dir, status.DefaultStatusPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(statusPath), 0o755))
+	require.NoError(t, os.WriteFile(statusPath, []byte("development_status:\n  1-1-setup: backlog\n"), 0o644))
+
+	_, _, code := run(t, context.Background(), "-p", "/bmad-bmm-create-story 1-1-setup")
+	require.Equal(t, 0, code)
+
+	got, err := status.NewReader(dir).GetStoryStatus("1-1-setup")
+	require.NoError(t, err)
+	assert.Equal(t, status.StatusReadyForDev, got)
+}
+
+func TestRun_RawAndNoResult(t *testing.T) {
+	setupScenario(t, `
+sessions:
+  - events:
+      - raw: "this is not json"
+    no_result: true
+    exit_code: 3
+`)
+
+	events, _, code := run(t, context.Background(), "-p", "x")
+
+	assert.Equal(t, 3, code)
+	require.Len(t, events, 2)
+	assert.True(t, events[1].IsParseError())
+}
+
+func TestRun_HangIsInterrupted(t *testing.T) {
+	setupScenario(t, `
+sessions:
+  - hang: 1h
+    delay: 1ms
+`)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	events, _, code := run(t, ctx, "-p", "x")
+
+	assert.Equal(t, ExitInterrupted, code)
+	require.Len(t, events, 1, "only the init event is emitted")
+}
+
+func TestRun_Failures(t *testing.T) {
+	t.Run("no scenario", func(t *testing.T) {
+		t.Setenv(EnvScenario, "")
+		_, stderr, code := run(t, context.Background(), "-p", "x")
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, "FAKE_CLAUDE_SCENARIO is not set")
+	})
+
+	t.Run("no matching session", func(t *testing.T) {
+		setupScenario(t, `sessions: [{match: "^dev-story"}]`)
+		_, stderr, code := run(t, context.Background(), "-p", "code-review 1-1")
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, `matches prompt "code-review 1-1"`)
+	})
+
+	t.Run("invalid match", func(t *testing.T) {
+		_, err := ParseScenario([]byte(`sessions: [{match: "("}]`))
+		assert.ErrorContains(t, err, "session 1: invalid match")
+	})
+}
+
+func TestParseScenario_Durations(t *testing.T) {
+	s, err := ParseScenario([]byte(`
+sessions:
+  - delay: 10ms
+    hang: 2s
+    events: [{text: a, delay: 0s}]
+`))
+
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Millisecond, s.Sessions[0].Delay)
+	assert.Equal(t, 2*time.Second, s.Sessions[0].Hang)
+	require.NotNil(t, s.Sessions[0].Events[0].Delay)
+	assert.Zero(t, *s.Sessions[0].Events[0].Delay)
+}
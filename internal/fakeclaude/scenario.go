@@ -0,0 +1,179 @@
+package fakeclaude
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario scripts the sessions fake-claude plays.
+//
+// Each invocation plays the first session whose Match fits the prompt and
+// whose Times are not used up, so a scenario can answer each workflow
+// differently and fail a step once before letting it succeed:
+//
+//	sessions:
+//	  - match: "dev-story (\\S+)"
+//	    times: 1
+//	    result: {text: "API Error: 529 overloaded", is_error: true}
+//	    exit_code: 1
+//	  - match: "dev-story (\\S+)"
+//	    events:
+//	      - text: "Implementing $1"
+//	      - tool: Bash
+//	        command: go test ./...
+//	        stdout: ok
+//	    set_status: {"$1": review}
+type Scenario struct {
+	// Sessions are the scripted sessions, tried in order.
+	Sessions []Session `yaml:"sessions"`
+}
+
+// Session scripts one Claude session.
+//
+// The session emits a system init event, the Events, and a result event, then
+// writes the Stderr lines and exits with ExitCode. In the text of events,
+// results, stderr lines and SetStatus, $1 or ${name} refer to the groups of
+// the Match expression; write $$ for a literal $ in sessions whose Match has
+// groups.
+type Session struct {
+	// Match is a regular expression matched against the prompt. Empty
+	// matches every prompt.
+	Match string `yaml:"match"`
+
+	// Times is the number of invocations the session answers before the
+	// next matching session takes over. Zero means no limit.
+	Times int `yaml:"times"`
+
+	// SessionID is the session ID reported. Defaults to the resumed
+	// session's ID, or "fake-session-N" for the Nth invocation.
+	SessionID string `yaml:"session_id"`
+
+	// Events are emitted in order after the init event.
+	Events []Event `yaml:"events"`
+
+	// Delay is the pause before each event and before the result.
+	Delay time.Duration `yaml:"delay"`
+
+	// Hang is a pause after the events, before the result, such as to
+	// trigger an idle timeout. A signal ends it early.
+	Hang time.Duration `yaml:"hang"`
+
+	// Result is the final result event. Defaults to a successful result
+	// whose text is that of the last text event.
+	Result *Result `yaml:"result"`
+
+	// NoResult leaves out the result event, like a Claude process that died.
+	NoResult bool `yaml:"no_result"`
+
+	// Stderr lines are written to stderr before exiting.
+	Stderr []string `yaml:"stderr"`
+
+	// ExitCode is the process exit code.
+	ExitCode int `yaml:"exit_code"`
+
+	// SetStatus maps story keys to statuses to write into sprint-status.yaml
+	// in the working directory before exiting, as Claude's BMAD workflows do.
+	SetStatus map[string]string `yaml:"set_status"`
+
+	// match is the compiled Match.
+	match *regexp.Regexp
+}
+
+// Event is one scripted event. Set exactly one of Text, Thinking, Tool or Raw.
+type Event struct {
+	// Text is emitted as an assistant text block.
+	Text string `yaml:"text"`
+
+	// Thinking is emitted as an assistant thinking block.
+	Thinking string `yaml:"thinking"`
+
+	// Tool is the name of a tool call, emitted as an assistant tool_use
+	// block followed by a user event with its result.
+	Tool string `yaml:"tool"`
+
+	// Command, FilePath and Description are the input of the tool call.
+	Command     string `yaml:"command"`
+	FilePath    string `yaml:"file_path"`
+	Description string `yaml:"description"`
+
+	// Input holds other input fields of the tool call, such as
+	// old_string and new_string for Edit. Top-level string values are
+	// expanded like Command.
+	Input map[string]any `yaml:"input"`
+
+	// Stdout, ToolStderr and IsError are the result of the tool call.
+	Stdout     string `yaml:"stdout"`
+	ToolStderr string `yaml:"tool_stderr"`
+	IsError    bool   `yaml:"is_error"`
+
+	// Raw is written to stdout as a line of its own, verbatim, such as to
+	// test how malformed output is handled.
+	Raw string `yaml:"raw"`
+
+	// Delay overrides [Session.Delay] before this event.
+	Delay *time.Duration `yaml:"delay"`
+}
+
+// Result scripts the final result event of a session.
+type Result struct {
+	// Text is the result text, e.g. an error message.
+	Text string `yaml:"text"`
+
+	// IsError marks the session as failed.
+	IsError bool `yaml:"is_error"`
+
+	// Subtype is the result subtype. Defaults to "success", or
+	// "error_during_execution" when IsError is set.
+	Subtype string `yaml:"subtype"`
+
+	// CostUSD is the reported session cost.
+	CostUSD float64 `yaml:"cost_usd"`
+
+	// InputTokens and OutputTokens are the reported session usage.
+	InputTokens  int64 `yaml:"input_tokens"`
+	OutputTokens int64 `yaml:"output_tokens"`
+}
+
+// LoadScenario reads a scenario from a YAML file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario: %w", err)
+	}
+	return ParseScenario(data)
+}
+
+// ParseScenario parses a scenario from YAML.
+func ParseScenario(data []byte) (*Scenario, error) {
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing scenario: %w", err)
+	}
+	for i := range s.Sessions {
+		session := &s.Sessions[i]
+		match, err := regexp.Compile(session.Match)
+		if err != nil {
+			return nil, fmt.Errorf("session %d: invalid match: %w", i+1, err)
+		}
+		session.match = match
+	}
+	return &s, nil
+}
+
+// find returns the index of the session that answers prompt, given how many
+// times each session has been used, and the groups of its match.
+func (s *Scenario) find(prompt string, uses map[int]int) (int, []int, bool) {
+	for i, session := range s.Sessions {
+		if session.Times > 0 && uses[i] >= session.Times {
+			continue
+		}
+		if groups := session.match.FindStringSubmatchIndex(prompt); groups != nil {
+			return i, groups, true
+		}
+	}
+	return 0, nil, false
+}
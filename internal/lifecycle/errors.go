@@ -0,0 +1,70 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+
+	"bmad-automate/internal/claude"
+)
+
+// ErrResumeUnsupported is returned when a session resume is requested but the
+// configured [WorkflowRunner] does not implement [SessionResumer].
+var ErrResumeUnsupported = errors.New("workflow runner does not support session resume")
+
+// ErrInterrupted is matched by errors returned from [Executor.Execute] when a
+// lifecycle stopped early because of an interrupt: either a drain requested
+// with [WithDrain] or cancellation of the context. Progress is saved to the
+// [StateStore], if one is set.
+var ErrInterrupted = errors.New("lifecycle interrupted")
+
+// ErrStaleState is matched by errors from [Executor.ExecuteFromState] when the
+// saved progress no longer agrees with the lifecycle or the story's status,
+// for example because sprint-status.yaml was edited since the run stopped.
+var ErrStaleState = errors.New("saved lifecycle state is stale")
+
+// StepError reports a lifecycle step whose workflow exited with a non-zero code.
+//
+// SessionID carries the Claude session of the failed attempt when the runner
+// reports one, so callers can offer to resume it. Use errors.As to extract it.
+type StepError struct {
+	// Workflow is the name of the workflow that failed.
+	Workflow string
+
+	// ExitCode is the non-zero exit code returned by the workflow.
+	ExitCode int
+
+	// SessionID is the Claude session ID of the failed attempt, if known.
+	SessionID string
+
+	// Todos is Claude's task list as the failed attempt ended, if the runner
+	// reports one (see [TodoReporter]).
+	Todos []claude.Todo
+
+	// Err is the underlying cause reported by the runner, if known
+	// (see [FailureReporter]). It is nil for a plain non-zero exit.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *StepError) Error() string {
+	msg := fmt.Sprintf("workflow failed: %s returned exit code %d", e.Workflow, e.ExitCode)
+	if e.SessionID != "" {
+		msg += fmt.Sprintf(" (session %s)", e.SessionID)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// TimedOut reports whether the step failed because its Claude session was
+// killed by a configured timeout or idle timeout (see [claude.ErrTimeout]),
+// as opposed to Claude exiting with an error of its own.
+func (e *StepError) TimedOut() bool {
+	return errors.Is(e.Err, claude.ErrTimeout)
+}
+
+// Unwrap returns the underlying cause, so errors.Is and errors.As see it.
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
@@ -7,14 +7,24 @@
 // Key concepts:
 //   - Lifecycle steps are determined by [router.GetLifecycle] based on current status
 //   - Each step runs a workflow then updates status via [StatusWriter]
-//   - Progress can be tracked via [ProgressCallback]
+//   - Progress can be tracked via [ProgressCallback] and [StepCallback]
+//   - Failed steps can be retried by resuming their Claude session (see [SessionResumer])
+//   - Transient failures are retried with backoff according to a [RetryPolicy]
+//   - Steps stopped by the Claude usage limit wait for it to reset, then resume
+//   - Progress of failed or interrupted runs is recorded via [StateStore]
+//   - Interrupts either drain the current step ([WithDrain]) or cancel it outright
 package lifecycle
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
+	"bmad-automate/internal/claude"
 	"bmad-automate/internal/router"
+	"bmad-automate/internal/state"
 	"bmad-automate/internal/status"
 )
 
@@ -27,6 +37,36 @@ type WorkflowRunner interface {
 	RunSingle(ctx context.Context, workflowName, storyKey string) int
 }
 
+// SessionResumer is implemented by workflow runners that can resume a Claude session.
+//
+// LastSessionID returns the session ID of the most recent run. ResumeSingle
+// continues an earlier session for a workflow step and returns the exit code.
+// The [workflow.Runner] type implements this interface; [Executor] uses it,
+// when available, to resume failed steps instead of restarting them.
+type SessionResumer interface {
+	LastSessionID() string
+	ResumeSingle(ctx context.Context, workflowName, storyKey, sessionID string) int
+}
+
+// FailureReporter is implemented by workflow runners that can explain a failure.
+//
+// LastError returns the cause of the most recent failed run, or nil if only
+// the exit code is known. The [workflow.Runner] type implements this interface;
+// [Executor] attaches the cause to the returned [StepError].
+type FailureReporter interface {
+	LastError() error
+}
+
+// TodoReporter is implemented by workflow runners that track Claude's task list.
+//
+// LastTodos returns the task list Claude last set with the TodoWrite tool for
+// the story of the most recent run, or nil if it kept none. The
+// [workflow.Runner] type implements this interface; [Executor] reports the
+// list in each [StepResult] and failed [StepError].
+type TodoReporter interface {
+	LastTodos() []claude.Todo
+}
+
 // StatusReader is the interface for looking up story status.
 //
 // GetStoryStatus retrieves the current [status.Status] for a story key.
@@ -43,6 +83,17 @@ type StatusWriter interface {
 	UpdateStatus(storyKey string, newStatus status.Status) error
 }
 
+// StateStore is the interface for persisting lifecycle progress.
+//
+// Save records where a failed or interrupted lifecycle stopped, Load returns
+// the saved record ([state.ErrNoState] if none), and Clear removes it.
+// The [state.Manager] type implements this interface.
+type StateStore interface {
+	Save(st state.State) error
+	Load() (state.State, error)
+	Clear() error
+}
+
 // ProgressCallback is invoked before each workflow step begins execution.
 //
 // The callback receives stepIndex (1-based), totalSteps count, and the workflow name.
@@ -50,6 +101,30 @@ type StatusWriter interface {
 // via [Executor.SetProgressCallback].
 type ProgressCallback func(stepIndex, totalSteps int, workflow string)
 
+// StepResult describes a lifecycle step that has finished running.
+type StepResult struct {
+	// StoryKey is the story the step ran for.
+	StoryKey string
+
+	// Workflow is the name of the workflow the step ran.
+	Workflow string
+
+	// Duration is how long the step took, including retries.
+	Duration time.Duration
+
+	// Todos is Claude's task list as the step ended, if the runner implements
+	// [TodoReporter] and Claude kept one.
+	Todos []claude.Todo
+
+	// Err is the reason the step failed, or nil if it succeeded.
+	Err error
+}
+
+// StepCallback is invoked after each workflow step finishes, whether it
+// succeeded or failed. The callback is optional and can be set via
+// [Executor.SetStepCallback].
+type StepCallback func(result StepResult)
+
 // Executor orchestrates the complete story lifecycle from current status to done.
 //
 // Executor uses dependency injection for testability: [WorkflowRunner] executes workflows,
@@ -60,6 +135,17 @@ type Executor struct {
 	statusReader     StatusReader
 	statusWriter     StatusWriter
 	progressCallback ProgressCallback
+	stepCallback     StepCallback
+	resumeRetries    int
+	stateStore       StateStore
+	retryPolicy      RetryPolicy
+	retryCallback    RetryCallback
+
+	usageLimitCallback UsageLimitCallback
+
+	// sleep waits between retries and now reads the clock; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) bool
+	now   func() time.Time
 }
 
 // NewExecutor creates a new Executor with the required dependencies.
@@ -72,6 +158,8 @@ func NewExecutor(runner WorkflowRunner, reader StatusReader, writer StatusWriter
 		runner:       runner,
 		statusReader: reader,
 		statusWriter: writer,
+		sleep:        sleepContext,
+		now:          time.Now,
 	}
 }
 
@@ -84,6 +172,41 @@ func (e *Executor) SetProgressCallback(cb ProgressCallback) {
 	e.progressCallback = cb
 }
 
+// SetStepCallback configures an optional callback invoked after each workflow
+// step finishes.
+//
+// The callback receives the step's [StepResult], including Claude's task list
+// so far, which the caller can show before the next step begins.
+func (e *Executor) SetStepCallback(cb StepCallback) {
+	e.stepCallback = cb
+}
+
+// SetResumeRetries configures how many times a failed step is retried by resuming
+// its Claude session.
+//
+// A retry continues the failed session with --resume and a "continue where you
+// left off" prompt rather than restarting the workflow. Retries only happen when
+// the runner implements [SessionResumer] and reported a session ID. The default
+// is 0 (no retries).
+//
+// Resume retries happen at once and cover failures the [RetryPolicy] does not
+// retry; a failure the policy retries uses up its attempts instead, so the two
+// never multiply. Budget and usage-limit failures are not resumed.
+func (e *Executor) SetResumeRetries(n int) {
+	e.resumeRetries = n
+}
+
+// SetStateStore configures where lifecycle progress is recorded.
+//
+// When set, every completed step saves a [state.State] naming the story, the
+// next step, and the status the lifecycle started from; a lifecycle that fails
+// or is interrupted saves the step it stopped at. A lifecycle that completes
+// clears the record.
+// No state is recorded by default.
+func (e *Executor) SetStateStore(store StateStore) {
+	e.stateStore = store
+}
+
 // Execute runs the complete story lifecycle from current status to done.
 //
 // Execute looks up the story's current status, determines the remaining workflow steps
@@ -91,9 +214,51 @@ func (e *Executor) SetProgressCallback(cb ProgressCallback) {
 // workflow, the story status is updated to the next state.
 //
 // Execute uses fail-fast behavior: it stops on the first error and returns immediately.
+// A step stopped for exceeding its budget fails with an error matching
+// [budget.ErrExceeded], and the story status is not advanced.
 // Errors can occur from status lookup failure, workflow execution failure (non-zero exit),
 // or status update failure. For stories already done, Execute returns [router.ErrStoryComplete].
+// A failed workflow is reported as a [*StepError].
+//
+// If ctx carries a drain signal ([WithDrain]) that fires, Execute stops after
+// the step in progress; if ctx is canceled, the step in progress is abandoned.
+// Either way the returned error matches [ErrInterrupted].
 func (e *Executor) Execute(ctx context.Context, storyKey string) error {
+	return e.execute(ctx, storyKey, "")
+}
+
+// ExecuteResume runs the story lifecycle, resuming the given Claude session for the first step.
+//
+// The first remaining step continues sessionID instead of starting a fresh
+// session; later steps run normally. This is used to pick up a step that died
+// part-way through. Returns [ErrResumeUnsupported] if the runner does not
+// implement [SessionResumer].
+func (e *Executor) ExecuteResume(ctx context.Context, storyKey, sessionID string) error {
+	if _, ok := e.runner.(SessionResumer); !ok {
+		return ErrResumeUnsupported
+	}
+	return e.execute(ctx, storyKey, sessionID)
+}
+
+// ExecuteFromState continues a lifecycle from progress saved in a [state.State].
+//
+// The steps are rebuilt from saved.StartStatus and execution continues at
+// saved.StepIndex, so steps are not skipped even when an earlier step has
+// already moved the story to a status (such as done) that [Execute] would treat
+// as complete. Progress, drain, and failure handling are the same as [Execute].
+//
+// Returns an error matching [ErrStaleState] if the saved position is not a
+// valid lifecycle position or does not agree with the story's current status.
+func (e *Executor) ExecuteFromState(ctx context.Context, saved state.State) error {
+	steps, err := e.checkState(saved)
+	if err != nil {
+		return err
+	}
+	return e.runSteps(ctx, saved.StoryKey, status.Status(saved.StartStatus), steps, saved.StepIndex, "")
+}
+
+// execute runs the lifecycle, resuming resumeSessionID for the first step if set.
+func (e *Executor) execute(ctx context.Context, storyKey, resumeSessionID string) error {
 	// Get current story status
 	currentStatus, err := e.statusReader.GetStoryStatus(storyKey)
 	if err != nil {
@@ -106,28 +271,200 @@ func (e *Executor) Execute(ctx context.Context, storyKey string) error {
 		return err // Returns router.ErrStoryComplete for done stories
 	}
 
+	return e.runSteps(ctx, storyKey, currentStatus, steps, 0, resumeSessionID)
+}
+
+// runSteps runs steps[from:] for a lifecycle that began at startStatus.
+//
+// Progress is saved after every completed step so an interrupted run can
+// continue with [Executor.ExecuteFromState]. The first step run resumes
+// resumeSessionID if set.
+func (e *Executor) runSteps(ctx context.Context, storyKey string, startStatus status.Status, steps []router.LifecycleStep, from int, resumeSessionID string) error {
 	// Get total steps count for progress reporting
 	totalSteps := len(steps)
 
 	// Execute each step in sequence
-	for i, step := range steps {
+	for i := from; i < totalSteps; i++ {
+		step := steps[i]
+
+		// Stop between steps if a drain was requested
+		if i > from && Draining(ctx) {
+			return e.saveState(storyKey, i, totalSteps, startStatus,
+				fmt.Errorf("%w: stopped before %s", ErrInterrupted, step.Workflow))
+		}
+
 		// Call progress callback if set
 		if e.progressCallback != nil {
 			e.progressCallback(i+1, totalSteps, step.Workflow)
 		}
 
-		// Run the workflow
-		exitCode := e.runner.RunSingle(ctx, step.Workflow, storyKey)
-		if exitCode != 0 {
-			return fmt.Errorf("workflow failed: %s returned exit code %d", step.Workflow, exitCode)
+		// Run the workflow, resuming the given session for the first step only
+		sessionID := ""
+		if i == from {
+			sessionID = resumeSessionID
+		}
+		stepStart := e.now()
+		err := e.runStepWithRetry(ctx, step.Workflow, storyKey, sessionID)
+		if e.stepCallback != nil {
+			e.stepCallback(StepResult{
+				StoryKey: storyKey,
+				Workflow: step.Workflow,
+				Duration: e.now().Sub(stepStart),
+				Todos:    e.lastTodos(),
+				Err:      err,
+			})
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("%w: %w", ErrInterrupted, err)
+			}
+			return e.saveState(storyKey, i, totalSteps, startStatus, err)
 		}
 
 		// Update status after successful workflow
 		if err := e.statusWriter.UpdateStatus(storyKey, step.NextStatus); err != nil {
+			return e.saveState(storyKey, i, totalSteps, startStatus, err)
+		}
+
+		// Record progress so a crash or interrupt resumes at the next step
+		if err := e.saveState(storyKey, i+1, totalSteps, startStatus, nil); err != nil {
 			return err
 		}
 	}
 
+	return e.clearState(storyKey)
+}
+
+// checkState validates saved progress against the lifecycle and the story's
+// current status, returning the lifecycle steps to resume.
+//
+// The story's status must match what the saved position implies: the start
+// status before any step has run, otherwise the status written by the last
+// completed step. Two relaxations cover workflows that update the sprint
+// status themselves: statuses with the same remaining lifecycle (such as
+// ready-for-dev and in-progress) are equivalent, and the status the next step
+// would write is accepted, in which case that step simply runs again.
+func (e *Executor) checkState(saved state.State) ([]router.LifecycleStep, error) {
+	steps, err := router.GetLifecycle(status.Status(saved.StartStatus))
+	if err != nil || saved.TotalSteps != len(steps) || saved.StepIndex < 0 || saved.StepIndex > len(steps) {
+		return nil, fmt.Errorf("%w: step %d of %d from status %q is not a valid lifecycle position",
+			ErrStaleState, saved.StepIndex, saved.TotalSteps, saved.StartStatus)
+	}
+
+	current, err := e.statusReader.GetStoryStatus(saved.StoryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := status.Status(saved.StartStatus)
+	if saved.StepIndex > 0 {
+		expected = steps[saved.StepIndex-1].NextStatus
+	}
+	if current == expected || sameLifecycle(current, expected) {
+		return steps, nil
+	}
+	if saved.StepIndex < len(steps) && current == steps[saved.StepIndex].NextStatus {
+		return steps, nil
+	}
+
+	return nil, fmt.Errorf("%w: story %s is %s in sprint status, but the saved state expects %s before %s",
+		ErrStaleState, saved.StoryKey, current, expected, stepName(steps, saved.StepIndex))
+}
+
+// sameLifecycle reports whether two statuses have identical remaining lifecycles.
+func sameLifecycle(a, b status.Status) bool {
+	stepsA, errA := router.GetLifecycle(a)
+	stepsB, errB := router.GetLifecycle(b)
+	return errA == nil && errB == nil && slices.Equal(stepsA, stepsB)
+}
+
+// stepName names the step at index i for messages.
+func stepName(steps []router.LifecycleStep, i int) string {
+	if i < len(steps) {
+		return steps[i].Workflow
+	}
+	return "completion"
+}
+
+// saveState records that the lifecycle reached stepIndex and returns err.
+//
+// A failure to save the state is joined to err rather than replacing it.
+// With a nil err, saveState returns just the save failure, if any.
+func (e *Executor) saveState(storyKey string, stepIndex, totalSteps int, startStatus status.Status, err error) error {
+	if e.stateStore == nil {
+		return err
+	}
+	saveErr := e.stateStore.Save(state.State{
+		StoryKey:    storyKey,
+		StepIndex:   stepIndex,
+		TotalSteps:  totalSteps,
+		StartStatus: string(startStatus),
+	})
+	if saveErr != nil {
+		return errors.Join(err, fmt.Errorf("saving lifecycle state: %w", saveErr))
+	}
+	return err
+}
+
+// clearState removes saved state belonging to storyKey after it completes.
+//
+// State saved for a different story is left alone.
+func (e *Executor) clearState(storyKey string) error {
+	if e.stateStore == nil {
+		return nil
+	}
+	saved, err := e.stateStore.Load()
+	if errors.Is(err, state.ErrNoState) {
+		return nil
+	}
+	if err == nil && saved.StoryKey != storyKey {
+		return nil
+	}
+	// Unreadable state is cleared too, so it cannot block later runs.
+	if err := e.stateStore.Clear(); err != nil {
+		return fmt.Errorf("clearing lifecycle state: %w", err)
+	}
+	return nil
+}
+
+// runStep makes a single attempt at a workflow step. Retries are left to
+// [Executor.runStepWithRetry].
+//
+// If sessionID is set, the step resumes that session instead of starting fresh.
+func (e *Executor) runStep(ctx context.Context, workflow, storyKey, sessionID string) error {
+	resumer, canResume := e.runner.(SessionResumer)
+
+	var exitCode int
+	if sessionID != "" && canResume {
+		exitCode = resumer.ResumeSingle(ctx, workflow, storyKey, sessionID)
+	} else {
+		exitCode = e.runner.RunSingle(ctx, workflow, storyKey)
+	}
+
+	if exitCode != 0 {
+		stepErr := &StepError{Workflow: workflow, ExitCode: exitCode, Todos: e.lastTodos(), Err: e.lastError()}
+		if canResume {
+			stepErr.SessionID = resumer.LastSessionID()
+		}
+		return stepErr
+	}
+
+	return nil
+}
+
+// lastError returns the runner's explanation of its last failure, if it offers one.
+func (e *Executor) lastError() error {
+	if reporter, ok := e.runner.(FailureReporter); ok {
+		return reporter.LastError()
+	}
+	return nil
+}
+
+// lastTodos returns Claude's task list from the runner, if it tracks one.
+func (e *Executor) lastTodos() []claude.Todo {
+	if reporter, ok := e.runner.(TodoReporter); ok {
+		return reporter.LastTodos()
+	}
 	return nil
 }
 
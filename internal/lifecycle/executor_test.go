@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"bmad-automate/internal/budget"
+	"bmad-automate/internal/claude"
 	"bmad-automate/internal/router"
+	"bmad-automate/internal/state"
 	"bmad-automate/internal/status"
 
 	"github.com/stretchr/testify/assert"
@@ -400,3 +404,579 @@ func TestGetSteps(t *testing.T) {
 		})
 	}
 }
+
+// MockResumingRunner implements WorkflowRunner and SessionResumer for testing.
+type MockResumingRunner struct {
+	MockWorkflowRunner
+	// ResumeFunc allows tests to control resume behavior.
+	ResumeFunc func(workflowName, sessionID string) int
+	// SessionID is returned by LastSessionID.
+	SessionID string
+	// Resumes records the session IDs passed to ResumeSingle.
+	Resumes []string
+}
+
+func (m *MockResumingRunner) LastSessionID() string {
+	return m.SessionID
+}
+
+func (m *MockResumingRunner) ResumeSingle(ctx context.Context, workflowName, storyKey, sessionID string) int {
+	m.Resumes = append(m.Resumes, sessionID)
+	if m.ResumeFunc != nil {
+		return m.ResumeFunc(workflowName, sessionID)
+	}
+	return 0
+}
+
+func TestExecute_StepErrorCarriesSessionID(t *testing.T) {
+	runner := &MockResumingRunner{SessionID: "sess-1"}
+	runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int {
+		if workflowName == "dev-story" {
+			return 2
+		}
+		return 0
+	}
+	reader := &MockStatusReader{}
+	writer := &MockStatusWriter{}
+
+	executor := NewExecutor(runner, reader, writer)
+	err := executor.Execute(context.Background(), "story-1")
+
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "dev-story", stepErr.Workflow)
+	assert.Equal(t, 2, stepErr.ExitCode)
+	assert.Equal(t, "sess-1", stepErr.SessionID)
+	assert.Contains(t, err.Error(), "workflow failed: dev-story returned exit code 2 (session sess-1)")
+	assert.Empty(t, runner.Resumes, "no retries by default")
+}
+
+func TestExecute_ResumeRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		retries     int
+		resumeFails int
+		wantResumes int
+		wantErr     bool
+	}{
+		{name: "resume succeeds on first retry", retries: 2, resumeFails: 0, wantResumes: 1},
+		{name: "resume succeeds on second retry", retries: 2, resumeFails: 1, wantResumes: 2},
+		{name: "retries exhausted", retries: 2, resumeFails: 5, wantResumes: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &MockResumingRunner{SessionID: "sess-1"}
+			runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int {
+				if workflowName == "dev-story" {
+					return 1
+				}
+				return 0
+			}
+			failures := 0
+			runner.ResumeFunc = func(workflowName, sessionID string) int {
+				if failures < tt.resumeFails {
+					failures++
+					return 1
+				}
+				return 0
+			}
+			reader := &MockStatusReader{
+				GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+					return status.StatusReadyForDev, nil
+				},
+			}
+			writer := &MockStatusWriter{}
+
+			executor := NewExecutor(runner, reader, writer)
+			executor.SetResumeRetries(tt.retries)
+			err := executor.Execute(context.Background(), "story-1")
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Len(t, writer.Calls, 0, "status must not advance past failed step")
+			} else {
+				require.NoError(t, err)
+				assert.Len(t, writer.Calls, 3)
+			}
+			assert.Len(t, runner.Resumes, tt.wantResumes)
+			for _, id := range runner.Resumes {
+				assert.Equal(t, "sess-1", id)
+			}
+		})
+	}
+}
+
+func TestExecuteResume(t *testing.T) {
+	runner := &MockResumingRunner{}
+	reader := &MockStatusReader{
+		GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+			return status.StatusReview, nil
+		},
+	}
+	writer := &MockStatusWriter{}
+
+	executor := NewExecutor(runner, reader, writer)
+	err := executor.ExecuteResume(context.Background(), "story-1", "sess-9")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sess-9"}, runner.Resumes, "first step resumes the session")
+	require.Len(t, runner.Calls, 1, "later steps run normally")
+	assert.Equal(t, "git-commit", runner.Calls[0].WorkflowName)
+}
+
+func TestExecuteResume_Unsupported(t *testing.T) {
+	executor := NewExecutor(&MockWorkflowRunner{}, &MockStatusReader{}, &MockStatusWriter{})
+
+	err := executor.ExecuteResume(context.Background(), "story-1", "sess-9")
+
+	assert.ErrorIs(t, err, ErrResumeUnsupported)
+}
+
+// MockReportingRunner implements WorkflowRunner, SessionResumer, and FailureReporter.
+type MockReportingRunner struct {
+	MockResumingRunner
+	Err error
+}
+
+func (m *MockReportingRunner) LastError() error {
+	return m.Err
+}
+
+func TestExecute_BudgetExceeded(t *testing.T) {
+	runner := &MockReportingRunner{Err: &budget.ExceededError{Scope: budget.ScopeStory}}
+	runner.SessionID = "sess-1"
+	runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int {
+		return 1
+	}
+	reader := &MockStatusReader{
+		GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+			return status.StatusReadyForDev, nil
+		},
+	}
+	writer := &MockStatusWriter{}
+
+	executor := NewExecutor(runner, reader, writer)
+	executor.SetResumeRetries(3)
+	err := executor.Execute(context.Background(), "story-1")
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, budget.ErrExceeded)
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "dev-story", stepErr.Workflow)
+	assert.Empty(t, runner.Resumes, "budget failures are not retried")
+	assert.Empty(t, writer.Calls, "sprint status is left untouched")
+}
+
+func TestExecute_TimeoutIsDistinct(t *testing.T) {
+	runner := &MockReportingRunner{Err: &claude.TimeoutError{Idle: true, After: 10 * time.Minute}}
+	runner.SessionID = "sess-1"
+	runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int {
+		return claude.ExitCodeTimeout
+	}
+	reader := &MockStatusReader{
+		GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+			return status.StatusReview, nil
+		},
+	}
+
+	executor := NewExecutor(runner, reader, &MockStatusWriter{})
+	err := executor.Execute(context.Background(), "story-1")
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, claude.ErrTimeout)
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.True(t, stepErr.TimedOut())
+	assert.Equal(t, "code-review", stepErr.Workflow)
+	assert.Equal(t, claude.ExitCodeTimeout, stepErr.ExitCode)
+	assert.Contains(t, err.Error(), "no output for 10m0s")
+}
+
+// MockTodoRunner implements WorkflowRunner and TodoReporter.
+type MockTodoRunner struct {
+	MockWorkflowRunner
+	Todos []claude.Todo
+}
+
+func (m *MockTodoRunner) LastTodos() []claude.Todo {
+	return m.Todos
+}
+
+func TestExecute_ReportsTodos(t *testing.T) {
+	runner := &MockTodoRunner{}
+	runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int {
+		switch workflowName {
+		case "dev-story":
+			runner.Todos = []claude.Todo{
+				{Content: "Add model", Status: claude.TodoCompleted},
+				{Content: "Add handler", Status: claude.TodoPending},
+			}
+		case "code-review":
+			runner.Todos = []claude.Todo{
+				{Content: "Add model", Status: claude.TodoCompleted},
+				{Content: "Add handler", Status: claude.TodoInProgress},
+			}
+			return 1
+		}
+		return 0
+	}
+	reader := &MockStatusReader{
+		GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+			return status.StatusReadyForDev, nil
+		},
+	}
+
+	executor := NewExecutor(runner, reader, &MockStatusWriter{})
+	var results []StepResult
+	executor.SetStepCallback(func(result StepResult) {
+		results = append(results, result)
+	})
+	err := executor.Execute(context.Background(), "story-1")
+
+	require.Len(t, results, 2, "the callback runs after failed steps too")
+	assert.Equal(t, "story-1", results[0].StoryKey)
+	assert.Equal(t, "dev-story", results[0].Workflow)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, claude.TodoPending, results[0].Todos[1].Status)
+	assert.Equal(t, "code-review", results[1].Workflow)
+	assert.Equal(t, claude.TodoInProgress, results[1].Todos[1].Status)
+
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.ErrorIs(t, results[1].Err, stepErr)
+	assert.Equal(t, runner.Todos, stepErr.Todos, "the failed step's task list is kept")
+}
+
+func TestExecute_StepCallbackWithoutTodos(t *testing.T) {
+	executor := NewExecutor(&MockWorkflowRunner{}, &MockStatusReader{}, &MockStatusWriter{})
+	var results []StepResult
+	executor.SetStepCallback(func(result StepResult) {
+		results = append(results, result)
+	})
+
+	require.NoError(t, executor.Execute(context.Background(), "story-1"))
+
+	require.NotEmpty(t, results)
+	for _, result := range results {
+		assert.Nil(t, result.Todos, "runners without TodoReporter report no task list")
+	}
+}
+
+func TestStepError_TimedOut(t *testing.T) {
+	assert.False(t, (&StepError{Workflow: "dev-story", ExitCode: 1}).TimedOut())
+	assert.False(t, (&StepError{Workflow: "dev-story", ExitCode: 1, Err: errors.New("boom")}).TimedOut())
+	assert.True(t, (&StepError{Workflow: "dev-story", ExitCode: 124, Err: &claude.TimeoutError{After: time.Hour}}).TimedOut())
+}
+
+// MockStateStore implements StateStore for testing.
+type MockStateStore struct {
+	Saved   *state.State
+	Cleared bool
+	SaveErr error
+}
+
+func (m *MockStateStore) Save(st state.State) error {
+	if m.SaveErr != nil {
+		return m.SaveErr
+	}
+	m.Saved = &st
+	return nil
+}
+
+func (m *MockStateStore) Load() (state.State, error) {
+	if m.Saved == nil {
+		return state.State{}, state.ErrNoState
+	}
+	return *m.Saved, nil
+}
+
+func (m *MockStateStore) Clear() error {
+	m.Saved = nil
+	m.Cleared = true
+	return nil
+}
+
+func TestExecute_StateStore(t *testing.T) {
+	tests := []struct {
+		name        string
+		saved       *state.State
+		failOn      string
+		wantErr     bool
+		wantSaved   *state.State
+		wantCleared bool
+	}{
+		{
+			name:      "failure saves the failed step",
+			failOn:    "code-review",
+			wantErr:   true,
+			wantSaved: &state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 3, StartStatus: "in-progress"},
+		},
+		{
+			name:        "success clears state for the same story",
+			saved:       &state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 3},
+			wantCleared: true,
+		},
+		{
+			name:        "success replaces state for another story",
+			saved:       &state.State{StoryKey: "story-9", StepIndex: 2, TotalSteps: 4},
+			wantCleared: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &MockWorkflowRunner{
+				RunSingleFunc: func(ctx context.Context, workflowName, storyKey string) int {
+					if workflowName == tt.failOn {
+						return 1
+					}
+					return 0
+				},
+			}
+			reader := &MockStatusReader{
+				GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+					return status.StatusInProgress, nil
+				},
+			}
+			store := &MockStateStore{Saved: tt.saved}
+
+			executor := NewExecutor(runner, reader, &MockStatusWriter{})
+			executor.SetStateStore(store)
+			err := executor.Execute(context.Background(), "story-1")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantSaved, store.Saved)
+			assert.Equal(t, tt.wantCleared, store.Cleared)
+		})
+	}
+}
+
+func TestExecute_StateSaveFailureIsReported(t *testing.T) {
+	runner := &MockWorkflowRunner{
+		RunSingleFunc: func(ctx context.Context, workflowName, storyKey string) int { return 1 },
+	}
+	store := &MockStateStore{SaveErr: errors.New("disk full")}
+
+	executor := NewExecutor(runner, &MockStatusReader{}, &MockStatusWriter{})
+	executor.SetStateStore(store)
+	err := executor.Execute(context.Background(), "story-1")
+
+	var stepErr *StepError
+	assert.ErrorAs(t, err, &stepErr, "the step failure is kept")
+	assert.ErrorContains(t, err, "saving lifecycle state: disk full")
+}
+
+func TestExecute_DrainStopsBetweenSteps(t *testing.T) {
+	drain := make(chan struct{})
+	runner := &MockWorkflowRunner{
+		RunSingleFunc: func(ctx context.Context, workflowName, storyKey string) int {
+			if workflowName == "create-story" {
+				close(drain)
+			}
+			return 0
+		},
+	}
+	writer := &MockStatusWriter{}
+	store := &MockStateStore{}
+
+	executor := NewExecutor(runner, &MockStatusReader{}, writer)
+	executor.SetStateStore(store)
+	err := executor.Execute(WithDrain(context.Background(), drain), "story-1")
+
+	require.ErrorIs(t, err, ErrInterrupted)
+	assert.Contains(t, err.Error(), "stopped before dev-story")
+	require.Len(t, runner.Calls, 1, "the running step finishes, the next does not start")
+	require.Len(t, writer.Calls, 1, "the finished step's status is written")
+	assert.Equal(t, &state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 4, StartStatus: "backlog"}, store.Saved)
+}
+
+func TestExecute_CanceledContextIsInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner := &MockResumingRunner{SessionID: "sess-1"}
+	runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int {
+		cancel()
+		return -1
+	}
+	store := &MockStateStore{}
+
+	executor := NewExecutor(runner, &MockStatusReader{}, &MockStatusWriter{})
+	executor.SetStateStore(store)
+	executor.SetResumeRetries(3)
+	err := executor.Execute(ctx, "story-1")
+
+	require.ErrorIs(t, err, ErrInterrupted)
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "sess-1", stepErr.SessionID)
+	assert.Empty(t, runner.Resumes, "a canceled step is not retried")
+	assert.Equal(t, 0, store.Saved.StepIndex)
+}
+
+func TestDraining(t *testing.T) {
+	assert.False(t, Draining(context.Background()))
+
+	drain := make(chan struct{})
+	ctx := WithDrain(context.Background(), drain)
+	assert.False(t, Draining(ctx))
+
+	close(drain)
+	assert.True(t, Draining(ctx))
+}
+
+// recordingStateStore records every saved state for testing.
+type recordingStateStore struct {
+	MockStateStore
+	History []state.State
+}
+
+func (m *recordingStateStore) Save(st state.State) error {
+	m.History = append(m.History, st)
+	return m.MockStateStore.Save(st)
+}
+
+func TestExecute_SavesProgressAfterEachStep(t *testing.T) {
+	reader := &MockStatusReader{
+		GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+			return status.StatusReview, nil
+		},
+	}
+	store := &recordingStateStore{}
+
+	executor := NewExecutor(&MockWorkflowRunner{}, reader, &MockStatusWriter{})
+	executor.SetStateStore(store)
+	require.NoError(t, executor.Execute(context.Background(), "story-1"))
+
+	assert.Equal(t, []state.State{
+		{StoryKey: "story-1", StepIndex: 1, TotalSteps: 2, StartStatus: "review"},
+		{StoryKey: "story-1", StepIndex: 2, TotalSteps: 2, StartStatus: "review"},
+	}, store.History)
+	assert.True(t, store.Cleared)
+}
+
+func TestExecuteFromState(t *testing.T) {
+	tests := []struct {
+		name          string
+		saved         state.State
+		currentStatus status.Status
+		wantWorkflows []string
+		wantStale     bool
+	}{
+		{
+			name:          "continues at the saved step",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 2, TotalSteps: 4, StartStatus: "backlog"},
+			currentStatus: status.StatusReview,
+			wantWorkflows: []string{"code-review", "git-commit"},
+		},
+		{
+			name:          "runs git-commit after code-review marked the story done",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 2, StartStatus: "review"},
+			currentStatus: status.StatusDone,
+			wantWorkflows: []string{"git-commit"},
+		},
+		{
+			name:          "accepts an equivalent status",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 4, StartStatus: "backlog"},
+			currentStatus: status.StatusInProgress,
+			wantWorkflows: []string{"dev-story", "code-review", "git-commit"},
+		},
+		{
+			name:          "accepts the status the interrupted step wrote",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 3, StartStatus: "in-progress"},
+			currentStatus: status.StatusDone,
+			wantWorkflows: []string{"code-review", "git-commit"},
+		},
+		{
+			name:          "completed lifecycle runs nothing",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 2, TotalSteps: 2, StartStatus: "review"},
+			currentStatus: status.StatusDone,
+		},
+		{
+			name:          "status moved on since the run stopped",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 4, StartStatus: "backlog"},
+			currentStatus: status.StatusDone,
+			wantStale:     true,
+		},
+		{
+			name:          "step count does not match the lifecycle",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 1, TotalSteps: 5, StartStatus: "backlog"},
+			currentStatus: status.StatusReadyForDev,
+			wantStale:     true,
+		},
+		{
+			name:          "step index out of range",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 3, TotalSteps: 2, StartStatus: "review"},
+			currentStatus: status.StatusDone,
+			wantStale:     true,
+		},
+		{
+			name:          "start status has no lifecycle",
+			saved:         state.State{StoryKey: "story-1", StepIndex: 0, TotalSteps: 0, StartStatus: "done"},
+			currentStatus: status.StatusDone,
+			wantStale:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &MockWorkflowRunner{}
+			reader := &MockStatusReader{
+				GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+					return tt.currentStatus, nil
+				},
+			}
+			store := &MockStateStore{Saved: &tt.saved}
+
+			executor := NewExecutor(runner, reader, &MockStatusWriter{})
+			executor.SetStateStore(store)
+			err := executor.ExecuteFromState(context.Background(), tt.saved)
+
+			var workflows []string
+			for _, call := range runner.Calls {
+				workflows = append(workflows, call.WorkflowName)
+			}
+
+			if tt.wantStale {
+				require.ErrorIs(t, err, ErrStaleState)
+				assert.Empty(t, workflows)
+				assert.False(t, store.Cleared, "stale state is left for the user to inspect")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWorkflows, workflows)
+			assert.True(t, store.Cleared)
+		})
+	}
+}
+
+func TestExecuteFromState_FailureSavesFromOriginalStart(t *testing.T) {
+	runner := &MockWorkflowRunner{
+		RunSingleFunc: func(ctx context.Context, workflowName, storyKey string) int {
+			if workflowName == "git-commit" {
+				return 1
+			}
+			return 0
+		},
+	}
+	reader := &MockStatusReader{
+		GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+			return status.StatusReview, nil
+		},
+	}
+	store := &MockStateStore{}
+
+	executor := NewExecutor(runner, reader, &MockStatusWriter{})
+	executor.SetStateStore(store)
+	err := executor.ExecuteFromState(context.Background(),
+		state.State{StoryKey: "story-1", StepIndex: 2, TotalSteps: 4, StartStatus: "backlog"})
+
+	require.Error(t, err)
+	assert.Equal(t, &state.State{StoryKey: "story-1", StepIndex: 3, TotalSteps: 4, StartStatus: "backlog"}, store.Saved)
+}
@@ -0,0 +1,38 @@
+package lifecycle
+
+import "context"
+
+// drainKey is the context key for the drain signal.
+type drainKey struct{}
+
+// WithDrain returns a copy of ctx carrying a drain signal.
+//
+// Closing drain asks running lifecycles to stop gracefully: the step in
+// progress finishes (and its status update is written), then [Executor.Execute]
+// saves its progress and returns an error matching [ErrInterrupted]. Canceling
+// ctx itself remains the way to stop immediately.
+func WithDrain(ctx context.Context, drain <-chan struct{}) context.Context {
+	return context.WithValue(ctx, drainKey{}, drain)
+}
+
+// Draining reports whether a drain has been requested on ctx (see [WithDrain]).
+//
+// Batch callers such as queue and epic check this between stories.
+func Draining(ctx context.Context) bool {
+	drain := drainSignal(ctx)
+	if drain == nil {
+		return false
+	}
+	select {
+	case <-drain:
+		return true
+	default:
+		return false
+	}
+}
+
+// drainSignal returns the drain channel carried by ctx, or nil if there is none.
+func drainSignal(ctx context.Context) <-chan struct{} {
+	drain, _ := ctx.Value(drainKey{}).(<-chan struct{})
+	return drain
+}
@@ -0,0 +1,269 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"bmad-automate/internal/budget"
+	"bmad-automate/internal/claude"
+)
+
+// RetryPolicy controls how a step that failed for a transient reason is retried.
+//
+// A failure is retried only if the runner explains it (see [FailureReporter])
+// and [claude.Classify] puts it in one of the RetryOn classes. Going over a
+// spending budget ([budget.ErrExceeded]) is never retried. The zero value
+// never retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per step, including the
+	// first. Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the second attempt.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// Multiplier scales the wait after each further failed attempt.
+	// Values below 1 keep the wait constant.
+	Multiplier float64
+
+	// RetryOn lists the failure classes worth retrying.
+	RetryOn []claude.FailureClass
+
+	// WaitForUsageLimit makes a step that hit the Claude usage limit wait
+	// for the limit to reset and then resume its session, instead of
+	// failing. Waiting does not use up attempts.
+	WaitForUsageLimit bool
+
+	// MaxUsageLimitWait is the longest wait for a usage limit to reset; a
+	// limit that resets later fails the step. It is also how long to wait
+	// when Claude did not say when the limit resets. Zero means no cap, in
+	// which case an unknown reset time fails the step.
+	MaxUsageLimitWait time.Duration
+
+	// UsageLimitMargin is added to the reset time before resuming.
+	UsageLimitMargin time.Duration
+}
+
+// Retryable reports whether a failure of the given class should be retried.
+func (p RetryPolicy) Retryable(class claude.FailureClass) bool {
+	return p.MaxAttempts > 1 && slices.Contains(p.RetryOn, class)
+}
+
+// Backoff returns the wait after the given failed attempt (1-based).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt && p.Multiplier > 1; i++ {
+		delay *= p.Multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// RetryEvent describes a failed attempt at a step whose failure class is
+// retryable. It is passed to the [RetryCallback].
+type RetryEvent struct {
+	// Workflow is the name of the step's workflow.
+	Workflow string
+
+	// Attempt is the 1-based number of the attempt that failed.
+	Attempt int
+
+	// MaxAttempts is the configured [RetryPolicy.MaxAttempts].
+	MaxAttempts int
+
+	// Class is the failure class of the attempt.
+	Class claude.FailureClass
+
+	// Err is the step's failure.
+	Err error
+
+	// Delay is the wait before the next attempt. It is zero when GivingUp.
+	Delay time.Duration
+
+	// GivingUp is true when no attempts remain and the step fails.
+	GivingUp bool
+}
+
+// RetryCallback is invoked after each failed attempt of a step whose failure
+// is retryable, before the wait for the next attempt.
+//
+// This enables logging each attempt. The callback is optional and can be set
+// via [Executor.SetRetryCallback].
+type RetryCallback func(event RetryEvent)
+
+// UsageLimitCallback is invoked while a step waits for the Claude usage limit
+// to reset: when the wait begins and then about once a minute, with the time
+// the step will resume and the time remaining until then.
+//
+// This enables a countdown display. The callback is optional and can be set
+// via [Executor.SetUsageLimitCallback].
+type UsageLimitCallback func(workflow string, resumeAt time.Time, remaining time.Duration)
+
+// usageLimitTick is how often a usage-limit wait reports the time remaining.
+const usageLimitTick = time.Minute
+
+// SetRetryPolicy configures automatic retries of steps that fail for
+// transient reasons, such as an overloaded API or a network error.
+//
+// A retry waits for the policy's backoff, then resumes the failed attempt's
+// Claude session when the runner implements [SessionResumer] and reported one,
+// so work done before the failure is kept; otherwise the step runs again from
+// the start. Retries stop early when ctx is canceled or a drain is requested
+// ([WithDrain]). No retries happen by default.
+func (e *Executor) SetRetryPolicy(policy RetryPolicy) {
+	e.retryPolicy = policy
+}
+
+// SetRetryCallback configures an optional callback for logging retries.
+func (e *Executor) SetRetryCallback(cb RetryCallback) {
+	e.retryCallback = cb
+}
+
+// SetUsageLimitCallback configures an optional callback for displaying
+// usage-limit waits.
+func (e *Executor) SetUsageLimitCallback(cb UsageLimitCallback) {
+	e.usageLimitCallback = cb
+}
+
+// runStepWithRetry runs a step, retrying it according to the [RetryPolicy]
+// and the resume retries (see [Executor.SetResumeRetries]). It is the only
+// place a failed step is retried.
+func (e *Executor) runStepWithRetry(ctx context.Context, workflow, storyKey, sessionID string) error {
+	var lastResumeAt time.Time
+	resumes := 0
+	for attempt := 1; ; attempt++ {
+		err := e.runStep(ctx, workflow, storyKey, sessionID)
+		if err == nil || ctx.Err() != nil || Draining(ctx) {
+			return err
+		}
+
+		stepErr, ok := err.(*StepError)
+		if !ok || errors.Is(stepErr.Err, budget.ErrExceeded) {
+			return err // running again would only spend more
+		}
+		class := claude.Classify(stepErr.Err)
+
+		if class == claude.FailureUsageLimit && e.retryPolicy.WaitForUsageLimit {
+			resumeAt, ok := e.usageLimitResumeTime(stepErr.Err)
+			if !ok || !resumeAt.After(lastResumeAt) {
+				return err // too far off, or the limit did not reset when expected
+			}
+			if !e.waitUntil(ctx, workflow, resumeAt) {
+				return err
+			}
+			lastResumeAt = resumeAt
+			sessionID = stepErr.SessionID
+			attempt-- // waiting for the limit does not use up an attempt
+			continue
+		}
+
+		if !e.retryPolicy.Retryable(class) {
+			// Resume the failed session right away, if resume retries remain
+			if class == claude.FailureUsageLimit || stepErr.SessionID == "" || resumes >= e.resumeRetries {
+				return err
+			}
+			resumes++
+			sessionID = stepErr.SessionID
+			attempt-- // resume retries are counted on their own
+			continue
+		}
+
+		event := RetryEvent{
+			Workflow:    workflow,
+			Attempt:     attempt,
+			MaxAttempts: e.retryPolicy.MaxAttempts,
+			Class:       class,
+			Err:         err,
+		}
+		if attempt >= e.retryPolicy.MaxAttempts {
+			event.GivingUp = true
+			e.notifyRetry(event)
+			return err
+		}
+		event.Delay = e.retryPolicy.Backoff(attempt)
+		e.notifyRetry(event)
+
+		if !e.sleep(ctx, event.Delay) {
+			return err
+		}
+		sessionID = stepErr.SessionID
+	}
+}
+
+// usageLimitResumeTime returns when a step stopped by the usage limit should
+// resume, or false if that is more than [RetryPolicy.MaxUsageLimitWait] away.
+func (e *Executor) usageLimitResumeTime(err error) (time.Time, bool) {
+	now := e.now()
+	policy := e.retryPolicy
+
+	var resetAt time.Time
+	if usageErr := claude.NewUsageLimitError(err, now); usageErr != nil {
+		resetAt = usageErr.ResetAt
+	}
+	if resetAt.IsZero() {
+		if policy.MaxUsageLimitWait <= 0 {
+			return time.Time{}, false
+		}
+		return now.Add(policy.MaxUsageLimitWait), true
+	}
+
+	resumeAt := resetAt.Add(policy.UsageLimitMargin)
+	if resumeAt.Before(now) {
+		resumeAt = now
+	}
+	if policy.MaxUsageLimitWait > 0 && resumeAt.Sub(now) > policy.MaxUsageLimitWait {
+		return time.Time{}, false
+	}
+	return resumeAt, true
+}
+
+// waitUntil waits for resumeAt, reporting the time remaining to the usage-limit
+// callback. It returns false if the wait ended early because ctx was canceled
+// or a drain was requested.
+func (e *Executor) waitUntil(ctx context.Context, workflow string, resumeAt time.Time) bool {
+	for {
+		remaining := resumeAt.Sub(e.now())
+		if remaining <= 0 {
+			return true
+		}
+		if e.usageLimitCallback != nil {
+			e.usageLimitCallback(workflow, resumeAt, remaining)
+		}
+		if !e.sleep(ctx, min(remaining, usageLimitTick)) {
+			return false
+		}
+	}
+}
+
+// notifyRetry calls the retry callback, if set.
+func (e *Executor) notifyRetry(event RetryEvent) {
+	if e.retryCallback != nil {
+		e.retryCallback(event)
+	}
+}
+
+// sleepContext waits for d, returning false early if ctx is canceled or a
+// drain is requested.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-drainSignal(ctx):
+		return false
+	}
+}
@@ -0,0 +1,313 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/budget"
+	"bmad-automate/internal/claude"
+	"bmad-automate/internal/status"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute, Multiplier: 2}
+
+	assert.Equal(t, 10*time.Second, policy.Backoff(1))
+	assert.Equal(t, 20*time.Second, policy.Backoff(2))
+	assert.Equal(t, 40*time.Second, policy.Backoff(3))
+	assert.Equal(t, time.Minute, policy.Backoff(4), "capped at MaxBackoff")
+	assert.Equal(t, time.Minute, policy.Backoff(50))
+
+	constant := RetryPolicy{InitialBackoff: 5 * time.Second}
+	assert.Equal(t, 5*time.Second, constant.Backoff(3), "no multiplier keeps the wait constant")
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, RetryOn: []claude.FailureClass{claude.FailureOverloaded}}
+
+	assert.True(t, policy.Retryable(claude.FailureOverloaded))
+	assert.False(t, policy.Retryable(claude.FailureUnknown))
+
+	policy.MaxAttempts = 1
+	assert.False(t, policy.Retryable(claude.FailureOverloaded), "one attempt disables retries")
+}
+
+// newRetryTestExecutor returns an executor for a review-status story whose
+// code-review step fails failures times with err, recording the waits.
+func newRetryTestExecutor(err error, failures int) (*Executor, *MockReportingRunner, *[]time.Duration) {
+	runner := &MockReportingRunner{Err: err}
+	runner.SessionID = "sess-1"
+	calls := 0
+	fail := func(workflowName string) int {
+		if workflowName != "code-review" {
+			return 0
+		}
+		calls++
+		if calls <= failures {
+			return 1
+		}
+		return 0
+	}
+	runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int { return fail(workflowName) }
+	runner.ResumeFunc = func(workflowName, sessionID string) int { return fail(workflowName) }
+
+	reader := &MockStatusReader{
+		GetStoryStatusFunc: func(storyKey string) (status.Status, error) {
+			return status.StatusReview, nil
+		},
+	}
+
+	var waits []time.Duration
+	executor := NewExecutor(runner, reader, &MockStatusWriter{})
+	executor.SetRetryPolicy(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		Multiplier:     2,
+		RetryOn:        []claude.FailureClass{claude.FailureOverloaded, claude.FailureNetwork},
+	})
+	executor.sleep = func(ctx context.Context, d time.Duration) bool {
+		waits = append(waits, d)
+		return true
+	}
+	return executor, runner, &waits
+}
+
+func TestExecute_RetriesTransientFailure(t *testing.T) {
+	overloaded := &claude.ExitError{Code: 1, Stderr: []string{"API Error: 529 Overloaded"}}
+	executor, runner, waits := newRetryTestExecutor(overloaded, 2)
+
+	var events []RetryEvent
+	executor.SetRetryCallback(func(event RetryEvent) { events = append(events, event) })
+
+	require.NoError(t, executor.Execute(context.Background(), "story-1"))
+
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *waits)
+	assert.Equal(t, []string{"sess-1", "sess-1"}, runner.Resumes, "retries resume the failed session")
+	require.Len(t, events, 2)
+	assert.Equal(t, 1, events[0].Attempt)
+	assert.Equal(t, 3, events[0].MaxAttempts)
+	assert.Equal(t, claude.FailureOverloaded, events[0].Class)
+	assert.Equal(t, time.Second, events[0].Delay)
+	assert.False(t, events[1].GivingUp)
+}
+
+func TestExecute_ResumeRetriesDoNotMultiplyRetries(t *testing.T) {
+	overloaded := &claude.ExitError{Code: 1, Stderr: []string{"API Error: 529 Overloaded"}}
+	executor, runner, waits := newRetryTestExecutor(overloaded, 10)
+	executor.SetResumeRetries(2)
+
+	err := executor.Execute(context.Background(), "story-1")
+
+	require.Error(t, err)
+	assert.Len(t, runner.Calls, 1)
+	assert.Len(t, runner.Resumes, 2, "a retried failure gets the policy's attempts only")
+	assert.Len(t, *waits, 2, "every retry waits for the backoff")
+}
+
+func TestExecute_ResumeRetriesCoverOtherFailures(t *testing.T) {
+	failed := &claude.ExitError{Code: 1, Stderr: []string{"FAIL: 3 tests failed"}}
+	executor, runner, waits := newRetryTestExecutor(failed, 2)
+	executor.SetResumeRetries(2)
+
+	require.NoError(t, executor.Execute(context.Background(), "story-1"))
+
+	assert.Equal(t, []string{"sess-1", "sess-1"}, runner.Resumes)
+	assert.Empty(t, *waits, "resume retries do not wait")
+}
+
+func TestExecute_RetryGivesUp(t *testing.T) {
+	network := &claude.ResultError{Message: "Connection error."}
+	executor, _, waits := newRetryTestExecutor(network, 10)
+
+	var events []RetryEvent
+	executor.SetRetryCallback(func(event RetryEvent) { events = append(events, event) })
+
+	err := executor.Execute(context.Background(), "story-1")
+
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "code-review", stepErr.Workflow)
+	assert.Len(t, *waits, 2)
+	require.Len(t, events, 3)
+	assert.True(t, events[2].GivingUp)
+	assert.Zero(t, events[2].Delay)
+}
+
+func TestExecute_NoRetryForOtherFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"plain exit code", nil},
+		{"unclassified error", &claude.ExitError{Code: 1, Stderr: []string{"tests failed"}}},
+		{"budget exceeded", &budget.ExceededError{Scope: budget.ScopeStep}},
+		{"class not configured", &claude.ExitError{Code: 1, Stderr: []string{"API Error: 429 Too Many Requests"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, _, waits := newRetryTestExecutor(tt.err, 1)
+
+			err := executor.Execute(context.Background(), "story-1")
+
+			assert.Error(t, err)
+			assert.Empty(t, *waits)
+		})
+	}
+}
+
+func TestExecute_NoRetryForBudgetStop(t *testing.T) {
+	// Budget stops are not retried even when every class is retryable.
+	exceeded := &budget.ExceededError{
+		Scope:  budget.ScopeStory,
+		Limits: budget.Limits{MaxCostUSD: 500},
+		Spent:  budget.Spend{CostUSD: 500},
+	}
+	executor, _, waits := newRetryTestExecutor(exceeded, 1)
+	executor.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		RetryOn:     []claude.FailureClass{claude.FailureOverloaded, claude.FailureUnknown},
+	})
+
+	err := executor.Execute(context.Background(), "story-1")
+
+	assert.ErrorIs(t, err, budget.ErrExceeded)
+	assert.Empty(t, *waits, "budget stops are never retried")
+}
+
+func TestExecute_DrainStopsRetries(t *testing.T) {
+	overloaded := &claude.ExitError{Code: 1, Stderr: []string{"overloaded_error"}}
+	executor, _, _ := newRetryTestExecutor(overloaded, 10)
+	executor.sleep = sleepContext
+
+	drain := make(chan struct{})
+	close(drain)
+	err := executor.Execute(WithDrain(context.Background(), drain), "story-1")
+
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+}
+
+// useFakeClock makes executor's sleeps advance a fake clock starting at start
+// instead of waiting, recording the sleeps in waits.
+func useFakeClock(executor *Executor, start time.Time, waits *[]time.Duration) {
+	now := start
+	executor.now = func() time.Time { return now }
+	executor.sleep = func(ctx context.Context, d time.Duration) bool {
+		*waits = append(*waits, d)
+		now = now.Add(d)
+		return true
+	}
+}
+
+func TestExecute_WaitsForUsageLimit(t *testing.T) {
+	start := time.Date(2025, 10, 9, 13, 0, 0, 0, time.UTC)
+	limit := &claude.ResultError{Message: "5-hour limit reached ∙ resets 3pm"}
+	executor, runner, waits := newRetryTestExecutor(limit, 1)
+	executor.retryPolicy.WaitForUsageLimit = true
+	executor.retryPolicy.MaxUsageLimitWait = 6 * time.Hour
+	executor.retryPolicy.UsageLimitMargin = time.Minute
+	useFakeClock(executor, start, waits)
+
+	var remaining []time.Duration
+	var resumeAt time.Time
+	executor.SetUsageLimitCallback(func(workflow string, at time.Time, left time.Duration) {
+		assert.Equal(t, "code-review", workflow)
+		resumeAt = at
+		remaining = append(remaining, left)
+	})
+
+	require.NoError(t, executor.Execute(context.Background(), "story-1"))
+
+	assert.Equal(t, time.Date(2025, 10, 9, 15, 1, 0, 0, time.UTC), resumeAt, "reset time plus margin")
+	assert.Len(t, *waits, 121, "sleeps in one-minute ticks")
+	assert.Equal(t, 2*time.Hour+time.Minute, remaining[0])
+	assert.Equal(t, time.Minute, remaining[len(remaining)-1])
+	assert.Equal(t, []string{"sess-1"}, runner.Resumes, "the wait resumes the stopped session")
+}
+
+func TestExecute_UsageLimitWaitDoesNotUseAttempts(t *testing.T) {
+	start := time.Date(2025, 10, 9, 13, 0, 0, 0, time.UTC)
+	executor, _, waits := newRetryTestExecutor(nil, 0)
+	executor.retryPolicy.WaitForUsageLimit = true
+	executor.retryPolicy.MaxUsageLimitWait = time.Hour
+	useFakeClock(executor, start, waits)
+
+	// Fail with a usage limit that resets a little later each time
+	runner := executor.runner.(*MockReportingRunner)
+	limits := 0
+	fail := func(workflowName string) int {
+		if workflowName != "code-review" || limits == 5 {
+			runner.Err = nil
+			return 0
+		}
+		limits++
+		reset := start.Add(time.Duration(limits) * 10 * time.Minute).Unix()
+		runner.Err = &claude.ResultError{Message: fmt.Sprintf("Claude AI usage limit reached|%d", reset)}
+		return 1
+	}
+	runner.RunSingleFunc = func(ctx context.Context, workflowName, storyKey string) int { return fail(workflowName) }
+	runner.ResumeFunc = func(workflowName, sessionID string) int { return fail(workflowName) }
+
+	require.NoError(t, executor.Execute(context.Background(), "story-1"))
+	assert.Equal(t, 5, limits, "more waits than MaxAttempts")
+}
+
+func TestExecute_UsageLimitGivesUp(t *testing.T) {
+	start := time.Date(2025, 10, 9, 13, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		err     error
+		maxWait time.Duration
+		waits   int
+	}{
+		{"reset beyond max wait", &claude.ResultError{Message: "Weekly limit reached ∙ resets 9am"}, 6 * time.Hour, 0},
+		{"unknown reset without max wait", &claude.ResultError{Message: "Claude usage limit reached"}, 0, 0},
+		{"limit did not reset", &claude.ResultError{Message: "5-hour limit reached ∙ resets 2pm"}, 6 * time.Hour, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, _, waits := newRetryTestExecutor(tt.err, 10)
+			executor.retryPolicy.WaitForUsageLimit = true
+			executor.retryPolicy.MaxUsageLimitWait = tt.maxWait
+			useFakeClock(executor, start, waits)
+
+			err := executor.Execute(context.Background(), "story-1")
+
+			var stepErr *StepError
+			require.ErrorAs(t, err, &stepErr)
+			assert.Equal(t, claude.FailureUsageLimit, claude.Classify(stepErr.Err))
+			assert.Len(t, *waits, tt.waits)
+		})
+	}
+}
+
+func TestExecute_UsageLimitWaitDisabled(t *testing.T) {
+	limit := &claude.ResultError{Message: "5-hour limit reached ∙ resets 3pm"}
+	executor, runner, waits := newRetryTestExecutor(limit, 1)
+	executor.SetResumeRetries(2)
+
+	err := executor.Execute(context.Background(), "story-1")
+
+	assert.Error(t, err)
+	assert.Empty(t, *waits)
+	assert.Empty(t, runner.Resumes, "the step fails without waiting")
+}
+
+func TestSleepContext(t *testing.T) {
+	assert.True(t, sleepContext(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, sleepContext(ctx, time.Hour))
+
+	drain := make(chan struct{})
+	close(drain)
+	assert.False(t, sleepContext(WithDrain(context.Background(), drain), time.Hour))
+}
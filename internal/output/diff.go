@@ -0,0 +1,149 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxDiffCells caps the size of the table used to match lines. Larger edits
+// are shown as all of the old text removed and all of the new text added.
+const maxDiffCells = 1_000_000
+
+// diffOp is one line of a line diff: ' ' unchanged, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns a compact unified diff of an edit from oldText to
+// newText, styled for the terminal. Unchanged lines more than [diffContext]
+// lines from a change are left out, with a marker between the hunks.
+func diffLines(oldText, newText string) []string {
+	ops := lineDiff(splitLines(oldText), splitLines(newText))
+
+	// Keep the changed lines and the context around them.
+	keep := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		for j := max(i-diffContext, 0); j <= min(i+diffContext, len(ops)-1); j++ {
+			keep[j] = true
+		}
+	}
+
+	var lines []string
+	for i, op := range ops {
+		if !keep[i] {
+			continue
+		}
+		if i > 0 && !keep[i-1] && len(lines) > 0 {
+			lines = append(lines, diffHunkStyle.Render("@@"))
+		}
+		lines = append(lines, renderDiffOp(op))
+	}
+	return lines
+}
+
+// renderDiffOp styles a diff line by its kind.
+func renderDiffOp(op diffOp) string {
+	text := string(op.kind) + op.line
+	switch op.kind {
+	case '-':
+		return diffRemoveStyle.Render(text)
+	case '+':
+		return diffAddStyle.Render(text)
+	default:
+		return diffContextStyle.Render(text)
+	}
+}
+
+// diffStat counts the added and removed lines of an edit (e.g., "+2 -1").
+func diffStat(oldText, newText string) string {
+	var added, removed int
+	for _, op := range lineDiff(splitLines(oldText), splitLines(newText)) {
+		switch op.kind {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return fmt.Sprintf("+%d -%d", added, removed)
+}
+
+// lineDiff matches the lines of a and b by their longest common subsequence
+// and returns the edit script from a to b, removals before additions.
+func lineDiff(a, b []string) []diffOp {
+	if len(a)*len(b) > maxDiffCells {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// splitLines splits text into lines, ignoring a final newline. Empty text
+// has no lines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// collapseLines keeps at most maxLines lines, showing the first and last
+// portions like [truncateOutput]. A maxLines of zero or less keeps them all.
+func collapseLines(lines []string, maxLines int) []string {
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return lines
+	}
+	return strings.Split(truncateOutput(strings.Join(lines, "\n"), maxLines), "\n")
+}
@@ -0,0 +1,95 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"bmad-automate/internal/claude"
+)
+
+func TestDiffLines(t *testing.T) {
+	oldText := "func f() error {\n    x := 1\n    return nil\n}\n"
+	newText := "func f() error {\n    x := 1\n    if x > 0 {\n        return errX\n    }\n    return nil\n}\n"
+
+	assert.Equal(t, []string{
+		" func f() error {",
+		"     x := 1",
+		"+    if x > 0 {",
+		"+        return errX",
+		"+    }",
+		"     return nil",
+		" }",
+	}, diffLines(oldText, newText))
+}
+
+func TestDiffLines_Hunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("line %d", i))
+		newLines = append(newLines, fmt.Sprintf("line %d", i))
+	}
+	newLines[1] = "line two"
+	newLines[17] = "line eighteen"
+
+	assert.Equal(t, []string{
+		" line 1",
+		"-line 2",
+		"+line two",
+		" line 3",
+		" line 4",
+		" line 5",
+		"@@",
+		" line 15",
+		" line 16",
+		" line 17",
+		"-line 18",
+		"+line eighteen",
+		" line 19",
+		" line 20",
+	}, diffLines(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n")), "distant unchanged lines are left out")
+}
+
+func TestDiffLines_NewAndRemovedText(t *testing.T) {
+	assert.Equal(t, []string{"+package main"}, diffLines("", "package main\n"))
+	assert.Equal(t, []string{"-// TODO"}, diffLines("// TODO", ""))
+	assert.Empty(t, diffLines("same", "same"))
+}
+
+func TestDiffStat(t *testing.T) {
+	assert.Equal(t, "+2 -1", diffStat("a\nb\nc", "a\nx\ny\nc"))
+	assert.Equal(t, "+0 -0", diffStat("a", "a"))
+}
+
+func TestCollapseLines(t *testing.T) {
+	lines := []string{"1", "2", "3", "4", "5", "6"}
+
+	assert.Equal(t, lines, collapseLines(lines, 0))
+	assert.Equal(t, lines, collapseLines(lines, 6))
+	assert.Equal(t, []string{"1", "2", "  ... (2 lines omitted) ...", "5", "6"}, collapseLines(lines, 4))
+}
+
+func TestDefaultPrinter_ToolUse_LongEditCollapses(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+	var newText strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&newText, "added line %d\n", i)
+	}
+
+	p.ToolUse(claude.Event{
+		Type:      claude.EventTypeAssistant,
+		ToolName:  "Edit",
+		ToolInput: &claude.EditInput{FilePath: "big.go", NewString: newText.String()},
+	}, 10)
+
+	output := buf.String()
+	assert.Contains(t, output, "File: big.go (+30 -0)")
+	assert.Contains(t, output, "+added line 5")
+	assert.Contains(t, output, "(20 lines omitted)")
+	assert.NotContains(t, output, "+added line 6\n")
+	assert.Contains(t, output, "+added line 30")
+}
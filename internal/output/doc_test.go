@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"bmad-automate/internal/claude"
 	"bmad-automate/internal/output"
 )
 
@@ -22,7 +23,7 @@ func Example_printer() {
 	// captures: "● Session started"
 
 	// Step progress methods
-	printer.StepStart(1, 4, "create-story")
+	printer.StepStart(1, 4, "create-story", nil)
 	// captures: "[1/4] create-story"
 
 	// Verify printer captured output
@@ -48,7 +49,11 @@ func Example_styles() {
 	printer.Text("Processing your request...")
 
 	// Tool usage shows Claude's tool invocations
-	printer.ToolUse("Bash", "List files", "ls -la", "")
+	printer.ToolUse(claude.Event{
+		Type:      claude.EventTypeAssistant,
+		ToolName:  "Bash",
+		ToolInput: &claude.BashInput{Command: "ls -la", Description: "List files"},
+	}, 20)
 
 	// Check output was captured
 	if buf.Len() > 0 {
@@ -104,7 +109,7 @@ func Example_commandOutput() {
 
 	// Footer shows result
 	duration := 5 * time.Second
-	printer.CommandFooter(duration, true, 0)
+	printer.CommandFooter(duration, true, 0, nil)
 
 	if buf.Len() > 0 {
 		fmt.Println("command output captured")
@@ -170,3 +175,24 @@ func Example_cycleSummary() {
 	// Output:
 	// cycle summary captured
 }
+
+// Example_toolUse demonstrates how tool calls are described from their input.
+//
+// Known tools are described from their typed input, such as the pattern and
+// path of a search; other tools show the common fields or their raw input.
+func Example_toolUse() {
+	var buf bytes.Buffer
+	printer := output.NewPrinterWithWriter(&buf)
+
+	printer.ToolUse(claude.Event{
+		Type:      claude.EventTypeAssistant,
+		ToolName:  "Grep",
+		ToolInput: &claude.GrepInput{Pattern: "foo", Path: "internal/**"},
+	}, 20)
+
+	fmt.Print(buf.String())
+	// Output:
+	// ┌─ Tool: Grep
+	// │  'foo' in internal/**
+	// └─
+}
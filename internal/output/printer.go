@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"bmad-automate/internal/claude"
 )
 
 // StepResult represents the result of a single workflow step execution.
@@ -19,6 +21,12 @@ type StepResult struct {
 	Duration time.Duration
 	// Success indicates whether the step completed successfully.
 	Success bool
+	// Tokens is the total number of tokens the step used (input, output, and cache).
+	Tokens int64
+	// CostUSD is the API cost of the step in US dollars.
+	CostUSD float64
+	// Todos is Claude's task list as the step ended, if it kept one.
+	Todos []claude.Todo
 }
 
 // StoryResult represents the result of processing a story in queue or epic operations.
@@ -36,6 +44,12 @@ type StoryResult struct {
 	FailedAt string
 	// Skipped indicates the story was skipped because it was already done.
 	Skipped bool
+	// Tokens is the total number of tokens used while processing the story.
+	Tokens int64
+	// CostUSD is the API cost of processing the story in US dollars.
+	CostUSD float64
+	// Todos is Claude's task list for the story as it ended, if it kept one.
+	Todos []claude.Todo
 }
 
 // Printer defines the interface for structured terminal output operations.
@@ -51,20 +65,37 @@ type Printer interface {
 	// SessionEnd prints completion status for the session with total duration.
 	SessionEnd(duration time.Duration, success bool)
 
-	// StepStart prints a numbered step header (e.g., "[1/4] create-story").
-	StepStart(step, total int, name string)
+	// StepStart prints a numbered step header (e.g., "[1/4] create-story"),
+	// followed by the progress of the story's task checklist so far, if
+	// Claude kept one.
+	StepStart(step, total int, name string, todos []claude.Todo)
 	// StepEnd prints step completion status with duration.
 	StepEnd(duration time.Duration, success bool)
 
-	// ToolUse displays Claude tool invocation details including name,
-	// description, command, and file path as applicable.
-	ToolUse(name, description, command, filePath string)
+	// ToolUse displays a Claude tool call: the tool name and what the call
+	// does, described from its input (e.g., the command, file, or search
+	// pattern). File edits are shown as a diff, collapsed to truncateLines
+	// lines. The event's ToolUseID pairs the call with its result; it may
+	// be empty.
+	ToolUse(event claude.Event, truncateLines int)
 	// ToolResult displays tool execution output, optionally truncating
-	// stdout to the specified number of lines.
-	ToolResult(stdout, stderr string, truncateLines int)
+	// stdout to the specified number of lines. The id is the tool_use ID
+	// the result answers; it may be empty.
+	ToolResult(id, stdout, stderr string, truncateLines int)
 
 	// Text displays plain text content from Claude.
 	Text(message string)
+	// Thinking displays Claude's extended thinking.
+	Thinking(message string)
+	// FollowUp displays a message sent to Claude during a session, such as
+	// an automatic answer to a question it asked.
+	FollowUp(message string)
+	// Warning displays a non-fatal problem, such as Claude output that
+	// could not be parsed.
+	Warning(message string)
+	// Countdown displays a wait in progress, such as for the Claude usage
+	// limit to reset, with the time remaining.
+	Countdown(message string, remaining time.Duration)
 	// Divider prints a visual separator line between sections.
 	Divider()
 
@@ -86,8 +117,9 @@ type Printer interface {
 	// CommandHeader prints the header before running a workflow command.
 	CommandHeader(label, prompt string, truncateLength int)
 	// CommandFooter prints the footer after a command completes with
-	// duration, success status, and exit code.
-	CommandFooter(duration time.Duration, success bool, exitCode int)
+	// duration, success status, and exit code, and the progress and open
+	// tasks of the task checklist if Claude kept one.
+	CommandFooter(duration time.Duration, success bool, exitCode int, todos []claude.Todo)
 }
 
 // DefaultPrinter implements [Printer] with lipgloss terminal styling.
@@ -95,15 +127,28 @@ type Printer interface {
 // It is the production implementation used for CLI output. The styles
 // are defined in styles.go and provide consistent color and formatting
 // across all output operations.
+//
+// DefaultPrinter remembers pending tool calls by ID. When a tool result does
+// not directly follow its own call (e.g., Claude issued several calls at
+// once), the result is labeled with the tool it belongs to.
+//
+// Each TodoWrite call shows Claude's updated task checklist. The printer
+// keeps no checklist of its own: the step header and command footer show the
+// one they are given by the caller.
 type DefaultPrinter struct {
 	out io.Writer
+
+	// pendingTools maps tool_use IDs to the names of calls awaiting results.
+	pendingTools map[string]string
+	// lastToolID is the ID of the most recently printed tool call.
+	lastToolID string
 }
 
 // NewPrinter creates a new [DefaultPrinter] that writes to stdout.
 //
 // This is the standard constructor for production CLI output.
 func NewPrinter() *DefaultPrinter {
-	return &DefaultPrinter{out: os.Stdout}
+	return NewPrinterWithWriter(os.Stdout)
 }
 
 // NewPrinterWithWriter creates a new [DefaultPrinter] with a custom writer.
@@ -111,7 +156,7 @@ func NewPrinter() *DefaultPrinter {
 // This constructor enables output capture in tests by providing a bytes.Buffer
 // or other io.Writer implementation instead of stdout.
 func NewPrinterWithWriter(w io.Writer) *DefaultPrinter {
-	return &DefaultPrinter{out: w}
+	return &DefaultPrinter{out: w, pendingTools: make(map[string]string)}
 }
 
 func (p *DefaultPrinter) writeln(format string, args ...interface{}) {
@@ -128,10 +173,13 @@ func (p *DefaultPrinter) SessionEnd(duration time.Duration, success bool) {
 	p.writeln("%s Session complete", iconInProgress)
 }
 
-// StepStart prints step start header.
-func (p *DefaultPrinter) StepStart(step, total int, name string) {
+// StepStart prints step start header and the checklist progress, if any.
+func (p *DefaultPrinter) StepStart(step, total int, name string, todos []claude.Todo) {
 	header := fmt.Sprintf("[%d/%d] %s", step, total, name)
 	p.writeln(stepHeaderStyle.Render(header))
+	if len(todos) > 0 {
+		p.writeln("  %s", mutedStyle.Render(todoProgress(todos)))
+	}
 }
 
 // StepEnd prints step completion status.
@@ -139,25 +187,34 @@ func (p *DefaultPrinter) StepEnd(duration time.Duration, success bool) {
 	// Step end is usually handled by CommandFooter
 }
 
-// ToolUse prints tool invocation details.
-func (p *DefaultPrinter) ToolUse(name, description, command, filePath string) {
-	p.writeln("%s Tool: %s", iconTool, toolNameStyle.Render(name))
-
-	if description != "" {
-		p.writeln("%s  %s", iconToolLine, description)
+// ToolUse prints a tool call, one line per detail of its input.
+func (p *DefaultPrinter) ToolUse(event claude.Event, truncateLines int) {
+	if event.ToolUseID != "" {
+		p.pendingTools[event.ToolUseID] = event.ToolName
 	}
-	if command != "" {
-		p.writeln("%s  $ %s", iconToolLine, command)
-	}
-	if filePath != "" {
-		p.writeln("%s  File: %s", iconToolLine, filePath)
+	p.lastToolID = event.ToolUseID
+
+	p.writeln("%s Tool: %s", iconTool, toolNameStyle.Render(event.ToolName))
+
+	for _, line := range toolLines(event, truncateLines) {
+		p.writeln("%s  %s", iconToolLine, line)
 	}
 
 	p.writeln(iconToolEnd)
 }
 
 // ToolResult prints tool execution results.
-func (p *DefaultPrinter) ToolResult(stdout, stderr string, truncateLines int) {
+//
+// If the result answers a call other than the one printed last, a label
+// naming that call is printed first.
+func (p *DefaultPrinter) ToolResult(id, stdout, stderr string, truncateLines int) {
+	if name, ok := p.pendingTools[id]; ok {
+		delete(p.pendingTools, id)
+		if id != p.lastToolID && (stdout != "" || stderr != "") {
+			p.writeln("   %s", mutedStyle.Render(fmt.Sprintf("↳ %s result (%s)", name, id)))
+		}
+	}
+
 	if stdout != "" {
 		output := truncateOutput(stdout, truncateLines)
 		// Indent each line
@@ -176,6 +233,33 @@ func (p *DefaultPrinter) Text(message string) {
 	}
 }
 
+// Thinking prints Claude's extended thinking in a muted style.
+func (p *DefaultPrinter) Thinking(message string) {
+	if message != "" {
+		p.writeln("%s\n", mutedStyle.Render("Thinking: "+message))
+	}
+}
+
+// FollowUp prints a message sent to Claude during the session.
+func (p *DefaultPrinter) FollowUp(message string) {
+	if message != "" {
+		p.writeln("%s %s\n", labelStyle.Render(iconFollowUp+" Answer:"), message)
+	}
+}
+
+// Warning prints a non-fatal problem with a warning marker.
+func (p *DefaultPrinter) Warning(message string) {
+	if message != "" {
+		p.writeln("%s\n", warningStyle.Render(iconWarning+" Warning: "+message))
+	}
+}
+
+// Countdown prints a wait message with the time remaining, rounded to the
+// second.
+func (p *DefaultPrinter) Countdown(message string, remaining time.Duration) {
+	p.writeln("%s\n", warningStyle.Render(fmt.Sprintf("%s %s (%s remaining)", iconWaiting, message, remaining.Round(time.Second))))
+}
+
 // Divider prints a visual divider.
 func (p *DefaultPrinter) Divider() {
 	p.writeln(dividerStyle.Render(strings.Repeat("═", 65)))
@@ -197,12 +281,26 @@ func (p *DefaultPrinter) CycleSummary(storyKey string, steps []StepResult, total
 	sb.WriteString(fmt.Sprintf("Story: %s\n", storyKey))
 	sb.WriteString(strings.Repeat("─", 50) + "\n")
 
+	var totalTokens int64
+	var totalCost float64
 	for i, step := range steps {
-		sb.WriteString(fmt.Sprintf("[%d] %-15s %s\n", i+1, step.Name, step.Duration.Round(time.Millisecond)))
+		line := fmt.Sprintf("[%d] %-15s %s", i+1, step.Name, step.Duration.Round(time.Millisecond))
+		if usage := formatUsage(step.Tokens, step.CostUSD); usage != "" {
+			line += "  " + mutedStyle.Render(usage)
+		}
+		if len(step.Todos) > 0 {
+			line += "  " + mutedStyle.Render(todoProgress(step.Todos))
+		}
+		sb.WriteString(line + "\n")
+		totalTokens += step.Tokens
+		totalCost += step.CostUSD
 	}
 
 	sb.WriteString(strings.Repeat("─", 50) + "\n")
 	sb.WriteString(fmt.Sprintf("Total: %s", totalDuration.Round(time.Millisecond)))
+	if usage := formatUsage(totalTokens, totalCost); usage != "" {
+		sb.WriteString(" | " + usage)
+	}
 
 	p.writeln(summaryStyle.Render(sb.String()))
 }
@@ -239,7 +337,11 @@ func (p *DefaultPrinter) QueueSummary(results []StoryResult, allKeys []string, t
 	completed := 0
 	failed := 0
 	skipped := 0
+	var totalTokens int64
+	var totalCost float64
 	for _, r := range results {
+		totalTokens += r.Tokens
+		totalCost += r.CostUSD
 		if r.Skipped {
 			skipped++
 		} else if r.Success {
@@ -277,9 +379,16 @@ func (p *DefaultPrinter) QueueSummary(results []StoryResult, allKeys []string, t
 		}
 		if suffix != "" {
 			sb.WriteString(fmt.Sprintf("%s %-30s %s\n", status, r.Key, suffix))
-		} else {
-			sb.WriteString(fmt.Sprintf("%s %-30s %s\n", status, r.Key, r.Duration.Round(time.Second)))
+			continue
+		}
+		line := fmt.Sprintf("%s %-30s %s", status, r.Key, r.Duration.Round(time.Second))
+		if usage := formatUsage(r.Tokens, r.CostUSD); usage != "" {
+			line += "  " + mutedStyle.Render(usage)
 		}
+		if len(r.Todos) > 0 {
+			line += "  " + mutedStyle.Render(todoProgress(r.Todos))
+		}
+		sb.WriteString(line + "\n")
 	}
 
 	if remaining > 0 {
@@ -290,6 +399,9 @@ func (p *DefaultPrinter) QueueSummary(results []StoryResult, allKeys []string, t
 
 	sb.WriteString(strings.Repeat("─", 50) + "\n")
 	sb.WriteString(fmt.Sprintf("Total: %s", totalDuration.Round(time.Second)))
+	if usage := formatUsage(totalTokens, totalCost); usage != "" {
+		sb.WriteString(" | " + usage)
+	}
 
 	p.writeln(summaryStyle.Render(sb.String()))
 }
@@ -304,7 +416,7 @@ func (p *DefaultPrinter) CommandHeader(label, prompt string, truncateLength int)
 }
 
 // CommandFooter prints the footer after a command completes.
-func (p *DefaultPrinter) CommandFooter(duration time.Duration, success bool, exitCode int) {
+func (p *DefaultPrinter) CommandFooter(duration time.Duration, success bool, exitCode int, todos []claude.Todo) {
 	p.writeln("")
 	p.Divider()
 	if success {
@@ -312,9 +424,38 @@ func (p *DefaultPrinter) CommandFooter(duration time.Duration, success bool, exi
 	} else {
 		p.writeln("  %s | Duration: %s | Exit code: %d", errorStyle.Render(iconError+" FAILED"), duration.Round(time.Millisecond), exitCode)
 	}
+	if len(todos) > 0 {
+		p.writeln("  %s", todoProgress(todos))
+		for _, todo := range todos {
+			if todo.Status != claude.TodoCompleted {
+				p.writeln("    %s", todoLine(todo))
+			}
+		}
+	}
 	p.Divider()
 }
 
+// formatUsage formats token and cost totals (e.g., "12.3k tokens, $0.42").
+// Returns an empty string when neither is known.
+func formatUsage(tokens int64, costUSD float64) string {
+	if tokens == 0 && costUSD == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s tokens, $%.2f", formatTokens(tokens), costUSD)
+}
+
+// formatTokens formats a token count compactly (e.g., 950, 12.3k, 1.2M).
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
 // truncateString truncates a string to maxLen, adding "..." if truncated.
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -2,11 +2,14 @@ package output
 
 import (
 	"bytes"
+	"encoding/json"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"bmad-automate/internal/claude"
 )
 
 func TestNewPrinter(t *testing.T) {
@@ -44,41 +47,93 @@ func TestDefaultPrinter_StepStart(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
 
-	p.StepStart(1, 4, "create-story")
+	p.StepStart(1, 4, "create-story", nil)
 
 	output := buf.String()
 	assert.Contains(t, output, "[1/4]")
 	assert.Contains(t, output, "create-story")
 }
 
+// toolUse builds a tool call event as parsed from Claude's stream.
+func toolUse(id, name, input string) claude.Event {
+	event := claude.Event{Type: claude.EventTypeAssistant, ToolUseID: id, ToolName: name}
+	event.ToolInput, event.ToolInputRaw = claude.DecodeToolInput(name, json.RawMessage(input))
+	return event
+}
+
 func TestDefaultPrinter_ToolUse(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
 
-	p.ToolUse("Bash", "List files", "ls -la", "")
+	p.ToolUse(toolUse("", "Bash", `{"command":"ls -la","description":"List files"}`), 20)
 
 	output := buf.String()
 	assert.Contains(t, output, "Bash")
 	assert.Contains(t, output, "List files")
-	assert.Contains(t, output, "ls -la")
+	assert.Contains(t, output, "$ ls -la")
+}
+
+func TestDefaultPrinter_ToolUse_Kinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		tool  string
+		input string
+		want  []string
+	}{
+		{"read", "Read", `{"file_path":"/path/to/file.go"}`, []string{"File: /path/to/file.go"}},
+		{"read range", "Read", `{"file_path":"main.go","offset":10,"limit":50}`, []string{"File: main.go (lines 10-59)"}},
+		{"write", "Write", `{"file_path":"notes.md","content":"# Notes"}`, []string{"File: notes.md (1 line)"}},
+		{"edit", "Edit", `{"file_path":"a.go","old_string":"x","new_string":"y","replace_all":true}`, []string{"File: a.go (+1 -1, all occurrences)", "-x", "+y"}},
+		{"multi edit", "MultiEdit", `{"file_path":"a.go","edits":[{"old_string":"a","new_string":"b"},{"old_string":"c","new_string":"d"}]}`, []string{"File: a.go (2 edits)", "@@ edit 1 of 2 (+1 -1) @@", "-a", "+b", "@@ edit 2 of 2 (+1 -1) @@", "-c", "+d"}},
+		{"notebook edit", "NotebookEdit", `{"notebook_path":"nb.ipynb","cell_id":"c1","new_source":"x","edit_mode":"insert"}`, []string{"Notebook: nb.ipynb (insert cell c1)"}},
+		{"grep", "Grep", `{"pattern":"foo","path":"internal/**"}`, []string{"'foo' in internal/**"}},
+		{"grep filters", "Grep", `{"pattern":"TODO","glob":"*.go","-i":true}`, []string{"'TODO' (files *.go, ignoring case)"}},
+		{"glob", "Glob", `{"pattern":"**/*_test.go","path":"internal"}`, []string{"'**/*_test.go' in internal"}},
+		{"ls", "LS", `{"path":"/repo"}`, []string{"Dir: /repo"}},
+		{"web fetch", "WebFetch", `{"url":"https://go.dev/doc","prompt":"List the release notes"}`, []string{"URL: https://go.dev/doc", "List the release notes"}},
+		{"web search", "WebSearch", `{"query":"lipgloss borders"}`, []string{"Search: 'lipgloss borders'"}},
+		{"task", "Task", `{"description":"Find callers","prompt":"Find every caller","subagent_type":"Explore"}`, []string{"Agent: Explore", "Find callers"}},
+		{"background shell", "Bash", `{"command":"npm run dev","run_in_background":true}`, []string{"$ npm run dev", "(background)"}},
+		{"bash output", "BashOutput", `{"bash_id":"bash_1","filter":"ERROR"}`, []string{"Shell: bash_1 (filter 'ERROR')"}},
+		{"kill shell", "KillShell", `{"shell_id":"bash_1"}`, []string{"Shell: bash_1"}},
+		{"plan", "ExitPlanMode", `{"plan":"Add the parser\nThen the tests"}`, []string{"Plan: Add the parser …"}},
+		{"todo write", "TodoWrite", `{"todos":[{"content":"Write parser","status":"completed"},{"content":"Write tests","status":"in_progress","activeForm":"Writing tests"},{"content":"Update docs","status":"pending"}]}`,
+			[]string{"Tasks: 1/3 done", "Write parser", "Writing tests", "○ Update docs"}},
+		{"unknown tool", "mcp__github__create_issue", `{"title":"Bug","labels":["p1"]}`, []string{`{"labels":["p1"],"title":"Bug"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := NewPrinterWithWriter(&buf)
+
+			p.ToolUse(toolUse("", tt.tool, tt.input), 20)
+
+			output := buf.String()
+			assert.Contains(t, output, "Tool: ")
+			assert.Contains(t, output, tt.tool)
+			for _, want := range tt.want {
+				assert.Contains(t, output, want)
+			}
+		})
+	}
 }
 
-func TestDefaultPrinter_ToolUse_WithFilePath(t *testing.T) {
+func TestDefaultPrinter_ToolUse_CommonFields(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
 
-	p.ToolUse("Read", "", "", "/path/to/file.go")
+	p.ToolUse(claude.Event{Type: claude.EventTypeAssistant, ToolName: "Custom", ToolDescription: "Deploy", ToolFilePath: "deploy.yaml"}, 20)
 
 	output := buf.String()
-	assert.Contains(t, output, "Read")
-	assert.Contains(t, output, "/path/to/file.go")
+	assert.Contains(t, output, "Deploy")
+	assert.Contains(t, output, "File: deploy.yaml")
 }
 
 func TestDefaultPrinter_ToolResult(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
 
-	p.ToolResult("file1.go\nfile2.go", "", 20)
+	p.ToolResult("", "file1.go\nfile2.go", "", 20)
 
 	output := buf.String()
 	assert.Contains(t, output, "file1.go")
@@ -89,13 +144,74 @@ func TestDefaultPrinter_ToolResult_WithStderr(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
 
-	p.ToolResult("", "error message", 20)
+	p.ToolResult("", "", "error message", 20)
 
 	output := buf.String()
 	assert.Contains(t, output, "stderr")
 	assert.Contains(t, output, "error message")
 }
 
+func TestDefaultPrinter_ToolResult_PairsByID(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+
+	p.ToolUse(toolUse("toolu_1", "Bash", `{"command":"ls"}`), 20)
+	p.ToolUse(toolUse("toolu_2", "Grep", `{"pattern":"main"}`), 20)
+	buf.Reset()
+
+	p.ToolResult("toolu_1", "file1.go", "", 20)
+	assert.Contains(t, buf.String(), "Bash result (toolu_1)", "out-of-order result is labeled")
+
+	buf.Reset()
+	p.ToolUse(toolUse("toolu_3", "Bash", `{"command":"pwd"}`), 20)
+	p.ToolResult("toolu_3", "/repo", "", 20)
+	assert.NotContains(t, buf.String(), "result (toolu_3)", "result right after its call needs no label")
+}
+
+func TestDefaultPrinter_Thinking(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+
+	p.Thinking("Consider the edge cases")
+	assert.Contains(t, buf.String(), "Thinking: Consider the edge cases")
+
+	buf.Reset()
+	p.Thinking("")
+	assert.Empty(t, buf.String())
+}
+
+func TestDefaultPrinter_Warning(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+
+	p.Warning("line 7: oversized field truncated")
+	assert.Contains(t, buf.String(), "Warning: line 7: oversized field truncated")
+
+	buf.Reset()
+	p.Warning("")
+	assert.Empty(t, buf.String())
+}
+
+func TestDefaultPrinter_FollowUp(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+
+	p.FollowUp("Use the existing helper.")
+	assert.Contains(t, buf.String(), "Answer: Use the existing helper.")
+
+	buf.Reset()
+	p.FollowUp("")
+	assert.Empty(t, buf.String())
+}
+
+func TestDefaultPrinter_Countdown(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+
+	p.Countdown("Usage limit reached; resuming at 15:01", 2*time.Hour+13*time.Minute+400*time.Millisecond)
+	assert.Contains(t, buf.String(), "Usage limit reached; resuming at 15:01 (2h13m0s remaining)")
+}
+
 func TestDefaultPrinter_Text(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
@@ -131,7 +247,7 @@ func TestDefaultPrinter_CommandFooter_Success(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
 
-	p.CommandFooter(5*time.Second, true, 0)
+	p.CommandFooter(5*time.Second, true, 0, nil)
 
 	output := buf.String()
 	assert.Contains(t, output, "SUCCESS")
@@ -141,13 +257,46 @@ func TestDefaultPrinter_CommandFooter_Failure(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
 
-	p.CommandFooter(5*time.Second, false, 1)
+	p.CommandFooter(5*time.Second, false, 1, nil)
 
 	output := buf.String()
 	assert.Contains(t, output, "FAILED")
 	assert.Contains(t, output, "Exit code: 1")
 }
 
+func TestDefaultPrinter_TodoChecklist(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+	p.StepStart(2, 4, "dev-story", nil)
+	assert.NotContains(t, buf.String(), "Tasks:", "no checklist yet")
+
+	p.ToolUse(toolUse("", "TodoWrite", `{"todos":[
+		{"content":"Add model","status":"completed"},
+		{"content":"Add handler","status":"in_progress","activeForm":"Adding handler"},
+		{"content":"Write tests","status":"pending"}]}`), 20)
+	assert.Contains(t, buf.String(), "Tasks: 1/3 done", "each update shows the progress")
+
+	todos := []claude.Todo{
+		{Content: "Add model", Status: claude.TodoCompleted},
+		{Content: "Add handler", Status: claude.TodoInProgress, ActiveForm: "Adding handler"},
+		{Content: "Write tests", Status: claude.TodoPending},
+	}
+	buf.Reset()
+	p.CommandFooter(5*time.Second, true, 0, todos)
+	output := buf.String()
+	assert.Contains(t, output, "Tasks: 1/3 done")
+	assert.Contains(t, output, "Adding handler")
+	assert.Contains(t, output, "○ Write tests")
+	assert.NotContains(t, output, "Add model", "finished tasks are left out of the footer")
+
+	buf.Reset()
+	p.StepStart(3, 4, "code-review", todos)
+	output = buf.String()
+	assert.Contains(t, output, "[3/4] code-review")
+	assert.Contains(t, output, "Tasks: 1/3 done", "the step header shows the story's progress so far")
+	assert.NotContains(t, output, "Write tests")
+}
+
 func TestDefaultPrinter_CycleHeader(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
@@ -177,6 +326,25 @@ func TestDefaultPrinter_CycleSummary(t *testing.T) {
 	assert.Contains(t, output, "dev-story")
 }
 
+func TestDefaultPrinter_CycleSummary_WithUsage(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+
+	steps := []StepResult{
+		{Name: "create-story", Duration: 10 * time.Second, Success: true, Tokens: 12_300, CostUSD: 0.25},
+		{Name: "dev-story", Duration: 30 * time.Second, Success: true, Tokens: 1_500_000, CostUSD: 1.5,
+			Todos: []claude.Todo{{Content: "Add model", Status: claude.TodoCompleted}, {Content: "Write tests", Status: claude.TodoPending}}},
+	}
+
+	p.CycleSummary("test-story", steps, 40*time.Second)
+
+	output := buf.String()
+	assert.Contains(t, output, "12.3k tokens, $0.25")
+	assert.Contains(t, output, "1.5M tokens, $1.50")
+	assert.Contains(t, output, "1.5M tokens, $1.75")
+	assert.Contains(t, output, "Tasks: 1/2 done")
+}
+
 func TestDefaultPrinter_CycleFailed(t *testing.T) {
 	var buf bytes.Buffer
 	p := NewPrinterWithWriter(&buf)
@@ -246,6 +414,33 @@ func TestDefaultPrinter_QueueSummary_WithFailure(t *testing.T) {
 	assert.Contains(t, output, "(pending)")
 }
 
+func TestDefaultPrinter_QueueSummary_WithUsage(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinterWithWriter(&buf)
+
+	results := []StoryResult{
+		{Key: "story-1", Success: true, Duration: 10 * time.Second, Tokens: 900, CostUSD: 0.1},
+		{Key: "story-2", Skipped: true, Success: true},
+		{Key: "story-3", Success: true, Duration: 20 * time.Second, Tokens: 2_100, CostUSD: 0.2,
+			Todos: []claude.Todo{{Content: "Add model", Status: claude.TodoCompleted}, {Content: "Write tests", Status: claude.TodoCompleted}}},
+	}
+
+	p.QueueSummary(results, []string{"story-1", "story-2", "story-3"}, 30*time.Second)
+
+	output := buf.String()
+	assert.Contains(t, output, "900 tokens, $0.10")
+	assert.Contains(t, output, "2.1k tokens, $0.20")
+	assert.Contains(t, output, "3.0k tokens, $0.30")
+	assert.Contains(t, output, "Tasks: 2/2 done")
+	assert.Equal(t, 1, strings.Count(output, "Tasks:"), "only stories with a task list show progress")
+}
+
+func TestFormatUsage(t *testing.T) {
+	assert.Equal(t, "", formatUsage(0, 0))
+	assert.Equal(t, "42 tokens, $0.00", formatUsage(42, 0))
+	assert.Equal(t, "0 tokens, $0.01", formatUsage(0, 0.0123))
+}
+
 func TestTruncateString(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -24,7 +24,7 @@ var (
 	colorPrimary   = lipgloss.Color("39")  // Bright blue - headers, borders
 	colorSuccess   = lipgloss.Color("42")  // Green - success indicators
 	colorError     = lipgloss.Color("196") // Red - error indicators
-	colorWarning   = lipgloss.Color("214") // Orange - tool names
+	colorWarning   = lipgloss.Color("214") // Orange - tool names, warnings
 	colorMuted     = lipgloss.Color("245") // Gray - secondary info, dividers
 	colorHighlight = lipgloss.Color("177") // Purple - labels, queue headers
 )
@@ -72,6 +72,10 @@ var (
 			Bold(true).
 			Foreground(colorWarning)
 
+	// warningStyle formats non-fatal warnings (e.g., unparseable output).
+	warningStyle = lipgloss.NewStyle().
+			Foreground(colorWarning)
+
 	// dividerStyle formats visual separator lines.
 	dividerStyle = lipgloss.NewStyle().
 			Foreground(colorMuted)
@@ -82,6 +86,22 @@ var (
 			BorderForeground(colorPrimary).
 			Padding(0, 1)
 
+	// diffAddStyle formats lines added by a file edit.
+	diffAddStyle = lipgloss.NewStyle().
+			Foreground(colorSuccess)
+
+	// diffRemoveStyle formats lines removed by a file edit.
+	diffRemoveStyle = lipgloss.NewStyle().
+			Foreground(colorError)
+
+	// diffContextStyle formats unchanged lines around a file edit.
+	diffContextStyle = lipgloss.NewStyle().
+				Foreground(colorMuted)
+
+	// diffHunkStyle formats the markers between the hunks of a diff.
+	diffHunkStyle = lipgloss.NewStyle().
+			Foreground(colorPrimary)
+
 	// queueHeaderStyle formats queue operation headers.
 	queueHeaderStyle = lipgloss.NewStyle().
 				Bold(true).
@@ -97,7 +117,10 @@ const (
 	iconSuccess    = "✓"  // Completed successfully
 	iconError      = "✗"  // Failed
 	iconPending    = "○"  // Not yet started
+	iconWarning    = "⚠"  // Non-fatal problem
 	iconInProgress = "●"  // Currently running
+	iconWaiting    = "⏳"  // Waiting before continuing
+	iconFollowUp   = "↪"  // Message sent to Claude mid-session
 	iconTool       = "┌─" // Tool block start
 	iconToolEnd    = "└─" // Tool block end
 	iconToolLine   = "│"  // Tool block continuation
@@ -0,0 +1,223 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"bmad-automate/internal/claude"
+)
+
+// maxToolFieldLength caps the length of free-text tool fields, such as a
+// subagent prompt or the raw input of an unknown tool.
+const maxToolFieldLength = 100
+
+// toolLines returns the lines describing a tool call, below its name. Known
+// tools are described from their typed input; other tools fall back to the
+// common fields, then to their raw input. Edits are shown as a diff of at
+// most truncateLines lines.
+func toolLines(event claude.Event, truncateLines int) []string {
+	switch in := event.ToolInput.(type) {
+	case *claude.BashInput:
+		var lines []string
+		if in.Description != "" {
+			lines = append(lines, in.Description)
+		}
+		command := "$ " + in.Command
+		if in.RunInBackground {
+			command += " " + mutedStyle.Render("(background)")
+		}
+		return append(lines, command)
+
+	case *claude.BashOutputInput:
+		line := "Shell: " + in.BashID
+		if in.Filter != "" {
+			line += fmt.Sprintf(" (filter '%s')", in.Filter)
+		}
+		return []string{line}
+
+	case *claude.KillShellInput:
+		return []string{"Shell: " + in.ShellID}
+
+	case *claude.ReadInput:
+		return []string{"File: " + in.FilePath + lineRange(in.Offset, in.Limit)}
+
+	case *claude.WriteInput:
+		return []string{fmt.Sprintf("File: %s (%s)", in.FilePath, plural(len(splitLines(in.Content)), "line"))}
+
+	case *claude.EditInput:
+		line := fmt.Sprintf("File: %s (%s", in.FilePath, diffStat(in.OldString, in.NewString))
+		if in.ReplaceAll {
+			line += ", all occurrences"
+		}
+		lines := []string{line + ")"}
+		return append(lines, collapseLines(diffLines(in.OldString, in.NewString), truncateLines)...)
+
+	case *claude.MultiEditInput:
+		lines := []string{fmt.Sprintf("File: %s (%s)", in.FilePath, plural(len(in.Edits), "edit"))}
+		var diff []string
+		for i, edit := range in.Edits {
+			if len(in.Edits) > 1 {
+				diff = append(diff, diffHunkStyle.Render(fmt.Sprintf("@@ edit %d of %d (%s) @@", i+1, len(in.Edits), diffStat(edit.OldString, edit.NewString))))
+			}
+			diff = append(diff, diffLines(edit.OldString, edit.NewString)...)
+		}
+		return append(lines, collapseLines(diff, truncateLines)...)
+
+	case *claude.NotebookEditInput:
+		line := "Notebook: " + in.NotebookPath
+		switch {
+		case in.EditMode != "" && in.CellID != "":
+			line += fmt.Sprintf(" (%s cell %s)", in.EditMode, in.CellID)
+		case in.EditMode != "":
+			line += fmt.Sprintf(" (%s cell)", in.EditMode)
+		case in.CellID != "":
+			line += fmt.Sprintf(" (cell %s)", in.CellID)
+		}
+		return []string{line}
+
+	case *claude.GlobInput:
+		return []string{quotePattern(in.Pattern) + inPath(in.Path)}
+
+	case *claude.GrepInput:
+		line := quotePattern(in.Pattern) + inPath(in.Path)
+		var filters []string
+		if in.Glob != "" {
+			filters = append(filters, "files "+in.Glob)
+		}
+		if in.Type != "" {
+			filters = append(filters, "type "+in.Type)
+		}
+		if in.CaseInsensitive {
+			filters = append(filters, "ignoring case")
+		}
+		if len(filters) > 0 {
+			line += " (" + strings.Join(filters, ", ") + ")"
+		}
+		return []string{line}
+
+	case *claude.LSInput:
+		return []string{"Dir: " + in.Path}
+
+	case *claude.WebFetchInput:
+		lines := []string{"URL: " + in.URL}
+		if in.Prompt != "" {
+			lines = append(lines, truncateString(firstLine(in.Prompt), maxToolFieldLength))
+		}
+		return lines
+
+	case *claude.WebSearchInput:
+		return []string{fmt.Sprintf("Search: '%s'", in.Query)}
+
+	case *claude.TaskInput:
+		var lines []string
+		if in.SubagentType != "" {
+			lines = append(lines, "Agent: "+in.SubagentType)
+		}
+		if in.Description != "" {
+			lines = append(lines, in.Description)
+		}
+		return lines
+
+	case *claude.TodoWriteInput:
+		lines := make([]string, 0, len(in.Todos)+1)
+		lines = append(lines, todoProgress(in.Todos))
+		for _, todo := range in.Todos {
+			lines = append(lines, todoLine(todo))
+		}
+		return lines
+
+	case *claude.ExitPlanModeInput:
+		return []string{"Plan: " + truncateString(firstLine(in.Plan), maxToolFieldLength)}
+	}
+
+	var lines []string
+	if event.ToolDescription != "" {
+		lines = append(lines, event.ToolDescription)
+	}
+	if event.ToolCommand != "" {
+		lines = append(lines, "$ "+event.ToolCommand)
+	}
+	if event.ToolFilePath != "" {
+		lines = append(lines, "File: "+event.ToolFilePath)
+	}
+	if len(lines) == 0 && len(event.ToolInputRaw) > 0 {
+		if data, err := json.Marshal(event.ToolInputRaw); err == nil {
+			lines = append(lines, truncateString(string(data), maxToolFieldLength))
+		}
+	}
+	return lines
+}
+
+// todoProgress summarizes a task list (e.g., "Tasks: 4/9 done").
+func todoProgress(todos []claude.Todo) string {
+	done := 0
+	for _, todo := range todos {
+		if todo.Status == claude.TodoCompleted {
+			done++
+		}
+	}
+	return fmt.Sprintf("Tasks: %d/%d done", done, len(todos))
+}
+
+// todoLine formats a task list item with a status icon. The item in
+// progress is shown by what Claude is doing, if it said.
+func todoLine(todo claude.Todo) string {
+	switch todo.Status {
+	case claude.TodoCompleted:
+		return successStyle.Render(iconSuccess) + " " + mutedStyle.Render(todo.Content)
+	case claude.TodoInProgress:
+		text := todo.Content
+		if todo.ActiveForm != "" {
+			text = todo.ActiveForm
+		}
+		return labelStyle.Render(iconInProgress + " " + text)
+	default:
+		return iconPending + " " + todo.Content
+	}
+}
+
+// lineRange describes the lines a Read call selects (e.g., " (lines 10-59)"),
+// or returns an empty string for the whole file. Offset is the first line.
+func lineRange(offset, limit int) string {
+	switch {
+	case limit > 0:
+		first := max(offset, 1)
+		return fmt.Sprintf(" (lines %d-%d)", first, first+limit-1)
+	case offset > 0:
+		return fmt.Sprintf(" (from line %d)", offset)
+	default:
+		return ""
+	}
+}
+
+// quotePattern quotes a search pattern in single quotes.
+func quotePattern(pattern string) string {
+	return "'" + pattern + "'"
+}
+
+// inPath describes where a search looks, or returns an empty string for the
+// working directory.
+func inPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " in " + path
+}
+
+// plural formats a count with a noun (e.g., "1 edit", "3 edits").
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// firstLine returns the first line of s, with " …" appended if there are more.
+func firstLine(s string) string {
+	line, rest, found := strings.Cut(strings.TrimSpace(s), "\n")
+	if found && strings.TrimSpace(rest) != "" {
+		return line + " …"
+	}
+	return line
+}
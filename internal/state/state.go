@@ -1,9 +1,10 @@
 // Package state provides lifecycle execution state persistence for resume functionality.
 //
-// When a lifecycle execution fails (e.g., due to a Claude CLI error), the state
-// is saved to disk so that execution can be resumed from the point of failure
-// rather than starting over from the beginning. This is particularly valuable
-// for long-running story lifecycles.
+// Lifecycle progress is saved to disk after every completed step and when an
+// execution fails (e.g., due to a Claude CLI error), so that execution can be
+// resumed from the point of failure rather than starting over from the
+// beginning. This is particularly valuable for long-running story lifecycles
+// and for queue and epic batches, whose position is recorded as well.
 //
 // Key types:
 //   - [State] represents the persisted execution state (story key, step index, etc.)
@@ -33,8 +34,8 @@ var ErrNoState = errors.New("no state file exists")
 
 // State represents the persisted lifecycle execution state.
 //
-// This struct is serialized to JSON and saved to disk when a lifecycle
-// execution fails, enabling resume from the point of failure.
+// This struct is serialized to JSON and saved to disk as a lifecycle
+// progresses, enabling resume from the point of failure.
 type State struct {
 	// StoryKey is the identifier of the story being processed.
 	StoryKey string `json:"story_key"`
@@ -48,8 +49,19 @@ type State struct {
 	TotalSteps int `json:"total_steps"`
 
 	// StartStatus is the story's status when execution began.
-	// Stored for debugging and context when viewing saved state.
+	// The lifecycle steps are rebuilt from it on resume.
 	StartStatus string `json:"start_status"`
+
+	// Command is the command that started the execution ("run", "queue" or
+	// "epic"). Resume is refused from a different command.
+	Command string `json:"command,omitempty"`
+
+	// EpicID is the epic being processed when Command is "epic".
+	EpicID string `json:"epic_id,omitempty"`
+
+	// Stories lists every story of a queue or epic batch in order.
+	// On resume, the batch continues with the stories after StoryKey.
+	Stories []string `json:"stories,omitempty"`
 }
 
 // Manager handles state persistence operations.
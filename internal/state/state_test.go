@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -42,6 +43,19 @@ func TestStateStruct(t *testing.T) {
 	}
 }
 
+// TestStateOmitsEmptyBatchFields verifies single-story state keeps the original JSON shape
+func TestStateOmitsEmptyBatchFields(t *testing.T) {
+	data, err := json.Marshal(State{StoryKey: "PROJ-123", TotalSteps: 3})
+	if err != nil {
+		t.Fatalf("failed to marshal state: %v", err)
+	}
+
+	want := `{"story_key":"PROJ-123","step_index":0,"total_steps":3,"start_status":""}`
+	if string(data) != want {
+		t.Errorf("JSON: got %s, want %s", data, want)
+	}
+}
+
 // TestSaveWritesValidJSON verifies Save writes valid JSON to file
 func TestSaveWritesValidJSON(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -69,7 +83,7 @@ func TestSaveWritesValidJSON(t *testing.T) {
 		t.Fatalf("file contains invalid JSON: %v", err)
 	}
 
-	if decoded != state {
+	if !reflect.DeepEqual(decoded, state) {
 		t.Errorf("saved state mismatch: got %+v, want %+v", decoded, state)
 	}
 }
@@ -84,6 +98,8 @@ func TestLoadReturnsSavedState(t *testing.T) {
 		StepIndex:   0,
 		TotalSteps:  4,
 		StartStatus: "Todo",
+		Command:     "queue",
+		Stories:     []string{"PROJ-788", "PROJ-789"},
 	}
 
 	if err := mgr.Save(original); err != nil {
@@ -95,7 +111,7 @@ func TestLoadReturnsSavedState(t *testing.T) {
 		t.Fatalf("Load failed: %v", err)
 	}
 
-	if loaded != original {
+	if !reflect.DeepEqual(loaded, original) {
 		t.Errorf("loaded state mismatch: got %+v, want %+v", loaded, original)
 	}
 }
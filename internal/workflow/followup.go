@@ -0,0 +1,119 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"bmad-automate/internal/autoanswer"
+	"bmad-automate/internal/budget"
+	"bmad-automate/internal/claude"
+)
+
+// Turn describes a session turn that has just ended with a result event.
+type Turn struct {
+	// Workflow is the name of the workflow, empty for raw prompts.
+	Workflow string
+
+	// StoryKey is the story the session works on, empty for raw prompts.
+	StoryKey string
+
+	// Number counts the turns of the session, starting at 1 for the turn
+	// that answered the prompt.
+	Number int
+
+	// LastText is the last text Claude wrote during the turn.
+	LastText string
+
+	// Result is the result event that ended the turn.
+	Result claude.Event
+}
+
+// Responder decides whether to answer a turn. It returns the follow-up
+// message to send, and false to let the session end. The context is the
+// session's; it is canceled when the session is.
+type Responder func(ctx context.Context, turn Turn) (reply string, ok bool)
+
+// SetResponder configures a responder that is asked after each turn of a
+// session whether to send a follow-up message, for example to answer a
+// question Claude asked. Sessions stay open while the responder replies,
+// which requires an executor that accepts input, such as a
+// [claude.DefaultExecutor] with the stream-json input format; with other
+// executors the session ends as usual. Follow-ups are off by default.
+func (r *Runner) SetResponder(responder Responder) {
+	r.responder = responder
+}
+
+// followUps holds the conversation state of one session with a responder.
+type followUps struct {
+	responder Responder
+	input     *claude.Input
+	turn      Turn
+}
+
+func newFollowUps(responder Responder, workflowName, storyKey string) *followUps {
+	return &followUps{
+		responder: responder,
+		input:     claude.NewInput(),
+		turn:      Turn{Workflow: workflowName, StoryKey: storyKey, Number: 1},
+	}
+}
+
+// observe tracks the text of the current turn and, when the turn ends, sends
+// the responder's reply or closes the input. The responder is not asked when
+// the session does not accept input, or has failed, as reported by failure.
+func (f *followUps) observe(ctx context.Context, event claude.Event, failure error) error {
+	switch {
+	case event.IsText():
+		f.turn.LastText = event.Text
+		return nil
+	case !event.SessionComplete:
+		return nil
+	}
+
+	turn := f.turn
+	turn.Result = event
+	f.turn = Turn{Workflow: turn.Workflow, StoryKey: turn.StoryKey, Number: turn.Number + 1}
+
+	if !f.input.Ready() {
+		return nil
+	}
+	if failure != nil || claude.NewResultError(event) != nil {
+		return f.input.Close()
+	}
+	reply, ok := f.responder(ctx, turn)
+	if !ok {
+		return f.input.Close()
+	}
+	if err := f.input.Send(reply); err != nil {
+		f.input.Close()
+		return err
+	}
+	return nil
+}
+
+// SetAutoAnswer configures the runner to answer the questions Claude asks at
+// the end of a turn according to policy, replacing any responder set with
+// [Runner.SetResponder]. Answers are shown with [output.Printer.FollowUp];
+// the spend of answerer sessions counts against the story's budget.
+func (r *Runner) SetAutoAnswer(policy *autoanswer.Policy) {
+	r.SetResponder(func(ctx context.Context, turn Turn) (string, bool) {
+		if !policy.IsQuestion(turn.LastText) {
+			return "", false
+		}
+		reply, err := policy.Respond(ctx, autoanswer.Question{
+			Workflow: turn.Workflow,
+			StoryKey: turn.StoryKey,
+			Text:     turn.LastText,
+			Exchange: turn.Number,
+		})
+		if reply.Stats != nil {
+			r.budget.Commit(turn.StoryKey, budget.Spend{Tokens: reply.Stats.Usage.TotalTokens(), CostUSD: reply.Stats.TotalCostUSD})
+		}
+		if err != nil {
+			r.printer.Warning(fmt.Sprintf("not answering Claude's question: %v", err))
+			return "", false
+		}
+		r.printer.FollowUp(reply.Text)
+		return reply.Text, true
+	})
+}
@@ -118,10 +118,14 @@ func (q *QueueRunner) RunQueueWithStatus(ctx context.Context, storyKeys []string
 		exitCode := q.runner.RunSingle(ctx, workflowName, storyKey)
 		duration := time.Since(storyStart)
 
+		stats := q.runner.LastStats()
 		result := output.StoryResult{
 			Key:      storyKey,
 			Success:  exitCode == 0,
 			Duration: duration,
+			Tokens:   stats.Usage.TotalTokens(),
+			CostUSD:  stats.TotalCostUSD,
+			Todos:    q.runner.LastTodos(),
 		}
 
 		if exitCode != 0 {
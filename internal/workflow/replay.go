@@ -0,0 +1,179 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"bmad-automate/internal/claude"
+)
+
+// ReplayOptions controls how [Runner.Replay] plays back a transcript.
+type ReplayOptions struct {
+	// Label is shown in the command header.
+	Label string
+
+	// Speed scales the recorded timing: 1 replays in real time, 10 ten
+	// times faster. Zero replays without delay.
+	Speed float64
+
+	// FromTool starts the replay at the Nth tool call (1-based), skipping
+	// the events before it without delay. Zero starts at the beginning.
+	FromTool int
+
+	// Tools, if set, shows only calls to the named tools, with their results.
+	Tools []string
+
+	// FailuresOnly shows only tool calls whose result was an error.
+	FailuresOnly bool
+}
+
+// filtered reports whether the options hide events other than tool calls.
+func (o ReplayOptions) filtered() bool {
+	return len(o.Tools) > 0 || o.FailuresOnly
+}
+
+// Replay plays back a recorded session, such as one archived by
+// [Runner.SetArchive], rendering its events through the printer exactly as a
+// live session would be. The transcript's stream-json is read by the
+// [claude.DefaultParser] via a [claude.ReplayExecutor].
+//
+// Returns the recorded exit code of the session. An error is returned only
+// if the transcript cannot be read, FromTool is beyond its tool calls, or
+// ctx is canceled.
+func (r *Runner) Replay(ctx context.Context, path string, opts ReplayOptions) (int, error) {
+	transcript, err := claude.ReadTranscript(path)
+	if err != nil {
+		return 1, err
+	}
+
+	jumpTo := 0
+	if opts.FromTool > 0 {
+		line, count := toolCallLine(transcript.Lines, opts.FromTool)
+		if line < 0 {
+			return 1, fmt.Errorf("cannot start at tool call %d: the session made %d", opts.FromTool, count)
+		}
+		jumpTo = line
+	}
+
+	executor := claude.NewReplayExecutor(claude.ReplayConfig{
+		Transcripts: []string{path},
+		Speed:       opts.Speed,
+		JumpTo:      jumpTo,
+	})
+
+	r.lastTodos = nil
+	r.todoStory = ""
+	r.printer.CommandHeader(opts.Label, transcript.Prompt, r.config.Output.TruncateLength)
+	filter := newReplayFilter(opts)
+	exitCode, err := executor.ExecuteWithResult(ctx, transcript.Prompt, func(event claude.Event) {
+		for _, shown := range filter.pass(event) {
+			r.handleEvent(shown)
+		}
+	})
+	if ctx.Err() != nil {
+		return 1, ctx.Err()
+	}
+
+	if err != nil {
+		// As in a live session, a plain non-zero exit is left to the footer
+		var exitErr *claude.ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Printf("Error executing claude: %v\n", err)
+		}
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
+	r.printer.CommandFooter(transcript.Duration(), exitCode == 0, exitCode, r.lastTodos)
+	return exitCode, nil
+}
+
+// toolCallLine returns the index of the line holding the nth tool call, or
+// -1 and the number of tool calls if there are fewer than n.
+func toolCallLine(lines [][]byte, n int) (int, int) {
+	count := 0
+	for i, line := range lines {
+		events, err := claude.ParseLine(string(line))
+		if err != nil {
+			continue
+		}
+		for _, event := range events {
+			if event.IsToolUse() {
+				if count++; count == n {
+					return i, count
+				}
+			}
+		}
+	}
+	return -1, count
+}
+
+// replayFilter selects the events [Runner.Replay] shows.
+type replayFilter struct {
+	opts ReplayOptions
+
+	// tools counts the tool calls seen so far.
+	tools int
+
+	// shown holds the IDs of the tool calls shown, whose results are shown too.
+	shown map[string]bool
+
+	// pending holds tool calls whose result decides whether they are shown.
+	pending map[string]claude.Event
+}
+
+func newReplayFilter(opts ReplayOptions) *replayFilter {
+	return &replayFilter{
+		opts:    opts,
+		shown:   make(map[string]bool),
+		pending: make(map[string]claude.Event),
+	}
+}
+
+// pass returns the events to show for event, in order: none, the event
+// itself, or a held tool call followed by its result.
+func (f *replayFilter) pass(event claude.Event) []claude.Event {
+	if event.IsToolUse() {
+		f.tools++
+	}
+
+	switch {
+	case event.SessionStarted, event.SessionComplete, event.IsParseError():
+		return []claude.Event{event}
+
+	case f.tools < f.opts.FromTool:
+		return nil
+
+	case event.IsToolUse():
+		if len(f.opts.Tools) > 0 && !slices.Contains(f.opts.Tools, event.ToolName) {
+			return nil
+		}
+		if f.opts.FailuresOnly {
+			f.pending[event.ToolUseID] = event
+			return nil
+		}
+		f.shown[event.ToolUseID] = true
+		return []claude.Event{event}
+
+	case event.IsToolResult():
+		if call, ok := f.pending[event.ToolUseID]; ok {
+			delete(f.pending, event.ToolUseID)
+			if event.ToolIsError {
+				return []claude.Event{call, event}
+			}
+			return nil
+		}
+		if f.shown[event.ToolUseID] {
+			return []claude.Event{event}
+		}
+		return nil
+
+	case f.opts.filtered():
+		return nil
+
+	default:
+		return []claude.Event{event}
+	}
+}
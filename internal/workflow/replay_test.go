@@ -0,0 +1,102 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bmad-automate/internal/claude"
+	"bmad-automate/internal/config"
+	"bmad-automate/internal/output"
+)
+
+// replaySession replays the named transcript in testdata/transcripts and
+// returns the exit code and output.
+func replaySession(t *testing.T, name string, opts ReplayOptions) (int, string, error) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	runner := NewRunner(&claude.MockExecutor{}, output.NewPrinterWithWriter(buf), config.DefaultConfig())
+	code, err := runner.Replay(context.Background(), filepath.Join("testdata", "transcripts", name+claude.TranscriptExt), opts)
+	return code, buf.String(), err
+}
+
+func TestRunner_Replay_RendersTranscript(t *testing.T) {
+	code, out, err := replaySession(t, "dev-story-success", ReplayOptions{Label: "dev-story: 1-2"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "dev-story: 1-2")
+	assert.Contains(t, out, "/bmad-bmm-dev-story 1-2 - Complete all tasks.")
+	assert.Contains(t, out, "I'll implement story 1-2 and run the tests.")
+	assert.Contains(t, out, "internal/greeting/greeting.go")
+	assert.Contains(t, out, "go test ./...")
+	assert.Contains(t, out, "Story 1-2 is implemented and all tests pass.")
+}
+
+func TestRunner_Replay_RecordedFailure(t *testing.T) {
+	code, out, err := replaySession(t, "dev-story-overloaded", ReplayOptions{})
+
+	require.NoError(t, err, "a failed session replays without error")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "I'll start by reading the story file.")
+}
+
+func TestRunner_Replay_FromTool(t *testing.T) {
+	code, out, err := replaySession(t, "dev-story-success", ReplayOptions{FromTool: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.NotContains(t, out, "I'll implement story 1-2", "events before the tool call are skipped")
+	assert.NotContains(t, out, "internal/greeting/greeting.go")
+	assert.Contains(t, out, "go test ./...")
+	assert.Contains(t, out, "all tests pass", "events after the tool call are shown")
+
+	_, _, err = replaySession(t, "dev-story-success", ReplayOptions{FromTool: 3})
+	assert.ErrorContains(t, err, "cannot start at tool call 3: the session made 2")
+}
+
+func TestRunner_Replay_ToolFilter(t *testing.T) {
+	_, out, err := replaySession(t, "dev-story-success", ReplayOptions{Tools: []string{"Bash"}})
+
+	require.NoError(t, err)
+	assert.Contains(t, out, "go test ./...")
+	assert.Contains(t, out, "example.com/greeting", "results of shown calls are shown")
+	assert.NotContains(t, out, "internal/greeting/greeting.go", "other tools are hidden")
+	assert.NotContains(t, out, "I'll implement story 1-2", "text is hidden")
+	assert.Contains(t, out, "Session started")
+}
+
+func TestRunner_Replay_FailuresOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session"+claude.TranscriptExt)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+		`{"type":"system","subtype":"init","session_id":"s"}`,
+		`{"type":"assistant","session_id":"s","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go build ./..."}}]}}`,
+		`{"type":"user","session_id":"s","message":{"content":[{"type":"tool_result","tool_use_id":"t1"}]},"tool_use_result":{"stdout":"built"}}`,
+		`{"type":"assistant","session_id":"s","message":{"id":"m2","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go vet ./..."}}]}}`,
+		`{"type":"user","session_id":"s","message":{"content":[{"type":"tool_result","tool_use_id":"t2","is_error":true}]},"tool_use_result":{"stderr":"vet: unused variable"}}`,
+		`{"type":"result","subtype":"success","session_id":"s","result":"done"}`,
+	}, "\n")), 0o644))
+
+	buf := &bytes.Buffer{}
+	runner := NewRunner(&claude.MockExecutor{}, output.NewPrinterWithWriter(buf), config.DefaultConfig())
+	code, err := runner.Replay(context.Background(), path, ReplayOptions{FailuresOnly: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	out := buf.String()
+	assert.NotContains(t, out, "go build ./...")
+	assert.NotContains(t, out, "built")
+	assert.Contains(t, out, "go vet ./...")
+	assert.Contains(t, out, "vet: unused variable")
+}
+
+func TestRunner_Replay_MissingTranscript(t *testing.T) {
+	_, _, err := replaySession(t, "missing", ReplayOptions{})
+	assert.ErrorContains(t, err, "reading transcript")
+}
@@ -2,9 +2,12 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"bmad-automate/internal/archive"
+	"bmad-automate/internal/budget"
 	"bmad-automate/internal/claude"
 	"bmad-automate/internal/config"
 	"bmad-automate/internal/output"
@@ -15,12 +18,40 @@ import (
 // Runner is the primary executor for development workflows. It combines a
 // [claude.Executor] for spawning Claude processes, an [output.Printer] for
 // formatted terminal output, and a [config.Config] for prompt templates.
+// Spend is checked against the configured [config.BudgetConfig] while events
+// stream in; a session that goes over budget is canceled. With an archive set
+// by [Runner.SetArchive], every session is recorded to disk. With a responder
+// set by [Runner.SetResponder], sessions can be sent follow-up messages.
 //
 // Use [NewRunner] to create a properly initialized Runner instance.
 type Runner struct {
 	executor claude.Executor
 	printer  output.Printer
 	config   *config.Config
+
+	// lastSessionID is the Claude session ID seen during the most recent run.
+	lastSessionID string
+
+	// lastStats holds the result statistics of the most recent run.
+	lastStats claude.ResultStats
+
+	// lastTodos is the task list Claude last set with TodoWrite while working
+	// on todoStory. It carries over between the story's workflows, so each
+	// step starts from the checklist the previous one left behind.
+	lastTodos []claude.Todo
+	todoStory string
+
+	// lastErr explains why the most recent run failed, when known.
+	lastErr error
+
+	// budget tracks spend across the runner's lifetime.
+	budget *budget.Tracker
+
+	// archive records every session when set.
+	archive *archive.Archive
+
+	// responder answers the results of sessions when set.
+	responder Responder
 }
 
 // NewRunner creates a new workflow runner with the specified dependencies.
@@ -37,14 +68,34 @@ func NewRunner(executor claude.Executor, printer output.Printer, cfg *config.Con
 		executor: executor,
 		printer:  printer,
 		config:   cfg,
+		budget: budget.NewTracker(
+			budgetLimits(cfg.Budget.Step),
+			budgetLimits(cfg.Budget.Story),
+			budgetLimits(cfg.Budget.Invocation),
+		),
 	}
 }
 
+// SetArchive configures an archive that records every session the runner
+// runs: its events, stderr, prompt, and outcome. The archive is pruned to its
+// retention limits after each session. Archiving is off by default; failures
+// to archive are reported as warnings and do not fail the run.
+func (r *Runner) SetArchive(a *archive.Archive) {
+	r.archive = a
+}
+
+// budgetLimits converts configured limits to their [budget.Limits] form.
+func budgetLimits(c config.BudgetLimitsConfig) budget.Limits {
+	return budget.Limits{MaxCostUSD: c.MaxCostUSD, MaxTokens: c.MaxTokens}
+}
+
 // RunSingle executes a single named workflow for a story.
 //
 // The workflowName must match a workflow defined in the configuration (e.g.,
 // "analyze", "implement", "test"). The storyKey is substituted into the
-// workflow's prompt template.
+// workflow's prompt template. The session runs on the workflow's agent (see
+// [config.WorkflowConfig.Agent]), which the executor selects from the
+// [claude.RunOptions] on the context.
 //
 // Returns the exit code from Claude CLI (0 for success, non-zero for failure).
 func (r *Runner) RunSingle(ctx context.Context, workflowName, storyKey string) int {
@@ -54,8 +105,64 @@ func (r *Runner) RunSingle(ctx context.Context, workflowName, storyKey string) i
 		return 1
 	}
 
-	label := fmt.Sprintf("%s: %s", workflowName, storyKey)
-	return r.runClaude(ctx, prompt, label)
+	return r.runClaude(r.workflowContext(ctx, workflowName), prompt, r.workflowLabel(workflowName, storyKey), workflowName, storyKey)
+}
+
+// ResumeSingle resumes an earlier Claude session for a workflow step.
+//
+// The session identified by sessionID is continued with --resume and the
+// configured [config.ClaudeConfig.ResumePrompt], so Claude picks up where the
+// interrupted run stopped instead of starting the workflow from scratch. The
+// workflow's permission settings still apply.
+//
+// Returns the exit code from Claude CLI (0 for success, non-zero for failure).
+func (r *Runner) ResumeSingle(ctx context.Context, workflowName, storyKey, sessionID string) int {
+	ctx = r.workflowContext(ctx, workflowName)
+	opts := claude.RunOptionsFromContext(ctx)
+	opts.ResumeSessionID = sessionID
+	ctx = claude.WithRunOptions(ctx, opts)
+
+	label := r.workflowLabel(workflowName, storyKey) + " (resume)"
+	return r.runClaude(ctx, r.config.Claude.ResumePrompt, label, workflowName, storyKey)
+}
+
+// LastSessionID returns the Claude session ID from the most recent run.
+//
+// Returns an empty string if no run has happened yet or the last session
+// ended before Claude reported its session ID.
+func (r *Runner) LastSessionID() string {
+	return r.lastSessionID
+}
+
+// LastStats returns the usage, cost, and timing statistics from the most recent run.
+//
+// Returns the zero value if no run has happened yet or the last session
+// ended without a result event.
+func (r *Runner) LastStats() claude.ResultStats {
+	return r.lastStats
+}
+
+// LastTodos returns the task list Claude kept with the TodoWrite tool for
+// the story of the most recent run, as of its last update.
+//
+// The list is kept across runs for the same story and cleared when a run
+// starts for a different story or a raw prompt. Returns nil if no session
+// for the story has used TodoWrite.
+func (r *Runner) LastTodos() []claude.Todo {
+	return r.lastTodos
+}
+
+// LastError returns the reason the most recent run failed, if known.
+//
+// Returns nil if the last run succeeded or failed with only a non-zero exit
+// code. A session whose result event was marked is_error returns a
+// [claude.ResultError]; otherwise the executor's error, such as a
+// [claude.ExitError], is returned. Either is wrapped in a
+// [claude.UsageLimitError], with the parsed reset time, when it reports that
+// the Claude usage limit was reached. A run stopped for exceeding
+// its budget returns an error matching [budget.ErrExceeded].
+func (r *Runner) LastError() error {
+	return r.lastErr
 }
 
 // RunRaw executes an arbitrary prompt without template expansion.
@@ -65,7 +172,9 @@ func (r *Runner) RunSingle(ctx context.Context, workflowName, storyKey string) i
 //
 // Returns the exit code from Claude CLI (0 for success, non-zero for failure).
 func (r *Runner) RunRaw(ctx context.Context, prompt string) int {
-	return r.runClaude(ctx, prompt, "raw")
+	perms := claudePermissions(r.config.Claude.Permissions)
+	ctx = claude.WithRunOptions(ctx, claude.RunOptions{Permissions: &perms})
+	return r.runClaude(ctx, prompt, "raw", "raw", "")
 }
 
 // RunFullCycle executes all configured steps in sequence for a story.
@@ -102,16 +211,19 @@ func (r *Runner) RunFullCycle(ctx context.Context, storyKey string) int {
 	results := make([]output.StepResult, len(steps))
 
 	for i, step := range steps {
-		r.printer.StepStart(i+1, len(steps), step.Name)
+		r.printer.StepStart(i+1, len(steps), step.Name, r.storyTodos(storyKey))
 
 		stepStart := time.Now()
-		exitCode := r.runClaude(ctx, step.Prompt, fmt.Sprintf("%s: %s", step.Name, storyKey))
+		exitCode := r.runClaude(r.workflowContext(ctx, step.Name), step.Prompt, r.workflowLabel(step.Name, storyKey), step.Name, storyKey)
 		duration := time.Since(stepStart)
 
 		results[i] = output.StepResult{
 			Name:     step.Name,
 			Duration: duration,
 			Success:  exitCode == 0,
+			Tokens:   r.lastStats.Usage.TotalTokens(),
+			CostUSD:  r.lastStats.TotalCostUSD,
+			Todos:    r.lastTodos,
 		}
 
 		if exitCode != 0 {
@@ -126,38 +238,210 @@ func (r *Runner) RunFullCycle(ctx context.Context, storyKey string) int {
 	return 0
 }
 
+// workflowContext attaches the workflow's per-invocation [claude.RunOptions] to ctx.
+func (r *Runner) workflowContext(ctx context.Context, workflowName string) context.Context {
+	perms := claudePermissions(r.config.GetPermissions(workflowName))
+	wf := r.config.Workflows[workflowName]
+	return claude.WithRunOptions(ctx, claude.RunOptions{
+		Permissions: &perms,
+		Timeout:     wf.Timeout,
+		IdleTimeout: wf.IdleTimeout,
+		Agent:       wf.Agent,
+	})
+}
+
+// workflowLabel returns the command header label for a workflow step, naming
+// the agent when the workflow does not run on Claude.
+func (r *Runner) workflowLabel(workflowName, storyKey string) string {
+	label := fmt.Sprintf("%s: %s", workflowName, storyKey)
+	if agent := r.config.Workflows[workflowName].Agent; agent != "" {
+		label += fmt.Sprintf(" [%s]", agent)
+	}
+	return label
+}
+
+// claudePermissions converts configured permissions to their [claude.Permissions] form.
+func claudePermissions(p config.PermissionsConfig) claude.Permissions {
+	return claude.Permissions{
+		Mode:            p.Mode,
+		AllowedTools:    p.AllowedTools,
+		DisallowedTools: p.DisallowedTools,
+	}
+}
+
 // runClaude executes Claude CLI with the given prompt and handles streaming output.
 //
 // This is the core execution method used by all public Runner methods.
 // It displays a command header, streams events to the printer via handleEvent,
 // and displays a footer with timing and exit status.
-func (r *Runner) runClaude(ctx context.Context, prompt, label string) int {
+//
+// Spend is checked against the budget for storyKey (empty for raw prompts)
+// before the session starts and after every event. Going over budget cancels
+// the session and fails the run with a [budget.ExceededError]. The session is
+// archived under workflowName when an archive is set.
+func (r *Runner) runClaude(ctx context.Context, prompt, label, workflowName, storyKey string) int {
 	r.printer.CommandHeader(label, prompt, r.config.Output.TruncateLength)
 
+	r.lastSessionID = ""
+	r.lastStats = claude.ResultStats{}
+	r.lastTodos = r.storyTodos(storyKey)
+	r.todoStory = storyKey
+	r.lastErr = nil
 	startTime := time.Now()
 
+	if err := r.budget.Begin(storyKey); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		r.lastErr = err
+		r.printer.CommandFooter(time.Since(startTime), false, 1, r.lastTodos)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	recorder := r.startArchive(workflowName, storyKey, prompt, claude.RunOptionsFromContext(ctx))
+	if recorder != nil {
+		opts := claude.RunOptionsFromContext(ctx)
+		opts.Stderr = recorder.Stderr
+		ctx = claude.WithRunOptions(ctx, opts)
+	}
+
+	var conversation *followUps
+	if r.responder != nil {
+		conversation = newFollowUps(r.responder, workflowName, storyKey)
+		opts := claude.RunOptionsFromContext(ctx)
+		opts.Input = conversation.input
+		ctx = claude.WithRunOptions(ctx, opts)
+		defer conversation.input.Close()
+	}
+
+	var session *claude.Session
 	handler := func(event claude.Event) {
 		r.handleEvent(event)
+		if recorder != nil {
+			recorder.Event(event)
+		}
+		if conversation != nil {
+			if err := conversation.observe(ctx, event, r.lastErr); err != nil {
+				r.printer.Warning(fmt.Sprintf("sending follow-up message: %v", err))
+			}
+		}
+
+		if resultErr := claude.NewResultError(event); resultErr != nil && r.lastErr == nil {
+			r.lastErr = resultErr
+		}
+		if r.lastErr == nil {
+			if err := r.budget.Check(storyKey, sessionSpend(session)); err != nil {
+				r.lastErr = err
+				cancel()
+			}
+		}
 	}
 
-	exitCode, err := r.executor.ExecuteWithResult(ctx, prompt, handler)
+	var (
+		exitCode int
+		spend    budget.Spend
+	)
+	session, err := r.executor.Start(ctx, prompt)
+	if err == nil {
+		exitCode, err = session.Handle(handler)
+		spend = sessionSpend(session)
+	}
 	if err != nil {
-		fmt.Printf("Error executing claude: %v\n", err)
+		// A plain non-zero exit is reported by the footer and in LastError
+		var exitErr *claude.ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Printf("Error executing claude: %v\n", err)
+		}
+		if exitCode == 0 {
+			exitCode = 1
+		}
+		if r.lastErr == nil {
+			r.lastErr = err
+		}
+	}
+
+	r.budget.Commit(storyKey, spend)
+	if usageErr := claude.NewUsageLimitError(r.lastErr, time.Now()); usageErr != nil {
+		r.lastErr = usageErr
+	}
+	if r.lastErr != nil && r.lastErr != err {
+		fmt.Printf("Error: %v\n", r.lastErr)
 		exitCode = 1
 	}
+	if recorder != nil {
+		r.finishArchive(recorder, archive.Outcome{
+			ExitCode:   exitCode,
+			SessionErr: err,
+			Err:        r.lastErr,
+			SessionID:  r.lastSessionID,
+			Stats:      r.lastStats,
+		})
+	}
 
 	duration := time.Since(startTime)
-	r.printer.CommandFooter(duration, exitCode == 0, exitCode)
+	r.printer.CommandFooter(duration, exitCode == 0, exitCode, r.lastTodos)
 
 	return exitCode
 }
 
+// storyTodos returns the task list kept for storyKey, or nil if the runner
+// last worked on another story or a raw prompt.
+func (r *Runner) storyTodos(storyKey string) []claude.Todo {
+	if storyKey == "" || storyKey != r.todoStory {
+		return nil
+	}
+	return r.lastTodos
+}
+
+// startArchive starts archiving a session, returning nil when there is no
+// archive or it cannot be written.
+func (r *Runner) startArchive(workflowName, storyKey, prompt string, opts claude.RunOptions) *archive.Recorder {
+	if r.archive == nil {
+		return nil
+	}
+	recorder, err := r.archive.Start(storyKey, workflowName, prompt, opts)
+	if err != nil {
+		r.printer.Warning(fmt.Sprintf("not archiving this run: %v", err))
+		return nil
+	}
+	return recorder
+}
+
+// finishArchive records the outcome of an archived session and prunes the
+// archive.
+func (r *Runner) finishArchive(recorder *archive.Recorder, outcome archive.Outcome) {
+	if err := recorder.Finish(outcome); err != nil {
+		r.printer.Warning(fmt.Sprintf("archiving run to %s: %v", recorder.Dir(), err))
+	}
+	if _, err := r.archive.Prune(); err != nil {
+		r.printer.Warning(fmt.Sprintf("pruning run archive: %v", err))
+	}
+}
+
+// sessionSpend returns the spend of a session so far, from the totals it
+// keeps in [claude.Session.Stats].
+func sessionSpend(session *claude.Session) budget.Spend {
+	stats := session.Stats()
+	return budget.Spend{Tokens: stats.Tokens, CostUSD: stats.CostUSD}
+}
+
 // handleEvent routes a Claude streaming event to the appropriate printer method.
 //
 // Events are dispatched based on their type: session start/end, text output,
 // tool usage, and tool results. Each event type is formatted differently
 // by the printer for terminal display.
 func (r *Runner) handleEvent(event claude.Event) {
+	if event.SessionID != "" {
+		r.lastSessionID = event.SessionID
+	}
+	if event.Stats != nil {
+		r.lastStats = *event.Stats
+	}
+	if todos := event.Todos(); todos != nil {
+		r.lastTodos = todos
+	}
+
 	switch {
 	case event.SessionStarted:
 		r.printer.SessionStart()
@@ -165,11 +449,17 @@ func (r *Runner) handleEvent(event claude.Event) {
 	case event.IsText():
 		r.printer.Text(event.Text)
 
+	case event.IsThinking():
+		r.printer.Thinking(event.Thinking)
+
 	case event.IsToolUse():
-		r.printer.ToolUse(event.ToolName, event.ToolDescription, event.ToolCommand, event.ToolFilePath)
+		r.printer.ToolUse(event, r.config.Output.TruncateLines)
 
 	case event.IsToolResult():
-		r.printer.ToolResult(event.ToolStdout, event.ToolStderr, r.config.Output.TruncateLines)
+		r.printer.ToolResult(event.ToolUseID, event.ToolStdout, event.ToolStderr, r.config.Output.TruncateLines)
+
+	case event.IsParseError():
+		r.printer.Warning(event.Err.Error())
 
 	case event.SessionComplete:
 		r.printer.SessionEnd(0, true) // Duration handled elsewhere
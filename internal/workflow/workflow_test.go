@@ -3,11 +3,17 @@ package workflow
 import (
 	"bytes"
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"bmad-automate/internal/archive"
+	"bmad-automate/internal/budget"
 	"bmad-automate/internal/claude"
 	"bmad-automate/internal/config"
 	"bmad-automate/internal/output"
@@ -50,6 +56,219 @@ func TestRunner_RunSingle(t *testing.T) {
 	assert.Contains(t, mockExecutor.RecordedPrompts[0], "test-123")
 }
 
+func TestRunner_RunSingle_PassesWorkflowPermissions(t *testing.T) {
+	runner, mockExecutor, _ := setupTestRunner()
+	runner.config.Claude.Permissions = config.PermissionsConfig{Mode: "acceptEdits"}
+	runner.config.Workflows["git-commit"] = config.WorkflowConfig{
+		PromptTemplate: "commit {{.StoryKey}}",
+		Permissions: config.PermissionsConfig{
+			AllowedTools: []string{"Bash(git push:*)"},
+		},
+	}
+
+	ctx := context.Background()
+	runner.RunSingle(ctx, "code-review", "test-123")
+	runner.RunSingle(ctx, "git-commit", "test-123")
+
+	require.Len(t, mockExecutor.RecordedOptions, 2)
+	require.NotNil(t, mockExecutor.RecordedOptions[0].Permissions)
+	assert.Equal(t, "acceptEdits", mockExecutor.RecordedOptions[0].Permissions.Mode)
+	assert.Empty(t, mockExecutor.RecordedOptions[0].Permissions.AllowedTools)
+
+	require.NotNil(t, mockExecutor.RecordedOptions[1].Permissions)
+	assert.Equal(t, "acceptEdits", mockExecutor.RecordedOptions[1].Permissions.Mode)
+	assert.Equal(t, []string{"Bash(git push:*)"}, mockExecutor.RecordedOptions[1].Permissions.AllowedTools)
+}
+
+func TestRunner_RunSingle_PassesWorkflowTimeouts(t *testing.T) {
+	runner, mockExecutor, _ := setupTestRunner()
+	runner.config.Workflows["code-review"] = config.WorkflowConfig{
+		PromptTemplate: "review {{.StoryKey}}",
+		Timeout:        45 * time.Minute,
+		IdleTimeout:    10 * time.Minute,
+	}
+
+	runner.RunSingle(context.Background(), "code-review", "test-123")
+	runner.ResumeSingle(context.Background(), "code-review", "test-123", "sess-1")
+
+	require.Len(t, mockExecutor.RecordedOptions, 2)
+	for _, opts := range mockExecutor.RecordedOptions {
+		assert.Equal(t, 45*time.Minute, opts.Timeout)
+		assert.Equal(t, 10*time.Minute, opts.IdleTimeout)
+	}
+}
+
+func TestRunner_RunSingle_PassesWorkflowAgent(t *testing.T) {
+	runner, mockExecutor, buf := setupTestRunner()
+	runner.config.Agents = map[string]config.AgentConfig{"codex": {Command: "codex"}}
+	runner.config.Workflows["code-review"] = config.WorkflowConfig{
+		PromptTemplate: "review {{.StoryKey}}",
+		Agent:          "codex",
+	}
+
+	ctx := context.Background()
+	runner.RunSingle(ctx, "dev-story", "test-123")
+	runner.RunSingle(ctx, "code-review", "test-123")
+	runner.ResumeSingle(ctx, "code-review", "test-123", "sess-1")
+
+	require.Len(t, mockExecutor.RecordedOptions, 3)
+	assert.Empty(t, mockExecutor.RecordedOptions[0].Agent)
+	assert.Equal(t, "codex", mockExecutor.RecordedOptions[1].Agent)
+	assert.Equal(t, "codex", mockExecutor.RecordedOptions[2].Agent)
+	assert.Contains(t, buf.String(), "code-review: test-123 [codex]")
+}
+
+func TestRunner_RunSingle_TimeoutIsReported(t *testing.T) {
+	runner, _, _ := setupTestRunner()
+	timeoutErr := &claude.TimeoutError{Idle: true, After: 10 * time.Minute}
+	runner.executor = &claude.MockExecutor{Error: timeoutErr}
+
+	exitCode := runner.RunSingle(context.Background(), "code-review", "test-123")
+
+	assert.NotZero(t, exitCode)
+	assert.ErrorIs(t, runner.LastError(), claude.ErrTimeout)
+}
+
+func TestRunner_RunSingle_ResultErrorIsReported(t *testing.T) {
+	runner, mockExecutor, _ := setupTestRunner()
+	mockExecutor.Events = []claude.Event{
+		{Type: claude.EventTypeSystem, SessionStarted: true},
+		{
+			Type: claude.EventTypeResult, SessionComplete: true,
+			Stats: &claude.ResultStats{IsError: true, Result: "API Error: 529 Overloaded"},
+		},
+	}
+	mockExecutor.ExitCode = 1
+
+	exitCode := runner.RunSingle(context.Background(), "dev-story", "test-123")
+
+	assert.Equal(t, 1, exitCode)
+	var resultErr *claude.ResultError
+	require.ErrorAs(t, runner.LastError(), &resultErr)
+	assert.Equal(t, claude.FailureOverloaded, claude.Classify(runner.LastError()))
+}
+
+func TestRunner_RunSingle_UsageLimitIsReported(t *testing.T) {
+	runner, mockExecutor, _ := setupTestRunner()
+	mockExecutor.Events = []claude.Event{
+		{Type: claude.EventTypeSystem, SessionStarted: true},
+		{
+			Type: claude.EventTypeResult, SessionComplete: true,
+			Stats: &claude.ResultStats{IsError: true, Result: "Claude AI usage limit reached|1760025600"},
+		},
+	}
+	mockExecutor.ExitCode = 1
+
+	runner.RunSingle(context.Background(), "dev-story", "test-123")
+
+	var usageErr *claude.UsageLimitError
+	require.ErrorAs(t, runner.LastError(), &usageErr)
+	assert.True(t, usageErr.ResetAt.Equal(time.Unix(1760025600, 0)))
+	var resultErr *claude.ResultError
+	assert.ErrorAs(t, runner.LastError(), &resultErr, "the result error stays in the chain")
+}
+
+func TestRunner_LastSessionID(t *testing.T) {
+	runner, mockExecutor, _ := setupTestRunner()
+	assert.Empty(t, runner.LastSessionID())
+
+	mockExecutor.Events = []claude.Event{
+		{Type: claude.EventTypeSystem, SessionStarted: true, SessionID: "sess-1"},
+		{Type: claude.EventTypeResult, SessionComplete: true, SessionID: "sess-1"},
+	}
+	runner.RunSingle(context.Background(), "dev-story", "test-123")
+	assert.Equal(t, "sess-1", runner.LastSessionID())
+
+	mockExecutor.Events = nil
+	runner.RunSingle(context.Background(), "dev-story", "test-123")
+	assert.Empty(t, runner.LastSessionID(), "session ID is reset for each run")
+}
+
+func TestRunner_LastStats(t *testing.T) {
+	runner, mockExecutor, _ := setupTestRunner()
+	mockExecutor.Events = []claude.Event{
+		{Type: claude.EventTypeSystem, SessionStarted: true},
+		{Type: claude.EventTypeResult, SessionComplete: true, Stats: &claude.ResultStats{
+			TotalCostUSD: 0.5,
+			Usage:        claude.Usage{InputTokens: 100, OutputTokens: 50},
+			NumTurns:     3,
+		}},
+	}
+
+	runner.RunSingle(context.Background(), "dev-story", "test-123")
+
+	stats := runner.LastStats()
+	assert.InDelta(t, 0.5, stats.TotalCostUSD, 1e-9)
+	assert.Equal(t, int64(150), stats.Usage.TotalTokens())
+	assert.Equal(t, 3, stats.NumTurns)
+}
+
+func TestRunner_LastTodos(t *testing.T) {
+	runner, mockExecutor, buf := setupTestRunner()
+	todoWrite := func(todos ...claude.Todo) claude.Event {
+		return claude.Event{Type: claude.EventTypeAssistant, ToolName: "TodoWrite", ToolInput: &claude.TodoWriteInput{Todos: todos}}
+	}
+	mockExecutor.Events = []claude.Event{
+		{Type: claude.EventTypeSystem, SessionStarted: true},
+		todoWrite(claude.Todo{Content: "Write parser", Status: claude.TodoInProgress}, claude.Todo{Content: "Write tests", Status: claude.TodoPending}),
+		todoWrite(claude.Todo{Content: "Write parser", Status: claude.TodoCompleted}, claude.Todo{Content: "Write tests", Status: claude.TodoPending}),
+		{Type: claude.EventTypeResult, SessionComplete: true},
+	}
+
+	runner.RunSingle(context.Background(), "dev-story", "test-123")
+
+	assert.Equal(t, []claude.Todo{
+		{Content: "Write parser", Status: claude.TodoCompleted},
+		{Content: "Write tests", Status: claude.TodoPending},
+	}, runner.LastTodos(), "the last update is kept")
+	assert.Contains(t, buf.String(), "Tasks: 1/2 done")
+
+	mockExecutor.Events = nil
+	runner.RunSingle(context.Background(), "code-review", "test-123")
+	assert.Len(t, runner.LastTodos(), 2, "the task list carries over between the story's workflows")
+
+	runner.RunSingle(context.Background(), "dev-story", "test-456")
+	assert.Nil(t, runner.LastTodos(), "the task list is reset for another story")
+
+	mockExecutor.Events = []claude.Event{todoWrite(claude.Todo{Content: "Write parser", Status: claude.TodoPending})}
+	runner.RunSingle(context.Background(), "dev-story", "test-456")
+	require.Len(t, runner.LastTodos(), 1)
+
+	mockExecutor.Events = nil
+	runner.RunRaw(context.Background(), "hello")
+	assert.Nil(t, runner.LastTodos(), "the task list is reset for a raw prompt")
+}
+
+func TestRunner_RunFullCycle_ReportsUsage(t *testing.T) {
+	runner, mockExecutor, buf := setupTestRunner()
+	mockExecutor.Events = []claude.Event{
+		{Type: claude.EventTypeResult, SessionComplete: true, Stats: &claude.ResultStats{
+			TotalCostUSD: 0.25,
+			Usage:        claude.Usage{InputTokens: 1000},
+		}},
+	}
+
+	exitCode := runner.RunFullCycle(context.Background(), "test-story")
+
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, buf.String(), "1.0k tokens, $0.25")
+	assert.Contains(t, buf.String(), "4.0k tokens, $1.00")
+}
+
+func TestRunner_ResumeSingle(t *testing.T) {
+	runner, mockExecutor, buf := setupTestRunner()
+
+	exitCode := runner.ResumeSingle(context.Background(), "dev-story", "test-123", "sess-1")
+
+	assert.Equal(t, 0, exitCode)
+	require.Len(t, mockExecutor.RecordedPrompts, 1)
+	assert.Equal(t, runner.config.Claude.ResumePrompt, mockExecutor.RecordedPrompts[0])
+	require.Len(t, mockExecutor.RecordedOptions, 1)
+	assert.Equal(t, "sess-1", mockExecutor.RecordedOptions[0].ResumeSessionID)
+	assert.NotNil(t, mockExecutor.RecordedOptions[0].Permissions, "workflow permissions still apply")
+	assert.Contains(t, buf.String(), "dev-story: test-123 (resume)")
+}
+
 func TestRunner_RunSingle_UnknownWorkflow(t *testing.T) {
 	runner, _, _ := setupTestRunner()
 
@@ -121,8 +340,10 @@ type failOnNthCallExecutor struct {
 	current *int
 }
 
-func (f *failOnNthCallExecutor) Execute(ctx context.Context, prompt string) (<-chan claude.Event, error) {
-	return f.inner.Execute(ctx, prompt)
+func (f *failOnNthCallExecutor) Start(ctx context.Context, prompt string) (*claude.Session, error) {
+	return claude.StartSession(ctx, func(ctx context.Context, handler claude.EventHandler) (int, error) {
+		return f.ExecuteWithResult(ctx, prompt, handler)
+	}), nil
 }
 
 func (f *failOnNthCallExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler claude.EventHandler) (int, error) {
@@ -165,6 +386,18 @@ func TestRunner_HandleEvent(t *testing.T) {
 		ToolStdout: "file1.go",
 	})
 	assert.Contains(t, buf.String(), "file1.go")
+
+	buf.Reset()
+
+	// Test thinking
+	runner.handleEvent(claude.Event{Type: claude.EventTypeAssistant, Thinking: "Let me check the tests"})
+	assert.Contains(t, buf.String(), "Thinking: Let me check the tests")
+
+	buf.Reset()
+
+	// Test parse error
+	runner.handleEvent(claude.Event{Type: claude.EventTypeParseError, Err: errors.New("line 3: invalid stream-json line")})
+	assert.Contains(t, buf.String(), "Warning: line 3: invalid stream-json line")
 }
 
 func TestStepResult_IsSuccess(t *testing.T) {
@@ -198,3 +431,233 @@ func TestNewQueueRunner(t *testing.T) {
 
 // Note: QueueRunner.RunQueueWithStatus tests are in internal/cli/queue_test.go
 // since they require status.Reader and full CLI integration testing
+
+// streamingExecutor emits events until the context is canceled, like a real
+// session. A session whose events do not end with a result keeps running
+// until it is canceled.
+type streamingExecutor struct {
+	events   []claude.Event
+	canceled bool
+	prompts  int
+}
+
+func (s *streamingExecutor) Start(ctx context.Context, prompt string) (*claude.Session, error) {
+	return claude.StartSession(ctx, func(ctx context.Context, handler claude.EventHandler) (int, error) {
+		return s.ExecuteWithResult(ctx, prompt, handler)
+	}), nil
+}
+
+func (s *streamingExecutor) ExecuteWithResult(ctx context.Context, prompt string, handler claude.EventHandler) (int, error) {
+	s.prompts++
+	for _, event := range s.events {
+		if ctx.Err() != nil {
+			s.canceled = true
+			return -1, nil
+		}
+		handler(event)
+	}
+	if len(s.events) == 0 || !s.events[len(s.events)-1].SessionComplete {
+		<-ctx.Done()
+		s.canceled = true
+		return -1, nil
+	}
+	return 0, nil
+}
+
+func assistantUsage(id string, tokens int64) claude.Event {
+	return claude.Event{
+		Type:      claude.EventTypeAssistant,
+		Text:      "working",
+		MessageID: id,
+		Usage:     &claude.Usage{InputTokens: tokens},
+	}
+}
+
+func TestRunner_Budget_StepTokensCancelSession(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Budget.Step = config.BudgetLimitsConfig{MaxTokens: 1500}
+	executor := &streamingExecutor{events: []claude.Event{
+		assistantUsage("msg-1", 1000),
+		assistantUsage("msg-1", 1000), // same message repeats its usage
+		assistantUsage("msg-2", 1000),
+		assistantUsage("msg-3", 1000),
+	}}
+	runner := NewRunner(executor, output.NewPrinterWithWriter(&bytes.Buffer{}), cfg)
+
+	exitCode := runner.RunSingle(context.Background(), "dev-story", "story-1")
+
+	assert.Equal(t, 1, exitCode)
+	assert.True(t, executor.canceled, "session should be canceled once over budget")
+	assert.ErrorIs(t, runner.LastError(), budget.ErrExceeded)
+
+	var exceeded *budget.ExceededError
+	require.ErrorAs(t, runner.LastError(), &exceeded)
+	assert.Equal(t, budget.ScopeStep, exceeded.Scope)
+	// The session's totals may already include events not yet handled.
+	assert.GreaterOrEqual(t, exceeded.Spent.Tokens, int64(2000))
+	assert.LessOrEqual(t, exceeded.Spent.Tokens, int64(3000), "a repeated message is counted once")
+}
+
+func TestRunner_Budget_CostCheckedOnResult(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Budget.Step = config.BudgetLimitsConfig{MaxCostUSD: 1}
+	executor := &streamingExecutor{events: []claude.Event{
+		{Type: claude.EventTypeResult, SessionComplete: true, Stats: &claude.ResultStats{TotalCostUSD: 1.5}},
+	}}
+	runner := NewRunner(executor, output.NewPrinterWithWriter(&bytes.Buffer{}), cfg)
+
+	exitCode := runner.RunSingle(context.Background(), "dev-story", "story-1")
+
+	assert.Equal(t, 1, exitCode, "a step that finished over budget is still failed")
+	assert.ErrorIs(t, runner.LastError(), budget.ErrExceeded)
+}
+
+func TestRunner_Budget_StoryLimitRefusesNextStep(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Budget.Story = config.BudgetLimitsConfig{MaxCostUSD: 1}
+	executor := &streamingExecutor{events: []claude.Event{
+		{Type: claude.EventTypeResult, SessionComplete: true, Stats: &claude.ResultStats{TotalCostUSD: 0.6}},
+	}}
+	runner := NewRunner(executor, output.NewPrinterWithWriter(&bytes.Buffer{}), cfg)
+	ctx := context.Background()
+
+	assert.Equal(t, 0, runner.RunSingle(ctx, "create-story", "story-1"))
+	assert.NoError(t, runner.LastError())
+
+	assert.Equal(t, 1, runner.RunSingle(ctx, "dev-story", "story-1"), "second step pushes story over budget")
+	assert.ErrorIs(t, runner.LastError(), budget.ErrExceeded)
+
+	assert.Equal(t, 1, runner.RunSingle(ctx, "code-review", "story-1"), "story budget already spent")
+	assert.Equal(t, 2, executor.prompts, "no session started once budget is spent")
+
+	assert.Equal(t, 0, runner.RunSingle(ctx, "create-story", "story-2"), "other stories have their own budget")
+}
+
+// replayRunner returns a runner whose sessions replay the named transcripts
+// in testdata/transcripts.
+func replayRunner(t *testing.T, names ...string) (*Runner, *bytes.Buffer) {
+	t.Helper()
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join("testdata", "transcripts", name+claude.TranscriptExt)
+	}
+	buf := &bytes.Buffer{}
+	executor := claude.NewReplayExecutor(claude.ReplayConfig{Transcripts: paths})
+	return NewRunner(executor, output.NewPrinterWithWriter(buf), config.DefaultConfig()), buf
+}
+
+func TestRunner_Replay_CapturedSuccess(t *testing.T) {
+	runner, buf := replayRunner(t, "dev-story-success")
+
+	exitCode := runner.RunSingle(context.Background(), "dev-story", "1-2")
+
+	assert.Equal(t, 0, exitCode)
+	assert.NoError(t, runner.LastError())
+	assert.Equal(t, "3f1c2a9e-5d4b-4a7e-9c21-7b0e8d6f4a10", runner.LastSessionID())
+	assert.InDelta(t, 0.0734, runner.LastStats().TotalCostUSD, 1e-9)
+	assert.Contains(t, buf.String(), "go test ./...")
+	assert.Contains(t, buf.String(), "all tests pass")
+}
+
+func TestRunner_Replay_CapturedOverload(t *testing.T) {
+	runner, _ := replayRunner(t, "dev-story-overloaded")
+
+	exitCode := runner.RunSingle(context.Background(), "dev-story", "1-2")
+
+	assert.Equal(t, 1, exitCode)
+	var resultErr *claude.ResultError
+	require.ErrorAs(t, runner.LastError(), &resultErr)
+	assert.Equal(t, claude.FailureOverloaded, claude.Classify(runner.LastError()))
+}
+
+func TestRunner_Archive_RecordsSessions(t *testing.T) {
+	runner, _ := replayRunner(t, "dev-story-success", "dev-story-overloaded")
+	runs := archive.New(archive.Config{Dir: t.TempDir()})
+	runner.SetArchive(runs)
+
+	require.Equal(t, 0, runner.RunSingle(context.Background(), "dev-story", "1-2"))
+	require.Equal(t, 1, runner.RunSingle(context.Background(), "dev-story", "1-2"))
+
+	list, err := runs.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	ok, failed := list[0], list[1]
+	assert.Equal(t, "1-2", ok.StoryKey)
+	assert.Equal(t, "dev-story", ok.Workflow)
+	assert.True(t, ok.Success)
+	assert.Equal(t, "3f1c2a9e-5d4b-4a7e-9c21-7b0e8d6f4a10", ok.SessionID)
+	assert.InDelta(t, 0.0734, ok.Stats.TotalCostUSD, 1e-9)
+
+	assert.False(t, failed.Success)
+	assert.Equal(t, 1, failed.ExitCode)
+	assert.Equal(t, runner.LastError().Error(), failed.Error)
+
+	// The archived transcript replays like the original session
+	replay, buf := replayRunner(t)
+	replay.executor = claude.NewReplayExecutor(claude.ReplayConfig{Transcripts: []string{failed.Path(archive.EventsFile)}})
+	assert.Equal(t, 1, replay.RunSingle(context.Background(), "dev-story", "1-2"))
+	assert.Equal(t, claude.FailureOverloaded, claude.Classify(replay.LastError()))
+	assert.Contains(t, buf.String(), "I'll start by reading the story file.")
+}
+
+func TestRunner_Archive_PassesStderr(t *testing.T) {
+	runner, _, _ := setupTestRunner()
+	runner.executor = &stderrExecutor{lines: []string{"warning: one", "warning: two"}}
+	runs := archive.New(archive.Config{Dir: t.TempDir()})
+	runner.SetArchive(runs)
+
+	require.Equal(t, 0, runner.RunRaw(context.Background(), "hello"))
+
+	list, err := runs.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "raw", list[0].Workflow)
+	stderr, err := os.ReadFile(list[0].Path(archive.StderrFile))
+	require.NoError(t, err)
+	assert.Equal(t, "warning: one\nwarning: two\n", string(stderr))
+}
+
+func TestRunner_Archive_Unwritable(t *testing.T) {
+	runner, _, buf := setupTestRunner()
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	runner.SetArchive(archive.New(archive.Config{Dir: file}))
+
+	assert.Equal(t, 0, runner.RunSingle(context.Background(), "dev-story", "1-2"), "archive failures do not fail the run")
+	assert.Contains(t, buf.String(), "not archiving this run")
+}
+
+// stderrExecutor writes lines to the session's stderr handler.
+type stderrExecutor struct {
+	lines []string
+}
+
+func (s *stderrExecutor) Start(ctx context.Context, prompt string) (*claude.Session, error) {
+	return claude.StartSession(ctx, func(ctx context.Context, handler claude.EventHandler) (int, error) {
+		return s.ExecuteWithResult(ctx, prompt, handler)
+	}), nil
+}
+
+func (s *stderrExecutor) ExecuteWithResult(ctx context.Context, _ string, _ claude.EventHandler) (int, error) {
+	for _, line := range s.lines {
+		claude.RunOptionsFromContext(ctx).Stderr(line)
+	}
+	return 0, nil
+}
+
+func TestRunner_SetResponder_InputUnsupported(t *testing.T) {
+	runner, mockExecutor, _ := setupTestRunner()
+	asked := false
+	runner.SetResponder(func(ctx context.Context, turn Turn) (string, bool) {
+		asked = true
+		return "Yes", true
+	})
+
+	exitCode := runner.RunSingle(context.Background(), "dev-story", "1-1")
+
+	assert.Equal(t, 0, exitCode)
+	require.Len(t, mockExecutor.RecordedOptions, 1)
+	assert.NotNil(t, mockExecutor.RecordedOptions[0].Input, "the session is given an input")
+	assert.False(t, asked, "the responder is not asked when the executor does not accept input")
+}